@@ -0,0 +1,20 @@
+package examples
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/omriharel/deej/pkg/deej"
+)
+
+// LogActionName is the name the example action is registered under. map a button to it
+// in the config, i.e. "3: log:hello there", to have that button log "hello there" when pressed
+const LogActionName = "log"
+
+// RegisterLogAction registers a button action that logs its argument. custom actions get the button
+// event that triggered them along with their argument, and run whenever a mapped button is pressed
+func RegisterLogAction(logger *zap.SugaredLogger) error {
+	return deej.RegisterAction(LogActionName, func(event deej.ButtonPressEvent, argument string) error {
+		logger.Infow("Button pressed", "button", event.ButtonID, "value", event.ButtonValue, "message", argument)
+		return nil
+	})
+}
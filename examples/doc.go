@@ -0,0 +1,6 @@
+// Package examples shows how to build on deej as a library: listening to slider events,
+// adding custom button actions and talking to devices over a custom transport.
+//
+// it's meant to be read (and copied from) by integrators, and is kept compiling by its tests,
+// so it doesn't fall behind the deej package itself
+package examples
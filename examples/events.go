@@ -0,0 +1,34 @@
+package examples
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/omriharel/deej/pkg/deej"
+)
+
+// LogSliderMoves logs every slider move deej sees, until the event channel is closed.
+// it's the simplest possible event consumer - anything reacting to sliders starts out like this
+func LogSliderMoves(d *deej.Deej, logger *zap.SugaredLogger) {
+	sliderEvents := d.SubscribeToSliderMoveEvents()
+
+	go func() {
+		for event := range sliderEvents {
+			logger.Infow("Slider moved", "slider", event.SliderID, "percent", event.PercentValue)
+		}
+	}()
+}
+
+// LogButtonPresses logs every button press and release deej sees. button events are raw value changes,
+// delivered regardless of whatever actions the buttons are mapped to
+func LogButtonPresses(d *deej.Deej, logger *zap.SugaredLogger) {
+	buttonEvents := d.SubscribeToButtonPressEvents()
+
+	go func() {
+		for event := range buttonEvents {
+			logger.Infow("Button changed",
+				"button", event.ButtonID,
+				"previousValue", event.PreviousValue,
+				"value", event.ButtonValue)
+		}
+	}()
+}
@@ -0,0 +1,49 @@
+package examples_test
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/omriharel/deej/examples"
+	"github.com/omriharel/deej/pkg/deej"
+)
+
+func ExampleRegisterStdioTransport() {
+	if err := examples.RegisterStdioTransport(); err != nil {
+		panic(err)
+	}
+
+	// with connection_type set to "stdio", deej now reads its lines from the terminal
+}
+
+func ExampleRegisterLogAction() {
+	if err := examples.RegisterLogAction(zap.NewExample().Sugar()); err != nil {
+		panic(err)
+	}
+
+	// buttons mapped to "log:<message>" now log their message when pressed
+}
+
+func ExampleLogSliderMoves() {
+	logger, err := deej.NewLogger("")
+	if err != nil {
+		panic(err)
+	}
+
+	// extensions need to be registered before deej is created
+	if err := examples.RegisterStdioTransport(); err != nil {
+		panic(err)
+	}
+
+	d, err := deej.NewDeej(logger, false)
+	if err != nil {
+		panic(err)
+	}
+
+	examples.LogSliderMoves(d, logger)
+	examples.LogButtonPresses(d, logger)
+
+	// this blocks until deej is stopped
+	if err := d.Initialize(); err != nil {
+		panic(err)
+	}
+}
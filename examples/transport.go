@@ -0,0 +1,31 @@
+package examples
+
+import (
+	"io"
+	"os"
+
+	"github.com/omriharel/deej/pkg/deej"
+)
+
+// StdioConnectionType is the connection type the example transport is registered under. set connection_type
+// to it in the config, then type lines like "512|1023|0" into deej's terminal to move sliders without a device
+const StdioConnectionType = "stdio"
+
+// stdioConn reads lines from deej's standard input, and writes anything deej sends to its standard output
+type stdioConn struct {
+	io.Reader
+	io.Writer
+}
+
+// the standard streams belong to the process, so there's nothing to close
+func (stdioConn) Close() error {
+	return nil
+}
+
+// RegisterStdioTransport registers a transport that talks to the terminal instead of a device. real transports
+// work the same way: they get the user's connection info, and return a connection carrying deej-formatted lines
+func RegisterStdioTransport() error {
+	return deej.RegisterTransport(StdioConnectionType, func(info deej.ConnectionInfo) (io.ReadWriteCloser, error) {
+		return stdioConn{Reader: os.Stdin, Writer: os.Stdout}, nil
+	})
+}
@@ -0,0 +1,140 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/micmonay/keybd_event"
+	"go.uber.org/zap"
+)
+
+// a button's actions are steps, done one after the other: each key is pressed on its own, in order, so
+// [CTRL+C, ALT+TAB, CTRL+V] copies, switches windows and pastes. delay:<ms> steps wait in between, for apps that
+// need a moment to catch up. actions that wait anywhere along the way (their macros included) run in the
+// background, so the device's other sliders and buttons don't wait on them. panicking, reconnecting or the
+// device's buttons changing drop whatever's still waiting, and stop whatever's running at its next step
+const (
+	actionDelayPrefix = "delay:"
+
+	maxActionDelay = time.Minute
+)
+
+var errInvalidActionDelay = errors.New("expected milliseconds, up to a minute")
+
+// parseActionDelay returns how long a delay:<ms> step waits
+func parseActionDelay(entry string) (time.Duration, error) {
+	milliseconds, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(entry, actionDelayPrefix)))
+	delay := time.Duration(milliseconds) * time.Millisecond
+
+	if err != nil || delay <= 0 || delay > maxActionDelay {
+		return 0, errInvalidActionDelay
+	}
+
+	return delay, nil
+}
+
+// stepWaiter hands out the channel delay steps give up waiting on, and closes it when pending actions are dropped
+type stepWaiter struct {
+	lock   sync.Locker
+	cancel chan bool
+}
+
+func newStepWaiter() *stepWaiter {
+	return &stepWaiter{
+		lock:   &sync.Mutex{},
+		cancel: make(chan bool),
+	}
+}
+
+// cancelled returns a channel that's closed once the steps started now should stop waiting
+func (w *stepWaiter) cancelled() chan bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.cancel
+}
+
+// reset stops every step that's waiting right now
+func (w *stepWaiter) reset() {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	close(w.cancel)
+	w.cancel = make(chan bool)
+}
+
+// stepsCancelled returns whether the steps waiting on cancel should stop
+func stepsCancelled(cancel chan bool) bool {
+	select {
+	case <-cancel:
+		return true
+	default:
+		return false
+	}
+}
+
+// hasDelays returns whether the actions, or the macros they run, wait anywhere along the way
+func (sio *SerialIO) hasDelays(actions []buttonAction) bool {
+	for _, action := range actions {
+		switch action.kind {
+		case buttonActionDelay:
+			return true
+
+		// macros can't run other macros, so there's only one level to look into
+		case buttonActionMacro:
+			for _, step := range sio.deej.config.Macros[action.macro] {
+				if step.kind == buttonActionDelay {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// performButtonActions does what the given actions say, one after the other
+func (sio *SerialIO) performButtonActions(logger *zap.SugaredLogger, notifier Notifier, buttonEvent ButtonPressEvent, actions []buttonAction) {
+	cancel := sio.steps.cancelled()
+
+	if sio.hasDelays(actions) {
+		go sio.performSteps(logger, notifier, buttonEvent, actions, cancel)
+		return
+	}
+
+	sio.performSteps(logger, notifier, buttonEvent, actions, cancel)
+}
+
+// keyPresser presses the keys of a single run of steps. the keyboard is only set up once there's something
+// to press with it, and reused for every key after that
+type keyPresser struct {
+	kb *keybd_event.KeyBonding
+}
+
+// press presses a key action's key, along with its modifier keys
+func (p *keyPresser) press(action buttonAction) error {
+	if p.kb == nil {
+		bonding, err := keybd_event.NewKeyBonding()
+		if err != nil {
+			return fmt.Errorf("create key bonding: %w", err)
+		}
+
+		p.kb = &bonding
+	}
+
+	p.kb.SetKeys(action.keyCode)
+	p.kb.HasCTRL(action.ctrl)
+	p.kb.HasSHIFT(action.shift)
+	p.kb.HasALT(action.alt)
+	p.kb.HasSuper(action.win)
+
+	if err := p.kb.Launching(); err != nil {
+		return fmt.Errorf("press key: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,155 @@
+package deej
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// accessibility mode: when announce_volume is on, a slider that moved by at least announce_volume_step
+// has its targets and new volume read out loud once it comes to rest, i.e. "spotify, 40 percent". this is
+// for users who build a deej box precisely because on-screen mixers are hard (or impossible) for them to use
+const (
+	defaultAnnounceVolumeStep = 0.1
+
+	// how long a slider has to stay put before it's announced, so only where it ends up gets read out
+	announceSettleTime = 400 * time.Millisecond
+)
+
+// speaker reads text out loud, cutting off whatever it was saying before
+type speaker interface {
+	speak(text string) error
+}
+
+type volumeAnnouncer struct {
+	deej   *Deej
+	logger *zap.SugaredLogger
+
+	// the last value announced for each slider (or the first one seen), and the latest one waiting to be
+	lock      sync.Locker
+	announced map[int]float32
+	latest    map[int]float32
+	pending   map[int]*time.Timer
+
+	// created the first time there's something to say, so nothing's spoken to unless the user wants it
+	speakerLock sync.Locker
+	speaker     speaker
+	speakerErr  error
+}
+
+func newVolumeAnnouncer(deej *Deej, logger *zap.SugaredLogger) *volumeAnnouncer {
+	return &volumeAnnouncer{
+		deej:        deej,
+		logger:      logger.Named("announcer"),
+		lock:        &sync.Mutex{},
+		announced:   map[int]float32{},
+		latest:      map[int]float32{},
+		pending:     map[int]*time.Timer{},
+		speakerLock: &sync.Mutex{},
+	}
+}
+
+func (a *volumeAnnouncer) sliderMoved(event SliderMoveEvent) {
+	if !a.deej.config.AnnounceVolume {
+		return
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	// the first value we see is just where the slider was when deej started, that's nothing to talk about
+	last, seen := a.announced[event.SliderID]
+	if !seen {
+		a.announced[event.SliderID] = event.PercentValue
+		return
+	}
+
+	a.latest[event.SliderID] = event.PercentValue
+
+	if timer, ok := a.pending[event.SliderID]; ok {
+		timer.Reset(announceSettleTime)
+		return
+	}
+
+	if !a.significant(last, event.PercentValue) {
+		return
+	}
+
+	a.pending[event.SliderID] = time.AfterFunc(announceSettleTime, func() {
+		a.announce(event.SliderID)
+	})
+}
+
+func (a *volumeAnnouncer) announce(sliderID int) {
+	a.lock.Lock()
+
+	value := a.latest[sliderID]
+	delete(a.pending, sliderID)
+
+	// the slider may have come back to where it was
+	if !a.significant(a.announced[sliderID], value) {
+		a.lock.Unlock()
+		return
+	}
+
+	a.announced[sliderID] = value
+	a.lock.Unlock()
+
+	targets, _ := a.deej.config.SliderMapping.get(sliderID)
+	text := fmt.Sprintf("%s, %d percent", a.spokenTargets(sliderID, targets), int(math.Round(float64(value)*100)))
+
+	speaker, err := a.getSpeaker()
+	if err != nil {
+		return
+	}
+
+	a.logger.Debugw("Announcing slider volume", "slider", sliderID, "text", text)
+
+	if err := speaker.speak(text); err != nil {
+		a.logger.Warnw("Failed to announce slider volume", "text", text, "error", err)
+	}
+}
+
+func (a *volumeAnnouncer) significant(from float32, to float32) bool {
+	return math.Abs(float64(to-from)) >= float64(a.deej.config.AnnounceVolumeStep)-0.0001
+}
+
+// spokenTargets turns a slider's targets into something that sounds right when read out loud
+func (a *volumeAnnouncer) spokenTargets(sliderID int, targets []string) string {
+	names := []string{}
+
+	for _, target := range targets {
+		name := strings.ToLower(target)
+		name = strings.TrimPrefix(name, specialTargetTransformPrefix)
+		name = strings.TrimPrefix(name, channelTargetPrefix)
+		name = strings.TrimSuffix(name, ".exe")
+
+		names = append(names, name)
+	}
+
+	if len(names) == 0 {
+		return fmt.Sprintf("slider %d", sliderID+a.deej.config.MappingIndexBase)
+	}
+
+	return strings.Join(names, " and ")
+}
+
+func (a *volumeAnnouncer) getSpeaker() (speaker, error) {
+	a.speakerLock.Lock()
+	defer a.speakerLock.Unlock()
+
+	if a.speaker == nil && a.speakerErr == nil {
+		a.speaker, a.speakerErr = newSpeaker(a.logger)
+
+		if a.speakerErr != nil {
+			a.logger.Warnw("Failed to set up text-to-speech, volume won't be announced", "error", a.speakerErr)
+			a.deej.notifier.Notify("Can't announce volume!", "Text-to-speech isn't available, check the logs for details.")
+		}
+	}
+
+	return a.speaker, a.speakerErr
+}
@@ -0,0 +1,115 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// the route button action moves a single app to another output device, leaving the default device (and every
+// other app) alone, i.e. "route:game.exe:Headphones". with a list of devices, i.e. "route:game.exe:[Speakers,Headphones]",
+// each press moves the app to the next connected one. devices are matched like cycleout's are
+const actionRoutePrefix = "route:"
+
+var errAppRoutingUnsupported = errors.New("routing apps to output devices isn't supported here")
+
+// parseRouteAction reads the app and device names out of a route action's argument
+func parseRouteAction(argument string) (string, []string, error) {
+	parts := strings.SplitN(argument, ":", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+		return "", nil, fmt.Errorf("expected %q, got %q", "<app>:<device>", argument)
+	}
+
+	app, devices := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+
+	// a single device needs no brackets
+	if !strings.HasPrefix(devices, "[") {
+		if devices == "" {
+			return "", nil, errors.New("no device given")
+		}
+
+		return strings.ToLower(app), []string{devices}, nil
+	}
+
+	names, err := parseCycleOutputList(cycleOutputListPrefix + devices)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return strings.ToLower(app), names, nil
+}
+
+// routeApp moves all of an app's sessions to the device after the one it was last routed to (out of the given
+// names), and returns it. apps that haven't been routed yet go to the first connected one
+func (m *sessionMap) routeApp(app string, names []string) (OutputDevice, error) {
+	router, ok := m.sessionFinder.(appRouter)
+	if !ok {
+		return OutputDevice{}, errAppRoutingUnsupported
+	}
+
+	switcher, ok := m.sessionFinder.(outputDeviceSwitcher)
+	if !ok {
+		return OutputDevice{}, errAppRoutingUnsupported
+	}
+
+	sessions, ok := m.get(app)
+	if !ok {
+		return OutputDevice{}, fmt.Errorf("no audio sessions for %s", app)
+	}
+
+	devices, err := switcher.OutputDevices()
+	if err != nil {
+		return OutputDevice{}, fmt.Errorf("list output devices: %w", err)
+	}
+
+	candidates := []OutputDevice{}
+
+	for _, name := range names {
+		if device, ok := matchOutputDevice(devices, name); ok {
+			candidates = append(candidates, device)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return OutputDevice{}, errors.New("none of the listed output devices are connected")
+	}
+
+	m.lock.Lock()
+	lastID := m.appRoutes[app]
+	m.lock.Unlock()
+
+	next := candidates[0]
+
+	for idx, candidate := range candidates {
+		if candidate.ID == lastID {
+			next = candidates[(idx+1)%len(candidates)]
+			break
+		}
+	}
+
+	for _, session := range sessions {
+		if err := router.RouteSession(session, next.ID); err != nil {
+			return OutputDevice{}, fmt.Errorf("route %s: %w", session.Key(), err)
+		}
+	}
+
+	m.lock.Lock()
+	m.appRoutes[app] = next.ID
+	m.lock.Unlock()
+
+	return next, nil
+}
+
+func (sio *SerialIO) routeApp(logger *zap.SugaredLogger, notifier Notifier, app string, names []string) {
+	device, err := sio.deej.sessions.routeApp(app, names)
+	if err != nil {
+		logger.Warnw("Failed to route app to output device", "app", app, "devices", names, "error", err)
+		return
+	}
+
+	logger.Infow("Routed app to output device", "app", app, "device", device.Name)
+
+	notifier.Notify("App output changed", fmt.Sprintf("%s now plays on %s", app, device.Name))
+}
@@ -0,0 +1,29 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jfreymuth/pulse/proto"
+)
+
+func (sf *paSessionFinder) RouteSession(session Session, deviceID string) error {
+	s, ok := session.(*paSession)
+	if !ok {
+		return errors.New("only app sessions can be routed")
+	}
+
+	request := proto.MoveSinkInput{
+		SinkInputIndex: s.sinkInputIndex,
+		DeviceIndex:    proto.Undefined,
+		DeviceName:     deviceID,
+	}
+
+	if err := sf.client.Request(&request, nil); err != nil {
+		return fmt.Errorf("move sink input: %w", err)
+	}
+
+	sf.logger.Debugw("Moved session to output device", "session", s.Key(), "id", deviceID)
+
+	return nil
+}
@@ -0,0 +1,96 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	ole "github.com/go-ole/go-ole"
+	wca "github.com/moutend/go-wca"
+)
+
+// like the default device, per-app devices have no public API. the settings app's "app volume and device
+// preferences" page uses the (undocumented) IAudioPolicyConfigFactory, whose IID changed in windows 10 21H2
+const audioPolicyConfigClass = "Windows.Media.Internal.AudioPolicyConfig"
+
+var iidsAudioPolicyConfigFactory = []*ole.GUID{
+	ole.NewGUID("{ab3d4648-e242-459f-b02f-541c70306324}"), // 21H2 and later
+	ole.NewGUID("{2a59116d-6c4f-45e0-a74f-707e3fef9258}"), // earlier windows 10 versions
+}
+
+// the factory takes device interface paths rather than plain endpoint ids
+const (
+	mmDevAPIDevicePrefix  = `\\?\SWD#MMDEVAPI#`
+	renderInterfaceSuffix = "#{e6327cad-dcec-4949-ae8a-991e976a79d3}"
+)
+
+type audioPolicyConfigFactoryVtbl struct {
+	ole.IInspectableVtbl
+	_                                            [19]uintptr
+	SetPersistedDefaultAudioEndpoint             uintptr
+	GetPersistedDefaultAudioEndpoint             uintptr
+	ClearAllPersistedApplicationDefaultEndpoints uintptr
+}
+
+// apps pick their device for these roles, communications streams are left to the app's own settings
+var appRoutingRoles = []uint32{wca.EConsole, wca.EMultimedia}
+
+func (sf *wcaSessionFinder) RouteSession(session Session, deviceID string) error {
+	s, ok := session.(*wcaSession)
+	if !ok || s.pid == 0 {
+		return errors.New("only app sessions can be routed")
+	}
+
+	if err := sf.coInitialize(); err != nil {
+		return err
+	}
+	defer ole.CoUninitialize()
+
+	factory, err := audioPolicyConfigFactory()
+	if err != nil {
+		return err
+	}
+	defer factory.Release()
+
+	endpoint, err := ole.NewHString(mmDevAPIDevicePrefix + deviceID + renderInterfaceSuffix)
+	if err != nil {
+		return fmt.Errorf("create device id string: %w", err)
+	}
+	defer ole.DeleteHString(endpoint)
+
+	vtable := (*audioPolicyConfigFactoryVtbl)(unsafe.Pointer(factory.RawVTable))
+
+	for _, role := range appRoutingRoles {
+		hr, _, _ := syscall.Syscall6(vtable.SetPersistedDefaultAudioEndpoint, 5,
+			uintptr(unsafe.Pointer(factory)),
+			uintptr(s.pid),
+			uintptr(wca.ERender),
+			uintptr(role),
+			uintptr(endpoint),
+			0)
+
+		if hr != 0 {
+			return fmt.Errorf("set persisted default endpoint (role %d): %w", role, ole.NewError(hr))
+		}
+	}
+
+	sf.logger.Debugw("Moved session to output device", "session", s.Key(), "id", deviceID)
+
+	return nil
+}
+
+func audioPolicyConfigFactory() (*ole.IInspectable, error) {
+	var lastErr error
+
+	for _, iid := range iidsAudioPolicyConfigFactory {
+		factory, err := ole.RoGetActivationFactory(audioPolicyConfigClass, iid)
+		if err == nil {
+			return factory, nil
+		}
+
+		lastErr = err
+	}
+
+	return nil, fmt.Errorf("get audio policy config factory: %w", lastErr)
+}
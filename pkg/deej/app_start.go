@@ -0,0 +1,107 @@
+package deej
+
+import (
+	"time"
+)
+
+// with apply_volume_on_app_start enabled, deej keeps an eye out for apps starting up and sets them to their
+// slider's volume right away. without it, a freshly started app plays at whatever volume the OS gives it
+// (usually full blast) until its slider moves or the session map refreshes on its own
+const appStartPollInterval = 2 * time.Second
+
+func (m *sessionMap) watchAppStarts(done chan bool) {
+	defer m.deej.supervisor.guard(moduleAppStart)
+
+	ticker := time.NewTicker(appStartPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		if !m.deej.config.ApplyVolumeOnAppStart {
+			continue
+		}
+
+		startedKeys := m.findStartedSessions()
+		if len(startedKeys) == 0 {
+			continue
+		}
+
+		m.logger.Debugw("Detected newly started sessions", "keys", startedKeys)
+
+		// pick up the new sessions properly, then bring them in line with their sliders
+		m.refreshSessions(true)
+		m.applySlidersTo(startedKeys)
+	}
+}
+
+// findStartedSessions returns the keys of sessions that exist right now but aren't in the map yet
+func (m *sessionMap) findStartedSessions() []string {
+	sessions, err := m.sessionFinder.GetAllSessions()
+	if err != nil {
+		m.logger.Warnw("Failed to get sessions while looking for started apps", "error", err)
+		return nil
+	}
+
+	// count what we found per key, since process groups have several sessions under the same key
+	found := map[string]int{}
+	for _, session := range sessions {
+		found[session.Key()]++
+		session.Release()
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	startedKeys := []string{}
+	for key, count := range found {
+		if count > len(m.m[key]) {
+			startedKeys = append(startedKeys, key)
+		}
+	}
+
+	return startedKeys
+}
+
+// applySlidersTo re-applies the current value of every slider that controls any of the given session keys
+func (m *sessionMap) applySlidersTo(keys []string) {
+	physical, virtual := m.deej.serial.SliderValues()
+
+	m.deej.config.SliderMapping.iterate(func(sliderID int, targets []string) {
+		if !m.controlsAny(targets, keys) {
+			return
+		}
+
+		value, ok := virtual[sliderID]
+		if !ok {
+
+			// we haven't heard from the device yet, its first line will take care of this anyway
+			if sliderID >= len(physical) {
+				return
+			}
+
+			value = physical[sliderID]
+		}
+
+		m.logger.Debugw("Applying slider volume to started app", "slider", sliderID, "volume", value)
+		m.applySliderVolume(sliderID, targets, value, false)
+	})
+}
+
+func (m *sessionMap) controlsAny(targets []string, keys []string) bool {
+	for _, target := range targets {
+		for _, resolvedTarget := range m.resolveTarget(target) {
+			for _, key := range keys {
+				if resolvedTarget == key {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,179 @@
+package deej
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// AutoMixGroup is a set of targets sharing a priority in auto-mix. groups are listed from highest
+// to lowest priority: whenever any higher-priority group is playing something, this group's volume
+// is reduced by ReduceBy (a fraction of whatever its slider is set to)
+type AutoMixGroup struct {
+	Targets  []string `mapstructure:"targets"`
+	ReduceBy float32  `mapstructure:"reduce_by"`
+}
+
+// autoMixer implements auto-mix: it keeps watching which prioritized groups are active, and
+// reduces lower-priority ones accordingly. sliders still set each target's baseline volume
+type autoMixer struct {
+	sessions *sessionMap
+	logger   *zap.SugaredLogger
+
+	// session key -> the factor its slider's value is currently multiplied by
+	factors map[string]float32
+	lock    sync.Locker
+
+	// when each group was last heard playing, by group index
+	lastActive map[int]time.Time
+}
+
+// activeSession is implemented by sessions that can tell whether they're currently playing
+type activeSession interface {
+	isActive() bool
+}
+
+const (
+	autoMixInterval = 250 * time.Millisecond
+
+	// keep reducing for a bit after higher-priority audio stops, so pauses between words don't pump the volume
+	autoMixHoldTime = 1500 * time.Millisecond
+)
+
+func newAutoMixer(sessions *sessionMap, logger *zap.SugaredLogger) *autoMixer {
+	return &autoMixer{
+		sessions:   sessions,
+		logger:     logger.Named("auto_mix"),
+		factors:    map[string]float32{},
+		lock:       &sync.Mutex{},
+		lastActive: map[int]time.Time{},
+	}
+}
+
+func (am *autoMixer) run(done chan bool) {
+	defer am.sessions.deej.supervisor.guard(moduleAutoMix)
+
+	ticker := time.NewTicker(autoMixInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		am.sessions.deej.supervisor.heartbeat(moduleAutoMix)
+
+		factors := am.computeFactors()
+
+		am.lock.Lock()
+		changed := !equalFactors(factors, am.factors)
+		am.factors = factors
+		am.lock.Unlock()
+
+		if changed {
+			am.logger.Debugw("Auto-mix levels changed", "factors", factors)
+			am.reapplySliders()
+		}
+	}
+}
+
+func (am *autoMixer) computeFactors() map[string]float32 {
+	groups := am.sessions.deej.config.AutoMix
+	factors := map[string]float32{}
+
+	if len(groups) == 0 {
+		return factors
+	}
+
+	now := time.Now()
+	higherPriorityActive := false
+
+	for groupIdx, group := range groups {
+		groupSessions := am.sessions.targetSessions(group.Targets)
+
+		// the group only gets reduced if something above it is playing
+		if higherPriorityActive && group.ReduceBy > 0 {
+			for _, session := range groupSessions {
+				factors[session.Key()] = 1 - group.ReduceBy
+			}
+		}
+
+		if anyActive(groupSessions) {
+			am.lastActive[groupIdx] = now
+		}
+
+		if now.Sub(am.lastActive[groupIdx]) < autoMixHoldTime {
+			higherPriorityActive = true
+		}
+	}
+
+	return factors
+}
+
+// reapplySliders sets every slider's targets to their (possibly reduced) volumes again
+func (am *autoMixer) reapplySliders() {
+	physical, virtual := am.sessions.deej.serial.SliderValues()
+
+	apply := func(sliderID int, value float32) {
+		if value < 0 {
+			return
+		}
+
+		if targets, ok := am.sessions.deej.config.SliderMapping.get(sliderID); ok {
+			am.sessions.applySliderVolume(sliderID, targets, value, false)
+		}
+	}
+
+	for sliderID, value := range physical {
+		apply(sliderID, value)
+	}
+
+	for sliderID, value := range virtual {
+		apply(sliderID, value)
+	}
+}
+
+// adjust returns the volume a session should actually be set to, given its slider's value
+func (am *autoMixer) adjust(sessionKey string, percentValue float32) float32 {
+	am.lock.Lock()
+	defer am.lock.Unlock()
+
+	if factor, ok := am.factors[sessionKey]; ok {
+		return percentValue * factor
+	}
+
+	return percentValue
+}
+
+// baseline undoes adjust, giving the slider value behind a session's current volume
+func (am *autoMixer) baseline(sessionKey string, volume float32) float32 {
+	am.lock.Lock()
+	defer am.lock.Unlock()
+
+	if factor, ok := am.factors[sessionKey]; ok && factor > 0 {
+		if baseline := volume / factor; baseline < 1 {
+			return baseline
+		}
+
+		return 1
+	}
+
+	return volume
+}
+
+func equalFactors(a map[string]float32, b map[string]float32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for key, factor := range a {
+		if other, ok := b[key]; !ok || other != factor {
+			return false
+		}
+	}
+
+	return true
+}
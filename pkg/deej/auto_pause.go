@@ -0,0 +1,172 @@
+package deej
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// AutoPauseRule pauses a media player while any of its triggers is playing something (i.e. a call app), and resumes
+// it once they've been quiet for ResumeDelay milliseconds. the player is told to pause and play through the OS' media
+// API where there is one (MPRIS on linux). otherwise it gets the play/pause media key, which toggles - so that's only
+// pressed while the player is known to be in the state it should leave (playing to pause it, silent to resume it).
+// the player is only paused if it was playing to begin with, and resuming it by hand mid-call is respected until the
+// call is over
+type AutoPauseRule struct {
+	Triggers    []string `mapstructure:"triggers"`
+	Player      string   `mapstructure:"player"`
+	ResumeDelay int      `mapstructure:"resume_delay"`
+}
+
+const (
+	autoPauseInterval = 500 * time.Millisecond
+
+	defaultAutoPauseResumeDelay = 5000
+
+	// a player may keep reporting sound for a moment after being paused, so it's only
+	// considered resumed by the user if it's still playing after this long
+	autoPauseSettleTime = 2 * time.Second
+
+	autoPausePlayPauseKey = "MEDIA_PLAY_PAUSE"
+
+	autoPauseMethodPause = "Pause"
+	autoPauseMethodPlay  = "Play"
+)
+
+// autoPauseState is what a single rule has done so far
+type autoPauseState struct {
+	lastTriggered time.Time
+
+	// when the rule paused its player, zero while it hasn't
+	pausedAt time.Time
+
+	// the user resumed the player while the rule had it paused, so it's left alone until the triggers go quiet
+	overridden bool
+}
+
+// autoPauser applies the auto-pause rules, by their index in the config
+type autoPauser struct {
+	sessions *sessionMap
+	logger   *zap.SugaredLogger
+
+	states map[int]*autoPauseState
+}
+
+func newAutoPauser(sessions *sessionMap, logger *zap.SugaredLogger) *autoPauser {
+	return &autoPauser{
+		sessions: sessions,
+		logger:   logger.Named("auto_pause"),
+		states:   map[int]*autoPauseState{},
+	}
+}
+
+func (ap *autoPauser) run(done chan bool) {
+	defer ap.sessions.deej.supervisor.guard(moduleAutoPause)
+
+	ticker := time.NewTicker(autoPauseInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+
+		for ruleIdx, rule := range ap.sessions.deej.config.AutoPause {
+			state, ok := ap.states[ruleIdx]
+			if !ok {
+				state = &autoPauseState{}
+				ap.states[ruleIdx] = state
+			}
+
+			ap.apply(rule, state, now)
+		}
+	}
+}
+
+func (ap *autoPauser) apply(rule AutoPauseRule, state *autoPauseState, now time.Time) {
+	if anyActive(ap.sessions.targetSessions(rule.Triggers)) {
+		state.lastTriggered = now
+	}
+
+	triggered := now.Sub(state.lastTriggered) < time.Duration(rule.ResumeDelay)*time.Millisecond
+	playing := anyActive(ap.sessions.targetSessions([]string{rule.Player}))
+	paused := !state.pausedAt.IsZero()
+
+	switch {
+	case !triggered:
+		state.overridden = false
+
+		if paused {
+			ap.logger.Infow("Triggers went quiet, resuming player", "player", rule.Player)
+			state.pausedAt = time.Time{}
+			ap.control(rule.Player, autoPauseMethodPlay, !playing)
+		}
+
+	case paused && playing && now.Sub(state.pausedAt) > autoPauseSettleTime:
+		ap.logger.Debugw("Player resumed while paused, leaving it alone", "player", rule.Player)
+		state.pausedAt = time.Time{}
+		state.overridden = true
+
+	case !paused && playing && !state.overridden:
+		ap.logger.Infow("Trigger started playing, pausing player", "player", rule.Player, "triggers", rule.Triggers)
+		state.pausedAt = now
+		ap.control(rule.Player, autoPauseMethodPause, true)
+	}
+}
+
+// control has the player pause or play through the media API. without one, the play/pause key is pressed instead,
+// but only if toggleKnown says the player is in the state it's supposed to leave - otherwise it may toggle the wrong way
+func (ap *autoPauser) control(player string, method string, toggleKnown bool) {
+	err := controlMediaPlayerWithin(player, method, mediaControlTimeout)
+	if err == nil {
+		return
+	}
+
+	// the player may still carry it out, and pressing the key as well would undo it
+	if err == errMediaAPITimeout {
+		ap.logger.Warnw("Player didn't answer in time, leaving it alone", "player", player, "method", method)
+		return
+	}
+
+	if err != errNoMediaAPI {
+		ap.logger.Debugw("Failed to control player through the media API", "player", player, "method", method, "error", err)
+	}
+
+	if !toggleKnown {
+		ap.logger.Debugw("Player isn't in a known state, leaving it alone", "player", player, "method", method)
+		return
+	}
+
+	if err := pressKey(autoPausePlayPauseKey, 1); err != nil {
+		ap.logger.Warnw("Failed to press play/pause", "error", err)
+	}
+}
+
+// targetSessions returns the sessions behind a list of targets, as they'd be resolved for a slider
+func (m *sessionMap) targetSessions(targets []string) []Session {
+	result := []Session{}
+
+	for _, target := range targets {
+		for _, resolvedTarget := range m.resolveTarget(target) {
+			if sessions, ok := m.find(resolvedTarget); ok {
+				result = append(result, sessions...)
+			}
+		}
+	}
+
+	return result
+}
+
+func anyActive(sessions []Session) bool {
+	for _, session := range sessions {
+		if active, ok := session.(activeSession); ok && active.isActive() {
+			return true
+		}
+	}
+
+	return false
+}
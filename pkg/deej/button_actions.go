@@ -0,0 +1,739 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thoas/go-funk"
+)
+
+// every button mapping entry is resolved into a buttonAction when the config loads. this way, typos in key
+// names and unknown actions are reported right away (along with what would've been valid), rather than
+// being silently ignored the first time the button is pressed. entries that can't be resolved are left out
+type buttonActionKind int
+
+const (
+	buttonActionKey buttonActionKind = iota
+	buttonActionUndo
+	buttonActionPanic
+	buttonActionRestore
+	buttonActionPauseToggle
+	buttonActionCycleOutput
+	buttonActionSystemSounds
+	buttonActionRoute
+	buttonActionExec
+	buttonActionWebhook
+	buttonActionOBS
+	buttonActionDiscord
+	buttonActionMedia
+	buttonActionSystem
+	buttonActionLaunch
+	buttonActionType
+	buttonActionMacro
+	buttonActionProfile
+	buttonActionRecordMacro
+	buttonActionDelay
+	buttonActionSliderMute
+	buttonActionVolumeStep
+	buttonActionMicMute
+	buttonActionCycleSliderTarget
+	buttonActionCustom
+)
+
+// modifier keys that can be held down while pressing a key, i.e. CTRL+SHIFT+F5
+const (
+	keyModifierCtrl  = "CTRL"
+	keyModifierShift = "SHIFT"
+	keyModifierAlt   = "ALT"
+	keyModifierWin   = "WIN"
+
+	keyComboSeparator = "+"
+)
+
+var keyModifiers = []string{keyModifierCtrl, keyModifierShift, keyModifierAlt, keyModifierWin}
+
+var errUnknownButtonAction = errors.New("unknown action")
+
+// names deej used to have special cases for, and what they're written as now. older configs keep working
+var legacyButtonActions = map[string]string{
+	"FORCE_REFRESH":       keyModifierCtrl + keyComboSeparator + "F5",
+	"WIN_MIC_MUTE_TOGGLE": actionMicPrefix + muteModeToggle,
+}
+
+type buttonAction struct {
+
+	// the mapping entry this was resolved from
+	name string
+	kind buttonActionKind
+
+	// for key presses, and the modifier keys held down meanwhile
+	keyCode int
+	ctrl    bool
+	shift   bool
+	alt     bool
+	win     bool
+
+	// for cycling output devices, and routing an app to them
+	outputDevices []string
+	app           string
+
+	// for muting things: one of muteModes, and for sliders, which one (as the user counts them)
+	muteMode  string
+	sliderIdx int
+
+	// for cycling a slider's target
+	targets []string
+
+	// for stepping a slider's volume, how much to move it by
+	volumeDelta float32
+
+	// for switching profiles: the profile's (lowercased) name and the bank offset
+	profile    string
+	bankOffset int
+
+	// for running commands: a command line, or the name of one under exec_commands
+	command string
+
+	// for calling webhooks: a method and url, or the name of one under webhooks
+	webhook string
+
+	// for controlling OBS
+	obs obsRequest
+
+	// for controlling Discord, which of discordToggleMute and discordToggleDeafen to flip
+	discordToggle string
+
+	// for controlling media players, the media API method to call (falling back to pressing keyCode)
+	mediaMethod string
+
+	// for launching (or bringing up) apps
+	path string
+
+	// for typing text, the key presses that type it
+	keystrokes []buttonAction
+
+	// for running macros, by lowercased name
+	macro string
+
+	// for recording macros, the button to record one for (as the user counts them)
+	buttonIdx int
+
+	// for waiting between steps
+	delay time.Duration
+
+	// for custom actions
+	run      ActionRunner
+	argument string
+}
+
+// buttonActionSyntax is one kind of button mapping entry. parseButtonAction tries each of these in order,
+// and "deej actions" lists them, so whatever's added here is both understood and documented
+type buttonActionSyntax struct {
+	syntax      string
+	description string
+	example     string
+
+	matches func(entry string) bool
+	parse   func(action buttonAction) (buttonAction, error)
+}
+
+var buttonActionSyntaxes = []buttonActionSyntax{
+	{
+		syntax:      deejActionUndo,
+		description: "reverts the most recent volume change deej made",
+		example:     deejActionUndo,
+		matches:     func(entry string) bool { return entry == deejActionUndo },
+		parse: func(action buttonAction) (buttonAction, error) {
+			action.kind = buttonActionUndo
+			return action, nil
+		},
+	},
+	{
+		syntax:      deejActionPanic,
+		description: "mutes every app right away, and keeps deej from changing any volume until deej:restore",
+		example:     deejActionPanic,
+		matches:     func(entry string) bool { return entry == deejActionPanic },
+		parse: func(action buttonAction) (buttonAction, error) {
+			action.kind = buttonActionPanic
+			return action, nil
+		},
+	},
+	{
+		syntax:      deejActionRestore,
+		description: "brings back the volumes from before deej:panic",
+		example:     deejActionRestore,
+		matches:     func(entry string) bool { return entry == deejActionRestore },
+		parse: func(action buttonAction) (buttonAction, error) {
+			action.kind = buttonActionRestore
+			return action, nil
+		},
+	},
+	{
+		syntax:      deejActionPauseToggle,
+		description: "ignores sliders and keeps deej from changing any volume, until pressed again",
+		example:     deejActionPauseToggle,
+		matches:     func(entry string) bool { return entry == deejActionPauseToggle },
+		parse: func(action buttonAction) (buttonAction, error) {
+			action.kind = buttonActionPauseToggle
+			return action, nil
+		},
+	},
+	{
+		syntax:      actionCycleOutputPrefix + cycleOutputListPrefix + "[<device>,<device>,...]",
+		description: "switches the default output device to the next connected one in the list",
+		example:     actionCycleOutputPrefix + cycleOutputListPrefix + "[Speakers,Headphones]",
+		matches:     func(entry string) bool { return strings.HasPrefix(entry, actionCycleOutputPrefix) },
+		parse: func(action buttonAction) (buttonAction, error) {
+			devices, err := parseCycleOutputList(strings.TrimPrefix(action.name, actionCycleOutputPrefix))
+			if err != nil {
+				return action, fmt.Errorf("invalid %q action: %w", action.name, err)
+			}
+
+			action.kind = buttonActionCycleOutput
+			action.outputDevices = devices
+
+			return action, nil
+		},
+	},
+	{
+		syntax:      actionCycleOutputsPrefix + "[<device>,<device>,...]",
+		description: "same as " + actionCycleOutputPrefix + cycleOutputListPrefix + "[...]",
+		example:     actionCycleOutputsPrefix + "[Speakers,Headphones]",
+		matches:     func(entry string) bool { return strings.HasPrefix(entry, actionCycleOutputsPrefix) },
+		parse: func(action buttonAction) (buttonAction, error) {
+			devices, err := parseCycleOutputList(cycleOutputListPrefix + strings.TrimPrefix(action.name, actionCycleOutputsPrefix))
+			if err != nil {
+				return action, fmt.Errorf("invalid %q action: %w", action.name, err)
+			}
+
+			action.kind = buttonActionCycleOutput
+			action.outputDevices = devices
+
+			return action, nil
+		},
+	},
+	{
+		syntax:      actionSwitchOutputPrefix + "<device>",
+		description: "makes a single output device the default one",
+		example:     actionSwitchOutputPrefix + "Headphones",
+		matches:     func(entry string) bool { return strings.HasPrefix(entry, actionSwitchOutputPrefix) },
+		parse: func(action buttonAction) (buttonAction, error) {
+			devices, err := parseSwitchOutputAction(strings.TrimPrefix(action.name, actionSwitchOutputPrefix))
+			if err != nil {
+				return action, fmt.Errorf("invalid %q action: %w", action.name, err)
+			}
+
+			action.kind = buttonActionCycleOutput
+			action.outputDevices = devices
+
+			return action, nil
+		},
+	},
+	{
+		syntax:      actionRoutePrefix + "<app>:<device>|[<device>,<device>,...]",
+		description: "moves an app to another output device, or to the next one in the list",
+		example:     actionRoutePrefix + "game.exe:[Speakers,Headphones]",
+		matches:     func(entry string) bool { return strings.HasPrefix(entry, actionRoutePrefix) },
+		parse: func(action buttonAction) (buttonAction, error) {
+			app, devices, err := parseRouteAction(strings.TrimPrefix(action.name, actionRoutePrefix))
+			if err != nil {
+				return action, fmt.Errorf("invalid %q action: %w", action.name, err)
+			}
+
+			action.kind = buttonActionRoute
+			action.app = app
+			action.outputDevices = devices
+
+			return action, nil
+		},
+	},
+	{
+		syntax:      actionSystemSoundsPrefix + strings.Join(muteModes, "|"),
+		description: "mutes, unmutes or toggles windows' system sounds, without changing their volume",
+		example:     actionSystemSoundsPrefix + muteModeToggle,
+		matches:     func(entry string) bool { return strings.HasPrefix(entry, actionSystemSoundsPrefix) },
+		parse: func(action buttonAction) (buttonAction, error) {
+			mode, err := parseMuteMode(strings.TrimPrefix(action.name, actionSystemSoundsPrefix))
+			if err != nil {
+				return action, fmt.Errorf("invalid %q action: %w", action.name, err)
+			}
+
+			action.kind = buttonActionSystemSounds
+			action.muteMode = mode
+
+			return action, nil
+		},
+	},
+	{
+		syntax:      actionMicPrefix + strings.Join(muteModes, "|"),
+		description: "mutes, unmutes or toggles the default input device",
+		example:     actionMicPrefix + muteModeToggle,
+		matches:     func(entry string) bool { return strings.HasPrefix(entry, actionMicPrefix) },
+		parse: func(action buttonAction) (buttonAction, error) {
+			mode, err := parseMuteMode(strings.TrimPrefix(action.name, actionMicPrefix))
+			if err != nil {
+				return action, fmt.Errorf("invalid %q action: %w", action.name, err)
+			}
+
+			action.kind = buttonActionMicMute
+			action.muteMode = mode
+
+			return action, nil
+		},
+	},
+	{
+		syntax:      strings.Join(sliderMutePrefixList(), "|") + "<slider>",
+		description: "mutes, unmutes or toggles whatever a slider controls, without changing its volume",
+		example:     actionMuteTogglePrefix + "1",
+		matches: func(entry string) bool {
+			_, ok := sliderMutePrefix(entry)
+			return ok
+		},
+		parse: func(action buttonAction) (buttonAction, error) {
+			mode, sliderIdx, err := parseSliderMuteAction(action.name)
+			if err != nil {
+				return action, fmt.Errorf("invalid %q action: %w", action.name, err)
+			}
+
+			action.kind = buttonActionSliderMute
+			action.muteMode = mode
+			action.sliderIdx = sliderIdx
+
+			return action, nil
+		},
+	},
+	{
+		syntax:      actionVolumeUpPrefix + "|" + actionVolumeDownPrefix + "<slider>[:<percent>]",
+		description: "turns whatever a slider controls up or down a step, without touching the default output device",
+		example:     actionVolumeUpPrefix + "2:5",
+		matches: func(entry string) bool {
+			_, ok := volumeStepPrefix(entry)
+			return ok
+		},
+		parse: func(action buttonAction) (buttonAction, error) {
+			sliderIdx, delta, err := parseVolumeStepAction(action.name)
+			if err != nil {
+				return action, fmt.Errorf("invalid %q action: %w", action.name, err)
+			}
+
+			action.kind = buttonActionVolumeStep
+			action.sliderIdx = sliderIdx
+			action.volumeDelta = delta
+
+			return action, nil
+		},
+	},
+	{
+		syntax:      actionCycleSliderTargetPrefix + "<slider>:[<target>,<target>,...]",
+		description: "points a slider at the next target in the list",
+		example:     actionCycleSliderTargetPrefix + "4:[spotify.exe,chrome.exe,discord.exe]",
+		matches:     func(entry string) bool { return strings.HasPrefix(entry, actionCycleSliderTargetPrefix) },
+		parse: func(action buttonAction) (buttonAction, error) {
+			sliderIdx, targets, err := parseCycleSliderTargetAction(strings.TrimPrefix(action.name, actionCycleSliderTargetPrefix))
+			if err != nil {
+				return action, fmt.Errorf("invalid %q action: %w", action.name, err)
+			}
+
+			action.kind = buttonActionCycleSliderTarget
+			action.sliderIdx = sliderIdx
+			action.targets = targets
+
+			return action, nil
+		},
+	},
+	{
+		syntax:      actionExecPrefix + "<command line>|<name>",
+		description: "runs a command through the shell, or one set up under exec_commands",
+		example:     actionExecPrefix + "notepad.exe notes.txt",
+		matches:     func(entry string) bool { return strings.HasPrefix(entry, actionExecPrefix) },
+		parse: func(action buttonAction) (buttonAction, error) {
+			command := strings.TrimSpace(strings.TrimPrefix(action.name, actionExecPrefix))
+			if command == "" {
+				return action, fmt.Errorf("invalid %q action: %w", action.name, errMissingExecCommand)
+			}
+
+			action.kind = buttonActionExec
+			action.command = command
+
+			return action, nil
+		},
+	},
+	{
+		syntax:      actionHTTPPrefix + "<method>:<url>|<name>",
+		description: "sends a request to a url, or one set up under webhooks",
+		example:     actionHTTPPrefix + "POST:http://homeassistant.local:8123/api/webhook/desk_lamp",
+		matches:     func(entry string) bool { return strings.HasPrefix(entry, actionHTTPPrefix) },
+		parse: func(action buttonAction) (buttonAction, error) {
+			webhook, err := parseWebhookAction(action.name)
+			if err != nil {
+				return action, fmt.Errorf("invalid %q action: %w", action.name, err)
+			}
+
+			action.kind = buttonActionWebhook
+			action.webhook = webhook
+
+			return action, nil
+		},
+	},
+	{
+		syntax:      actionOBSScenePrefix + "<scene>",
+		description: "switches OBS to a scene (see obs_websocket_url)",
+		example:     actionOBSScenePrefix + "Be Right Back",
+		matches:     func(entry string) bool { return strings.HasPrefix(entry, actionOBSScenePrefix) },
+		parse:       parseOBSButtonAction,
+	},
+	{
+		syntax:      actionOBSMutePrefix + "|" + actionOBSUnmutePrefix + "|" + actionOBSMuteTogglePrefix + "<source>",
+		description: "mutes, unmutes or toggles an OBS audio source",
+		example:     actionOBSMuteTogglePrefix + "Mic/Aux",
+		matches: func(entry string) bool {
+			return strings.HasPrefix(entry, actionOBSMutePrefix) ||
+				strings.HasPrefix(entry, actionOBSUnmutePrefix) ||
+				strings.HasPrefix(entry, actionOBSMuteTogglePrefix)
+		},
+		parse: parseOBSButtonAction,
+	},
+	{
+		syntax:      actionOBSRecordToggle + "|" + actionOBSStreamToggle,
+		description: "starts or stops recording or streaming in OBS",
+		example:     actionOBSRecordToggle,
+		matches: func(entry string) bool {
+			return entry == actionOBSRecordToggle || entry == actionOBSStreamToggle
+		},
+		parse: parseOBSButtonAction,
+	},
+	{
+		syntax:      actionDiscordMuteToggle + "|" + actionDiscordDeafenToggle,
+		description: "mutes or deafens Discord, or undoes it (see discord_client_id)",
+		example:     actionDiscordMuteToggle,
+		matches: func(entry string) bool {
+			return entry == actionDiscordMuteToggle || entry == actionDiscordDeafenToggle
+		},
+		parse: func(action buttonAction) (buttonAction, error) {
+			action.kind = buttonActionDiscord
+			action.discordToggle = discordToggleMute
+
+			if action.name == actionDiscordDeafenToggle {
+				action.discordToggle = discordToggleDeafen
+			}
+
+			return action, nil
+		},
+	},
+	{
+		syntax:      actionMediaPlayPause + "|" + actionMediaNext + "|" + actionMediaPrevious + " (or without the prefix)",
+		description: "plays, pauses or skips on the active media player, or presses the media key if there's none",
+		example:     actionMediaPlayPause,
+		matches: func(entry string) bool {
+			_, ok := mediaCommands[entry]
+			return ok
+		},
+		parse: func(action buttonAction) (buttonAction, error) {
+			command := mediaCommands[action.name]
+
+			action.kind = buttonActionMedia
+			action.mediaMethod = command.method
+			action.keyCode, _ = lookupBuiltinKey(command.fallbackKey)
+
+			return action, nil
+		},
+	},
+	{
+		syntax:      strings.Join(systemActions, "|"),
+		description: "locks the PC, puts it to sleep, or turns the displays off",
+		example:     actionSystemLock,
+		matches:     func(entry string) bool { return funk.ContainsString(systemActions, entry) },
+		parse: func(action buttonAction) (buttonAction, error) {
+			action.kind = buttonActionSystem
+			return action, nil
+		},
+	},
+	{
+		syntax:      actionLaunchPrefix + "<path>",
+		description: "brings an app's window to the front, or starts the app if it isn't running",
+		example:     actionLaunchPrefix + `C:\Users\me\AppData\Roaming\Spotify\Spotify.exe`,
+		matches:     func(entry string) bool { return strings.HasPrefix(entry, actionLaunchPrefix) },
+		parse: func(action buttonAction) (buttonAction, error) {
+			path, err := parseLaunchAction(strings.TrimPrefix(action.name, actionLaunchPrefix))
+			if err != nil {
+				return action, fmt.Errorf("invalid %q action: %w", action.name, err)
+			}
+
+			action.kind = buttonActionLaunch
+			action.path = path
+
+			return action, nil
+		},
+	},
+	{
+		syntax:      actionTypePrefix + "<text>",
+		description: "types out text, as if typed on a US keyboard",
+		example:     actionTypePrefix + "gg wp!",
+		matches:     func(entry string) bool { return strings.HasPrefix(entry, actionTypePrefix) },
+		parse: func(action buttonAction) (buttonAction, error) {
+			keystrokes, err := parseTypeAction(action.name)
+			if err != nil {
+				return action, fmt.Errorf("invalid %q action: %w", action.name, err)
+			}
+
+			action.kind = buttonActionType
+			action.keystrokes = keystrokes
+
+			return action, nil
+		},
+	},
+	{
+		syntax:      actionProfilePrefix + "<profile>|" + defaultProfileName + "|" + bankOffsetPrefix + "<offset>",
+		description: "switches to a profile, back to the regular mappings, or to a slider bank",
+		example:     actionProfilePrefix + "gaming",
+		matches:     func(entry string) bool { return strings.HasPrefix(entry, actionProfilePrefix) },
+		parse: func(action buttonAction) (buttonAction, error) {
+			value := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(action.name, actionProfilePrefix)))
+			if value == "" {
+				return action, fmt.Errorf("invalid %q action: %w", action.name, errMissingProfileName)
+			}
+
+			name, bankOffset, err := parseSelectorValue(value)
+			if err != nil {
+				return action, fmt.Errorf("invalid %q action: %w", action.name, err)
+			}
+
+			action.kind = buttonActionProfile
+			action.profile = name
+			action.bankOffset = bankOffset
+
+			return action, nil
+		},
+	},
+	{
+		syntax:      actionMacroPrefix + "<name>",
+		description: "runs the actions set up under macros one after the other, pressing their keys in order",
+		example:     actionMacroPrefix + "recorded_5",
+		matches:     func(entry string) bool { return strings.HasPrefix(entry, actionMacroPrefix) },
+		parse: func(action buttonAction) (buttonAction, error) {
+			name := strings.ToLower(strings.TrimSpace(strings.TrimPrefix(action.name, actionMacroPrefix)))
+			if name == "" {
+				return action, fmt.Errorf("invalid %q action: %w", action.name, errMissingMacroName)
+			}
+
+			action.kind = buttonActionMacro
+			action.macro = name
+
+			return action, nil
+		},
+	},
+	{
+		syntax:      actionDelayPrefix + "<milliseconds>",
+		description: "waits before going on with the button's next action (up to a minute)",
+		example:     actionDelayPrefix + "100",
+		matches:     func(entry string) bool { return strings.HasPrefix(entry, actionDelayPrefix) },
+		parse: func(action buttonAction) (buttonAction, error) {
+			delay, err := parseActionDelay(action.name)
+			if err != nil {
+				return action, fmt.Errorf("invalid %q action: %w", action.name, err)
+			}
+
+			action.kind = buttonActionDelay
+			action.delay = delay
+
+			return action, nil
+		},
+	},
+	{
+		syntax:      actionRecordMacroPrefix + "<button>",
+		description: "records a macro for a button: press once to start, type the keys, press again to stop",
+		example:     actionRecordMacroPrefix + "5",
+		matches:     func(entry string) bool { return strings.HasPrefix(entry, actionRecordMacroPrefix) },
+		parse: func(action buttonAction) (buttonAction, error) {
+			buttonIdx, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(action.name, actionRecordMacroPrefix)))
+			if err != nil || buttonIdx < 0 {
+				return action, fmt.Errorf("invalid %q action: %w", action.name, errInvalidRecordMacroButton)
+			}
+
+			action.kind = buttonActionRecordMacro
+			action.buttonIdx = buttonIdx
+
+			return action, nil
+		},
+	},
+	{
+		syntax:      strings.Join(keyModifiers, "|") + "+...+<key>",
+		description: "presses a key while holding modifier keys down",
+		example:     keyModifierCtrl + keyComboSeparator + keyModifierShift + keyComboSeparator + "F5",
+		matches: func(entry string) bool {
+			_, ok := parseKeyCombo(buttonAction{name: entry})
+			return ok
+		},
+		parse: func(action buttonAction) (buttonAction, error) {
+			action, _ = parseKeyCombo(action)
+			return action, nil
+		},
+	},
+	{
+		syntax:      "<key>",
+		description: "presses a single key, see below for all of them (KEY_<key> and VK_<key> work too)",
+		example:     "MEDIA_PLAY_PAUSE",
+		matches: func(entry string) bool {
+			_, ok := lookupKey(entry)
+			return ok
+		},
+		parse: func(action buttonAction) (buttonAction, error) {
+			action.kind = buttonActionKey
+			action.keyCode, _ = lookupKey(action.name)
+
+			return action, nil
+		},
+	},
+
+	// custom actions may take an argument, deej's own ones can't be anything else by now
+	{
+		syntax:      "<name>[:<argument>]",
+		description: "runs an action registered by the program embedding deej",
+		example:     "lights:toggle",
+		matches: func(entry string) bool {
+			_, _, ok := lookupAction(entry)
+			return ok
+		},
+		parse: func(action buttonAction) (buttonAction, error) {
+			action.run, action.argument, _ = lookupAction(action.name)
+			action.kind = buttonActionCustom
+
+			return action, nil
+		},
+	},
+}
+
+// parseOBSButtonAction resolves any of the OBS actions
+func parseOBSButtonAction(action buttonAction) (buttonAction, error) {
+	request, err := parseOBSAction(action.name)
+	if err != nil {
+		return action, fmt.Errorf("invalid %q action: %w", action.name, err)
+	}
+
+	action.kind = buttonActionOBS
+	action.obs = request
+
+	return action, nil
+}
+
+// parseKeyCombo resolves a key pressed along with modifier keys, returning false if the action isn't one
+func parseKeyCombo(action buttonAction) (buttonAction, bool) {
+	keys := strings.Split(action.name, keyComboSeparator)
+	if len(keys) < 2 {
+		return action, false
+	}
+
+	keyCode, ok := lookupKey(keys[len(keys)-1])
+	if !ok {
+		return action, false
+	}
+
+	for _, modifier := range keys[:len(keys)-1] {
+		switch modifier {
+		case keyModifierCtrl:
+			action.ctrl = true
+		case keyModifierShift:
+			action.shift = true
+		case keyModifierAlt:
+			action.alt = true
+		case keyModifierWin:
+			action.win = true
+		default:
+			return action, false
+		}
+	}
+
+	action.kind = buttonActionKey
+	action.keyCode = keyCode
+
+	return action, true
+}
+
+// parseButtonAction resolves a single button mapping entry
+func parseButtonAction(entry string) (buttonAction, error) {
+	if replacement, ok := legacyButtonActions[entry]; ok {
+		entry = replacement
+	}
+
+	action := buttonAction{name: entry}
+
+	for _, syntax := range buttonActionSyntaxes {
+		if syntax.matches(entry) {
+			return syntax.parse(action)
+		}
+	}
+
+	return action, fmt.Errorf("%w %q", errUnknownButtonAction, entry)
+}
+
+// parseButtonActions resolves all of a button's mapping entries, leaving out the ones that don't resolve
+func parseButtonActions(entries []string) ([]buttonAction, []error) {
+	actions := []buttonAction{}
+	problems := []error{}
+
+	for _, entry := range entries {
+		action, err := parseButtonAction(entry)
+		if err != nil {
+			problems = append(problems, err)
+			continue
+		}
+
+		actions = append(actions, action)
+	}
+
+	return actions, problems
+}
+
+// buttonActionNames returns the mapping entries the given actions were resolved from
+func buttonActionNames(actions []buttonAction) []string {
+	result := make([]string, len(actions))
+	for idx, action := range actions {
+		result[idx] = action.name
+	}
+
+	return result
+}
+
+// validButtonActions lists everything a button can be mapped to, for pointing users in the right direction
+func validButtonActions() []string {
+	result := []string{}
+	for _, syntax := range buttonActionSyntaxes {
+		result = append(result, syntax.syntax)
+	}
+
+	result = append(result, keyNames()...)
+
+	custom := customActionNames()
+	sort.Strings(custom)
+
+	return append(result, custom...)
+}
+
+// reportButtonActionProblems logs every mapping entry that couldn't be resolved, and lets the user know
+func (cc *CanonicalConfig) reportButtonActionProblems(problems []error) {
+	if len(problems) == 0 {
+		return
+	}
+
+	unknown := false
+	for _, problem := range problems {
+		cc.logger.Warnw("Invalid button action, ignoring it", "error", problem)
+		unknown = unknown || errors.Is(problem, errUnknownButtonAction)
+	}
+
+	// the list of valid actions is long, and only fits in the logs
+	if unknown {
+		cc.logger.Infow("Valid button actions", "actions", strings.Join(validButtonActions(), ", "))
+	}
+
+	summary := problems[0].Error()
+	if len(problems) > 1 {
+		summary = fmt.Sprintf("%s (and %d more)", summary, len(problems)-1)
+	}
+
+	cc.notifier.Notify("Invalid button mapping!", summary+". Check the logs for valid actions.")
+}
@@ -0,0 +1,82 @@
+package deej
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// buttons with a cooldown ignore presses that come too soon after the last one that did something, so pressing a
+// button mapped to something drastic (muting everything, switching the output device) twice by accident only does it
+// once. a press that's ignored stays ignored until the button is let go of, so its release actions don't fire either
+
+// buttonCooldownTracker remembers when each button last did something, and which presses it's ignoring
+type buttonCooldownTracker struct {
+	lastActivation map[int]time.Time
+	ignored        map[int]bool
+	lock           sync.Locker
+}
+
+func newButtonCooldownTracker() *buttonCooldownTracker {
+	return &buttonCooldownTracker{
+		lastActivation: map[int]time.Time{},
+		ignored:        map[int]bool{},
+		lock:           &sync.Mutex{},
+	}
+}
+
+// ignore returns whether a button event should be ignored, because its press came during the button's cooldown
+func (t *buttonCooldownTracker) ignore(buttonEvent ButtonPressEvent, at time.Time, cooldown time.Duration) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	buttonIdx := buttonEvent.ButtonID
+
+	if buttonEvent.PreviousValue != 0 || buttonEvent.ButtonValue == 0 {
+		if !t.ignored[buttonIdx] {
+			return false
+		}
+
+		if buttonEvent.ButtonValue == 0 {
+			delete(t.ignored, buttonIdx)
+		}
+
+		return true
+	}
+
+	if cooldown <= 0 {
+		return false
+	}
+
+	if last, ok := t.lastActivation[buttonIdx]; ok && at.Sub(last) < cooldown {
+		t.ignored[buttonIdx] = true
+		return true
+	}
+
+	t.lastActivation[buttonIdx] = at
+
+	return false
+}
+
+// reset forgets the presses being ignored, for when the buttons' state starts over. cooldowns carry on
+func (t *buttonCooldownTracker) reset() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.ignored = map[int]bool{}
+}
+
+func (sio *SerialIO) coolingDown(logger *zap.SugaredLogger, buttonEvent ButtonPressEvent, at time.Time) bool {
+	cooldown := sio.deej.config.ButtonCooldownByID[buttonEvent.ButtonID]
+
+	if !sio.cooldowns.ignore(buttonEvent, at, cooldown) {
+		return false
+	}
+
+	if buttonEvent.PreviousValue == 0 {
+		logger.Debugw("Button pressed during its cooldown, ignoring it", "button", buttonEvent.ButtonID, "cooldown", cooldown)
+	}
+
+	return true
+}
@@ -0,0 +1,149 @@
+package deej
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// cheap switches bounce, flipping between pressed and released a few times within milliseconds of being pressed or
+// let go of, which would otherwise fire their actions several times. with a debounce time, a button that just changed
+// holds off on further changes for that long. whatever the button settled on by then is applied once the time is up,
+// so a device that only sends lines when something changes doesn't leave it stuck on a bounce. this can be set for
+// all buttons, or for specific ones
+
+// buttonDebounceTime returns how long the given button ignores changes after each one
+func (cc *CanonicalConfig) buttonDebounceTime(buttonID int) time.Duration {
+	if debounceTime, ok := cc.ButtonDebounceTimeByID[buttonID]; ok {
+		return debounceTime
+	}
+
+	return cc.ButtonDebounceTime
+}
+
+// heldBackChange is the value a button last changed to while it was held off, and when it may have it
+type heldBackChange struct {
+	value int
+	due   time.Time
+	timer *time.Timer
+}
+
+// buttonDebouncer remembers when each button last changed, and what it changed to since if that was too soon.
+// settled gets a value once any of those changes is due, for whoever handles the device's lines to apply them
+type buttonDebouncer struct {
+	lastChange map[int]time.Time
+	heldBack   map[int]*heldBackChange
+	lock       sync.Locker
+
+	settled chan bool
+}
+
+func newButtonDebouncer() *buttonDebouncer {
+	return &buttonDebouncer{
+		lastChange: map[int]time.Time{},
+		heldBack:   map[int]*heldBackChange{},
+		lock:       &sync.Mutex{},
+		settled:    make(chan bool, 1),
+	}
+}
+
+// accept returns whether a button's change to the given value at the given time is real, rather than the button
+// bouncing. changes that came too soon are held back, the last of them is due once the debounce time is up
+func (d *buttonDebouncer) accept(buttonID int, value int, at time.Time, debounceTime time.Duration) bool {
+	if debounceTime <= 0 {
+		return true
+	}
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if last, ok := d.lastChange[buttonID]; ok && at.Sub(last) < debounceTime {
+		change, ok := d.heldBack[buttonID]
+		if !ok {
+			change = &heldBackChange{due: last.Add(debounceTime)}
+			change.timer = time.AfterFunc(change.due.Sub(at), d.signalSettled)
+			d.heldBack[buttonID] = change
+		}
+
+		change.value = value
+
+		return false
+	}
+
+	d.lastChange[buttonID] = at
+	d.drop(buttonID)
+
+	return true
+}
+
+// unchanged lets the debouncer know that a button is back where it was, so nothing held back for it matters anymore
+func (d *buttonDebouncer) unchanged(buttonID int) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	d.drop(buttonID)
+}
+
+// due returns the held back changes whose debounce time is up, by button, and forgets them
+func (d *buttonDebouncer) due(now time.Time) map[int]int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	values := map[int]int{}
+
+	for buttonID, change := range d.heldBack {
+		if !now.Before(change.due) {
+			values[buttonID] = change.value
+			delete(d.heldBack, buttonID)
+		}
+	}
+
+	return values
+}
+
+// drop forgets whatever's held back for a button. it expects the lock to be held
+func (d *buttonDebouncer) drop(buttonID int) {
+	if change, ok := d.heldBack[buttonID]; ok {
+		change.timer.Stop()
+		delete(d.heldBack, buttonID)
+	}
+}
+
+func (d *buttonDebouncer) signalSettled() {
+	select {
+	case d.settled <- true:
+	default:
+	}
+}
+
+// reset forgets every change, for when the buttons' state starts over
+func (d *buttonDebouncer) reset() {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	for buttonID := range d.heldBack {
+		d.drop(buttonID)
+	}
+
+	d.lastChange = map[int]time.Time{}
+}
+
+// applySettledButtons takes on the button changes the debouncer held back, now that they're due
+func (sio *SerialIO) applySettledButtons(logger *zap.SugaredLogger) {
+	settled := sio.debouncer.due(time.Now())
+	if len(settled) == 0 || len(sio.currentButtonValues) == 0 {
+		return
+	}
+
+	values := make([]int, len(sio.currentButtonValues))
+	copy(values, sio.currentButtonValues)
+
+	for buttonID, value := range settled {
+		if buttonID < len(values) {
+			values[buttonID] = value
+		}
+	}
+
+	sio.handleButtons(logger, values)
+}
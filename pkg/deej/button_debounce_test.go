@@ -0,0 +1,86 @@
+package deej
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestButtonDebouncer(t *testing.T) {
+	type change struct {
+		value int
+		after time.Duration
+	}
+
+	tests := []struct {
+		name         string
+		debounceTime time.Duration
+		changes      []change
+		accepted     []bool
+		due          map[int]int
+	}{
+		{
+			name:     "without a debounce time, every change is real",
+			changes:  []change{{1, 0}, {0, time.Millisecond}, {1, 2 * time.Millisecond}},
+			accepted: []bool{true, true, true},
+			due:      map[int]int{},
+		},
+		{
+			name:         "changes too soon after the last one are held back",
+			debounceTime: 50 * time.Millisecond,
+			changes:      []change{{1, 0}, {0, 5 * time.Millisecond}, {1, 10 * time.Millisecond}},
+			accepted:     []bool{true, false, false},
+			due:          map[int]int{0: 1},
+		},
+		{
+			name:         "the last change held back wins",
+			debounceTime: 50 * time.Millisecond,
+			changes:      []change{{1, 0}, {0, 5 * time.Millisecond}, {1, 10 * time.Millisecond}, {0, 20 * time.Millisecond}},
+			accepted:     []bool{true, false, false, false},
+			due:          map[int]int{0: 0},
+		},
+		{
+			name:         "changes after the debounce time are real",
+			debounceTime: 50 * time.Millisecond,
+			changes:      []change{{1, 0}, {0, 60 * time.Millisecond}},
+			accepted:     []bool{true, true},
+			due:          map[int]int{},
+		},
+		{
+			name:         "a real change drops whatever was held back",
+			debounceTime: 50 * time.Millisecond,
+			changes:      []change{{1, 0}, {0, 5 * time.Millisecond}, {1, 55 * time.Millisecond}},
+			accepted:     []bool{true, false, true},
+			due:          map[int]int{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			debouncer := newButtonDebouncer()
+			defer debouncer.reset()
+
+			start := time.Now()
+
+			accepted := []bool{}
+			for _, change := range test.changes {
+				accepted = append(accepted, debouncer.accept(0, change.value, start.Add(change.after), test.debounceTime))
+			}
+
+			if !reflect.DeepEqual(accepted, test.accepted) {
+				t.Errorf("accepted: got %v, want %v", accepted, test.accepted)
+			}
+
+			// nothing held back is due before its time
+			last := test.changes[len(test.changes)-1].after
+			if due := debouncer.due(start.Add(last)); len(due) != 0 {
+				t.Errorf("due early: got %v", due)
+			}
+
+			due := debouncer.due(start.Add(last + test.debounceTime))
+			if !reflect.DeepEqual(due, test.due) {
+				t.Errorf("due: got %v, want %v", due, test.due)
+			}
+		})
+	}
+}
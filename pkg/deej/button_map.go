@@ -5,31 +5,42 @@ import (
 	"strconv"
 	"sync"
 
+	"github.com/spf13/cast"
 	"github.com/thoas/go-funk"
 )
 
+// buttonMap holds each button's actions, already resolved from the user's mapping
 type buttonMap struct {
-	m    map[int][]string
+	m    map[int][]buttonAction
 	lock sync.Locker
 }
 
 func newButtonMap() *buttonMap {
 	return &buttonMap{
-		m:    make(map[int][]string),
+		m:    make(map[int][]buttonAction),
 		lock: &sync.Mutex{},
 	}
 }
 
-func buttonMapFromConfigs(userMapping map[string][]string) *buttonMap {
+// buttonMapFromConfigs also returns every mapping entry that couldn't be resolved into an action
+func buttonMapFromConfigs(userMapping map[string][]string, indexBase int) (*buttonMap, []error) {
 	resultMap := newButtonMap()
+	problems := []error{}
 
-	// copy targets from user config, ignoring empty values
+	// copy targets from user config, ignoring empty values.
+	// users may count their buttons from 1, internally we always count from 0
 	for buttonIdxString, targets := range userMapping {
 		buttonIdx, _ := strconv.Atoi(buttonIdxString)
 
-		resultMap.set(buttonIdx, funk.FilterString(targets, func(s string) bool {
+		actions, buttonProblems := parseButtonActions(funk.FilterString(targets, func(s string) bool {
 			return s != ""
 		}))
+
+		for _, problem := range buttonProblems {
+			problems = append(problems, fmt.Errorf("button %d: %w", buttonIdx, problem))
+		}
+
+		resultMap.set(buttonIdx-indexBase, actions)
 	}
 
 	// // add targets from internal configs, ignoring duplicate or empty values
@@ -49,31 +60,38 @@ func buttonMapFromConfigs(userMapping map[string][]string) *buttonMap {
 	// 	resultMap.set(buttonIdx, existingTargets)
 	// }
 
-	return resultMap
+	return resultMap, problems
 }
 
-func (m *buttonMap) iterate(f func(int, []string)) {
+func (m *buttonMap) get(key int) ([]buttonAction, bool) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
-	for key, value := range m.m {
-		f(key, value)
-	}
+	value, ok := m.m[key]
+	return value, ok
 }
 
-func (m *buttonMap) get(key int) ([]string, bool) {
+func (m *buttonMap) set(key int, value []buttonAction) {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
-	value, ok := m.m[key]
-	return value, ok
+	m.m[key] = value
 }
 
-func (m *buttonMap) set(key int, value []string) {
+// highestIndex returns the highest button index that has any targets mapped to it, or -1 if there are none
+func (m *buttonMap) highestIndex() int {
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
-	m.m[key] = value
+	highest := -1
+
+	for key, value := range m.m {
+		if len(value) > 0 && key > highest {
+			highest = key
+		}
+	}
+
+	return highest
 }
 
 func (m *buttonMap) String() string {
@@ -90,3 +108,69 @@ func (m *buttonMap) String() string {
 
 	return fmt.Sprintf("<%d buttons mapped to %d targets>", buttonCount, targetCount)
 }
+
+// buttonValueMap maps specific values of a button (i.e. how firmly a pressure-sensitive pad was pressed, or
+// which position a three-position switch is in) to their own actions, by button index and then by value.
+// value 0 is the button going back to rest
+type buttonValueMap struct {
+	m    map[int]map[int][]buttonAction
+	lock sync.Locker
+}
+
+// buttonValueMapFromConfig also returns every mapping entry that couldn't be resolved into an action
+func buttonValueMapFromConfig(userMapping map[string]interface{}, indexBase int) (*buttonValueMap, []error) {
+	resultMap := &buttonValueMap{
+		m:    make(map[int]map[int][]buttonAction),
+		lock: &sync.Mutex{},
+	}
+
+	problems := []error{}
+
+	for buttonIdxString, rawValues := range userMapping {
+		buttonIdx, err := strconv.Atoi(buttonIdxString)
+		if err != nil {
+			continue
+		}
+
+		values := map[int][]buttonAction{}
+
+		for valueString, rawActions := range cast.ToStringMap(rawValues) {
+			value, err := strconv.Atoi(valueString)
+			if err != nil || value < 0 {
+				continue
+			}
+
+			actions, valueProblems := parseButtonActions(buttonActionEntries(rawActions))
+			for _, problem := range valueProblems {
+				problems = append(problems, fmt.Errorf("button %d value %d: %w", buttonIdx, value, problem))
+			}
+
+			values[value] = actions
+		}
+
+		resultMap.m[buttonIdx-indexBase] = values
+	}
+
+	return resultMap, problems
+}
+
+// buttonActionEntries reads a list of mapping entries out of a nested mapping. a single action is given
+// as a plain string, which may contain spaces
+func buttonActionEntries(rawActions interface{}) []string {
+	if entry, ok := rawActions.(string); ok {
+		return []string{entry}
+	}
+
+	return funk.FilterString(cast.ToStringSlice(rawActions), func(s string) bool {
+		return s != ""
+	})
+}
+
+// get returns the actions mapped to a specific value of a button
+func (m *buttonValueMap) get(buttonIdx int, value int) ([]buttonAction, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	actions, ok := m.m[buttonIdx][value]
+	return actions, ok
+}
@@ -0,0 +1,110 @@
+package deej
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// some button actions show a notification with what they did (i.e. "Output device changed"), others don't. the
+// user can change that per action under button_osd, a list of entries naming the action just like a button mapping
+// does: text (and optionally a message, icon and duration) replaces whatever the action would show with a
+// notification of its own, shown every time it's done - even if it wouldn't show anything otherwise, like key presses.
+// for macros and steps that run in the background, that's once they're done, not when the button was pressed.
+// hidden keeps the action quiet altogether
+type ActionOSD struct {
+	Action  string `mapstructure:"action"`
+	Text    string `mapstructure:"text"`
+	Message string `mapstructure:"message"`
+	Icon    string `mapstructure:"icon"`
+
+	// in milliseconds, or 0 to leave it to the OS
+	Duration int `mapstructure:"duration"`
+
+	Hidden bool `mapstructure:"hidden"`
+}
+
+// actionOSDFromConfig reads button_osd, keyed by the lowercased action it's for. invalid entries are reported and
+// left out, which leaves those actions with their own notifications
+func actionOSDFromConfig(logger *zap.SugaredLogger, userOSD []ActionOSD) map[string]ActionOSD {
+	result := map[string]ActionOSD{}
+
+	for _, osd := range userOSD {
+		action := actionOSDKey(osd.Action)
+
+		// each entry either shows its own text or hides the action's notifications, not both
+		if action == "" || osd.Duration < 0 || (osd.Text != "") == osd.Hidden {
+			logger.Warnw("Invalid button OSD specified, ignoring it",
+				"key", configKeyButtonOSD,
+				"action", osd.Action,
+				"invalidValue", osd)
+
+			continue
+		}
+
+		result[action] = osd
+	}
+
+	return result
+}
+
+// actionOSDKey is how an action is looked up in button_osd: the same whichever way its mapping entry is written
+func actionOSDKey(name string) string {
+	if replacement, ok := legacyButtonActions[name]; ok {
+		name = replacement
+	}
+
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// quietNotifier stands in for deej's notifier while an action with its own OSD is being done
+type quietNotifier struct {
+	logger *zap.SugaredLogger
+}
+
+func (qn quietNotifier) Notify(title string, message string) {
+	qn.logger.Debugw("Not showing action notification, action has its own OSD", "title", title, "message", message)
+}
+
+// actionOSD returns an action's own OSD if it has one, and the notifier it should let the user know what it did through
+func (sio *SerialIO) actionOSD(logger *zap.SugaredLogger, notifier Notifier, action buttonAction) (ActionOSD, Notifier, bool) {
+	osd, ok := sio.deej.config.ActionOSD[actionOSDKey(action.name)]
+	if !ok {
+		return osd, notifier, false
+	}
+
+	return osd, quietNotifier{logger: logger}, true
+}
+
+// showActionOSD shows an action's own OSD once it's done
+func (sio *SerialIO) showActionOSD(logger *zap.SugaredLogger, osd ActionOSD) {
+	if osd.Hidden {
+		return
+	}
+
+	notification := Notification{
+		Title:    osd.Text,
+		Message:  osd.Message,
+		Icon:     osd.Icon,
+		Duration: time.Duration(osd.Duration) * time.Millisecond,
+	}
+
+	// notifiers that come from whoever embeds deej may not know about icons and durations
+	if notifier, ok := sio.deej.notifier.(DetailedNotifier); ok {
+		notifier.NotifyWith(notification)
+	} else {
+		sio.deej.notifier.Notify(notification.Title, notification.Message)
+	}
+
+	logger.Debugw("Showed action OSD", "action", osd.Action, "text", osd.Text)
+}
+
+func (osd ActionOSD) String() string {
+	if osd.Hidden {
+		return "hidden"
+	}
+
+	return fmt.Sprintf("%q (%dms)", osd.Text, osd.Duration)
+}
@@ -0,0 +1,80 @@
+package deej
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultButtonRepeatDelay    = 400 * time.Millisecond
+	defaultButtonRepeatInterval = 100 * time.Millisecond
+)
+
+// buttonRepeater keeps firing the actions of held buttons listed in repeat_buttons, like a keyboard does
+// when a key is held: once the button's been held for the repeat delay, and every repeat interval after that
+type buttonRepeater struct {
+	stops map[int]chan bool
+	lock  sync.Locker
+}
+
+func newButtonRepeater() *buttonRepeater {
+	return &buttonRepeater{
+		stops: map[int]chan bool{},
+		lock:  &sync.Mutex{},
+	}
+}
+
+// start begins repeating a press until stop is called for its button
+func (r *buttonRepeater) start(event ButtonPressEvent, delay time.Duration, interval time.Duration, fire func(ButtonPressEvent)) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if previous, ok := r.stops[event.ButtonID]; ok {
+		close(previous)
+	}
+
+	stop := make(chan bool)
+	r.stops[event.ButtonID] = stop
+
+	go func() {
+		select {
+		case <-stop:
+			return
+		case <-time.After(delay):
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			fire(event)
+
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func (r *buttonRepeater) stop(buttonIdx int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if stop, ok := r.stops[buttonIdx]; ok {
+		close(stop)
+		delete(r.stops, buttonIdx)
+	}
+}
+
+// reset stops repeating all buttons, i.e. when the device goes away mid-press
+func (r *buttonRepeater) reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for buttonIdx, stop := range r.stops {
+		close(stop)
+		delete(r.stops, buttonIdx)
+	}
+}
@@ -0,0 +1,71 @@
+package deej
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// ButtonStats is how many times a button was pressed since deej started, and when it was last pressed.
+// scripts get these from the HTTP API, and custom actions from Deej.ButtonStats - i.e. to do something
+// different on every 5th press
+type ButtonStats struct {
+	ButtonID  int
+	Presses   uint64
+	LastPress time.Time
+}
+
+// buttonStatsTracker counts physical presses (a button going from released to any value), whatever the
+// button is mapped to. the counts stay around for as long as deej runs, reconnecting doesn't reset them
+type buttonStatsTracker struct {
+	stats map[int]ButtonStats
+	lock  sync.Locker
+}
+
+func newButtonStatsTracker() *buttonStatsTracker {
+	return &buttonStatsTracker{
+		stats: map[int]ButtonStats{},
+		lock:  &sync.Mutex{},
+	}
+}
+
+func (t *buttonStatsTracker) pressed(buttonID int, at time.Time) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	stats := t.stats[buttonID]
+	stats.ButtonID = buttonID
+	stats.Presses++
+	stats.LastPress = at
+
+	t.stats[buttonID] = stats
+}
+
+func (t *buttonStatsTracker) get(buttonID int) ButtonStats {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	stats, ok := t.stats[buttonID]
+	if !ok {
+		return ButtonStats{ButtonID: buttonID}
+	}
+
+	return stats
+}
+
+// all returns the stats of every button pressed so far, by index
+func (t *buttonStatsTracker) all() []ButtonStats {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	result := make([]ButtonStats, 0, len(t.stats))
+	for _, stats := range t.stats {
+		result = append(result, stats)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ButtonID < result[j].ButtonID
+	})
+
+	return result
+}
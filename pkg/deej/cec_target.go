@@ -0,0 +1,177 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// mapping a slider to "cec:tv" drives the TV's volume over HDMI-CEC, for HTPCs hooked up to one. this goes
+// through cec-client (part of libcec), which has to be installed and on the PATH. CEC can only step the volume
+// up or down, so the slider works like a "keys:" target: one step every slider_key_step it moves. without
+// cec-client (or a CEC-capable HDMI port), the target is treated as missing and everything else keeps working
+const (
+	cecTargetPrefix = "cec:"
+	cecTargetTV     = "cec:tv"
+
+	cecClientExecutable = "cec-client"
+
+	cecCommandVolumeUp   = "volup"
+	cecCommandVolumeDown = "voldown"
+)
+
+var errCECUnavailable = errors.New("cec-client not found")
+
+func isCECTarget(target string) bool {
+	return strings.HasPrefix(strings.ToLower(target), cecTargetPrefix)
+}
+
+// cecClient keeps a single cec-client process around and feeds it commands, since starting it
+// takes a few seconds (it has to find and open the CEC adapter every time)
+type cecClient struct {
+	logger *zap.SugaredLogger
+	lock   sync.Locker
+
+	checked   bool
+	available bool
+
+	process *exec.Cmd
+	stdin   io.WriteCloser
+}
+
+func newCECClient(logger *zap.SugaredLogger) *cecClient {
+	return &cecClient{
+		logger: logger.Named("cec"),
+		lock:   &sync.Mutex{},
+	}
+}
+
+// send writes a command to cec-client a number of times, starting it first if needed
+func (c *cecClient) send(command string, times int) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if !c.checked {
+		c.checked = true
+
+		if _, err := exec.LookPath(cecClientExecutable); err != nil {
+			c.logger.Warnw("cec-client isn't installed, cec targets won't do anything", "error", err)
+		} else {
+			c.available = true
+		}
+	}
+
+	if !c.available {
+		return errCECUnavailable
+	}
+
+	if c.process == nil {
+		if err := c.start(); err != nil {
+			return fmt.Errorf("start cec-client: %w", err)
+		}
+	}
+
+	for i := 0; i < times; i++ {
+		if _, err := io.WriteString(c.stdin, command+"\n"); err != nil {
+			c.stopProcess()
+			return fmt.Errorf("write cec-client command: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// assumes the lock is held
+func (c *cecClient) start() error {
+
+	// only log errors, cec-client is very chatty otherwise
+	process := exec.Command(cecClientExecutable, "-d", "1")
+
+	stdin, err := process.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("get stdin pipe: %w", err)
+	}
+
+	if err := process.Start(); err != nil {
+		return fmt.Errorf("start process: %w", err)
+	}
+
+	c.logger.Debugw("Started cec-client", "pid", process.Process.Pid)
+
+	c.process = process
+	c.stdin = stdin
+
+	// notice when it goes away (i.e. the adapter was unplugged), so the next command starts it again
+	go func() {
+		err := process.Wait()
+
+		c.lock.Lock()
+		defer c.lock.Unlock()
+
+		if c.process == process {
+			c.logger.Infow("cec-client exited", "error", err)
+			c.process = nil
+			c.stdin = nil
+		}
+	}()
+
+	return nil
+}
+
+// assumes the lock is held
+func (c *cecClient) stopProcess() {
+	if c.process == nil {
+		return
+	}
+
+	c.stdin.Close()
+	c.process.Process.Kill()
+
+	c.process = nil
+	c.stdin = nil
+}
+
+func (c *cecClient) stop() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.stopProcess()
+}
+
+// applyCECTarget steps the TV's volume as many times as the slider moved steps. it returns false
+// if the target can't be reached at all
+func (m *sessionMap) applyCECTarget(sliderID int, target string, percentValue float32) (bool, error) {
+	if strings.ToLower(target) != cecTargetTV {
+		return false, fmt.Errorf("unknown cec target %q, only %q is supported", target, cecTargetTV)
+	}
+
+	steps := m.keySteps.steps(sliderID, target, percentValue, m.deej.config.SliderKeyStep)
+	if steps == 0 {
+		return true, nil
+	}
+
+	command := cecCommandVolumeUp
+	if steps < 0 {
+		command = cecCommandVolumeDown
+		steps = -steps
+	}
+
+	if steps > maxKeyPressesPerMove {
+		steps = maxKeyPressesPerMove
+	}
+
+	if err := m.cec.send(command, steps); err != nil {
+		if errors.Is(err, errCECUnavailable) {
+			return false, nil
+		}
+
+		return true, err
+	}
+
+	return true, nil
+}
@@ -0,0 +1,348 @@
+package deej
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// chords are buttons pressed together, mapped like "0+2" in chord_mapping. a press of a button that's part of
+// any chord is held back for the chord window: if the rest of a chord's buttons are pressed by then, the chord's
+// actions fire and the buttons' own actions don't (until they're all released). otherwise, the press goes
+// through to the button's own actions, just a little late. a chord that's part of a bigger one ("0+1" and
+// "0+1+2") is held back for another chord window in turn, in case the bigger one is on its way
+const (
+	chordSeparator = "+"
+
+	defaultChordWindow = 100 * time.Millisecond
+)
+
+type buttonChord struct {
+	name    string
+	buttons []int
+	actions []buttonAction
+}
+
+func (c buttonChord) contains(buttonID int) bool {
+	for _, member := range c.buttons {
+		if member == buttonID {
+			return true
+		}
+	}
+
+	return false
+}
+
+// chordsFromConfig also returns every chord that couldn't be read, and every action that couldn't be resolved
+func chordsFromConfig(userMapping map[string][]string, indexBase int) ([]buttonChord, []error) {
+	chords := []buttonChord{}
+	problems := []error{}
+
+	for name, entries := range userMapping {
+		buttons, err := parseChordButtons(name, indexBase)
+		if err != nil {
+			problems = append(problems, err)
+			continue
+		}
+
+		actions, actionProblems := parseButtonActions(entries)
+		for _, problem := range actionProblems {
+			problems = append(problems, fmt.Errorf("chord %s: %w", name, problem))
+		}
+
+		chords = append(chords, buttonChord{name: name, buttons: buttons, actions: actions})
+	}
+
+	// bigger chords first, so pressing all of "0+1+2" doesn't fire "0+1" on the way there
+	sort.Slice(chords, func(i, j int) bool {
+		if len(chords[i].buttons) != len(chords[j].buttons) {
+			return len(chords[i].buttons) > len(chords[j].buttons)
+		}
+
+		return chords[i].name < chords[j].name
+	})
+
+	return chords, problems
+}
+
+func parseChordButtons(name string, indexBase int) ([]int, error) {
+	buttons := []int{}
+
+	for _, part := range strings.Split(name, chordSeparator) {
+		buttonIdx, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid chord %q: %q isn't a button index", name, part)
+		}
+
+		for _, existing := range buttons {
+			if existing == buttonIdx-indexBase {
+				return nil, fmt.Errorf("invalid chord %q: button %d is listed twice", name, buttonIdx)
+			}
+		}
+
+		buttons = append(buttons, buttonIdx-indexBase)
+	}
+
+	if len(buttons) < 2 {
+		return nil, fmt.Errorf("invalid chord %q: needs at least two buttons, i.e. \"0%s2\"", name, chordSeparator)
+	}
+
+	sort.Ints(buttons)
+
+	return buttons, nil
+}
+
+// chordTracker holds back presses of chord buttons until it's clear whether they're part of a chord
+type chordTracker struct {
+	lock sync.Locker
+
+	// presses waiting for the rest of a chord, by button
+	pending map[int]*pendingChordPress
+
+	// buttons that were part of a chord, whose events are ignored until they're released
+	suppressed map[int]bool
+
+	// a chord that was pressed, waiting to see if it turns into a bigger one
+	held *heldChord
+}
+
+type heldChord struct {
+	chord buttonChord
+	press ButtonPressEvent
+	timer *time.Timer
+}
+
+func (h *heldChord) overlaps(chord buttonChord) bool {
+	for _, member := range h.chord.buttons {
+		if chord.contains(member) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type pendingChordPress struct {
+	press ButtonPressEvent
+	timer *time.Timer
+}
+
+func newChordTracker() *chordTracker {
+	return &chordTracker{
+		lock:       &sync.Mutex{},
+		pending:    map[int]*pendingChordPress{},
+		suppressed: map[int]bool{},
+	}
+}
+
+func (t *chordTracker) reset() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for buttonIdx, pending := range t.pending {
+		pending.timer.Stop()
+		delete(t.pending, buttonIdx)
+	}
+
+	t.suppressed = map[int]bool{}
+
+	if t.held != nil {
+		t.held.timer.Stop()
+		t.held = nil
+	}
+}
+
+// takeHeldChord stops waiting on the held chord and returns it (if there's one containing the given button),
+// so it can fire. its buttons stay quiet until they're released
+func (t *chordTracker) takeHeldChord(buttonIdx int) (*heldChord, bool) {
+	held := t.held
+	if held == nil || !held.chord.contains(buttonIdx) {
+		return nil, false
+	}
+
+	held.timer.Stop()
+	t.held = nil
+
+	for _, member := range held.chord.buttons {
+		delete(t.pending, member)
+		t.suppressed[member] = true
+	}
+
+	return held, true
+}
+
+// supersetPossible returns whether a bigger chord containing the given one could still be pressed
+func (t *chordTracker) supersetPossible(chords []buttonChord, chord buttonChord) bool {
+	for _, candidate := range chords {
+		if len(candidate.buttons) <= len(chord.buttons) {
+			continue
+		}
+
+		possible := true
+		for _, member := range chord.buttons {
+			possible = possible && candidate.contains(member)
+		}
+
+		// buttons still held down from an earlier chord can't be pressed again until they're released
+		for _, member := range candidate.buttons {
+			possible = possible && !t.suppressed[member]
+		}
+
+		if possible {
+			return true
+		}
+	}
+
+	return false
+}
+
+// handleChordButton returns whether it took care of a button event, which then shouldn't be handled as usual
+func (sio *SerialIO) handleChordButton(logger *zap.SugaredLogger, buttonEvent ButtonPressEvent) bool {
+	chords := sio.deej.config.Chords
+	buttonIdx := buttonEvent.ButtonID
+
+	isMember := false
+	for _, chord := range chords {
+		isMember = isMember || chord.contains(buttonIdx)
+	}
+
+	if !isMember {
+		return false
+	}
+
+	t := sio.chords
+	t.lock.Lock()
+
+	// a chord's buttons stay quiet until they're released
+	if t.suppressed[buttonIdx] {
+		if buttonEvent.ButtonValue == 0 {
+			delete(t.suppressed, buttonIdx)
+		}
+
+		t.lock.Unlock()
+		return true
+	}
+
+	if buttonEvent.PreviousValue != 0 || buttonEvent.ButtonValue == 0 {
+
+		// let go of a chord before it could turn into a bigger one, so that's it. the button is up already
+		if held, ok := t.takeHeldChord(buttonIdx); ok {
+			delete(t.suppressed, buttonIdx)
+			t.lock.Unlock()
+
+			logger.Debugw("Chord pressed", "chord", held.chord.name)
+			sio.runButtonActions(logger, held.press, held.chord.actions)
+
+			return true
+		}
+
+		// released before the window ran out, so the press it held back has to happen first
+		if pending, ok := t.pending[buttonIdx]; ok {
+			pending.timer.Stop()
+			delete(t.pending, buttonIdx)
+			sio.pressedButton(logger, pending.press)
+		}
+
+		t.lock.Unlock()
+		return false
+	}
+
+	pending := &pendingChordPress{press: buttonEvent}
+	pending.timer = time.AfterFunc(sio.deej.config.ChordWindow, func() {
+		t.lock.Lock()
+		defer t.lock.Unlock()
+
+		if t.pending[buttonIdx] != pending {
+			return
+		}
+
+		// no chord after all. this happens under the lock so the button's release can't overtake its press
+		delete(t.pending, buttonIdx)
+		sio.pressedButton(logger, pending.press)
+	})
+
+	t.pending[buttonIdx] = pending
+
+	for _, chord := range chords {
+
+		// any other chord that's complete was already taken care of
+		complete := chord.contains(buttonIdx)
+		for _, member := range chord.buttons {
+			_, ok := t.pending[member]
+			complete = complete && ok
+		}
+
+		if !complete {
+			continue
+		}
+
+		for _, member := range chord.buttons {
+			t.pending[member].timer.Stop()
+		}
+
+		// a held chord sharing buttons with this one won't fire, its other buttons stay quiet until released
+		if t.held != nil && t.held.overlaps(chord) {
+			t.held.timer.Stop()
+
+			for _, member := range t.held.chord.buttons {
+				if !chord.contains(member) {
+					delete(t.pending, member)
+					t.suppressed[member] = true
+				}
+			}
+
+			t.held = nil
+		}
+
+		if t.supersetPossible(chords, chord) {
+			sio.holdChord(logger, chord, buttonEvent)
+
+			t.lock.Unlock()
+			return true
+		}
+
+		for _, member := range chord.buttons {
+			delete(t.pending, member)
+			t.suppressed[member] = true
+		}
+
+		t.lock.Unlock()
+
+		logger.Debugw("Chord pressed", "chord", chord.name)
+		sio.runButtonActions(logger, buttonEvent, chord.actions)
+
+		return true
+	}
+
+	t.lock.Unlock()
+	return true
+}
+
+// holdChord waits a chord window for a pressed chord to turn into a bigger one, firing it if it doesn't.
+// its buttons stay pending meanwhile, so the bigger chord can pick them up. must be called under the lock
+func (sio *SerialIO) holdChord(logger *zap.SugaredLogger, chord buttonChord, buttonEvent ButtonPressEvent) {
+	t := sio.chords
+
+	held := &heldChord{chord: chord, press: buttonEvent}
+	held.timer = time.AfterFunc(sio.deej.config.ChordWindow, func() {
+		t.lock.Lock()
+
+		if t.held != held {
+			t.lock.Unlock()
+			return
+		}
+
+		t.takeHeldChord(chord.buttons[0])
+		t.lock.Unlock()
+
+		logger.Debugw("Chord pressed", "chord", chord.name)
+		sio.runButtonActions(logger, held.press, chord.actions)
+	})
+
+	t.held = held
+}
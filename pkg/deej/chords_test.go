@@ -0,0 +1,136 @@
+package deej
+
+import (
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseChordButtons(t *testing.T) {
+	tests := []struct {
+		name      string
+		indexBase int
+		buttons   []int
+		ok        bool
+	}{
+		{name: "0+2", buttons: []int{0, 2}, ok: true},
+		{name: "2 + 0 + 1", buttons: []int{0, 1, 2}, ok: true},
+		{name: "1+3", indexBase: 1, buttons: []int{0, 2}, ok: true},
+		{name: "0"},
+		{name: "0+0"},
+		{name: "0+a"},
+		{name: "0+"},
+	}
+
+	for _, test := range tests {
+		buttons, err := parseChordButtons(test.name, test.indexBase)
+		if (err == nil) != test.ok {
+			t.Errorf("%q: got error %v, want ok %v", test.name, err, test.ok)
+			continue
+		}
+
+		if test.ok && !reflect.DeepEqual(buttons, test.buttons) {
+			t.Errorf("%q: got %v, want %v", test.name, buttons, test.buttons)
+		}
+	}
+}
+
+func TestChords(t *testing.T) {
+
+	// every button and chord runs this action, with its own name as the argument
+	const chordConfig = `
+chord_window: 30
+button_mapping:
+  0: chord_test:0
+  1: chord_test:1
+  2: chord_test:2
+  3: chord_test:3
+chord_mapping:
+  0+1: chord_test:01
+  0+1+2: chord_test:012
+`
+
+	tests := []struct {
+		name  string
+		lines []string
+		fired []string
+	}{
+		{
+			name:  "a lone press goes through once the window is up",
+			lines: []string{"~0~0~0~0~", "~1~0~0~0~"},
+			fired: []string{"0"},
+		},
+		{
+			name:  "a press released within the window goes through right away",
+			lines: []string{"~0~0~0~0~", "~1~0~0~0~", "~0~0~0~0~"},
+			fired: []string{"0"},
+		},
+		{
+			name:  "buttons outside any chord aren't held back",
+			lines: []string{"~0~0~0~0~", "~0~0~0~1~"},
+			fired: []string{"3"},
+		},
+		{
+			name:  "a chord fires instead of its buttons",
+			lines: []string{"~0~0~0~0~", "~1~0~0~0~", "~1~1~0~0~"},
+			fired: []string{"01"},
+		},
+		{
+			name:  "letting go of a chord fires it right away",
+			lines: []string{"~0~0~0~0~", "~1~1~0~0~", "~0~0~0~0~"},
+			fired: []string{"01"},
+		},
+		{
+			name:  "the bigger chord wins",
+			lines: []string{"~0~0~0~0~", "~1~0~0~0~", "~1~1~0~0~", "~1~1~1~0~"},
+			fired: []string{"012"},
+		},
+		{
+			name:  "a chord's buttons stay quiet until they're released",
+			lines: []string{"~0~0~0~0~", "~1~1~1~0~", "~1~0~1~0~", "~0~0~0~0~", "~0~1~0~0~"},
+			fired: []string{"012", "1"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fired := make(chan string, 16)
+
+			if err := RegisterAction("chord_test", func(_ ButtonPressEvent, argument string) error {
+				fired <- argument
+				return nil
+			}); err != nil {
+				t.Fatalf("register action: %v", err)
+			}
+
+			sio, factory := newTestSerialIO(t, chordConfig)
+
+			if err := sio.Start(); err != nil {
+				t.Fatalf("start: %v", err)
+			}
+
+			defer sio.Stop()
+
+			for _, line := range test.lines {
+				if _, err := io.WriteString(factory.device, line+"\r\n"); err != nil {
+					t.Fatalf("write line %q: %v", line, err)
+				}
+			}
+
+			got := []string{}
+			for collecting := true; collecting; {
+				select {
+				case argument := <-fired:
+					got = append(got, argument)
+				case <-time.After(testQuietPeriod):
+					collecting = false
+				}
+			}
+
+			if !reflect.DeepEqual(got, test.fired) {
+				t.Errorf("fired: got %v, want %v", got, test.fired)
+			}
+		})
+	}
+}
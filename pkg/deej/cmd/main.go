@@ -3,8 +3,11 @@ package main
 import (
 	"flag"
 	"fmt"
+	"io"
+	"os"
 
 	"github.com/omriharel/deej/pkg/deej"
+	"github.com/omriharel/deej/pkg/deej/util"
 )
 
 var (
@@ -13,16 +16,40 @@ var (
 	buildType  string
 
 	verbose bool
+
+	recordPath string
+	replayPath string
+
+	profiling bool
 )
 
+// commands that print a reference of what can go in the config
+var referenceCommands = map[string]func(io.Writer){
+	"targets": deej.PrintTargets,
+	"actions": deej.PrintActions,
+}
+
 func init() {
 	flag.BoolVar(&verbose, "verbose", false, "show verbose logs (useful for debugging serial)")
 	flag.BoolVar(&verbose, "v", false, "shorthand for --verbose")
+	flag.StringVar(&recordPath, "record", "", "record everything the device sends to this file")
+	flag.StringVar(&replayPath, "replay", "", "replay a file made with --record instead of connecting to the device")
+	flag.BoolVar(&profiling, "pprof", false, "serve runtime profiles under /debug/pprof/ on the HTTP API (needs http_api_listen)")
 	flag.Parse()
 }
 
 func main() {
 
+	// reference commands just print what deej understands, without starting it
+	if printReference, ok := referenceCommands[flag.Arg(0)]; ok {
+
+		// release builds on windows have no console of their own, an error just means we weren't started from one
+		util.AttachParentConsole()
+		printReference(os.Stdout)
+
+		return
+	}
+
 	// first we need a logger
 	logger, err := deej.NewLogger(buildType)
 	if err != nil {
@@ -59,6 +86,18 @@ func main() {
 		d.SetVersion(versionString)
 	}
 
+	if recordPath != "" {
+		d.RecordSerialTraffic(recordPath)
+	}
+
+	if replayPath != "" {
+		d.ReplaySerialTraffic(replayPath)
+	}
+
+	if profiling {
+		d.EnableProfiling()
+	}
+
 	// onwards, to glory
 	if err = d.Initialize(); err != nil {
 		named.Fatalw("Failed to initialize deej", "error", err)
@@ -0,0 +1,223 @@
+package deej
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// everything deej sends to the device (feedback, display labels, protocol requests) goes through a single
+// queue, so lines from different features never end up interleaved. once it fills up, new commands are rejected
+// by default (see queues.go for the other policies). if command_acks is enabled, the firmware
+// is expected to answer every line with "ACK" or "NACK" (optionally followed by ":<reason>"), and lines that
+// aren't acknowledged in time or get rejected are retried a few times before giving up
+type outboundCommand struct {
+	line   string
+	result chan error
+}
+
+type commandAck struct {
+	accepted bool
+	reason   string
+}
+
+const (
+	commandAckTimeout  = 500 * time.Millisecond
+	commandMaxAttempts = 3
+)
+
+var (
+	errCommandConnectionClosed = errors.New("connection closed")
+	errCommandQueueFull        = errors.New("queue full")
+	errCommandDropped          = errors.New("dropped from a full queue")
+)
+
+// SendCommand sends a single line to the device and waits until it's written (and acknowledged,
+// if the user enabled command acknowledgements). it's safe to call from multiple goroutines
+func (sio *SerialIO) SendCommand(cmd string) error {
+	ctx, ok := sio.connection()
+	if !ok {
+		return errors.New("send command: not connected")
+	}
+
+	command := outboundCommand{
+		line:   cmd,
+		result: make(chan error, 1),
+	}
+
+	if err := sio.enqueueCommand(ctx, command, true); err != nil {
+		return fmt.Errorf("send command: %w", err)
+	}
+
+	select {
+	case err := <-command.result:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("send command: %w", errCommandConnectionClosed)
+	}
+}
+
+// queueCommand queues a single line for the device without waiting for it to be written, for callers that can't
+// afford to wait. it returns false if the line couldn't be queued, in which case it's never sent
+func (sio *SerialIO) queueCommand(cmd string) bool {
+	ctx, ok := sio.connection()
+	if !ok {
+		return false
+	}
+
+	command := outboundCommand{
+		line:   cmd,
+		result: make(chan error, 1),
+	}
+
+	return sio.enqueueCommand(ctx, command, false) == nil
+}
+
+// enqueueCommand adds a command to the queue according to the commands queue's policy. callers that can't wait
+// for room have the command rejected instead, even if the policy is to block
+func (sio *SerialIO) enqueueCommand(ctx context.Context, command outboundCommand, wait bool) error {
+	select {
+	case sio.commandQueue <- command:
+		sio.commandCounters.queued(len(sio.commandQueue))
+		return nil
+	default:
+	}
+
+	switch {
+	case sio.commandPolicy == queuePolicyBlock && wait:
+		atomic.AddUint64(&sio.commandCounters.blocked, 1)
+
+		select {
+		case sio.commandQueue <- command:
+			sio.commandCounters.queued(len(sio.commandQueue))
+			return nil
+		case <-ctx.Done():
+			return errCommandConnectionClosed
+		}
+
+	case sio.commandPolicy == queuePolicyDropOldest:
+
+		// make room by failing the oldest command, unless the writer just took it
+		select {
+		case oldest := <-sio.commandQueue:
+			atomic.AddUint64(&sio.commandCounters.dropped, 1)
+			oldest.result <- errCommandDropped
+		default:
+		}
+
+		select {
+		case sio.commandQueue <- command:
+			sio.commandCounters.queued(len(sio.commandQueue))
+			return nil
+		default:
+		}
+	}
+
+	atomic.AddUint64(&sio.commandCounters.rejected, 1)
+
+	return errCommandQueueFull
+}
+
+func (sio *SerialIO) runCommandWriter(ctx context.Context, logger *zap.SugaredLogger) {
+	logger = logger.Named("commands")
+
+	for {
+		select {
+		case <-ctx.Done():
+
+			// fail whatever's left, so nothing meant for this connection reaches the next one
+			for {
+				select {
+				case command := <-sio.commandQueue:
+					command.result <- errCommandConnectionClosed
+				default:
+					return
+				}
+			}
+
+		case command := <-sio.commandQueue:
+			err := sio.writeCommand(ctx, logger, command.line)
+			if err != nil {
+				logger.Debugw("Failed to send command", "command", command.line, "error", err)
+			}
+
+			command.result <- err
+		}
+	}
+}
+
+func (sio *SerialIO) writeCommand(ctx context.Context, logger *zap.SugaredLogger, line string) error {
+	if !sio.deej.config.CommandAcks {
+		return sio.writeLine(line)
+	}
+
+	var lastErr error
+
+	for attempt := 1; attempt <= commandMaxAttempts; attempt++ {
+
+		// forget about replies to earlier commands that arrived too late
+		select {
+		case <-sio.commandAcks:
+		default:
+		}
+
+		if err := sio.writeLine(line); err != nil {
+			return err
+		}
+
+		select {
+		case ack := <-sio.commandAcks:
+			if ack.accepted {
+				return nil
+			}
+
+			lastErr = fmt.Errorf("device rejected command: %s", ack.reason)
+
+		case <-time.After(commandAckTimeout):
+			lastErr = errors.New("timed out waiting for acknowledgement")
+
+		case <-ctx.Done():
+			return errCommandConnectionClosed
+		}
+
+		logger.Debugw("Command not acknowledged", "command", line, "attempt", attempt, "error", lastErr)
+	}
+
+	return fmt.Errorf("send command %q: %w", line, lastErr)
+}
+
+// deliverCommandAck hands a reply over to the command writer. replies nobody's waiting for are dropped
+func (sio *SerialIO) deliverCommandAck(ack commandAck) {
+	select {
+	case sio.commandAcks <- ack:
+	default:
+	}
+}
+
+// writeLine sends a single line to the device, taking care not to interleave it with other writes
+func (sio *SerialIO) writeLine(line string) error {
+	sio.writeLock.Lock()
+	defer sio.writeLock.Unlock()
+
+	if !sio.connected || sio.conn == nil {
+		return fmt.Errorf("write line: not connected")
+	}
+
+	terminator := "\r\n"
+	switch sio.connInfo.LineTerminator {
+	case lineTerminatorLF:
+		terminator = "\n"
+	case lineTerminatorCR:
+		terminator = "\r"
+	}
+
+	if _, err := sio.conn.Write([]byte(line + terminator)); err != nil {
+		return fmt.Errorf("write line: %w", err)
+	}
+
+	return nil
+}
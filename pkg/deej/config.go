@@ -1,13 +1,22 @@
 package deej
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"fmt"
 	"path"
+	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cast"
 	"github.com/spf13/viper"
+	"github.com/thoas/go-funk"
 	"go.uber.org/zap"
 
 	"github.com/omriharel/deej/pkg/deej/util"
@@ -19,50 +28,408 @@ type CanonicalConfig struct {
 	SliderMapping *sliderMap
 	ButtonMapping *buttonMap
 
-	ConnectionInfo struct {
-		COMPort  string
-		BaudRate int
-	}
+	// actions for specific values of pressure-sensitive buttons
+	ButtonValueMapping *buttonValueMap
+
+	// actions for letting go of a button, button_mapping covers pressing it
+	ReleaseMapping *buttonMap
+
+	// buttons that alternate between two sets of actions, instead of their button_mapping ones
+	ToggleMapping *buttonToggleMap
+
+	// alternate actions for buttons pressed while a layer button is held
+	LayerMapping *buttonLayerMap
+
+	// actions for holding a button down rather than tapping it, and how long that takes
+	LongPressMapping   *buttonMap
+	LongPressThreshold time.Duration
+
+	// actions for pressing a button twice in quick succession, and how quickly that has to be. unless
+	// DoublePressImmediate is set, the regular actions of such buttons wait for that window to pass
+	DoublePressMapping   *buttonMap
+	DoublePressWindow    time.Duration
+	DoublePressImmediate bool
+
+	// buttons pressed together, and how close together that has to be
+	Chords      []buttonChord
+	ChordWindow time.Duration
+
+	// buttons whose actions repeat while they're held, like a keyboard's keys do (i.e. for volume keys)
+	RepeatButtons        []int
+	ButtonRepeatDelay    time.Duration
+	ButtonRepeatInterval time.Duration
+
+	// slider indexes that have no physical counterpart, and are only moved through deej itself
+	VirtualSliders []int
+
+	// keyboard shortcuts that move virtual sliders (see virtual_slider_hotkeys.go)
+	VirtualSliderHotkeys map[virtualSliderHotkey]virtualSliderStep
+
+	// alternative mappings, and which selector switch position activates which (see profiles.go)
+	Profiles        map[string]*profile
+	SelectorMapping map[int]string
+
+	// what the active profile looks like on the board, sent along with it if ProfileFeedback is on
+	ProfileFeedback bool
+	ProfileColor    string
+	ButtonLabels    map[int]string
+
+	EncoderMapping      *sliderMap
+	EncoderStep         float32
+	EncoderAcceleration float32
+
+	// how far a slider mapped to "keys:" targets moves for each key press
+	SliderKeyStep float32
+
+	ConnectionInfo ConnectionInfo
+
+	MappingIndexBase int
 
 	InvertSliders bool
 
+	// the raw value a slider reports at 100%, depends on the board's ADC resolution
+	MaxAnalogValue int
+
+	VolumeFeedback bool
+
+	// accessibility mode: read each slider's targets and volume out loud when it moves by at least a step
+	AnnounceVolume     bool
+	AnnounceVolumeStep float32
+
+	DisplayFeedback       bool
+	DisplayFeedbackFormat string
+
+	// whether to watch for apps recording from input devices, and what to do when recording starts and stops
+	RecordingIndicator      bool
+	RecordingStartedActions []buttonAction
+	RecordingStoppedActions []buttonAction
+
 	NoiseReductionLevel string
 
+	BinaryProtocol bool
+	CommandAcks    bool
+
+	// send every line back to the device, for measuring round trip latency
+	EchoFrames bool
+
+	// how often to ping the device, or 0 to never do that
+	PingInterval time.Duration
+
+	// how long the device may go without sending a valid line before deej reconnects, or 0 to never do that
+	SilenceTimeout time.Duration
+
+	// whether to watch sliders for signs of broken hardware, and how long one may read the same while others move
+	SliderHealthChecks bool
+	SliderStuckTime    time.Duration
+
+	// percentage of lines that may go missing before deej reconnects, or 0 to never reconnect
+	SequenceLossThreshold float64
+
+	SliderSeparator string
+	ButtonPrefix    string
+
+	AutoMix   []AutoMixGroup
+	AutoPause []AutoPauseRule
+
+	// commands buttons can run by name, lowercased
+	ExecCommands map[string]ExecCommand
+
+	// requests buttons can send by name, lowercased
+	Webhooks map[string]Webhook
+
+	// actions that show their own notification when they're done, or none at all, by lowercased mapping entry
+	ActionOSD map[string]ActionOSD
+
+	// actions that run one after the other, by lowercased name, and how long recording one may take
+	Macros          map[string][]buttonAction
+	MacroRecordTime time.Duration
+
+	MissingTargets []MissingTargetRule
+
+	ApplyVolumeOnAppStart bool
+
+	// whether to let the user know deej is up once it first connects
+	StartupNotification bool
+
+	// how long sliders need to stay put before their volume is applied, or 0 to apply it right away
+	SliderSettleTime     time.Duration
+	SliderSettleTimeByID map[int]time.Duration
+
+	// how long buttons ignore further changes after each one, or 0 to take every change as it comes
+	ButtonDebounceTime     time.Duration
+	ButtonDebounceTimeByID map[int]time.Duration
+
+	// how long after doing something buttons ignore being pressed again, by button
+	ButtonCooldownByID map[int]time.Duration
+
+	// per-slider noise thresholds, measured by the "auto" noise reduction level
+	NoiseThresholds map[int]float64
+
+	HTTPAPIListen string
+
+	// leader or follower in a relay setup, or empty if there's just this instance
+	RelayRole   string
+	RelayListen string
+
+	// the shared secret followers show the leader
+	RelayToken string
+
+	// names of integrations the user turned off
+	DisabledIntegrations []string
+
+	// where to reach obs-websocket, or empty to leave OBS alone
+	OBSWebSocketURL      string
+	OBSWebSocketPassword string
+
+	// the Discord app deej authorizes as, and whether to tell the board when Discord is muted or deafened
+	DiscordClientID     string
+	DiscordClientSecret string
+	DiscordFeedback     bool
+
+	// a file naming keys of the user's own, or empty if there's none
+	KeyMapFile string
+
 	logger             *zap.SugaredLogger
 	notifier           Notifier
 	stopWatcherChannel chan bool
 
+	// the config file and key map file watchers can both reload at once, so reloads take turns
+	reloadLock sync.Locker
+
+	// watches the key map file, if there is one
+	keyMapWatcher   *fsnotify.Watcher
+	keyMapWatchPath string
+
 	reloadConsumers []chan bool
 
+	// how every queue is set up, and how they're doing
+	queues *queueRegistry
+
+	// the regular mappings, and what's been selected to replace them
+	baseSliderMapping *sliderMap
+	baseButtonMapping *buttonMap
+	baseProfileColor  string
+	baseButtonLabels  map[int]string
+	activeProfile     string
+	activeBankOffset  int
+
+	// slider targets picked with cycle_slider_target, on top of whatever the active profile maps.
+	// buttons pick them while the config reloads, so they (and applying them) go under profileLock
+	sliderTargetOverrides map[int][]string
+	profileLock           sync.Locker
+
 	userConfig     *viper.Viper
 	internalConfig *viper.Viper
+	secretsConfig  *viper.Viper
+}
+
+// ConnectionInfo describes how deej should reach the board
+type ConnectionInfo struct {
+	Type string
+
+	COMPort  string
+	BaudRate int
+
+	WebSocketURL    string
+	WebSocketListen string
+
+	MQTTBroker      string
+	MQTTClientID    string
+	MQTTUsername    string
+	MQTTPassword    string
+	MQTTSliderTopic string
+	MQTTButtonTopic string
+
+	BluetoothAddress string
+	BluetoothChannel int
+	BluetoothMode    string
+
+	HIDDevicePath string
+	HIDVendorID   string
+	HIDProductID  string
+
+	RelayLeader string
+
+	// the simulated device's makeup and behavior
+	MockSliders        int
+	MockButtons        int
+	MockSweepPeriod    time.Duration
+	MockButtonInterval time.Duration
+
+	LineTerminator string
 }
 
 const (
 	userConfigFilepath     = "config.yaml"
 	internalConfigFilepath = "preferences.yaml"
+	secretsConfigFilepath  = "secrets.yaml"
 
 	userConfigName     = "config"
 	internalConfigName = "preferences"
+	secretsConfigName  = "secrets"
 
 	userConfigPath = "."
 
 	configType = "yaml"
 
-	configKeySliderMapping       = "slider_mapping"
-	configKeyButtonMapping       = "button_mapping"
-	configKeyInvertSliders       = "invert_sliders"
-	configKeyCOMPort             = "com_port"
-	configKeyBaudRate            = "baud_rate"
-	configKeyNoiseReductionLevel = "noise_reduction"
+	configKeySliderMapping        = "slider_mapping"
+	configKeyButtonMapping        = "button_mapping"
+	configKeyButtonValueMapping   = "button_value_mapping"
+	configKeyReleaseMapping       = "release_mapping"
+	configKeyToggleMapping        = "toggle_mapping"
+	configKeyLayerMapping         = "layer_mapping"
+	configKeyLongPressMapping     = "long_press_mapping"
+	configKeyLongPressThreshold   = "long_press_threshold"
+	configKeyDoublePressMapping   = "double_press_mapping"
+	configKeyDoublePressWindow    = "double_press_window"
+	configKeyDoublePressImmediate = "double_press_immediate"
+	configKeyRepeatButtons        = "repeat_buttons"
+	configKeyChordMapping         = "chord_mapping"
+	configKeyChordWindow          = "chord_window"
+	configKeyButtonRepeatDelay    = "button_repeat_delay"
+	configKeyButtonRepeatInterval = "button_repeat_interval"
+	configKeyMappingIndexBase     = "mapping_index_base"
+	configKeyVirtualSliders       = "virtual_sliders"
+	configKeyVirtualSliderHotkeys = "virtual_slider_hotkeys"
+	configKeyEncoderMapping       = "encoder_mapping"
+	configKeyProfiles             = "profiles"
+	configKeySelectorMapping      = "selector_mapping"
+	configKeyProfileFeedback      = "profile_feedback"
+	configKeyProfileColor         = "profile_color"
+	configKeyButtonLabels         = "button_labels"
+	configKeyEncoderStep          = "encoder_step"
+	configKeyEncoderAcceleration  = "encoder_acceleration"
+	configKeySliderKeyStep        = "slider_key_step"
+	configKeyInvertSliders        = "invert_sliders"
+	configKeyMaxAnalogValue       = "max_analog_value"
+	configKeyVolumeFeedback       = "volume_feedback"
+	configKeyAnnounceVolume       = "announce_volume"
+	configKeyAnnounceVolumeStep   = "announce_volume_step"
+	configKeyDisplayFeedback      = "display_feedback"
+	configKeyDisplayFormat        = "display_feedback_format"
+	configKeyRecordingIndicator   = "recording_indicator"
+	configKeyRecordingStarted     = "recording_started_actions"
+	configKeyRecordingStopped     = "recording_stopped_actions"
+	configKeyConnectionType       = "connection_type"
+	configKeyCOMPort              = "com_port"
+	configKeyBaudRate             = "baud_rate"
+	configKeyWebSocketURL         = "websocket_url"
+	configKeyWebSocketListen      = "websocket_listen"
+	configKeyMQTTBroker           = "mqtt_broker"
+	configKeyMQTTClientID         = "mqtt_client_id"
+	configKeyMQTTUsername         = "mqtt_username"
+	configKeyMQTTPassword         = "mqtt_password"
+	configKeyMQTTSliderTopic      = "mqtt_slider_topic"
+	configKeyMQTTButtonTopic      = "mqtt_button_topic"
+	configKeyBluetoothAddress     = "bluetooth_address"
+	configKeyBluetoothChannel     = "bluetooth_channel"
+	configKeyBluetoothMode        = "bluetooth_mode"
+	configKeyHIDDevicePath        = "hid_device"
+	configKeyHIDVendorID          = "hid_vendor_id"
+	configKeyHIDProductID         = "hid_product_id"
+	configKeyMockSliders          = "mock_sliders"
+	configKeyMockButtons          = "mock_buttons"
+	configKeyMockSweepPeriod      = "mock_sweep_period"
+	configKeyMockButtonInterval   = "mock_button_interval"
+	configKeyNoiseReductionLevel  = "noise_reduction"
+	configKeyBinaryProtocol       = "binary_protocol"
+	configKeyCommandAcks          = "command_acks"
+	configKeyEchoFrames           = "echo_frames"
+	configKeySequenceLoss         = "sequence_loss_threshold"
+	configKeyPingInterval         = "ping_interval"
+	configKeySilenceTimeout       = "silence_timeout"
+	configKeySliderHealthChecks   = "slider_health_checks"
+	configKeySliderStuckTime      = "slider_stuck_time"
+	configKeySliderSeparator      = "slider_separator"
+	configKeyButtonPrefix         = "button_prefix"
+	configKeyLineTerminator       = "line_terminator"
+	configKeyAutoMix              = "auto_mix"
+	configKeyAutoPause            = "auto_pause"
+	configKeyExecCommands         = "exec_commands"
+	configKeyWebhooks             = "webhooks"
+	configKeyButtonOSD            = "button_osd"
+	configKeyMacros               = "macros"
+	configKeyMacroRecordTime      = "macro_record_time"
+	configKeyMissingTargets       = "missing_targets"
+	configKeyApplyOnAppStart      = "apply_volume_on_app_start"
+	configKeyStartupNotification  = "startup_notification"
+	configKeySliderSettleTime     = "slider_settle_time"
+	configKeySliderSettleTimes    = "slider_settle_times"
+	configKeyButtonDebounceTime   = "button_debounce_time"
+	configKeyButtonDebounceTimes  = "button_debounce_times"
+	configKeyButtonCooldowns      = "button_cooldowns"
+	configKeyHTTPAPIListen        = "http_api_listen"
+	configKeyRelayRole            = "relay_role"
+	configKeyRelayListen          = "relay_listen"
+	configKeyRelayLeader          = "relay_leader"
+	configKeyRelayToken           = "relay_token"
+	configKeyDisabledIntegrations = "disabled_integrations"
+	configKeyOBSWebSocketURL      = "obs_websocket_url"
+	configKeyOBSWebSocketPassword = "obs_websocket_password"
+	configKeyDiscordClientID      = "discord_client_id"
+	configKeyDiscordClientSecret  = "discord_client_secret"
+	configKeyDiscordFeedback      = "discord_feedback"
+	configKeyKeyMapFile           = "key_map_file"
+	configKeyLogging              = "logging"
+	configKeyQueues               = "queues"
+	configKeyLoggingSinks         = "logging.sinks"
+	configKeyLoggingFormat        = "logging.format"
+	configKeyLoggingLevel         = "logging.level"
+
+	// internal config keys, not meant to be set by users
+	internalConfigKeyNoiseThresholds = "noise_thresholds"
+	internalConfigKeyHTTPAPIToken    = "http_api_token"
 
 	defaultCOMPort  = "COM4"
 	defaultBaudRate = 9600
+
+	connectionTypeSerial    = "serial"
+	connectionTypeWebSocket = "websocket"
+	connectionTypeMQTT      = "mqtt"
+	connectionTypeBluetooth = "bluetooth"
+	connectionTypeHID       = "hid"
+	connectionTypeMock      = "mock"
+	connectionTypeRelay     = "relay"
+
+	relayRoleLeader   = "leader"
+	relayRoleFollower = "follower"
+
+	defaultConnectionType = connectionTypeSerial
+
+	defaultEncoderStep = 0.02
+
+	// matches the arduino's 10-bit analog reads. sliders are still parsed up to 5 digits, hence the upper limit
+	defaultMaxAnalogValue = 1023
+	maxMaxAnalogValue     = 65535
+
+	defaultSliderSeparator = "|"
+	defaultButtonPrefix    = "~"
+
+	lineTerminatorCRLF    = "crlf"
+	lineTerminatorLF      = "lf"
+	lineTerminatorCR      = "cr"
+	defaultLineTerminator = lineTerminatorCRLF
+
+	defaultMQTTBroker      = "tcp://localhost:1883"
+	defaultMQTTSliderTopic = "deej/sliders"
+	defaultMQTTButtonTopic = "deej/buttons"
 )
 
+var relayRoles = []string{relayRoleLeader, relayRoleFollower}
+
 // has to be defined as a non-constant because we're using path.Join
 var internalConfigPath = path.Join(".", logDirectory)
 
+// custom transports registered through RegisterTransport are valid connection types too
+var builtinConnectionTypes = []string{
+	connectionTypeSerial,
+	connectionTypeWebSocket,
+	connectionTypeMQTT,
+	connectionTypeBluetooth,
+	connectionTypeHID,
+	connectionTypeMock,
+}
+
 var defaultSliderMapping = func() *sliderMap {
 	emptyMap := newSliderMap()
 	emptyMap.set(0, []string{masterSessionName})
@@ -75,10 +442,15 @@ func NewConfig(logger *zap.SugaredLogger, notifier Notifier) (*CanonicalConfig,
 	logger = logger.Named("config")
 
 	cc := &CanonicalConfig{
-		logger:             logger,
-		notifier:           notifier,
-		reloadConsumers:    []chan bool{},
-		stopWatcherChannel: make(chan bool),
+		logger:                logger,
+		notifier:              notifier,
+		reloadConsumers:       []chan bool{},
+		stopWatcherChannel:    make(chan bool),
+		reloadLock:            &sync.Mutex{},
+		activeProfile:         defaultProfileName,
+		sliderTargetOverrides: map[int][]string{},
+		profileLock:           &sync.Mutex{},
+		queues:                newQueueRegistry(logger, readQueueSettings(logger)),
 	}
 
 	// distinguish between the user-provided config (config.yaml) and the internal config (logs/preferences.yaml)
@@ -89,17 +461,94 @@ func NewConfig(logger *zap.SugaredLogger, notifier Notifier) (*CanonicalConfig,
 
 	userConfig.SetDefault(configKeySliderMapping, map[string][]string{})
 	userConfig.SetDefault(configKeyButtonMapping, map[string][]string{})
+	userConfig.SetDefault(configKeyButtonValueMapping, map[string]interface{}{})
+	userConfig.SetDefault(configKeyReleaseMapping, map[string][]string{})
+	userConfig.SetDefault(configKeyToggleMapping, map[string]interface{}{})
+	userConfig.SetDefault(configKeyLayerMapping, map[string]interface{}{})
+	userConfig.SetDefault(configKeyLongPressMapping, map[string][]string{})
+	userConfig.SetDefault(configKeyLongPressThreshold, defaultLongPressThreshold.Milliseconds())
+	userConfig.SetDefault(configKeyDoublePressMapping, map[string][]string{})
+	userConfig.SetDefault(configKeyDoublePressWindow, defaultDoublePressWindow.Milliseconds())
+	userConfig.SetDefault(configKeyMacroRecordTime, defaultMacroRecordTime.Milliseconds())
+	userConfig.SetDefault(configKeyDoublePressImmediate, false)
+	userConfig.SetDefault(configKeyRepeatButtons, []int{})
+	userConfig.SetDefault(configKeyChordMapping, map[string][]string{})
+	userConfig.SetDefault(configKeyChordWindow, defaultChordWindow.Milliseconds())
+	userConfig.SetDefault(configKeyButtonRepeatDelay, defaultButtonRepeatDelay.Milliseconds())
+	userConfig.SetDefault(configKeyButtonRepeatInterval, defaultButtonRepeatInterval.Milliseconds())
+	userConfig.SetDefault(configKeyMappingIndexBase, 0)
+	userConfig.SetDefault(configKeyVirtualSliders, []int{})
+	userConfig.SetDefault(configKeyVirtualSliderHotkeys, map[string]string{})
+	userConfig.SetDefault(configKeyProfiles, map[string]interface{}{})
+	userConfig.SetDefault(configKeySelectorMapping, map[string]string{})
+	userConfig.SetDefault(configKeyProfileFeedback, false)
+	userConfig.SetDefault(configKeyProfileColor, "")
+	userConfig.SetDefault(configKeyButtonLabels, map[string]string{})
+	userConfig.SetDefault(configKeyEncoderMapping, map[string][]string{})
+	userConfig.SetDefault(configKeyEncoderStep, defaultEncoderStep)
+	userConfig.SetDefault(configKeySliderKeyStep, defaultSliderKeyStep)
+	userConfig.SetDefault(configKeyEncoderAcceleration, 0)
 	userConfig.SetDefault(configKeyInvertSliders, false)
+	userConfig.SetDefault(configKeyApplyOnAppStart, false)
+	userConfig.SetDefault(configKeyStartupNotification, false)
+	userConfig.SetDefault(configKeySliderSettleTime, 0)
+	userConfig.SetDefault(configKeySliderSettleTimes, map[string]int{})
+	userConfig.SetDefault(configKeyButtonDebounceTime, 0)
+	userConfig.SetDefault(configKeyButtonDebounceTimes, map[string]int{})
+	userConfig.SetDefault(configKeyButtonCooldowns, map[string]int{})
+	userConfig.SetDefault(configKeyMaxAnalogValue, defaultMaxAnalogValue)
+	userConfig.SetDefault(configKeyVolumeFeedback, false)
+	userConfig.SetDefault(configKeyAnnounceVolume, false)
+	userConfig.SetDefault(configKeyRecordingIndicator, false)
+	userConfig.SetDefault(configKeyRecordingStarted, []string{})
+	userConfig.SetDefault(configKeyRecordingStopped, []string{})
+	userConfig.SetDefault(configKeyAnnounceVolumeStep, defaultAnnounceVolumeStep)
+	userConfig.SetDefault(configKeyDisplayFeedback, false)
+	userConfig.SetDefault(configKeyBinaryProtocol, false)
+	userConfig.SetDefault(configKeyCommandAcks, false)
+	userConfig.SetDefault(configKeyEchoFrames, false)
+	userConfig.SetDefault(configKeySequenceLoss, 0)
+	userConfig.SetDefault(configKeyPingInterval, 0)
+	userConfig.SetDefault(configKeySilenceTimeout, 0)
+	userConfig.SetDefault(configKeySliderHealthChecks, false)
+	userConfig.SetDefault(configKeySliderStuckTime, defaultSliderStuckTime.Milliseconds())
+	userConfig.SetDefault(configKeyKeyMapFile, "")
+	userConfig.SetDefault(configKeyOBSWebSocketURL, "")
+	userConfig.SetDefault(configKeyOBSWebSocketPassword, "")
+	userConfig.SetDefault(configKeyDiscordClientID, "")
+	userConfig.SetDefault(configKeyDiscordClientSecret, "")
+	userConfig.SetDefault(configKeyDiscordFeedback, false)
+	userConfig.SetDefault(configKeySliderSeparator, defaultSliderSeparator)
+	userConfig.SetDefault(configKeyButtonPrefix, defaultButtonPrefix)
+	userConfig.SetDefault(configKeyLineTerminator, defaultLineTerminator)
+	userConfig.SetDefault(configKeyDisplayFormat, defaultDisplayFeedbackFormat)
+	userConfig.SetDefault(configKeyConnectionType, defaultConnectionType)
 	userConfig.SetDefault(configKeyCOMPort, defaultCOMPort)
 	userConfig.SetDefault(configKeyBaudRate, defaultBaudRate)
+	userConfig.SetDefault(configKeyMQTTBroker, defaultMQTTBroker)
+	userConfig.SetDefault(configKeyMockSliders, defaultMockSliders)
+	userConfig.SetDefault(configKeyMockButtons, defaultMockButtons)
+	userConfig.SetDefault(configKeyMockSweepPeriod, defaultMockSweepPeriod.Milliseconds())
+	userConfig.SetDefault(configKeyMockButtonInterval, defaultMockButtonInterval.Milliseconds())
+	userConfig.SetDefault(configKeyMQTTSliderTopic, defaultMQTTSliderTopic)
+	userConfig.SetDefault(configKeyMQTTButtonTopic, defaultMQTTButtonTopic)
+	userConfig.SetDefault(configKeyBluetoothChannel, defaultBluetoothChannel)
+	userConfig.SetDefault(configKeyBluetoothMode, bluetoothModeRFCOMM)
 
 	internalConfig := viper.New()
 	internalConfig.SetConfigName(internalConfigName)
 	internalConfig.SetConfigType(configType)
 	internalConfig.AddConfigPath(internalConfigPath)
 
+	// secrets live next to the user config, but in their own file that doesn't get shared along with it
+	secretsConfig := viper.New()
+	secretsConfig.SetConfigName(secretsConfigName)
+	secretsConfig.SetConfigType(configType)
+	secretsConfig.AddConfigPath(userConfigPath)
+
 	cc.userConfig = userConfig
 	cc.internalConfig = internalConfig
+	cc.secretsConfig = secretsConfig
 
 	logger.Debug("Created config instance")
 
@@ -134,11 +583,22 @@ func (cc *CanonicalConfig) Load() error {
 		return fmt.Errorf("read user config: %w", err)
 	}
 
+	// settings under the current OS's own section override the ones above it
+	if err := cc.mergeOSSection(); err != nil {
+		cc.logger.Warnw("Failed to merge OS-specific config section", "error", err)
+		return fmt.Errorf("merge OS-specific config section: %w", err)
+	}
+
 	// load the internal config - this doesn't have to exist, so it can error
 	if err := cc.internalConfig.ReadInConfig(); err != nil {
 		cc.logger.Debugw("Viper failed to read internal config", "error", err, "reminder", "this is fine")
 	}
 
+	// load the secrets file - this is optional too
+	if err := cc.secretsConfig.ReadInConfig(); err != nil {
+		cc.logger.Debugw("Viper failed to read secrets file", "error", err, "reminder", "this is fine")
+	}
+
 	// canonize the configuration with viper's helpers
 	if err := cc.populateFromVipers(); err != nil {
 		cc.logger.Warnw("Failed to populate config fields", "error", err)
@@ -149,15 +609,35 @@ func (cc *CanonicalConfig) Load() error {
 	cc.logger.Infow("Config values",
 		"sliderMapping", cc.SliderMapping,
 		"buttonMapping", cc.ButtonMapping,
-		"connectionInfo", cc.ConnectionInfo,
+		"connectionInfo", cc.ConnectionInfo.redacted(),
+		"webhooks", cc.redactedWebhooks(),
 		"invertSliders", cc.InvertSliders)
 
 	return nil
 }
 
+// users who share one config file between machines can put settings that only apply to one OS under
+// its own section ("windows:" or "linux:"), i.e. a different COM port or spotify.exe instead of spotify.
+// that section is merged over the rest of the config key by key, so mappings only need to list the sliders that differ
+func (cc *CanonicalConfig) mergeOSSection() error {
+	overrides := cc.userConfig.GetStringMap(runtime.GOOS)
+	if len(overrides) == 0 {
+		return nil
+	}
+
+	if err := cc.userConfig.MergeConfigMap(overrides); err != nil {
+		return fmt.Errorf("merge %s section: %w", runtime.GOOS, err)
+	}
+
+	cc.logger.Debugw("Merged OS-specific config section", "os", runtime.GOOS, "keys", len(overrides))
+
+	return nil
+}
+
 // SubscribeToChanges allows external components to receive updates when the config is reloaded
 func (cc *CanonicalConfig) SubscribeToChanges() chan bool {
-	c := make(chan bool)
+	c := make(chan bool, cc.queues.settingsFor(queueConfigReloads).Size)
+	cc.queues.open(queueConfigReloads, c, func() int { return len(c) })
 	cc.reloadConsumers = append(cc.reloadConsumers, c)
 
 	return c
@@ -193,14 +673,7 @@ func (cc *CanonicalConfig) WatchConfigFileChanges() {
 				// wait a bit to let the editor actually flush the new file contents to disk
 				<-time.After(delayBetweenEventAndReload)
 
-				if err := cc.Load(); err != nil {
-					cc.logger.Warnw("Failed to reload config file", "error", err)
-				} else {
-					cc.logger.Info("Reloaded config successfully")
-					cc.notifier.Notify("Configuration reloaded!", "Your changes have been applied.")
-
-					cc.onConfigReloaded()
-				}
+				cc.reload()
 
 				// don't forget to update the time
 				lastAttemptedReload = now
@@ -208,10 +681,101 @@ func (cc *CanonicalConfig) WatchConfigFileChanges() {
 		}
 	})
 
+	// secrets.yaml may only show up later, so it's watched on our own
+	secretsWatcher := cc.watchFileForReloads(secretsConfigFilepath, "secrets file")
+
 	// wait till they stop us
 	<-cc.stopWatcherChannel
 	cc.logger.Debug("Stopping user config file watcher")
 	cc.userConfig.OnConfigChange(nil)
+	cc.watchKeyMapFile("")
+
+	if secretsWatcher != nil {
+		secretsWatcher.Close()
+	}
+}
+
+// watchFileForReloads reloads the config whenever the file at the given path changes (or shows up), until the
+// returned watcher is closed. the file's directory is watched rather than the file itself, since some editors save
+// by replacing the file altogether. it returns nil if the file can't be watched
+func (cc *CanonicalConfig) watchFileForReloads(path string, description string) *fsnotify.Watcher {
+
+	// many editors write a file several times when saving it
+	const (
+		minTimeBetweenReloads = 500 * time.Millisecond
+		delayBeforeReload     = 50 * time.Millisecond
+	)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		cc.logger.Warnw("Failed to watch file for changes", "file", description, "path", path, "error", err)
+		return nil
+	}
+
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		cc.logger.Warnw("Failed to watch file for changes", "file", description, "path", path, "error", err)
+		watcher.Close()
+
+		return nil
+	}
+
+	cc.logger.Debugw("Starting to watch file for changes", "file", description, "path", path)
+
+	go func() {
+		var lastAttemptedReload time.Time
+
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				if filepath.Clean(event.Name) != filepath.Clean(path) ||
+					event.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+					continue
+				}
+
+				now := time.Now()
+				if now.Sub(lastAttemptedReload) < minTimeBetweenReloads {
+					continue
+				}
+
+				lastAttemptedReload = now
+
+				cc.logger.Debugw("File modified, attempting reload", "file", description, "event", event)
+
+				// let the editor finish writing the file first
+				<-time.After(delayBeforeReload)
+				cc.reload()
+
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+
+				cc.logger.Debugw("File watcher error", "file", description, "path", path, "error", err)
+			}
+		}
+	}()
+
+	return watcher
+}
+
+// reload reads every config file again after one of them changed, and lets everyone know
+func (cc *CanonicalConfig) reload() {
+	cc.reloadLock.Lock()
+	defer cc.reloadLock.Unlock()
+
+	if err := cc.Load(); err != nil {
+		cc.logger.Warnw("Failed to reload config file", "error", err)
+		return
+	}
+
+	cc.logger.Info("Reloaded config successfully")
+	cc.notifier.Notify("Configuration reloaded!", "Your changes have been applied.")
+
+	cc.onConfigReloaded()
 }
 
 // StopWatchingConfigFile signals our filesystem watcher to stop
@@ -221,17 +785,214 @@ func (cc *CanonicalConfig) StopWatchingConfigFile() {
 
 func (cc *CanonicalConfig) populateFromVipers() error {
 
+	// figure out how the user counts their sliders and buttons before reading any mappings
+	cc.MappingIndexBase = cc.userConfig.GetInt(configKeyMappingIndexBase)
+	if cc.MappingIndexBase != 0 && cc.MappingIndexBase != 1 {
+		cc.logger.Warnw("Invalid mapping index base specified, using default value",
+			"key", configKeyMappingIndexBase,
+			"invalidValue", cc.MappingIndexBase,
+			"defaultValue", 0)
+
+		cc.MappingIndexBase = 0
+	}
+
+	// keys of the user's own have to be known before any mapping that presses them is read
+	// a relative path is taken to be next to the config file, wherever deej was started from
+	cc.KeyMapFile = cc.userConfig.GetString(configKeyKeyMapFile)
+	if cc.KeyMapFile != "" && !filepath.IsAbs(cc.KeyMapFile) {
+		cc.KeyMapFile = filepath.Join(filepath.Dir(cc.userConfig.ConfigFileUsed()), cc.KeyMapFile)
+	}
+	cc.loadKeyMap()
+
 	// merge the slider mappings from the user and internal configs
 	cc.SliderMapping = sliderMapFromConfigs(
 		cc.userConfig.GetStringMapStringSlice(configKeySliderMapping),
 		cc.internalConfig.GetStringMapStringSlice(configKeySliderMapping),
+		cc.MappingIndexBase,
 	)
 
-	cc.ButtonMapping = buttonMapFromConfigs(
+	// button actions are resolved right away, so bad entries can be reported now rather than on press
+	var buttonProblems, valueProblems, releaseProblems, toggleProblems, layerProblems, longPressProblems, doublePressProblems, chordProblems []error
+	var recordingStartedProblems, recordingStoppedProblems, macroProblems []error
+
+	cc.ButtonMapping, buttonProblems = buttonMapFromConfigs(
 		cc.userConfig.GetStringMapStringSlice(configKeyButtonMapping),
+		cc.MappingIndexBase,
+	)
+
+	cc.ButtonValueMapping, valueProblems = buttonValueMapFromConfig(
+		cc.userConfig.GetStringMap(configKeyButtonValueMapping),
+		cc.MappingIndexBase,
+	)
+
+	cc.ReleaseMapping, releaseProblems = buttonMapFromConfigs(
+		cc.userConfig.GetStringMapStringSlice(configKeyReleaseMapping),
+		cc.MappingIndexBase,
+	)
+
+	for idx, problem := range releaseProblems {
+		releaseProblems[idx] = fmt.Errorf("release: %w", problem)
+	}
+
+	cc.ToggleMapping, toggleProblems = toggleMapFromConfig(
+		cc.userConfig.GetStringMap(configKeyToggleMapping),
+		cc.MappingIndexBase,
+	)
+
+	for idx, problem := range toggleProblems {
+		toggleProblems[idx] = fmt.Errorf("toggle: %w", problem)
+	}
+
+	cc.LayerMapping, layerProblems = layerMapFromConfig(
+		cc.userConfig.GetStringMap(configKeyLayerMapping),
+		cc.MappingIndexBase,
+	)
+
+	cc.LongPressMapping, longPressProblems = buttonMapFromConfigs(
+		cc.userConfig.GetStringMapStringSlice(configKeyLongPressMapping),
+		cc.MappingIndexBase,
+	)
+
+	for idx, problem := range longPressProblems {
+		longPressProblems[idx] = fmt.Errorf("long press: %w", problem)
+	}
+
+	cc.DoublePressMapping, doublePressProblems = buttonMapFromConfigs(
+		cc.userConfig.GetStringMapStringSlice(configKeyDoublePressMapping),
+		cc.MappingIndexBase,
+	)
+
+	for idx, problem := range doublePressProblems {
+		doublePressProblems[idx] = fmt.Errorf("double press: %w", problem)
+	}
+
+	cc.Chords, chordProblems = chordsFromConfig(
+		cc.userConfig.GetStringMapStringSlice(configKeyChordMapping),
+		cc.MappingIndexBase,
+	)
+
+	cc.RecordingStartedActions, recordingStartedProblems = parseButtonActions(
+		cc.userConfig.GetStringSlice(configKeyRecordingStarted),
+	)
+
+	for idx, problem := range recordingStartedProblems {
+		recordingStartedProblems[idx] = fmt.Errorf("recording started: %w", problem)
+	}
+
+	cc.RecordingStoppedActions, recordingStoppedProblems = parseButtonActions(
+		cc.userConfig.GetStringSlice(configKeyRecordingStopped),
+	)
+
+	for idx, problem := range recordingStoppedProblems {
+		recordingStoppedProblems[idx] = fmt.Errorf("recording stopped: %w", problem)
+	}
+
+	cc.Macros, macroProblems = macrosFromConfig(cc.userConfig.GetStringMapStringSlice(configKeyMacros))
+
+	// profiles may replace the mappings we just loaded
+	profileProblems := cc.loadProfiles()
+
+	problems := append(buttonProblems, valueProblems...)
+	problems = append(problems, releaseProblems...)
+	problems = append(problems, toggleProblems...)
+	problems = append(problems, layerProblems...)
+	problems = append(problems, longPressProblems...)
+	problems = append(problems, doublePressProblems...)
+	problems = append(problems, chordProblems...)
+	problems = append(problems, recordingStartedProblems...)
+	problems = append(problems, recordingStoppedProblems...)
+	problems = append(problems, macroProblems...)
+	cc.reportButtonActionProblems(append(problems, profileProblems...))
+
+	// encoders are mapped just like sliders, they just move their targets differently
+	cc.EncoderMapping = sliderMapFromConfigs(
+		cc.userConfig.GetStringMapStringSlice(configKeyEncoderMapping),
+		nil,
+		cc.MappingIndexBase,
 	)
 
+	cc.EncoderStep = float32(cc.userConfig.GetFloat64(configKeyEncoderStep))
+	if cc.EncoderStep <= 0 || cc.EncoderStep > 1 {
+		cc.logger.Warnw("Invalid encoder step specified, using default value",
+			"key", configKeyEncoderStep,
+			"invalidValue", cc.EncoderStep,
+			"defaultValue", defaultEncoderStep)
+
+		cc.EncoderStep = defaultEncoderStep
+	}
+
+	cc.SliderKeyStep = float32(cc.userConfig.GetFloat64(configKeySliderKeyStep))
+	if cc.SliderKeyStep <= 0 || cc.SliderKeyStep > 1 {
+		cc.logger.Warnw("Invalid slider key step specified, using default value",
+			"key", configKeySliderKeyStep,
+			"invalidValue", cc.SliderKeyStep,
+			"defaultValue", defaultSliderKeyStep)
+
+		cc.SliderKeyStep = defaultSliderKeyStep
+	}
+
+	cc.AnnounceVolumeStep = float32(cc.userConfig.GetFloat64(configKeyAnnounceVolumeStep))
+	if cc.AnnounceVolumeStep <= 0 || cc.AnnounceVolumeStep > 1 {
+		cc.logger.Warnw("Invalid announce volume step specified, using default value",
+			"key", configKeyAnnounceVolumeStep,
+			"invalidValue", cc.AnnounceVolumeStep,
+			"defaultValue", defaultAnnounceVolumeStep)
+
+		cc.AnnounceVolumeStep = defaultAnnounceVolumeStep
+	}
+
+	cc.EncoderAcceleration = float32(cc.userConfig.GetFloat64(configKeyEncoderAcceleration))
+	if cc.EncoderAcceleration < 0 {
+		cc.logger.Warnw("Invalid encoder acceleration specified, using default value",
+			"key", configKeyEncoderAcceleration,
+			"invalidValue", cc.EncoderAcceleration,
+			"defaultValue", 0)
+
+		cc.EncoderAcceleration = 0
+	}
+
+	cc.VirtualSliders = []int{}
+	for _, sliderIdx := range cc.userConfig.GetIntSlice(configKeyVirtualSliders) {
+		cc.VirtualSliders = append(cc.VirtualSliders, sliderIdx-cc.MappingIndexBase)
+	}
+
+	cc.VirtualSliderHotkeys = map[virtualSliderHotkey]virtualSliderStep{}
+	for name, value := range cc.userConfig.GetStringMapString(configKeyVirtualSliderHotkeys) {
+		hotkey, ok := parseVirtualSliderHotkey(name)
+		if !ok {
+			cc.logger.Warnw("Unknown key in virtual slider hotkey, ignoring it", "key", configKeyVirtualSliderHotkeys, "hotkey", name)
+			continue
+		}
+
+		sliderIdx, percent, err := parseVirtualSliderStep(value)
+		if err == nil && !cc.isVirtualSlider(sliderIdx-cc.MappingIndexBase) {
+			err = fmt.Errorf("slider %d is not a virtual slider", sliderIdx)
+		}
+
+		if err != nil {
+			cc.logger.Warnw("Invalid virtual slider hotkey, ignoring it", "key", configKeyVirtualSliderHotkeys, "hotkey", name, "error", err)
+			continue
+		}
+
+		cc.VirtualSliderHotkeys[hotkey] = virtualSliderStep{sliderID: sliderIdx - cc.MappingIndexBase, percent: percent}
+	}
+
+	cc.RepeatButtons = []int{}
+	for _, buttonIdx := range cc.userConfig.GetIntSlice(configKeyRepeatButtons) {
+		cc.RepeatButtons = append(cc.RepeatButtons, buttonIdx-cc.MappingIndexBase)
+	}
+
 	// get the rest of the config fields - viper saves us a lot of effort here
+	cc.ConnectionInfo.Type = strings.ToLower(cc.userConfig.GetString(configKeyConnectionType))
+	if !containsFold(connectionTypes(), cc.ConnectionInfo.Type) {
+		cc.logger.Warnw("Invalid connection type specified, using default value",
+			"key", configKeyConnectionType,
+			"invalidValue", cc.ConnectionInfo.Type,
+			"defaultValue", defaultConnectionType)
+
+		cc.ConnectionInfo.Type = defaultConnectionType
+	}
+
 	cc.ConnectionInfo.COMPort = cc.userConfig.GetString(configKeyCOMPort)
 
 	cc.ConnectionInfo.BaudRate = cc.userConfig.GetInt(configKeyBaudRate)
@@ -244,18 +1005,570 @@ func (cc *CanonicalConfig) populateFromVipers() error {
 		cc.ConnectionInfo.BaudRate = defaultBaudRate
 	}
 
+	cc.ConnectionInfo.WebSocketURL = cc.getSecretString(configKeyWebSocketURL)
+	cc.ConnectionInfo.WebSocketListen = cc.userConfig.GetString(configKeyWebSocketListen)
+
+	cc.ConnectionInfo.MQTTBroker = cc.getSecretString(configKeyMQTTBroker)
+	cc.ConnectionInfo.MQTTClientID = cc.userConfig.GetString(configKeyMQTTClientID)
+	cc.ConnectionInfo.MQTTUsername = cc.getSecretString(configKeyMQTTUsername)
+	cc.ConnectionInfo.MQTTPassword = cc.getSecretString(configKeyMQTTPassword)
+	cc.ConnectionInfo.MQTTSliderTopic = cc.userConfig.GetString(configKeyMQTTSliderTopic)
+	cc.ConnectionInfo.MQTTButtonTopic = cc.userConfig.GetString(configKeyMQTTButtonTopic)
+
+	cc.ConnectionInfo.BluetoothAddress = cc.userConfig.GetString(configKeyBluetoothAddress)
+	cc.ConnectionInfo.BluetoothChannel = cc.userConfig.GetInt(configKeyBluetoothChannel)
+	if cc.ConnectionInfo.BluetoothChannel < 0 || cc.ConnectionInfo.BluetoothChannel > 30 {
+		cc.logger.Warnw("Invalid bluetooth channel specified, using default value",
+			"key", configKeyBluetoothChannel,
+			"invalidValue", cc.ConnectionInfo.BluetoothChannel,
+			"defaultValue", defaultBluetoothChannel)
+
+		cc.ConnectionInfo.BluetoothChannel = defaultBluetoothChannel
+	}
+
+	cc.ConnectionInfo.BluetoothMode = strings.ToLower(cc.userConfig.GetString(configKeyBluetoothMode))
+	if !funk.ContainsString(bluetoothModes, cc.ConnectionInfo.BluetoothMode) {
+		cc.logger.Warnw("Invalid bluetooth mode specified, using default value",
+			"key", configKeyBluetoothMode,
+			"invalidValue", cc.ConnectionInfo.BluetoothMode,
+			"defaultValue", bluetoothModeRFCOMM)
+
+		cc.ConnectionInfo.BluetoothMode = bluetoothModeRFCOMM
+	}
+
+	cc.ConnectionInfo.HIDDevicePath = cc.userConfig.GetString(configKeyHIDDevicePath)
+	cc.ConnectionInfo.HIDVendorID = cc.userConfig.GetString(configKeyHIDVendorID)
+	cc.ConnectionInfo.HIDProductID = cc.userConfig.GetString(configKeyHIDProductID)
+
+	cc.ConnectionInfo.MockSliders = cc.userConfig.GetInt(configKeyMockSliders)
+	if cc.ConnectionInfo.MockSliders < 0 {
+		cc.logger.Warnw("Invalid mock slider amount specified, using default value",
+			"key", configKeyMockSliders,
+			"invalidValue", cc.ConnectionInfo.MockSliders,
+			"defaultValue", defaultMockSliders)
+
+		cc.ConnectionInfo.MockSliders = defaultMockSliders
+	}
+
+	cc.ConnectionInfo.MockButtons = cc.userConfig.GetInt(configKeyMockButtons)
+	if cc.ConnectionInfo.MockButtons < 0 || cc.ConnectionInfo.MockSliders+cc.ConnectionInfo.MockButtons == 0 {
+		cc.logger.Warnw("Invalid mock button amount specified, using default value",
+			"key", configKeyMockButtons,
+			"invalidValue", cc.ConnectionInfo.MockButtons,
+			"defaultValue", defaultMockButtons)
+
+		cc.ConnectionInfo.MockButtons = defaultMockButtons
+	}
+
+	// both of these are given in milliseconds
+	cc.ConnectionInfo.MockSweepPeriod = time.Duration(cc.userConfig.GetInt(configKeyMockSweepPeriod)) * time.Millisecond
+	if cc.ConnectionInfo.MockSweepPeriod <= 0 {
+		cc.logger.Warnw("Invalid mock sweep period specified, using default value",
+			"key", configKeyMockSweepPeriod,
+			"invalidValue", cc.ConnectionInfo.MockSweepPeriod,
+			"defaultValue", defaultMockSweepPeriod)
+
+		cc.ConnectionInfo.MockSweepPeriod = defaultMockSweepPeriod
+	}
+
+	cc.ConnectionInfo.MockButtonInterval = time.Duration(cc.userConfig.GetInt(configKeyMockButtonInterval)) * time.Millisecond
+	if cc.ConnectionInfo.MockButtonInterval < 0 {
+		cc.logger.Warnw("Invalid mock button interval specified, using default value",
+			"key", configKeyMockButtonInterval,
+			"invalidValue", cc.ConnectionInfo.MockButtonInterval,
+			"defaultValue", defaultMockButtonInterval)
+
+		cc.ConnectionInfo.MockButtonInterval = defaultMockButtonInterval
+	}
+
+	cc.ConnectionInfo.LineTerminator = strings.ToLower(cc.userConfig.GetString(configKeyLineTerminator))
+	switch cc.ConnectionInfo.LineTerminator {
+	case lineTerminatorCRLF, lineTerminatorLF, lineTerminatorCR:
+	default:
+		cc.logger.Warnw("Invalid line terminator specified, using default value",
+			"key", configKeyLineTerminator,
+			"invalidValue", cc.ConnectionInfo.LineTerminator,
+			"defaultValue", defaultLineTerminator)
+
+		cc.ConnectionInfo.LineTerminator = defaultLineTerminator
+	}
+
+	// separators can't be digits (or nothing), since they separate digits
+	cc.SliderSeparator = cc.userConfig.GetString(configKeySliderSeparator)
+	if !validSeparator(cc.SliderSeparator) {
+		cc.logger.Warnw("Invalid slider separator specified, using default value",
+			"key", configKeySliderSeparator,
+			"invalidValue", cc.SliderSeparator,
+			"defaultValue", defaultSliderSeparator)
+
+		cc.SliderSeparator = defaultSliderSeparator
+	}
+
+	cc.ButtonPrefix = cc.userConfig.GetString(configKeyButtonPrefix)
+	if !validSeparator(cc.ButtonPrefix) {
+		cc.logger.Warnw("Invalid button prefix specified, using default value",
+			"key", configKeyButtonPrefix,
+			"invalidValue", cc.ButtonPrefix,
+			"defaultValue", defaultButtonPrefix)
+
+		cc.ButtonPrefix = defaultButtonPrefix
+	}
+
+	cc.AutoMix = []AutoMixGroup{}
+	if err := cc.userConfig.UnmarshalKey(configKeyAutoMix, &cc.AutoMix); err != nil {
+		cc.logger.Warnw("Invalid auto-mix groups specified, disabling auto-mix", "key", configKeyAutoMix, "error", err)
+		cc.AutoMix = []AutoMixGroup{}
+	}
+
+	for groupIdx := range cc.AutoMix {
+		if reduceBy := cc.AutoMix[groupIdx].ReduceBy; reduceBy < 0 || reduceBy > 1 {
+			cc.logger.Warnw("Invalid auto-mix reduction specified, not reducing this group",
+				"key", configKeyAutoMix,
+				"group", groupIdx,
+				"invalidValue", reduceBy)
+
+			cc.AutoMix[groupIdx].ReduceBy = 0
+		}
+	}
+
+	rules := []AutoPauseRule{}
+	if err := cc.userConfig.UnmarshalKey(configKeyAutoPause, &rules); err != nil {
+		cc.logger.Warnw("Invalid auto-pause rules specified, disabling auto-pause", "key", configKeyAutoPause, "error", err)
+		rules = []AutoPauseRule{}
+	}
+
+	cc.AutoPause = []AutoPauseRule{}
+	for ruleIdx, rule := range rules {
+		if rule.Player == "" || len(rule.Triggers) == 0 {
+			cc.logger.Warnw("Invalid auto-pause rule specified, it needs both a player and triggers",
+				"key", configKeyAutoPause,
+				"rule", ruleIdx)
+
+			continue
+		}
+
+		if rule.ResumeDelay <= 0 {
+			rule.ResumeDelay = defaultAutoPauseResumeDelay
+		}
+
+		cc.AutoPause = append(cc.AutoPause, rule)
+	}
+
+	commands := map[string]ExecCommand{}
+	if err := cc.userConfig.UnmarshalKey(configKeyExecCommands, &commands); err != nil {
+		cc.logger.Warnw("Invalid exec commands specified, ignoring them", "key", configKeyExecCommands, "error", err)
+		commands = map[string]ExecCommand{}
+	}
+
+	cc.ExecCommands = map[string]ExecCommand{}
+	for name, command := range commands {
+		if strings.TrimSpace(command.Command) == "" || command.Timeout < 0 {
+			cc.logger.Warnw("Invalid exec command specified, ignoring it",
+				"key", configKeyExecCommands,
+				"name", name,
+				"invalidValue", command)
+
+			continue
+		}
+
+		cc.ExecCommands[strings.ToLower(name)] = command
+	}
+
+	webhooks := map[string]Webhook{}
+	if err := cc.userConfig.UnmarshalKey(configKeyWebhooks, &webhooks); err != nil {
+		cc.logger.Warnw("Invalid webhooks specified, ignoring them", "key", configKeyWebhooks, "error", err)
+		webhooks = map[string]Webhook{}
+	}
+
+	cc.Webhooks = map[string]Webhook{}
+	for name, webhook := range webhooks {
+		webhook.URL = cc.resolveSecret(configKeyWebhooks, webhook.URL)
+		for header, value := range webhook.Headers {
+			webhook.Headers[header] = cc.resolveSecret(configKeyWebhooks, value)
+		}
+
+		if err := webhook.validate(); err != nil {
+			cc.logger.Warnw("Invalid webhook specified, ignoring it",
+				"key", configKeyWebhooks,
+				"name", name,
+				"error", err)
+
+			continue
+		}
+
+		cc.Webhooks[strings.ToLower(name)] = webhook
+	}
+
+	userOSD := []ActionOSD{}
+	if err := cc.userConfig.UnmarshalKey(configKeyButtonOSD, &userOSD); err != nil {
+		cc.logger.Warnw("Invalid button OSD specified, ignoring it", "key", configKeyButtonOSD, "error", err)
+		userOSD = []ActionOSD{}
+	}
+
+	cc.ActionOSD = actionOSDFromConfig(cc.logger, userOSD)
+
+	cc.MissingTargets = []MissingTargetRule{}
+	if err := cc.userConfig.UnmarshalKey(configKeyMissingTargets, &cc.MissingTargets); err != nil {
+		cc.logger.Warnw("Invalid missing target rules specified, ignoring them", "key", configKeyMissingTargets, "error", err)
+		cc.MissingTargets = []MissingTargetRule{}
+	}
+
+	validMissingTargets := []MissingTargetRule{}
+	for _, rule := range cc.MissingTargets {
+		rule.Target = strings.ToLower(rule.Target)
+		rule.Action = strings.ToLower(rule.Action)
+
+		if rule.Target == "" || !containsFold(missingTargetActions, rule.Action) {
+			cc.logger.Warnw("Invalid missing target rule specified, ignoring it",
+				"key", configKeyMissingTargets,
+				"target", rule.Target,
+				"action", rule.Action)
+
+			continue
+		}
+
+		validMissingTargets = append(validMissingTargets, rule)
+	}
+
+	cc.MissingTargets = validMissingTargets
+
 	cc.InvertSliders = cc.userConfig.GetBool(configKeyInvertSliders)
+	cc.ApplyVolumeOnAppStart = cc.userConfig.GetBool(configKeyApplyOnAppStart)
+	cc.StartupNotification = cc.userConfig.GetBool(configKeyStartupNotification)
+
+	cc.MaxAnalogValue = cc.userConfig.GetInt(configKeyMaxAnalogValue)
+	if cc.MaxAnalogValue <= 0 || cc.MaxAnalogValue > maxMaxAnalogValue {
+		cc.logger.Warnw("Invalid max analog value specified, using default value",
+			"key", configKeyMaxAnalogValue,
+			"invalidValue", cc.MaxAnalogValue,
+			"defaultValue", defaultMaxAnalogValue)
+
+		cc.MaxAnalogValue = defaultMaxAnalogValue
+	}
+
+	cc.VolumeFeedback = cc.userConfig.GetBool(configKeyVolumeFeedback)
+	cc.AnnounceVolume = cc.userConfig.GetBool(configKeyAnnounceVolume)
+	cc.DisplayFeedback = cc.userConfig.GetBool(configKeyDisplayFeedback)
+	cc.DisplayFeedbackFormat = cc.userConfig.GetString(configKeyDisplayFormat)
+	cc.ProfileFeedback = cc.userConfig.GetBool(configKeyProfileFeedback)
+	cc.RecordingIndicator = cc.userConfig.GetBool(configKeyRecordingIndicator)
 	cc.NoiseReductionLevel = cc.userConfig.GetString(configKeyNoiseReductionLevel)
+	cc.BinaryProtocol = cc.userConfig.GetBool(configKeyBinaryProtocol)
+	cc.CommandAcks = cc.userConfig.GetBool(configKeyCommandAcks)
+	cc.EchoFrames = cc.userConfig.GetBool(configKeyEchoFrames)
+
+	// the ping interval is given in milliseconds
+	cc.PingInterval = time.Duration(cc.userConfig.GetInt(configKeyPingInterval)) * time.Millisecond
+	if cc.PingInterval < 0 {
+		cc.logger.Warnw("Invalid ping interval specified, using default value",
+			"key", configKeyPingInterval,
+			"invalidValue", cc.PingInterval,
+			"defaultValue", 0)
+
+		cc.PingInterval = 0
+	}
+
+	// so is the silence timeout
+	cc.SilenceTimeout = time.Duration(cc.userConfig.GetInt(configKeySilenceTimeout)) * time.Millisecond
+	if cc.SilenceTimeout < 0 {
+		cc.logger.Warnw("Invalid silence timeout specified, using default value",
+			"key", configKeySilenceTimeout,
+			"invalidValue", cc.SilenceTimeout,
+			"defaultValue", 0)
+
+		cc.SilenceTimeout = 0
+	}
+
+	cc.SliderHealthChecks = cc.userConfig.GetBool(configKeySliderHealthChecks)
+
+	// the stuck time is given in milliseconds
+	cc.SliderStuckTime = time.Duration(cc.userConfig.GetInt(configKeySliderStuckTime)) * time.Millisecond
+	if cc.SliderStuckTime <= 0 {
+		cc.logger.Warnw("Invalid slider stuck time specified, using default value",
+			"key", configKeySliderStuckTime,
+			"invalidValue", cc.SliderStuckTime,
+			"defaultValue", defaultSliderStuckTime)
+
+		cc.SliderStuckTime = defaultSliderStuckTime
+	}
+
+	cc.SequenceLossThreshold = cc.userConfig.GetFloat64(configKeySequenceLoss)
+	if cc.SequenceLossThreshold < 0 || cc.SequenceLossThreshold > 100 {
+		cc.logger.Warnw("Invalid sequence loss threshold specified, using default value",
+			"key", configKeySequenceLoss,
+			"invalidValue", cc.SequenceLossThreshold,
+			"defaultValue", 0)
+
+		cc.SequenceLossThreshold = 0
+	}
+
+	cc.HTTPAPIListen = cc.userConfig.GetString(configKeyHTTPAPIListen)
+
+	cc.RelayRole = strings.ToLower(cc.userConfig.GetString(configKeyRelayRole))
+	if cc.RelayRole != "" && !funk.ContainsString(relayRoles, cc.RelayRole) {
+		cc.logger.Warnw("Invalid relay role specified, using default value",
+			"key", configKeyRelayRole,
+			"invalidValue", cc.RelayRole,
+			"defaultValue", "")
+
+		cc.RelayRole = ""
+	}
+
+	cc.RelayListen = cc.userConfig.GetString(configKeyRelayListen)
+	cc.ConnectionInfo.RelayLeader = cc.userConfig.GetString(configKeyRelayLeader)
+	cc.RelayToken = cc.getSecretString(configKeyRelayToken)
+
+	// followers get their device's lines from the leader, whatever connection type is set
+	if cc.RelayRole == relayRoleFollower {
+		cc.ConnectionInfo.Type = connectionTypeRelay
+	}
+
+	cc.DisabledIntegrations = []string{}
+	for _, name := range cc.userConfig.GetStringSlice(configKeyDisabledIntegrations) {
+		cc.DisabledIntegrations = append(cc.DisabledIntegrations, strings.ToLower(name))
+	}
+
+	cc.OBSWebSocketURL = cc.getSecretString(configKeyOBSWebSocketURL)
+	cc.OBSWebSocketPassword = cc.getSecretString(configKeyOBSWebSocketPassword)
+
+	cc.DiscordClientID = cc.userConfig.GetString(configKeyDiscordClientID)
+	cc.DiscordClientSecret = cc.getSecretString(configKeyDiscordClientSecret)
+	cc.DiscordFeedback = cc.userConfig.GetBool(configKeyDiscordFeedback)
+
+	// settle times are given in milliseconds
+	cc.SliderSettleTime = time.Duration(cc.userConfig.GetInt(configKeySliderSettleTime)) * time.Millisecond
+	if cc.SliderSettleTime < 0 {
+		cc.logger.Warnw("Invalid slider settle time specified, using default value",
+			"key", configKeySliderSettleTime,
+			"invalidValue", cc.SliderSettleTime,
+			"defaultValue", 0)
+
+		cc.SliderSettleTime = 0
+	}
+
+	cc.ButtonDebounceTime = time.Duration(cc.userConfig.GetInt(configKeyButtonDebounceTime)) * time.Millisecond
+	if cc.ButtonDebounceTime < 0 {
+		cc.logger.Warnw("Invalid button debounce time specified, using default value",
+			"key", configKeyButtonDebounceTime,
+			"invalidValue", cc.ButtonDebounceTime,
+			"defaultValue", 0)
+
+		cc.ButtonDebounceTime = 0
+	}
+
+	cc.LongPressThreshold = time.Duration(cc.userConfig.GetInt(configKeyLongPressThreshold)) * time.Millisecond
+	if cc.LongPressThreshold <= 0 {
+		cc.logger.Warnw("Invalid long press threshold specified, using default value",
+			"key", configKeyLongPressThreshold,
+			"invalidValue", cc.LongPressThreshold,
+			"defaultValue", defaultLongPressThreshold)
+
+		cc.LongPressThreshold = defaultLongPressThreshold
+	}
+
+	cc.DoublePressWindow = time.Duration(cc.userConfig.GetInt(configKeyDoublePressWindow)) * time.Millisecond
+	if cc.DoublePressWindow <= 0 {
+		cc.logger.Warnw("Invalid double press window specified, using default value",
+			"key", configKeyDoublePressWindow,
+			"invalidValue", cc.DoublePressWindow,
+			"defaultValue", defaultDoublePressWindow)
+
+		cc.DoublePressWindow = defaultDoublePressWindow
+	}
+
+	cc.DoublePressImmediate = cc.userConfig.GetBool(configKeyDoublePressImmediate)
+
+	cc.MacroRecordTime = time.Duration(cc.userConfig.GetInt(configKeyMacroRecordTime)) * time.Millisecond
+	if cc.MacroRecordTime <= 0 {
+		cc.logger.Warnw("Invalid macro record time specified, using default value",
+			"key", configKeyMacroRecordTime,
+			"invalidValue", cc.MacroRecordTime,
+			"defaultValue", defaultMacroRecordTime)
+
+		cc.MacroRecordTime = defaultMacroRecordTime
+	}
+
+	cc.ChordWindow = time.Duration(cc.userConfig.GetInt(configKeyChordWindow)) * time.Millisecond
+	if cc.ChordWindow <= 0 {
+		cc.logger.Warnw("Invalid chord window specified, using default value",
+			"key", configKeyChordWindow,
+			"invalidValue", cc.ChordWindow,
+			"defaultValue", defaultChordWindow)
+
+		cc.ChordWindow = defaultChordWindow
+	}
+
+	cc.ButtonRepeatDelay = time.Duration(cc.userConfig.GetInt(configKeyButtonRepeatDelay)) * time.Millisecond
+	if cc.ButtonRepeatDelay < 0 {
+		cc.logger.Warnw("Invalid button repeat delay specified, using default value",
+			"key", configKeyButtonRepeatDelay,
+			"invalidValue", cc.ButtonRepeatDelay,
+			"defaultValue", defaultButtonRepeatDelay)
+
+		cc.ButtonRepeatDelay = defaultButtonRepeatDelay
+	}
+
+	cc.ButtonRepeatInterval = time.Duration(cc.userConfig.GetInt(configKeyButtonRepeatInterval)) * time.Millisecond
+	if cc.ButtonRepeatInterval <= 0 {
+		cc.logger.Warnw("Invalid button repeat interval specified, using default value",
+			"key", configKeyButtonRepeatInterval,
+			"invalidValue", cc.ButtonRepeatInterval,
+			"defaultValue", defaultButtonRepeatInterval)
+
+		cc.ButtonRepeatInterval = defaultButtonRepeatInterval
+	}
+
+	cc.SliderSettleTimeByID = map[int]time.Duration{}
+	for sliderIdxString, settleTime := range cc.userConfig.GetStringMap(configKeySliderSettleTimes) {
+		sliderIdx, err := strconv.Atoi(sliderIdxString)
+		if err != nil || cast.ToInt(settleTime) < 0 {
+			cc.logger.Warnw("Invalid slider settle time specified, ignoring it",
+				"key", configKeySliderSettleTimes,
+				"slider", sliderIdxString,
+				"invalidValue", settleTime)
+
+			continue
+		}
+
+		cc.SliderSettleTimeByID[sliderIdx-cc.MappingIndexBase] = time.Duration(cast.ToInt(settleTime)) * time.Millisecond
+	}
+
+	cc.ButtonDebounceTimeByID = map[int]time.Duration{}
+	for buttonIdxString, debounceTime := range cc.userConfig.GetStringMap(configKeyButtonDebounceTimes) {
+		buttonIdx, err := strconv.Atoi(buttonIdxString)
+		if err != nil || cast.ToInt(debounceTime) < 0 {
+			cc.logger.Warnw("Invalid button debounce time specified, ignoring it",
+				"key", configKeyButtonDebounceTimes,
+				"button", buttonIdxString,
+				"invalidValue", debounceTime)
+
+			continue
+		}
+
+		cc.ButtonDebounceTimeByID[buttonIdx-cc.MappingIndexBase] = time.Duration(cast.ToInt(debounceTime)) * time.Millisecond
+	}
+
+	cc.ButtonCooldownByID = map[int]time.Duration{}
+	for buttonIdxString, cooldown := range cc.userConfig.GetStringMap(configKeyButtonCooldowns) {
+		buttonIdx, err := strconv.Atoi(buttonIdxString)
+		if err != nil || cast.ToInt(cooldown) < 0 {
+			cc.logger.Warnw("Invalid button cooldown specified, ignoring it",
+				"key", configKeyButtonCooldowns,
+				"button", buttonIdxString,
+				"invalidValue", cooldown)
+
+			continue
+		}
+
+		cc.ButtonCooldownByID[buttonIdx-cc.MappingIndexBase] = time.Duration(cast.ToInt(cooldown)) * time.Millisecond
+	}
+
+	cc.NoiseThresholds = map[int]float64{}
+	for sliderIdxString, threshold := range cc.internalConfig.GetStringMap(internalConfigKeyNoiseThresholds) {
+		sliderIdx, err := strconv.Atoi(sliderIdxString)
+		if err != nil {
+			continue
+		}
+
+		cc.NoiseThresholds[sliderIdx] = cast.ToFloat64(threshold)
+	}
 
 	cc.logger.Debug("Populated config fields from vipers")
 
 	return nil
 }
 
+// SaveNoiseThresholds persists auto-tuned noise thresholds to the internal config file
+func (cc *CanonicalConfig) SaveNoiseThresholds(thresholds map[int]float64) error {
+	serialized := map[string]float64{}
+	for sliderIdx, threshold := range thresholds {
+		serialized[strconv.Itoa(sliderIdx)] = threshold
+	}
+
+	cc.internalConfig.Set(internalConfigKeyNoiseThresholds, serialized)
+	cc.NoiseThresholds = thresholds
+
+	if err := cc.writeInternalConfig(); err != nil {
+		return fmt.Errorf("save noise thresholds: %w", err)
+	}
+
+	cc.logger.Debugw("Saved noise thresholds", "thresholds", thresholds)
+
+	return nil
+}
+
+// httpAPIToken returns the token HTTP API requests have to come with, making one up (and saving it) the first time.
+// each install gets its own, and it's kept with the internal config so it never ends up in a shared config.yaml
+func (cc *CanonicalConfig) httpAPIToken() (string, error) {
+	if token := cc.internalConfig.GetString(internalConfigKeyHTTPAPIToken); token != "" {
+		return token, nil
+	}
+
+	tokenBytes := make([]byte, 16)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return "", fmt.Errorf("generate http api token: %w", err)
+	}
+
+	token := hex.EncodeToString(tokenBytes)
+	cc.internalConfig.Set(internalConfigKeyHTTPAPIToken, token)
+
+	if err := cc.writeInternalConfig(); err != nil {
+		return "", fmt.Errorf("save http api token: %w", err)
+	}
+
+	cc.logger.Debug("Generated HTTP API token")
+
+	return token, nil
+}
+
+func (cc *CanonicalConfig) writeInternalConfig() error {
+	if err := util.EnsureDirExists(internalConfigPath); err != nil {
+		return fmt.Errorf("ensure internal config dir exists: %w", err)
+	}
+
+	if err := cc.internalConfig.WriteConfigAs(path.Join(internalConfigPath, internalConfigFilepath)); err != nil {
+		cc.logger.Warnw("Viper failed to write internal config", "error", err)
+		return fmt.Errorf("write internal config: %w", err)
+	}
+
+	return nil
+}
+
+func validSeparator(separator string) bool {
+	return separator != "" && !strings.ContainsAny(separator, "0123456789\r\n")
+}
+
+func (cc *CanonicalConfig) isVirtualSlider(sliderID int) bool {
+	return funk.ContainsInt(cc.VirtualSliders, sliderID)
+}
+
+func (cc *CanonicalConfig) repeatsButton(buttonID int) bool {
+	return funk.ContainsInt(cc.RepeatButtons, buttonID)
+}
+
 func (cc *CanonicalConfig) onConfigReloaded() {
 	cc.logger.Debug("Notifying consumers about configuration reload")
 
+	settings := cc.queues.settingsFor(queueConfigReloads)
+	counters := cc.queues.countersFor(queueConfigReloads)
+
 	for _, consumer := range cc.reloadConsumers {
+		select {
+		case consumer <- true:
+			counters.queued(len(consumer))
+			continue
+		default:
+		}
+
+		// the consumer hasn't gotten to the last reload yet, and this one won't tell it anything new
+		if settings.Policy == queuePolicyCoalesce && len(consumer) > 0 {
+			atomic.AddUint64(&counters.coalesced, 1)
+			continue
+		}
+
+		atomic.AddUint64(&counters.blocked, 1)
+
 		consumer <- true
+		counters.queued(len(consumer))
 	}
 }
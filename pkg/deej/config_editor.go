@@ -0,0 +1,356 @@
+package deej
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/spf13/cast"
+	"github.com/thoas/go-funk"
+)
+
+// the config editor lets the web UI change config.yaml without users having to touch the file itself.
+// changes are validated up front, then written to the file - the regular file watcher takes it from there,
+// so they're applied without a restart just like manual edits are. only the settings listed in configSections
+// can be changed this way, minus the ones in configFileOnlyKeys, and credentials are masked when settings are read
+
+// ConfigSection groups related settings together for display purposes
+type ConfigSection struct {
+	Title string   `json:"title"`
+	Keys  []string `json:"keys"`
+}
+
+// settings not listed in any section are still shown (last), but can only be changed in the file itself
+var configSections = []ConfigSection{
+	{
+		Title: "Device",
+		Keys: []string{
+			configKeyConnectionType,
+			configKeyCOMPort,
+			configKeyBaudRate,
+			configKeyWebSocketURL,
+			configKeyWebSocketListen,
+			configKeyBluetoothAddress,
+			configKeyBluetoothChannel,
+			configKeyBluetoothMode,
+			configKeyHIDDevicePath,
+			configKeyHIDVendorID,
+			configKeyHIDProductID,
+			configKeyMockSliders,
+			configKeyMockButtons,
+			configKeyMockSweepPeriod,
+			configKeyMockButtonInterval,
+			configKeyLineTerminator,
+			configKeySliderSeparator,
+			configKeyButtonPrefix,
+			configKeyMaxAnalogValue,
+			configKeyBinaryProtocol,
+			configKeyCommandAcks,
+			configKeyEchoFrames,
+			configKeySequenceLoss,
+			configKeyPingInterval,
+			configKeySilenceTimeout,
+			configKeySliderHealthChecks,
+			configKeySliderStuckTime,
+		},
+	},
+	{
+		Title: "Mappings",
+		Keys: []string{
+			configKeySliderMapping,
+			configKeyButtonMapping,
+			configKeyButtonValueMapping,
+			configKeyReleaseMapping,
+			configKeyToggleMapping,
+			configKeyLayerMapping,
+			configKeyLongPressMapping,
+			configKeyLongPressThreshold,
+			configKeyDoublePressMapping,
+			configKeyDoublePressWindow,
+			configKeyDoublePressImmediate,
+			configKeyChordMapping,
+			configKeyChordWindow,
+			configKeyRepeatButtons,
+			configKeyButtonRepeatDelay,
+			configKeyButtonRepeatInterval,
+			configKeyExecCommands,
+			configKeyWebhooks,
+			configKeyButtonOSD,
+			configKeyMacros,
+			configKeyMacroRecordTime,
+			configKeyKeyMapFile,
+			configKeyMappingIndexBase,
+			configKeyVirtualSliders,
+			configKeyVirtualSliderHotkeys,
+			configKeyEncoderMapping,
+			configKeyProfiles,
+			configKeySelectorMapping,
+			configKeyButtonLabels,
+			configKeyProfileColor,
+			configKeyInvertSliders,
+		},
+	},
+	{
+		Title: "Behavior",
+		Keys: []string{
+			configKeyNoiseReductionLevel,
+			configKeySliderSettleTime,
+			configKeySliderSettleTimes,
+			configKeyButtonDebounceTime,
+			configKeyButtonDebounceTimes,
+			configKeyButtonCooldowns,
+			configKeyEncoderStep,
+			configKeyEncoderAcceleration,
+			configKeySliderKeyStep,
+			configKeyAutoMix,
+			configKeyAutoPause,
+			configKeyMissingTargets,
+			configKeyApplyOnAppStart,
+			configKeyStartupNotification,
+			configKeyVolumeFeedback,
+			configKeyAnnounceVolume,
+			configKeyAnnounceVolumeStep,
+			configKeyDisplayFeedback,
+			configKeyDisplayFormat,
+			configKeyProfileFeedback,
+			configKeyRecordingIndicator,
+			configKeyRecordingStarted,
+			configKeyRecordingStopped,
+		},
+	},
+	{
+		Title: "Integrations",
+		Keys: []string{
+			configKeyMQTTBroker,
+			configKeyMQTTClientID,
+			configKeyMQTTUsername,
+			configKeyMQTTPassword,
+			configKeyMQTTSliderTopic,
+			configKeyMQTTButtonTopic,
+			configKeyHTTPAPIListen,
+			configKeyDisabledIntegrations,
+			configKeyOBSWebSocketURL,
+			configKeyOBSWebSocketPassword,
+			configKeyDiscordClientID,
+			configKeyDiscordClientSecret,
+			configKeyDiscordFeedback,
+			configKeyRelayRole,
+			configKeyRelayListen,
+			configKeyRelayLeader,
+			configKeyRelayToken,
+		},
+	},
+}
+
+// configFileOnlyKeys can't be changed from outside the config file even though they're listed above: commands
+// (which would let anyone who can reach the HTTP API run anything), webhooks (which carry credentials that are
+// masked when read), and where the HTTP API listens (which could expose it to the network)
+var configFileOnlyKeys = []string{
+	configKeyExecCommands,
+	configKeyWebhooks,
+	configKeyHTTPAPIListen,
+}
+
+// configSecretKeys hold credentials, which are masked when settings are read. references to secrets.yaml or
+// environment variables are shown as they are, since they don't give anything away
+var configSecretKeys = []string{
+	configKeyMQTTPassword,
+	configKeyOBSWebSocketPassword,
+	configKeyDiscordClientSecret,
+	configKeyRelayToken,
+}
+
+// configURLKeys may have credentials in them, which are masked when settings are read
+var configURLKeys = []string{
+	configKeyWebSocketURL,
+	configKeyMQTTBroker,
+	configKeyOBSWebSocketURL,
+}
+
+// maskedSecret stands in for credentials when settings are read. writing it back leaves the setting as it was
+const maskedSecret = "********"
+
+// UserSettings returns all of the user's settings (including defaults for ones they didn't set), with
+// credentials masked
+func (cc *CanonicalConfig) UserSettings() map[string]interface{} {
+	settings := cc.userConfig.AllSettings()
+
+	for _, key := range configSecretKeys {
+		if value := cast.ToString(settings[key]); value != "" && !isSecretReference(value) {
+			settings[key] = maskedSecret
+		}
+	}
+
+	for _, key := range configURLKeys {
+		if value := cast.ToString(settings[key]); value != "" {
+			settings[key] = maskURLCredentials(value)
+		}
+	}
+
+	if webhooks, ok := settings[configKeyWebhooks].(map[string]interface{}); ok {
+		for _, rawWebhook := range webhooks {
+			webhook := cast.ToStringMap(rawWebhook)
+
+			if url := cast.ToString(webhook["url"]); url != "" {
+				webhook["url"] = maskURLCredentials(url)
+			}
+
+			headers := cast.ToStringMap(webhook["headers"])
+			for name, value := range headers {
+				if !isSecretReference(cast.ToString(value)) {
+					headers[name] = maskedSecret
+				}
+			}
+		}
+	}
+
+	return settings
+}
+
+// EditableUserSettings returns the keys UpdateUserSettings accepts
+func EditableUserSettings() []string {
+	keys := []string{}
+
+	for _, section := range configSections {
+		for _, key := range section.Keys {
+			if !funk.ContainsString(configFileOnlyKeys, key) {
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	return keys
+}
+
+// UpdateUserSettings validates the given settings and writes them to the user's config file.
+// settings that aren't mentioned are left as they are, and so are masked credentials written back as they were read
+func (cc *CanonicalConfig) UpdateUserSettings(settings map[string]interface{}) error {
+	if problems := validateUserSettings(settings); len(problems) > 0 {
+		return fmt.Errorf("invalid settings: %s", strings.Join(problems, "; "))
+	}
+
+	for key, value := range settings {
+		if value, ok := value.(string); ok && strings.Contains(value, maskedSecret) {
+			delete(settings, key)
+		}
+	}
+
+	// only the settings that changed are rewritten, the rest of the file stays as the user wrote it (see config_writer.go)
+	if err := cc.writeUserSettings(settings); err != nil {
+		cc.logger.Warnw("Failed to write edited user config", "error", err)
+		return fmt.Errorf("write edited user config: %w", err)
+	}
+
+	cc.logger.Infow("Updated user config", "keys", len(settings))
+
+	return nil
+}
+
+// validateUserSettings returns a description of each problem with the given settings. it's stricter than
+// loading the config, which falls back to defaults - here, we'd rather tell the user right away
+func validateUserSettings(settings map[string]interface{}) []string {
+	problems := []string{}
+
+	editable := EditableUserSettings()
+	for key := range settings {
+		if !funk.ContainsString(editable, key) {
+			problems = append(problems, fmt.Sprintf("%s can only be changed in the config file", key))
+		}
+	}
+
+	// anything that can reach the API shouldn't get to run commands
+	for key, value := range settings {
+		if containsExecAction(value) {
+			problems = append(problems, fmt.Sprintf("%s: %s actions can only be added in the config file", key, actionExecPrefix))
+		}
+	}
+
+	oneOf := func(key string, allowed ...string) {
+		if value, ok := settings[key]; ok && cast.ToString(value) != "" {
+			if !containsFold(allowed, cast.ToString(value)) {
+				problems = append(problems, fmt.Sprintf("%s must be one of %s", key, strings.Join(allowed, ", ")))
+			}
+		}
+	}
+
+	intInRange := func(key string, min int, max int) {
+		if value, ok := settings[key]; ok {
+			number, err := cast.ToIntE(value)
+			if err != nil || number < min || number > max {
+				problems = append(problems, fmt.Sprintf("%s must be a number between %d and %d", key, min, max))
+			}
+		}
+	}
+
+	separator := func(key string) {
+		if value, ok := settings[key]; ok && !validSeparator(cast.ToString(value)) {
+			problems = append(problems, fmt.Sprintf("%s can't be empty or contain digits", key))
+		}
+	}
+
+	oneOf(configKeyConnectionType, connectionTypes()...)
+	oneOf(configKeyBluetoothMode, bluetoothModes...)
+	oneOf(configKeyLineTerminator, lineTerminatorCRLF, lineTerminatorLF, lineTerminatorCR)
+	oneOf(configKeyNoiseReductionLevel, "low", "default", "high", noiseReductionAuto)
+
+	intInRange(configKeyBaudRate, 1, 4000000)
+	intInRange(configKeyBluetoothChannel, 0, 30)
+	intInRange(configKeyMappingIndexBase, 0, 1)
+	intInRange(configKeyMaxAnalogValue, 1, maxMaxAnalogValue)
+
+	separator(configKeySliderSeparator)
+	separator(configKeyButtonPrefix)
+
+	return problems
+}
+
+// maskURLCredentials replaces the password in a url, if it has one
+func maskURLCredentials(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.User == nil {
+		return rawURL
+	}
+
+	if _, ok := parsed.User.Password(); !ok {
+		return rawURL
+	}
+
+	parsed.User = url.UserPassword(parsed.User.Username(), maskedSecret)
+
+	// url.String escapes the mask, which would keep it from being recognized when written back
+	return strings.Replace(parsed.String(), url.QueryEscape(maskedSecret), maskedSecret, 1)
+}
+
+// containsExecAction returns whether a setting has an exec: action anywhere in it
+func containsExecAction(value interface{}) bool {
+	switch value := value.(type) {
+	case string:
+		return strings.HasPrefix(strings.ToLower(strings.TrimSpace(value)), actionExecPrefix)
+
+	case []interface{}:
+		for _, item := range value {
+			if containsExecAction(item) {
+				return true
+			}
+		}
+
+	case map[string]interface{}:
+		for _, item := range value {
+			if containsExecAction(item) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(candidate, value) {
+			return true
+		}
+	}
+
+	return false
+}
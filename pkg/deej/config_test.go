@@ -0,0 +1,95 @@
+package deej
+
+import (
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// newTestConfig reads the given YAML as the user's config, without populating the config's fields from it yet
+func newTestConfig(t testing.TB, configYAML string) *CanonicalConfig {
+	t.Helper()
+
+	config, err := NewConfig(zap.NewNop().Sugar(), testNotifier{})
+	if err != nil {
+		t.Fatalf("create config: %v", err)
+	}
+
+	if err := config.userConfig.ReadConfig(strings.NewReader(configYAML)); err != nil {
+		t.Fatalf("read config: %v", err)
+	}
+
+	return config
+}
+
+func TestMergeOSSection(t *testing.T) {
+	otherOS := "windows"
+	if runtime.GOOS == otherOS {
+		otherOS = "linux"
+	}
+
+	// sections are written as "this_os:" and "other_os:" below, whatever the tests run on
+	osSections := strings.NewReplacer("this_os:", runtime.GOOS+":", "other_os:", otherOS+":")
+
+	tests := []struct {
+		name          string
+		config        string
+		comPort       string
+		sliderMapping map[int][]string
+	}{
+		{
+			name:          "without a section, nothing changes",
+			config:        "com_port: COM4\nslider_mapping:\n  0: master\n  1: chrome.exe",
+			comPort:       "COM4",
+			sliderMapping: map[int][]string{0: {"master"}, 1: {"chrome.exe"}},
+		},
+		{
+			name:          "the section's settings win",
+			config:        "com_port: COM4\nthis_os:\n  com_port: /dev/ttyUSB0",
+			comPort:       "/dev/ttyUSB0",
+			sliderMapping: map[int][]string{},
+		},
+		{
+			name:          "mappings are merged slider by slider",
+			config:        "slider_mapping:\n  0: master\n  1: chrome.exe\nthis_os:\n  slider_mapping:\n    1: chrome",
+			comPort:       defaultCOMPort,
+			sliderMapping: map[int][]string{0: {"master"}, 1: {"chrome"}},
+		},
+		{
+			name:          "other OS sections are ignored",
+			config:        "com_port: COM4\nother_os:\n  com_port: /dev/ttyUSB0",
+			comPort:       "COM4",
+			sliderMapping: map[int][]string{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := newTestConfig(t, osSections.Replace(test.config))
+
+			if err := config.mergeOSSection(); err != nil {
+				t.Fatalf("merge: %v", err)
+			}
+
+			if err := config.populateFromVipers(); err != nil {
+				t.Fatalf("populate config: %v", err)
+			}
+
+			if config.ConnectionInfo.COMPort != test.comPort {
+				t.Errorf("com port: got %q, want %q", config.ConnectionInfo.COMPort, test.comPort)
+			}
+
+			sliderMapping := map[int][]string{}
+			config.SliderMapping.iterate(func(sliderIdx int, targets []string) {
+				sliderMapping[sliderIdx] = targets
+			})
+
+			if !reflect.DeepEqual(sliderMapping, test.sliderMapping) {
+				t.Errorf("slider mapping: got %v, want %v", sliderMapping, test.sliderMapping)
+			}
+		})
+	}
+}
@@ -0,0 +1,408 @@
+package deej
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/omriharel/deej/pkg/deej/util"
+)
+
+// when deej changes config.yaml itself (the web UI, recorded macros, turning integrations off), it only rewrites the
+// settings that actually changed, in place. every other line - comments, blank lines, the order of keys and however
+// the user chose to format things - stays exactly as it was. settings the file doesn't have yet are added at the end
+// of the mapping they belong in, in alphabetical order so the same change always ends up the same way. the file as it
+// was before is kept under backups, in case a change goes wrong anyway
+const (
+	configBackupPath       = "backups"
+	configBackupPrefix     = "config-"
+	configBackupTimeFormat = "20060102-150405.000"
+
+	// older backups are removed past this many
+	maxConfigBackups = 20
+
+	configIndent = 2
+)
+
+var (
+	errConfigNotMapping = errors.New("config isn't a mapping of settings")
+	errConfigInline     = errors.New("config is written as a single inline mapping")
+)
+
+// writeUserSettings writes the given settings (dotted keys for nested ones, i.e. macros.recorded_5) to the user's
+// config file, backing it up first
+func (cc *CanonicalConfig) writeUserSettings(settings map[string]interface{}) error {
+	info, err := os.Stat(userConfigFilepath)
+	if err != nil {
+		return fmt.Errorf("stat user config: %w", err)
+	}
+
+	original, err := ioutil.ReadFile(userConfigFilepath)
+	if err != nil {
+		return fmt.Errorf("read user config: %w", err)
+	}
+
+	keys := []string{}
+	for key := range settings {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	edited := original
+	for _, key := range keys {
+		edited, err = setConfigValue(edited, strings.Split(key, "."), settings[key])
+		if err != nil {
+			return fmt.Errorf("set %s: %w", key, err)
+		}
+	}
+
+	if bytes.Equal(edited, original) {
+		cc.logger.Debug("User config already has these settings, not writing it")
+		return nil
+	}
+
+	// never touch the file without a way back
+	backupPath, err := backupUserConfig(original)
+	if err != nil {
+		return fmt.Errorf("back up user config: %w", err)
+	}
+
+	cc.logger.Debugw("Backed up user config", "path", backupPath)
+
+	if err := ioutil.WriteFile(userConfigFilepath, edited, info.Mode()); err != nil {
+		return fmt.Errorf("write user config: %w", err)
+	}
+
+	return nil
+}
+
+// backupUserConfig saves a copy of the user's config under a timestamped name, and removes the oldest
+// copies past maxConfigBackups
+func backupUserConfig(content []byte) (string, error) {
+	if err := util.EnsureDirExists(configBackupPath); err != nil {
+		return "", fmt.Errorf("ensure backup dir exists: %w", err)
+	}
+
+	backupPath := path.Join(configBackupPath,
+		configBackupPrefix+time.Now().Format(configBackupTimeFormat)+"."+configType)
+
+	if err := ioutil.WriteFile(backupPath, content, 0644); err != nil {
+		return "", fmt.Errorf("write backup: %w", err)
+	}
+
+	entries, err := ioutil.ReadDir(configBackupPath)
+	if err != nil {
+		return backupPath, nil
+	}
+
+	// timestamps sort the same way as the names they're in
+	backups := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), configBackupPrefix) {
+			backups = append(backups, entry.Name())
+		}
+	}
+
+	sort.Strings(backups)
+
+	for len(backups) > maxConfigBackups {
+		os.Remove(path.Join(configBackupPath, backups[0]))
+		backups = backups[1:]
+	}
+
+	return backupPath, nil
+}
+
+// configText is a YAML document's lines, which settings are spliced into
+type configText struct {
+	lines      []string
+	lineEnding string
+}
+
+func newConfigText(content []byte) *configText {
+	text := &configText{lineEnding: "\n"}
+	if bytes.Contains(content, []byte("\r\n")) {
+		text.lineEnding = "\r\n"
+	}
+
+	trimmed := strings.TrimRight(strings.ReplaceAll(string(content), "\r\n", "\n"), "\n")
+	if trimmed != "" {
+		text.lines = strings.Split(trimmed, "\n")
+	}
+
+	return text
+}
+
+func (t *configText) bytes() []byte {
+	return []byte(strings.Join(t.lines, t.lineEnding) + t.lineEnding)
+}
+
+// replace swaps the lines from start up to (not including) stop for the given ones, counting from 0
+func (t *configText) replace(start int, stop int, lines []string) {
+	result := append([]string{}, t.lines[:start]...)
+	result = append(result, lines...)
+
+	t.lines = append(result, t.lines[stop:]...)
+}
+
+// entryEnd returns where the entry starting at start ends, given where the next thing in the document starts. blank
+// lines and comments right before the next thing are left out, since they go with it rather than with the entry
+func (t *configText) entryEnd(start int, next int) int {
+	for next > start+1 {
+		line := strings.TrimSpace(t.lines[next-1])
+		if line != "" && !strings.HasPrefix(line, "#") {
+			break
+		}
+
+		next--
+	}
+
+	return next
+}
+
+// setConfigValue sets the setting at the given path within a YAML document, and returns the document
+func setConfigValue(content []byte, settingPath []string, value interface{}) ([]byte, error) {
+	var document yaml.Node
+	if err := yaml.Unmarshal(content, &document); err != nil {
+		return nil, fmt.Errorf("parse config: %w", err)
+	}
+
+	valueNode := &yaml.Node{}
+	if err := valueNode.Encode(value); err != nil {
+		return nil, fmt.Errorf("encode value: %w", err)
+	}
+
+	text := newConfigText(content)
+
+	// a file with nothing but comments in it is as good as an empty mapping
+	if len(document.Content) == 0 {
+		document.Content = []*yaml.Node{{Kind: yaml.MappingNode}}
+	}
+
+	mapping := document.Content[0]
+	if mapping.Kind != yaml.MappingNode {
+		return nil, errConfigNotMapping
+	}
+
+	if mapping.Style&yaml.FlowStyle != 0 {
+		return nil, errConfigInline
+	}
+
+	// where the current mapping ends (exclusive, counting lines from 0)
+	mappingEnd := len(text.lines)
+
+	for depth, name := range settingPath {
+		keyIdx := findConfigKey(mapping, name)
+
+		// settings the file doesn't have yet go right after the last one in their mapping
+		if keyIdx == -1 {
+			entry := []*yaml.Node{configKeyNode(name), buildConfigPath(settingPath[depth+1:], valueNode)}
+
+			// only the document itself can get here empty, nested mappings are written out anew below. it's all
+			// comments at best, so the setting goes at the very end
+			if len(mapping.Content) == 0 {
+				lines, err := renderConfigEntry(entry[0], entry[1], 0)
+				if err != nil {
+					return nil, err
+				}
+
+				if len(text.lines) > 0 {
+					lines = append([]string{""}, lines...)
+				}
+
+				text.replace(len(text.lines), len(text.lines), lines)
+
+				return text.bytes(), nil
+			}
+
+			lastKey := mapping.Content[len(mapping.Content)-2]
+			lastStart := lastKey.Line - 1
+			insertAt := text.entryEnd(lastStart, mappingEnd)
+
+			lines, err := renderConfigEntry(entry[0], entry[1], mapping.Content[0].Column-1)
+			if err != nil {
+				return nil, err
+			}
+
+			// settings of their own are kept apart from the ones before them, like the rest of the file does
+			if depth == 0 {
+				lines = append([]string{""}, lines...)
+			}
+
+			text.replace(insertAt, insertAt, lines)
+
+			return text.bytes(), nil
+		}
+
+		keyNode, current := mapping.Content[keyIdx], mapping.Content[keyIdx+1]
+
+		start := keyNode.Line - 1
+		next := mappingEnd
+		if keyIdx+2 < len(mapping.Content) {
+			next = mapping.Content[keyIdx+2].Line - 1
+		}
+
+		stop := text.entryEnd(start, next)
+
+		var replacement *yaml.Node
+
+		switch {
+		case depth == len(settingPath)-1:
+			if sameConfigValue(current, valueNode) {
+				return content, nil
+			}
+
+			replacement = keepConfigStyle(current, valueNode)
+
+		// whatever's inside flow mappings ({}) and values that aren't mappings yet is written out anew
+		case current.Kind != yaml.MappingNode || current.Style&yaml.FlowStyle != 0:
+			replacement = current
+			if current.Kind != yaml.MappingNode {
+				replacement = &yaml.Node{Kind: yaml.MappingNode, LineComment: current.LineComment}
+			}
+
+			replacement.Style = 0
+			setConfigNodePath(replacement, settingPath[depth+1:], valueNode)
+
+		default:
+			mapping = current
+			mappingEnd = stop
+
+			continue
+		}
+
+		lines, err := renderConfigEntry(keyNode, replacement, keyNode.Column-1)
+		if err != nil {
+			return nil, err
+		}
+
+		text.replace(start, stop, lines)
+
+		return text.bytes(), nil
+	}
+
+	return text.bytes(), nil
+}
+
+// findConfigKey returns the index of the given key within a mapping node's content, or -1 if it isn't there.
+// viper doesn't care about the case of keys, so neither does this
+func findConfigKey(mapping *yaml.Node, name string) int {
+	for idx := 0; idx+1 < len(mapping.Content); idx += 2 {
+		if strings.EqualFold(mapping.Content[idx].Value, name) {
+			return idx
+		}
+	}
+
+	return -1
+}
+
+// configKeyNode creates a key the way the user would've typed it, i.e. button numbers aren't quoted
+func configKeyNode(name string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Value: name}
+}
+
+// buildConfigPath nests the value in mappings, one for each part of the path
+func buildConfigPath(settingPath []string, value *yaml.Node) *yaml.Node {
+	for idx := len(settingPath) - 1; idx >= 0; idx-- {
+		value = &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{configKeyNode(settingPath[idx]), value}}
+	}
+
+	return value
+}
+
+// setConfigNodePath sets the value at the given path under a mapping node, creating (or replacing) whatever's
+// in the way
+func setConfigNodePath(mapping *yaml.Node, settingPath []string, value *yaml.Node) {
+	keyIdx := findConfigKey(mapping, settingPath[0])
+	if keyIdx == -1 {
+		mapping.Content = append(mapping.Content, configKeyNode(settingPath[0]), buildConfigPath(settingPath[1:], value))
+		return
+	}
+
+	if len(settingPath) == 1 {
+		mapping.Content[keyIdx+1] = keepConfigStyle(mapping.Content[keyIdx+1], value)
+		return
+	}
+
+	child := mapping.Content[keyIdx+1]
+	if child.Kind != yaml.MappingNode {
+		child = &yaml.Node{Kind: yaml.MappingNode}
+		mapping.Content[keyIdx+1] = child
+	}
+
+	child.Style = 0
+	setConfigNodePath(child, settingPath[1:], value)
+}
+
+// sameConfigValue returns whether two nodes hold the same value, regardless of how each is written
+func sameConfigValue(a *yaml.Node, b *yaml.Node) bool {
+	var aValue, bValue interface{}
+	if a.Decode(&aValue) != nil || b.Decode(&bValue) != nil {
+		return false
+	}
+
+	return reflect.DeepEqual(aValue, bValue)
+}
+
+// keepConfigStyle writes a new value the way the one it replaces was written: lists that were written inline
+// stay inline, quoted strings stay quoted, and comments next to the value stay next to it
+func keepConfigStyle(old *yaml.Node, replacement *yaml.Node) *yaml.Node {
+	if old.Kind == replacement.Kind && (old.Kind != yaml.ScalarNode || old.Tag == replacement.Tag) {
+		replacement.Style = old.Style
+	}
+
+	replacement.LineComment = old.LineComment
+
+	return replacement
+}
+
+// renderConfigEntry writes out a single key and its value, indented by the given amount of spaces
+func renderConfigEntry(key *yaml.Node, value *yaml.Node, indent int) ([]string, error) {
+
+	// comments above and below the entry stay where they are in the file, they're not part of what's replaced
+	entryKey := *key
+	entryKey.HeadComment = ""
+	entryKey.FootComment = ""
+
+	entryValue := *value
+	entryValue.HeadComment = ""
+	entryValue.FootComment = ""
+
+	var buffer bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(configIndent)
+
+	entry := &yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{&entryKey, &entryValue}}
+	if err := encoder.Encode(entry); err != nil {
+		return nil, fmt.Errorf("render %s: %w", key.Value, err)
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("render %s: %w", key.Value, err)
+	}
+
+	text := newConfigText(buffer.Bytes())
+
+	// same goes for comments that ended up after the entry's last line
+	lines := text.lines[:text.entryEnd(0, len(text.lines))]
+
+	prefix := strings.Repeat(" ", indent)
+	for idx, line := range lines {
+		if line != "" {
+			lines[idx] = prefix + line
+		}
+	}
+
+	return lines, nil
+}
@@ -0,0 +1,173 @@
+package deej
+
+import (
+	"errors"
+	"testing"
+)
+
+// a config the way a user might have written it, comments and all
+const testUserConfig = `# deej config
+com_port: COM4 # the usb one
+
+# sliders
+slider_mapping:
+  0: master
+  1: [chrome.exe, firefox.exe]
+
+# the end
+`
+
+func TestSetConfigValue(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      string
+		settingPath []string
+		value       interface{}
+		want        string
+		err         error
+	}{
+		{
+			name:        "values change in place, next to their comments",
+			config:      testUserConfig,
+			settingPath: []string{"com_port"},
+			value:       "COM5",
+			want: `# deej config
+com_port: COM5 # the usb one
+
+# sliders
+slider_mapping:
+  0: master
+  1: [chrome.exe, firefox.exe]
+
+# the end
+`,
+		},
+		{
+			name:        "the same value leaves the file alone",
+			config:      testUserConfig,
+			settingPath: []string{"com_port"},
+			value:       "COM4",
+			want:        testUserConfig,
+		},
+		{
+			name:        "inline lists stay inline",
+			config:      testUserConfig,
+			settingPath: []string{"slider_mapping", "1"},
+			value:       []string{"spotify.exe", "discord.exe"},
+			want: `# deej config
+com_port: COM4 # the usb one
+
+# sliders
+slider_mapping:
+  0: master
+  1: [spotify.exe, discord.exe]
+
+# the end
+`,
+		},
+		{
+			name:        "new settings go after the last one, before the comments that follow it",
+			config:      testUserConfig,
+			settingPath: []string{"baud_rate"},
+			value:       115200,
+			want: `# deej config
+com_port: COM4 # the usb one
+
+# sliders
+slider_mapping:
+  0: master
+  1: [chrome.exe, firefox.exe]
+
+baud_rate: 115200
+
+# the end
+`,
+		},
+		{
+			name:        "new nested settings go at the end of their mapping",
+			config:      testUserConfig,
+			settingPath: []string{"slider_mapping", "2"},
+			value:       "mic",
+			want: `# deej config
+com_port: COM4 # the usb one
+
+# sliders
+slider_mapping:
+  0: master
+  1: [chrome.exe, firefox.exe]
+  2: mic
+
+# the end
+`,
+		},
+		{
+			name:        "missing mappings are created",
+			config:      testUserConfig,
+			settingPath: []string{"macros", "recorded_5"},
+			value:       []string{"F5"},
+			want: `# deej config
+com_port: COM4 # the usb one
+
+# sliders
+slider_mapping:
+  0: master
+  1: [chrome.exe, firefox.exe]
+
+macros:
+  recorded_5:
+    - F5
+
+# the end
+`,
+		},
+		{
+			name:        "a file with only comments gets the setting at the end",
+			config:      "# nothing here yet\n",
+			settingPath: []string{"com_port"},
+			value:       "COM5",
+			want:        "# nothing here yet\n\ncom_port: COM5\n",
+		},
+		{
+			name:        "an empty file gets the setting",
+			settingPath: []string{"com_port"},
+			value:       "COM5",
+			want:        "com_port: COM5\n",
+		},
+		{
+			name:        "inline configs are refused",
+			config:      "{com_port: COM4}",
+			settingPath: []string{"com_port"},
+			value:       "COM5",
+			err:         errConfigInline,
+		},
+		{
+			name:        "configs that aren't mappings are refused",
+			config:      "- COM4\n",
+			settingPath: []string{"com_port"},
+			value:       "COM5",
+			err:         errConfigNotMapping,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := setConfigValue([]byte(test.config), test.settingPath, test.value)
+
+			if test.err != nil {
+				if !errors.Is(err, test.err) {
+					t.Fatalf("error: got %v, want %v", err, test.err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("set %v: %v", test.settingPath, err)
+			}
+
+			if string(got) != test.want {
+				t.Errorf("config: got\n%s\nwant\n%s", got, test.want)
+			}
+		})
+	}
+}
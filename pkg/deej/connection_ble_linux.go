@@ -0,0 +1,290 @@
+package deej
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godbus/dbus"
+)
+
+// BLE devices are reached through BlueZ, over the system D-Bus. with the Nordic UART service, the board sends its
+// lines as notifications from its TX characteristic, and takes ours as writes to its RX characteristic
+const (
+	bluezBusName             = "org.bluez"
+	bluezDeviceIface         = "org.bluez.Device1"
+	bluezCharacteristicIface = "org.bluez.GattCharacteristic1"
+
+	dbusPropertiesChanged = "org.freedesktop.DBus.Properties.PropertiesChanged"
+
+	nordicUARTRXUUID = "6e400002-b5a3-f393-e0a9-e50e24dcca9e"
+	nordicUARTTXUUID = "6e400003-b5a3-f393-e0a9-e50e24dcca9e"
+
+	// bluez only lists a device's characteristics once it has looked up its services, which takes a moment
+	// after connecting
+	bleServicesTimeout      = 10 * time.Second
+	bleServicesPollInterval = 100 * time.Millisecond
+
+	// what fits in a single write without negotiating a bigger MTU
+	bleUARTChunkSize = 20
+
+	// what the board may send ahead of us reading it, past which it's dropped
+	bleUARTMaxBuffered = 64 * 1024
+)
+
+var (
+	errBLEDeviceNotFound = errors.New("device unknown to bluez, pair it (or scan for it) first")
+	errBLEUARTNotFound   = errors.New("device doesn't offer the Nordic UART service")
+)
+
+// bluezObjects is everything bluez knows about, by object path and then interface
+type bluezObjects map[dbus.ObjectPath]map[string]map[string]dbus.Variant
+
+// bleUARTConn is a connection to a BLE UART device. it handles the bus' signals itself, rather than through a
+// channel, since godbus otherwise delivers each one from a goroutine of its own - and the board's lines would
+// come in out of order
+type bleUARTConn struct {
+	bus *dbus.Conn
+
+	device dbus.ObjectPath
+	rx     dbus.ObjectPath
+	tx     dbus.ObjectPath
+
+	// what the board sent that hasn't been read yet, and whether more can come
+	lock     sync.Locker
+	received *sync.Cond
+	buffer   bytes.Buffer
+	closed   bool
+}
+
+func dialBLEUART(address [6]byte) (io.ReadWriteCloser, error) {
+	c := &bleUARTConn{lock: &sync.Mutex{}}
+	c.received = sync.NewCond(c.lock)
+
+	bus, err := dbus.SystemBusPrivateHandler(dbus.NewDefaultHandler(), c)
+	if err != nil {
+		return nil, fmt.Errorf("connect to system bus: %w", err)
+	}
+
+	c.bus = bus
+
+	if err := c.open(formatBluetoothAddress(address)); err != nil {
+		bus.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// open finds the device and its UART characteristics, connecting to it if bluez hasn't already
+func (c *bleUARTConn) open(address string) error {
+	if err := c.bus.Auth(nil); err != nil {
+		return fmt.Errorf("authenticate to system bus: %w", err)
+	}
+
+	if err := c.bus.Hello(); err != nil {
+		return fmt.Errorf("greet system bus: %w", err)
+	}
+
+	objects, err := c.objects()
+	if err != nil {
+		return err
+	}
+
+	for path, interfaces := range objects {
+		if device, ok := interfaces[bluezDeviceIface]; ok && strings.EqualFold(variantString(device["Address"]), address) {
+			c.device = path
+			break
+		}
+	}
+
+	if c.device == "" {
+		return errBLEDeviceNotFound
+	}
+
+	if connected, _ := objects[c.device][bluezDeviceIface]["Connected"].Value().(bool); !connected {
+		if err := c.bus.Object(bluezBusName, c.device).Call(bluezDeviceIface+".Connect", 0).Err; err != nil {
+			return fmt.Errorf("connect to device: %w", err)
+		}
+	}
+
+	if err := c.findCharacteristics(); err != nil {
+		return err
+	}
+
+	// listen before asking for notifications, so the board's first lines aren't missed
+	match := fmt.Sprintf("type='signal',sender='%s',interface='org.freedesktop.DBus.Properties',path_namespace='%s'",
+		bluezBusName, c.device)
+
+	if err := c.bus.BusObject().Call("org.freedesktop.DBus.AddMatch", 0, match).Err; err != nil {
+		return fmt.Errorf("subscribe to device changes: %w", err)
+	}
+
+	if err := c.bus.Object(bluezBusName, c.tx).Call(bluezCharacteristicIface+".StartNotify", 0).Err; err != nil {
+		return fmt.Errorf("start notifications: %w", err)
+	}
+
+	return nil
+}
+
+// findCharacteristics waits for bluez to list the device's UART characteristics
+func (c *bleUARTConn) findCharacteristics() error {
+	deadline := time.Now().Add(bleServicesTimeout)
+
+	for {
+		objects, err := c.objects()
+		if err != nil {
+			return err
+		}
+
+		for path, interfaces := range objects {
+			characteristic, ok := interfaces[bluezCharacteristicIface]
+			if !ok || !strings.HasPrefix(string(path), string(c.device)+"/") {
+				continue
+			}
+
+			switch strings.ToLower(variantString(characteristic["UUID"])) {
+			case nordicUARTRXUUID:
+				c.rx = path
+			case nordicUARTTXUUID:
+				c.tx = path
+			}
+		}
+
+		if c.rx != "" && c.tx != "" {
+			return nil
+		}
+
+		// once the services are resolved, whatever's missing isn't coming
+		resolved, _ := objects[c.device][bluezDeviceIface]["ServicesResolved"].Value().(bool)
+		if resolved || time.Now().After(deadline) {
+			return errBLEUARTNotFound
+		}
+
+		time.Sleep(bleServicesPollInterval)
+	}
+}
+
+func (c *bleUARTConn) objects() (bluezObjects, error) {
+	objects := bluezObjects{}
+
+	err := c.bus.Object(bluezBusName, "/").Call("org.freedesktop.DBus.ObjectManager.GetManagedObjects", 0).Store(&objects)
+	if err != nil {
+		return nil, fmt.Errorf("list bluez objects: %w", err)
+	}
+
+	return objects, nil
+}
+
+// DeliverSignal implements dbus.SignalHandler, taking in the board's notifications and noticing it disconnect
+func (c *bleUARTConn) DeliverSignal(iface string, name string, signal *dbus.Signal) {
+	if signal.Name != dbusPropertiesChanged || len(signal.Body) < 2 {
+		return
+	}
+
+	changedIface, _ := signal.Body[0].(string)
+	changed, _ := signal.Body[1].(map[string]dbus.Variant)
+
+	switch {
+	case signal.Path == c.tx && changedIface == bluezCharacteristicIface:
+		value, ok := changed["Value"].Value().([]byte)
+		if !ok {
+			return
+		}
+
+		c.lock.Lock()
+		defer c.lock.Unlock()
+
+		if c.buffer.Len()+len(value) <= bleUARTMaxBuffered {
+			c.buffer.Write(value)
+			c.received.Broadcast()
+		}
+
+	case signal.Path == c.device && changedIface == bluezDeviceIface:
+		if connected, ok := changed["Connected"].Value().(bool); ok && !connected {
+			c.Terminate()
+		}
+	}
+}
+
+// Terminate implements dbus.Terminator, called once the bus connection closes. reads end from then on
+func (c *bleUARTConn) Terminate() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.closed = true
+	c.received.Broadcast()
+}
+
+// Read implements io.Reader
+func (c *bleUARTConn) Read(p []byte) (int, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	for c.buffer.Len() == 0 && !c.closed {
+		c.received.Wait()
+	}
+
+	if c.buffer.Len() == 0 {
+		return 0, io.EOF
+	}
+
+	return c.buffer.Read(p)
+}
+
+// Write implements io.Writer, splitting p into writes small enough for the device to take
+func (c *bleUARTConn) Write(p []byte) (int, error) {
+	written := 0
+
+	for written < len(p) {
+		end := written + bleUARTChunkSize
+		if end > len(p) {
+			end = len(p)
+		}
+
+		err := c.bus.Object(bluezBusName, c.rx).Call(bluezCharacteristicIface+".WriteValue", 0,
+			p[written:end], map[string]dbus.Variant{}).Err
+
+		if err != nil {
+			return written, fmt.Errorf("write to device: %w", err)
+		}
+
+		written = end
+	}
+
+	return written, nil
+}
+
+// Close implements io.Closer. the device itself stays connected, so it's quick to pick up again
+func (c *bleUARTConn) Close() error {
+	c.bus.Object(bluezBusName, c.tx).Call(bluezCharacteristicIface+".StopNotify", 0)
+
+	if err := c.bus.Close(); err != nil {
+		return fmt.Errorf("close system bus connection: %w", err)
+	}
+
+	return nil
+}
+
+func (c *bleUARTConn) String() string {
+	return "<ble uart connection>"
+}
+
+// formatBluetoothAddress turns six address bytes back into "98:D3:31:FB:12:34"
+func formatBluetoothAddress(address [6]byte) string {
+	parts := make([]string, len(address))
+	for idx, b := range address {
+		parts[idx] = fmt.Sprintf("%02X", b)
+	}
+
+	return strings.Join(parts, ":")
+}
+
+func variantString(variant dbus.Variant) string {
+	value, _ := variant.Value().(string)
+	return value
+}
@@ -0,0 +1,12 @@
+package deej
+
+import (
+	"errors"
+	"io"
+)
+
+var errBLEUnsupported = errors.New("BLE UART devices are only supported on linux for now")
+
+func dialBLEUART(address [6]byte) (io.ReadWriteCloser, error) {
+	return nil, errBLEUnsupported
+}
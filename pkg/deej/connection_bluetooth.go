@@ -0,0 +1,95 @@
+package deej
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// classic bluetooth serial modules (HC-05, HC-06 and friends) speak RFCOMM, which deej connects
+// to directly by address - no need to bind a virtual COM port or /dev/rfcommN first. BLE UART modules
+// (and boards like the ESP32 and nRF52) speak the Nordic UART service instead, with bluetooth_mode set to ble.
+// these modules tend to drop out when they go out of range or when the board's battery dips,
+// so unlike a wired serial port, a lost bluetooth link is retried in the background
+const (
+	bluetoothModeRFCOMM = "rfcomm"
+	bluetoothModeBLE    = "ble"
+
+	defaultBluetoothChannel = 1
+
+	minBluetoothReconnectDelay = time.Second
+	maxBluetoothReconnectDelay = 30 * time.Second
+)
+
+var bluetoothModes = []string{bluetoothModeRFCOMM, bluetoothModeBLE}
+
+// parseBluetoothAddress turns "98:D3:31:FB:12:34" into its six bytes, most significant first
+func parseBluetoothAddress(address string) ([6]byte, error) {
+	var result [6]byte
+
+	raw, err := hex.DecodeString(strings.NewReplacer(":", "", "-", "").Replace(address))
+	if err != nil || len(raw) != len(result) {
+		return result, fmt.Errorf("invalid bluetooth address: %q", address)
+	}
+
+	copy(result[:], raw)
+
+	return result, nil
+}
+
+func (sio *SerialIO) openBluetooth() (io.ReadWriteCloser, error) {
+	address, err := parseBluetoothAddress(sio.connInfo.BluetoothAddress)
+	if err != nil {
+		sio.logger.Warnw("Failed to parse bluetooth address", "address", sio.connInfo.BluetoothAddress, "error", err)
+		return nil, fmt.Errorf("parse bluetooth address: %w", err)
+	}
+
+	sio.logger.Debugw("Attempting bluetooth connection",
+		"address", sio.connInfo.BluetoothAddress,
+		"mode", sio.connInfo.BluetoothMode,
+		"channel", sio.connInfo.BluetoothChannel)
+
+	var conn io.ReadWriteCloser
+	if sio.connInfo.BluetoothMode == bluetoothModeBLE {
+		conn, err = dialBLEUART(address)
+	} else {
+		conn, err = dialRFCOMM(address, sio.connInfo.BluetoothChannel)
+	}
+
+	if err != nil {
+		sio.logger.Warnw("Failed to open bluetooth connection", "error", err)
+		return nil, fmt.Errorf("open bluetooth connection: %w", err)
+	}
+
+	return conn, nil
+}
+
+// reconnectBluetooth keeps trying to re-establish a lost bluetooth link with an increasing delay
+// between attempts. it gives up once someone else (re)connects us, or the user switches to another connection type
+func (sio *SerialIO) reconnectBluetooth(logger *zap.SugaredLogger) {
+	delay := minBluetoothReconnectDelay
+
+	for {
+		logger.Debugw("Waiting before bluetooth reconnection attempt", "delay", delay)
+		<-time.After(delay)
+
+		if sio.Connected() || sio.deej.config.ConnectionInfo.Type != connectionTypeBluetooth {
+			logger.Debug("No longer need to reconnect bluetooth device")
+			return
+		}
+
+		if err := sio.Start(); err == nil {
+			logger.Info("Reconnected to bluetooth device")
+			return
+		}
+
+		// the device is most likely powered off, out of range or no longer paired - don't hammer it
+		if delay *= 2; delay > maxBluetoothReconnectDelay {
+			delay = maxBluetoothReconnectDelay
+		}
+	}
+}
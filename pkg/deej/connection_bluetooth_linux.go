@@ -0,0 +1,31 @@
+package deej
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+func dialRFCOMM(address [6]byte, channel int) (io.ReadWriteCloser, error) {
+	fd, err := unix.Socket(unix.AF_BLUETOOTH, unix.SOCK_STREAM, unix.BTPROTO_RFCOMM)
+	if err != nil {
+		return nil, fmt.Errorf("create rfcomm socket: %w", err)
+	}
+
+	// bluez expects the address bytes in reverse (little-endian) order
+	sockaddr := &unix.SockaddrRFCOMM{Channel: uint8(channel)}
+	for idx := range address {
+		sockaddr.Addr[idx] = address[len(address)-1-idx]
+	}
+
+	// this fails with EHOSTDOWN when the device is off or out of range, and with
+	// ECONNREFUSED when it isn't paired or nothing listens on the given channel
+	if err := unix.Connect(fd, sockaddr); err != nil {
+		unix.Close(fd)
+		return nil, fmt.Errorf("connect rfcomm socket: %w", err)
+	}
+
+	return os.NewFile(uintptr(fd), "rfcomm"), nil
+}
@@ -0,0 +1,104 @@
+package deej
+
+import (
+	"fmt"
+	"io"
+
+	"golang.org/x/sys/windows"
+)
+
+// the well-known serial port profile service class, which lets windows look up the RFCOMM channel
+// through SDP by itself. this also means windows will only connect to devices that are actually paired
+var serialPortServiceClassID = windows.GUID{
+	Data1: 0x00001101,
+	Data2: 0x0000,
+	Data3: 0x1000,
+	Data4: [8]byte{0x80, 0x00, 0x00, 0x80, 0x5F, 0x9B, 0x34, 0xFB},
+}
+
+// rfcommConn is a blocking RFCOMM socket
+type rfcommConn struct {
+	handle windows.Handle
+}
+
+func dialRFCOMM(address [6]byte, channel int) (io.ReadWriteCloser, error) {
+	var wsaData windows.WSAData
+	if err := windows.WSAStartup(uint32(0x202), &wsaData); err != nil {
+		return nil, fmt.Errorf("call WSAStartup: %w", err)
+	}
+
+	handle, err := windows.Socket(windows.AF_BTH, windows.SOCK_STREAM, windows.BTHPROTO_RFCOMM)
+	if err != nil {
+		windows.WSACleanup()
+		return nil, fmt.Errorf("create rfcomm socket: %w", err)
+	}
+
+	sockaddr := &windows.SockaddrBth{
+		ServiceClassId: serialPortServiceClassID,
+		Port:           uint32(channel),
+	}
+
+	for _, b := range address {
+		sockaddr.BtAddr = sockaddr.BtAddr<<8 | uint64(b)
+	}
+
+	if err := windows.Connect(handle, sockaddr); err != nil {
+		windows.Closesocket(handle)
+		windows.WSACleanup()
+		return nil, fmt.Errorf("connect rfcomm socket: %w", err)
+	}
+
+	return &rfcommConn{handle: handle}, nil
+}
+
+// Read implements io.Reader
+func (c *rfcommConn) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	var received, flags uint32
+	buf := windows.WSABuf{Len: uint32(len(p)), Buf: &p[0]}
+
+	if err := windows.WSARecv(c.handle, &buf, 1, &received, &flags, nil, nil); err != nil {
+		return int(received), fmt.Errorf("receive from rfcomm socket: %w", err)
+	}
+
+	// a graceful shutdown from the device's side
+	if received == 0 {
+		return 0, io.EOF
+	}
+
+	return int(received), nil
+}
+
+// Write implements io.Writer
+func (c *rfcommConn) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	var sent uint32
+	buf := windows.WSABuf{Len: uint32(len(p)), Buf: &p[0]}
+
+	if err := windows.WSASend(c.handle, &buf, 1, &sent, 0, nil, nil); err != nil {
+		return int(sent), fmt.Errorf("send to rfcomm socket: %w", err)
+	}
+
+	return int(sent), nil
+}
+
+// Close implements io.Closer
+func (c *rfcommConn) Close() error {
+	defer windows.WSACleanup()
+
+	if err := windows.Closesocket(c.handle); err != nil {
+		return fmt.Errorf("close rfcomm socket: %w", err)
+	}
+
+	return nil
+}
+
+func (c *rfcommConn) String() string {
+	return "<rfcomm connection>"
+}
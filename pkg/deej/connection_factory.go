@@ -0,0 +1,50 @@
+package deej
+
+import (
+	"io"
+	"strings"
+)
+
+// connectionFactory opens the connection to a device. SerialIO is its own factory, and opens whatever the
+// user's config asks for. tests hand SerialIO a factory of their own instead, which gives out in-memory
+// connections, so the whole line handling pipeline can be exercised without a device
+type connectionFactory interface {
+	openConnection(info ConnectionInfo) (conn io.ReadWriteCloser, name string, err error)
+}
+
+// openConnection implements connectionFactory, returning the connection along with a name to log it under
+func (sio *SerialIO) openConnection(info ConnectionInfo) (conn io.ReadWriteCloser, name string, err error) {
+	switch {
+	case sio.replayPath != "":
+		conn, err = openReplay(sio.logger, sio.replayPath, sio.replayPosition)
+		name = replayConnectionName
+	case info.Type == connectionTypeWebSocket:
+		conn, err = sio.openWebSocket()
+		name = connectionTypeWebSocket
+	case info.Type == connectionTypeMQTT:
+		conn, err = sio.openMQTT()
+		name = connectionTypeMQTT
+	case info.Type == connectionTypeBluetooth:
+		conn, err = sio.openBluetooth()
+		name = connectionTypeBluetooth
+	case info.Type == connectionTypeHID:
+		conn, err = sio.openHID()
+		name = connectionTypeHID
+	case info.Type == connectionTypeRelay:
+		conn, err = sio.openRelay()
+		name = connectionTypeRelay
+	case info.Type == connectionTypeMock:
+		conn = openMock(sio.logger, info, sio.deej.config)
+		name = connectionTypeMock
+	default:
+		if open, ok := lookupTransport(info.Type); ok {
+			conn, err = open(info)
+			name = info.Type
+		} else {
+			conn, err = sio.openSerial()
+			name = strings.ToLower(sio.connOptions.PortName)
+		}
+	}
+
+	return conn, name, err
+}
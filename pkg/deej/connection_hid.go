@@ -0,0 +1,154 @@
+package deej
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// hidConn reads slider and button lines from a custom raw HID device (e.g. a Pro Micro running
+// a RawHID sketch) instead of a CDC serial port. every input report carries one regular deej line
+// ("512|1023|0" or "~0~1~"), padded with zeroes up to the report size. this sidesteps COM port
+// numbering and driver issues entirely, since HID devices need no drivers on any OS
+type hidConn struct {
+	linePipe
+
+	logger *zap.SugaredLogger
+	device io.ReadWriteCloser
+}
+
+const (
+
+	// raw HID reports are 64 bytes on pretty much every board that supports them
+	hidReportSize = 64
+)
+
+// parseUSBID parses a vendor/product ID written as hex, with or without a 0x prefix (e.g. "2341" or "0x2341")
+func parseUSBID(id string) (uint16, error) {
+	value, err := strconv.ParseUint(strings.TrimPrefix(strings.ToLower(id), "0x"), 16, 16)
+	if err != nil {
+		return 0, fmt.Errorf("invalid usb id %q: %w", id, err)
+	}
+
+	return uint16(value), nil
+}
+
+func openHID(logger *zap.SugaredLogger, info ConnectionInfo) (*hidConn, error) {
+	logger = logger.Named("hid")
+
+	path := info.HIDDevicePath
+
+	// no explicit device path - look the device up by its IDs instead
+	if path == "" {
+		vendorID, err := parseUSBID(info.HIDVendorID)
+		if err != nil {
+			return nil, fmt.Errorf("parse vendor id: %w", err)
+		}
+
+		productID, err := parseUSBID(info.HIDProductID)
+		if err != nil {
+			return nil, fmt.Errorf("parse product id: %w", err)
+		}
+
+		if path, err = findHIDDevice(vendorID, productID); err != nil {
+			logger.Warnw("Failed to find HID device", "vendorID", info.HIDVendorID, "productID", info.HIDProductID, "error", err)
+			return nil, fmt.Errorf("find hid device: %w", err)
+		}
+
+		logger.Debugw("Found HID device", "path", path)
+	}
+
+	device, err := openHIDDevice(path)
+	if err != nil {
+		logger.Warnw("Failed to open HID device", "path", path, "error", err)
+		return nil, fmt.Errorf("open hid device: %w", err)
+	}
+
+	hc := &hidConn{
+		linePipe: newLinePipe(),
+		logger:   logger,
+		device:   device,
+	}
+
+	go hc.readReports()
+
+	return hc, nil
+}
+
+func (hc *hidConn) readReports() {
+
+	// leave room for a leading report ID, which some platforms always include
+	report := make([]byte, hidReportSize+1)
+
+	for {
+		n, err := hc.device.Read(report)
+		if err != nil {
+			hc.closeWithError(fmt.Errorf("read hid report: %w", err))
+			return
+		}
+
+		line := hidReportLine(report[:n])
+		if len(line) == 0 {
+			continue
+		}
+
+		if err := hc.deliver(line); err != nil {
+			return
+		}
+	}
+}
+
+// hidReportLine extracts the text line out of a raw report: it drops a leading report ID
+// (always a non-printable byte) and cuts off the zero padding
+func hidReportLine(report []byte) []byte {
+	for len(report) > 0 && report[0] < ' ' {
+		report = report[1:]
+	}
+
+	for idx, b := range report {
+		if b == 0 {
+			return report[:idx]
+		}
+	}
+
+	return report
+}
+
+// Write implements io.Writer by sending p as output reports, split up and zero-padded as needed
+func (hc *hidConn) Write(p []byte) (int, error) {
+	written := 0
+
+	for written < len(p) {
+
+		// the first byte is the report ID, which is always 0 for devices that don't number their reports
+		report := make([]byte, hidReportSize+1)
+		chunk := copy(report[1:], p[written:])
+
+		if _, err := hc.device.Write(report); err != nil {
+			return written, fmt.Errorf("write hid report: %w", err)
+		}
+
+		written += chunk
+	}
+
+	return written, nil
+}
+
+// Close implements io.Closer
+func (hc *hidConn) Close() error {
+	err := hc.device.Close()
+	hc.close()
+
+	if err != nil {
+		return fmt.Errorf("close hid device: %w", err)
+	}
+
+	return nil
+}
+
+func (hc *hidConn) String() string {
+	return "<hid connection>"
+}
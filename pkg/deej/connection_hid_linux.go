@@ -0,0 +1,42 @@
+package deej
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const hidrawClassPath = "/sys/class/hidraw"
+
+// findHIDDevice looks through the kernel's hidraw nodes for one that belongs to the given device
+func findHIDDevice(vendorID uint16, productID uint16) (string, error) {
+	entries, err := ioutil.ReadDir(hidrawClassPath)
+	if err != nil {
+		return "", fmt.Errorf("list hidraw devices: %w", err)
+	}
+
+	// uevent files identify the device like so: HID_ID=0003:00002341:00008036
+	wantedID := fmt.Sprintf(":%08X:%08X", vendorID, productID)
+
+	for _, entry := range entries {
+		uevent, err := ioutil.ReadFile(filepath.Join(hidrawClassPath, entry.Name(), "device", "uevent"))
+		if err != nil {
+			continue
+		}
+
+		for _, line := range strings.Split(string(uevent), "\n") {
+			if strings.HasPrefix(line, "HID_ID=") && strings.HasSuffix(strings.ToUpper(line), wantedID) {
+				return filepath.Join("/dev", entry.Name()), nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("no hidraw device with id %04x:%04x: %w", vendorID, productID, os.ErrNotExist)
+}
+
+func openHIDDevice(path string) (io.ReadWriteCloser, error) {
+	return os.OpenFile(path, os.O_RDWR, 0)
+}
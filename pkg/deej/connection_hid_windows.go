@@ -0,0 +1,58 @@
+package deej
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/windows"
+)
+
+// the HID device interface class, used to enumerate all present HID devices
+var hidInterfaceClassID = windows.GUID{
+	Data1: 0x4D1E55B2,
+	Data2: 0xF16F,
+	Data3: 0x11CF,
+	Data4: [8]byte{0x88, 0xCB, 0x00, 0x11, 0x11, 0x00, 0x00, 0x30},
+}
+
+// findHIDDevice looks through the present HID device interfaces for one that belongs to the given device
+func findHIDDevice(vendorID uint16, productID uint16) (string, error) {
+	paths, err := windows.CM_Get_Device_Interface_List("", &hidInterfaceClassID, windows.CM_GET_DEVICE_INTERFACE_LIST_PRESENT)
+	if err != nil {
+		return "", fmt.Errorf("list hid device interfaces: %w", err)
+	}
+
+	// interface paths identify the device like so: \\?\hid#vid_2341&pid_8036&mi_02#...
+	wantedID := fmt.Sprintf("vid_%04x&pid_%04x", vendorID, productID)
+
+	for _, path := range paths {
+		if strings.Contains(strings.ToLower(path), wantedID) {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("no hid device with id %04x:%04x: %w", vendorID, productID, os.ErrNotExist)
+}
+
+func openHIDDevice(path string) (io.ReadWriteCloser, error) {
+	pathPtr, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, fmt.Errorf("convert device path: %w", err)
+	}
+
+	handle, err := windows.CreateFile(pathPtr,
+		windows.GENERIC_READ|windows.GENERIC_WRITE,
+		windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE,
+		nil,
+		windows.OPEN_EXISTING,
+		0,
+		0)
+
+	if err != nil {
+		return nil, fmt.Errorf("open device handle: %w", err)
+	}
+
+	return os.NewFile(uintptr(handle), path), nil
+}
@@ -0,0 +1,50 @@
+package deej
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// linePipe turns discrete messages (websocket frames, MQTT payloads and the like) into the
+// CRLF-terminated byte stream that SerialIO's line reader expects from a serial port
+type linePipe struct {
+	reader *io.PipeReader
+	writer *io.PipeWriter
+}
+
+func newLinePipe() linePipe {
+	reader, writer := io.Pipe()
+
+	return linePipe{
+		reader: reader,
+		writer: writer,
+	}
+}
+
+// deliver hands a single message over to the reader as one line
+func (lp linePipe) deliver(message []byte) error {
+
+	// the line handler expects CRLF-terminated lines, which message-based transports rarely send
+	line := strings.TrimRight(string(message), "\r\n") + "\r\n"
+
+	if _, err := io.WriteString(lp.writer, line); err != nil {
+		return fmt.Errorf("deliver line: %w", err)
+	}
+
+	return nil
+}
+
+// Read implements io.Reader
+func (lp linePipe) Read(p []byte) (int, error) {
+	return lp.reader.Read(p)
+}
+
+// closeWithError makes the reader fail, which ends SerialIO's read loop
+func (lp linePipe) closeWithError(err error) {
+	lp.writer.CloseWithError(err)
+}
+
+func (lp linePipe) close() error {
+	return lp.writer.Close()
+}
@@ -0,0 +1,194 @@
+package deej
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// mockConn is a simulated device, for working on deej without any hardware attached (connection_type: mock).
+// it sweeps its sliders back and forth (each one a bit behind the one before it) and presses its buttons
+// one after the other, reporting both in combined lines just like a real board would. it also plays along
+// with everything deej sends it: it acknowledges every line, answers pings and identifies itself
+type mockConn struct {
+	linePipe
+
+	logger *zap.SugaredLogger
+
+	numSliders     int
+	numButtons     int
+	sweepPeriod    time.Duration
+	buttonInterval time.Duration
+
+	sliderSeparator string
+	buttonPrefix    string
+	maxAnalogValue  int
+
+	// whatever deej wrote that doesn't make up a whole line yet
+	pending     strings.Builder
+	pendingLock sync.Mutex
+
+	closed    chan bool
+	closeOnce sync.Once
+}
+
+const (
+	mockLineInterval = 100 * time.Millisecond
+
+	// how long a simulated button stays pressed
+	mockButtonPressDuration = 300 * time.Millisecond
+
+	mockProtocolVersion = 1
+
+	defaultMockSliders        = 5
+	defaultMockButtons        = 4
+	defaultMockSweepPeriod    = 10 * time.Second
+	defaultMockButtonInterval = 5 * time.Second
+)
+
+func openMock(logger *zap.SugaredLogger, info ConnectionInfo, config *CanonicalConfig) *mockConn {
+	mc := &mockConn{
+		linePipe:        newLinePipe(),
+		logger:          logger.Named(connectionTypeMock),
+		numSliders:      info.MockSliders,
+		numButtons:      info.MockButtons,
+		sweepPeriod:     info.MockSweepPeriod,
+		buttonInterval:  info.MockButtonInterval,
+		sliderSeparator: config.SliderSeparator,
+		buttonPrefix:    config.ButtonPrefix,
+		maxAnalogValue:  config.MaxAnalogValue,
+		closed:          make(chan bool),
+	}
+
+	mc.logger.Debugw("Simulating device",
+		"sliders", mc.numSliders,
+		"buttons", mc.numButtons,
+		"sweepPeriod", mc.sweepPeriod,
+		"buttonInterval", mc.buttonInterval)
+
+	go mc.run()
+
+	return mc
+}
+
+func (mc *mockConn) run() {
+	start := time.Now()
+
+	ticker := time.NewTicker(mockLineInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-mc.closed:
+			return
+		case now := <-ticker.C:
+			if err := mc.deliver([]byte(mc.buildLine(now.Sub(start)))); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// buildLine works out what the device reports at the given time since it started
+func (mc *mockConn) buildLine(elapsed time.Duration) string {
+	var line strings.Builder
+
+	for sliderIdx := 0; sliderIdx < mc.numSliders; sliderIdx++ {
+		if sliderIdx > 0 {
+			line.WriteString(mc.sliderSeparator)
+		}
+
+		// a triangle wave going from 0 to max and back, each slider trailing the previous one a little
+		phase := math.Mod(elapsed.Seconds()/mc.sweepPeriod.Seconds()+float64(sliderIdx)/float64(mc.numSliders), 1)
+		position := 1 - math.Abs(2*phase-1)
+
+		line.WriteString(strconv.Itoa(int(math.Round(position * float64(mc.maxAnalogValue)))))
+	}
+
+	// buttons take turns being pressed, one every button interval
+	pressedButton := -1
+	if mc.buttonInterval > 0 && mc.numButtons > 0 {
+		turn := int(elapsed / mc.buttonInterval)
+		if turn > 0 && elapsed-time.Duration(turn)*mc.buttonInterval < mockButtonPressDuration {
+			pressedButton = (turn - 1) % mc.numButtons
+		}
+	}
+
+	for buttonIdx := 0; buttonIdx < mc.numButtons; buttonIdx++ {
+		line.WriteString(mc.buttonPrefix)
+
+		if buttonIdx == pressedButton {
+			line.WriteString("1")
+		} else {
+			line.WriteString("0")
+		}
+	}
+
+	if mc.numButtons > 0 {
+		line.WriteString(mc.buttonPrefix)
+	}
+
+	return line.String()
+}
+
+// Write implements io.Writer. the simulated device answers each line deej sends it
+func (mc *mockConn) Write(p []byte) (int, error) {
+	mc.pendingLock.Lock()
+	defer mc.pendingLock.Unlock()
+
+	mc.pending.Write(p)
+	buffered := mc.pending.String()
+
+	// keep whatever comes after the last line ending for next time
+	lastEnd := strings.LastIndexAny(buffered, "\r\n")
+	if lastEnd == -1 {
+		return len(p), nil
+	}
+
+	mc.pending.Reset()
+	mc.pending.WriteString(buffered[lastEnd+1:])
+
+	for _, line := range strings.FieldsFunc(buffered[:lastEnd], func(r rune) bool { return r == '\r' || r == '\n' }) {
+		mc.answer(line)
+	}
+
+	return len(p), nil
+}
+
+func (mc *mockConn) answer(line string) {
+	replies := []string{"ACK"}
+
+	switch line {
+	case pingCommand:
+		replies = append(replies, "PONG")
+	case handshakeProbe:
+		replies = append(replies, fmt.Sprintf("DEEJ:%d:%d:%d", mockProtocolVersion, mc.numSliders, mc.numButtons))
+	}
+
+	// answering from here would block deej's writer until its reader picks the answers up
+	go func() {
+		for _, reply := range replies {
+			if err := mc.deliver([]byte(reply)); err != nil {
+				return
+			}
+		}
+	}()
+}
+
+// Close implements io.Closer
+func (mc *mockConn) Close() error {
+
+	// deej may close it more than once (i.e. while reconnecting), the simulation only stops the first time
+	mc.closeOnce.Do(func() { close(mc.closed) })
+
+	return mc.close()
+}
+
+func (mc *mockConn) String() string {
+	return fmt.Sprintf("<mock device: %d sliders, %d buttons>", mc.numSliders, mc.numButtons)
+}
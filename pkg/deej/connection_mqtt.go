@@ -0,0 +1,135 @@
+//go:build !slim
+// +build !slim
+
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"go.uber.org/zap"
+)
+
+// mqttConn consumes slider and button lines published to an MQTT broker, which lets
+// wireless mixers (and home-automation setups) drive deej without a direct connection.
+// each message payload is treated as a single line, exactly as if it arrived over serial
+type mqttConn struct {
+	linePipe
+
+	logger *zap.SugaredLogger
+	client mqtt.Client
+}
+
+const (
+	mqttClientID       = "deej"
+	mqttConnectTimeout = 10 * time.Second
+	mqttDisconnectWait = 250 // milliseconds, as expected by paho
+
+	// deej doesn't care about duplicates (the same line twice is harmless), so "at most once" is enough
+	mqttQoS = 0
+)
+
+func connectMQTT(logger *zap.SugaredLogger, info ConnectionInfo) (*mqttConn, error) {
+	mc := &mqttConn{
+		linePipe: newLinePipe(),
+		logger:   logger.Named("mqtt"),
+	}
+
+	topics := []string{}
+	for _, topic := range []string{info.MQTTSliderTopic, info.MQTTButtonTopic} {
+		if topic != "" {
+			topics = append(topics, topic)
+		}
+	}
+
+	if len(topics) == 0 {
+		mc.logger.Warn("MQTT connection requested, but no topics are configured")
+		return nil, errors.New("mqtt: no slider or button topic configured")
+	}
+
+	clientID := info.MQTTClientID
+	if clientID == "" {
+		clientID = mqttClientID
+	}
+
+	options := mqtt.NewClientOptions().
+		AddBroker(info.MQTTBroker).
+		SetClientID(clientID).
+		SetUsername(info.MQTTUsername).
+		SetPassword(info.MQTTPassword).
+		SetAutoReconnect(true)
+
+	// subscriptions don't survive a reconnect with a clean session, so (re-)subscribe whenever we connect
+	options.SetOnConnectHandler(func(client mqtt.Client) {
+		for _, topic := range topics {
+			token := client.Subscribe(topic, mqttQoS, mc.handleMessage)
+			if token.Wait() && token.Error() != nil {
+				mc.logger.Warnw("Failed to subscribe to topic", "topic", topic, "error", token.Error())
+				continue
+			}
+
+			mc.logger.Debugw("Subscribed to topic", "topic", topic)
+		}
+	})
+
+	options.SetConnectionLostHandler(func(client mqtt.Client, err error) {
+		mc.logger.Infow("Lost connection to MQTT broker, will keep retrying", "error", err)
+	})
+
+	mc.client = mqtt.NewClient(options)
+
+	token := mc.client.Connect()
+	if !token.WaitTimeout(mqttConnectTimeout) {
+		mc.logger.Warnw("Timed out connecting to MQTT broker", "broker", info.MQTTBroker)
+		return nil, fmt.Errorf("connect to mqtt broker: timed out after %s", mqttConnectTimeout)
+	}
+
+	if err := token.Error(); err != nil {
+		mc.logger.Warnw("Failed to connect to MQTT broker", "broker", info.MQTTBroker, "error", err)
+		return nil, fmt.Errorf("connect to mqtt broker: %w", err)
+	}
+
+	mc.logger.Debugw("Connected to MQTT broker", "broker", info.MQTTBroker, "topics", topics)
+
+	return mc, nil
+}
+
+func (mc *mqttConn) handleMessage(client mqtt.Client, message mqtt.Message) {
+	if err := mc.deliver(message.Payload()); err != nil {
+		mc.logger.Debugw("Failed to deliver MQTT message", "topic", message.Topic(), "error", err)
+	}
+}
+
+// Write implements io.Writer. deej doesn't publish anything back to the broker (yet)
+func (mc *mqttConn) Write(p []byte) (int, error) {
+	return 0, errors.New("mqtt: writing is not supported")
+}
+
+// Close implements io.Closer
+func (mc *mqttConn) Close() error {
+	mc.client.Disconnect(mqttDisconnectWait)
+
+	return mc.close()
+}
+
+func (mc *mqttConn) String() string {
+	return "<mqtt connection>"
+}
+
+func (sio *SerialIO) openMQTT() (io.ReadWriteCloser, error) {
+	sio.logger.Debugw("Attempting MQTT connection",
+		"broker", sio.connInfo.MQTTBroker,
+		"sliderTopic", sio.connInfo.MQTTSliderTopic,
+		"buttonTopic", sio.connInfo.MQTTButtonTopic)
+
+	conn, err := connectMQTT(sio.logger, sio.connInfo)
+	if err != nil {
+		sio.logger.Warnw("Failed to open MQTT connection", "error", err)
+		return nil, fmt.Errorf("open mqtt connection: %w", err)
+	}
+
+	return conn, nil
+}
@@ -0,0 +1,121 @@
+//go:build !slim
+// +build !slim
+
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// relayConn is a follower's end of the relay link (see relay.go), which stands in for the device. lines the leader
+// passes on are read just like ones from a serial port, while whatever deej sends the device is dropped: the leader
+// is the one talking to it, and it's in the same state as this instance anyway
+type relayConn struct {
+	linePipe
+
+	relay *relay
+	peer  *relayPeer
+}
+
+// the leader may just be restarting, so a lost link is retried in the background
+const relayReconnectDelay = 5 * time.Second
+
+func (sio *SerialIO) openRelay() (io.ReadWriteCloser, error) {
+	address := sio.connInfo.RelayLeader
+	if address == "" {
+		sio.logger.Warn("Relay follower role set, but relay_leader isn't")
+		return nil, errors.New("relay: no leader address configured")
+	}
+
+	url := "ws://" + address + relayPath
+
+	sio.logger.Debugw("Attempting relay connection", "url", url)
+
+	header := http.Header{}
+	header.Set(relayTokenHeader, sio.deej.config.RelayToken)
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, header)
+	if err != nil {
+		sio.logger.Warnw("Failed to connect to relay leader", "url", url, "error", err)
+		return nil, fmt.Errorf("connect to relay leader: %w", err)
+	}
+
+	rc := &relayConn{
+		linePipe: newLinePipe(),
+		relay:    sio.deej.relay,
+	}
+
+	rc.peer = rc.relay.addPeer(conn, "leader", address)
+
+	go func() {
+		err := rc.readMessages()
+		rc.relay.removePeer(rc.peer)
+		rc.closeWithError(err)
+	}()
+
+	return rc, nil
+}
+
+// readMessages hands lines over to the reader and takes on the leader's state, until the link fails
+func (rc *relayConn) readMessages() error {
+	for {
+		var message relayMessage
+		if err := rc.peer.conn.ReadJSON(&message); err != nil {
+			return fmt.Errorf("read relay message: %w", err)
+		}
+
+		switch message.Type {
+		case relayMessageLine:
+			if err := rc.deliver([]byte(message.Line)); err != nil {
+				return fmt.Errorf("deliver relayed line: %w", err)
+			}
+
+		case relayMessageState:
+			if message.State != nil {
+				rc.relay.applyState(*message.State)
+			}
+		}
+	}
+}
+
+// Write implements io.Writer, dropping p since only the leader talks to the device
+func (rc *relayConn) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// Close implements io.Closer
+func (rc *relayConn) Close() error {
+	rc.relay.removePeer(rc.peer)
+
+	return rc.close()
+}
+
+func (rc *relayConn) String() string {
+	return "<relay connection>"
+}
+
+// reconnectRelay keeps trying to get the relay link back, until someone else (re)connects us
+// or the user stops following
+func (sio *SerialIO) reconnectRelay(logger *zap.SugaredLogger) {
+	for {
+		logger.Debugw("Waiting before relay reconnection attempt", "delay", relayReconnectDelay)
+		<-time.After(relayReconnectDelay)
+
+		if sio.Connected() || sio.deej.config.ConnectionInfo.Type != connectionTypeRelay {
+			logger.Debug("No longer need to reconnect relay link")
+			return
+		}
+
+		if err := sio.Start(); err == nil {
+			logger.Info("Reconnected to relay leader")
+			return
+		}
+	}
+}
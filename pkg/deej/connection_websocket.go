@@ -0,0 +1,190 @@
+//go:build !slim
+// +build !slim
+
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// websocketConn adapts a websocket link (e.g. an ESP32 board over WiFi) into the same
+// line-oriented byte stream that a serial port provides. every text message received from
+// the board is treated as a single line, so firmware can keep sending "512|1023|..." as-is.
+// it can either dial out to the board (client mode) or wait for the board to connect (server mode)
+type websocketConn struct {
+	linePipe
+
+	logger *zap.SugaredLogger
+
+	lock   sync.Mutex
+	peer   *websocket.Conn
+	server *http.Server
+}
+
+// boards (and relay followers) don't send an origin header, which the upgrader's default origin check lets
+// through. browsers always send one, so this still turns away web pages trying to connect from elsewhere
+var websocketUpgrader = websocket.Upgrader{}
+
+func newWebSocketConn(logger *zap.SugaredLogger) *websocketConn {
+	return &websocketConn{
+		linePipe: newLinePipe(),
+		logger:   logger.Named("websocket"),
+	}
+}
+
+// dialWebSocket connects to a board that runs a websocket server
+func dialWebSocket(logger *zap.SugaredLogger, url string) (*websocketConn, error) {
+	wc := newWebSocketConn(logger)
+
+	peer, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		wc.logger.Warnw("Failed to dial websocket", "url", url, "error", err)
+		return nil, fmt.Errorf("dial websocket: %w", err)
+	}
+
+	wc.logger.Debugw("Connected to websocket server", "url", url)
+	wc.setPeer(peer)
+
+	// in client mode, losing the peer means losing the connection
+	go func() {
+		err := wc.readMessages(peer)
+		wc.closeWithError(err)
+	}()
+
+	return wc, nil
+}
+
+// listenWebSocket waits for boards to connect to us. only one board is served at a time,
+// and a newly connected board replaces the previous one (this covers reconnects after a WiFi drop)
+func listenWebSocket(logger *zap.SugaredLogger, address string) (*websocketConn, error) {
+	wc := newWebSocketConn(logger)
+
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		wc.logger.Warnw("Failed to listen for websocket connections", "address", address, "error", err)
+		return nil, fmt.Errorf("listen for websocket connections: %w", err)
+	}
+
+	wc.server = &http.Server{Handler: http.HandlerFunc(wc.handleUpgrade)}
+
+	go func() {
+		if err := wc.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			wc.logger.Warnw("Websocket server stopped unexpectedly", "error", err)
+			wc.closeWithError(err)
+		}
+	}()
+
+	wc.logger.Debugw("Listening for websocket connections", "address", listener.Addr())
+
+	return wc, nil
+}
+
+func (wc *websocketConn) handleUpgrade(w http.ResponseWriter, r *http.Request) {
+	peer, err := websocketUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		wc.logger.Warnw("Failed to upgrade websocket connection", "remote", r.RemoteAddr, "error", err)
+		return
+	}
+
+	wc.logger.Infow("Board connected", "remote", r.RemoteAddr)
+	wc.setPeer(peer)
+
+	// in server mode a peer going away is fine, we just wait for the next one
+	if err := wc.readMessages(peer); err != nil {
+		wc.logger.Infow("Board disconnected", "remote", r.RemoteAddr, "error", err)
+	}
+}
+
+// readMessages pumps messages from the given peer into our pipe until it fails
+func (wc *websocketConn) readMessages(peer *websocket.Conn) error {
+	for {
+		_, message, err := peer.ReadMessage()
+		if err != nil {
+			return fmt.Errorf("read websocket message: %w", err)
+		}
+
+		if err := wc.deliver(message); err != nil {
+			return fmt.Errorf("deliver websocket message: %w", err)
+		}
+	}
+}
+
+func (wc *websocketConn) setPeer(peer *websocket.Conn) {
+	wc.lock.Lock()
+	defer wc.lock.Unlock()
+
+	if wc.peer != nil {
+		wc.peer.Close()
+	}
+
+	wc.peer = peer
+}
+
+// Write implements io.Writer by sending p as a single text message to the connected board
+func (wc *websocketConn) Write(p []byte) (int, error) {
+	wc.lock.Lock()
+	defer wc.lock.Unlock()
+
+	if wc.peer == nil {
+		return 0, errors.New("websocket: no board connected")
+	}
+
+	if err := wc.peer.WriteMessage(websocket.TextMessage, p); err != nil {
+		return 0, fmt.Errorf("write websocket message: %w", err)
+	}
+
+	return len(p), nil
+}
+
+// Close implements io.Closer
+func (wc *websocketConn) Close() error {
+	wc.lock.Lock()
+	defer wc.lock.Unlock()
+
+	if wc.server != nil {
+		wc.server.Close()
+	}
+
+	if wc.peer != nil {
+		wc.peer.Close()
+		wc.peer = nil
+	}
+
+	return wc.close()
+}
+
+func (wc *websocketConn) String() string {
+	return "<websocket connection>"
+}
+
+func (sio *SerialIO) openWebSocket() (io.ReadWriteCloser, error) {
+	var conn *websocketConn
+	var err error
+
+	// prefer dialing out to the board if we know where it is, otherwise let it come to us
+	if sio.connInfo.WebSocketURL != "" {
+		sio.logger.Debugw("Attempting websocket connection", "url", sio.connInfo.WebSocketURL)
+		conn, err = dialWebSocket(sio.logger, sio.connInfo.WebSocketURL)
+	} else if sio.connInfo.WebSocketListen != "" {
+		sio.logger.Debugw("Waiting for websocket connections", "address", sio.connInfo.WebSocketListen)
+		conn, err = listenWebSocket(sio.logger, sio.connInfo.WebSocketListen)
+	} else {
+		sio.logger.Warn("Websocket connection requested, but neither websocket_url nor websocket_listen are set")
+		return nil, errors.New("websocket: no url or listen address configured")
+	}
+
+	if err != nil {
+		sio.logger.Warnw("Failed to open websocket connection", "error", err)
+		return nil, fmt.Errorf("open websocket connection: %w", err)
+	}
+
+	return conn, nil
+}
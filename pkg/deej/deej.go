@@ -25,15 +25,31 @@ type Deej struct {
 	config   *CanonicalConfig
 	serial   *SerialIO
 	sessions *sessionMap
+	api      *httpAPI
+
+	integrations *integrationManager
+	relay        *relay
+	obs          *obsClient
+	discord      *discordClient
+
+	supervisor *supervisor
+
+	// pokes the tray into showing what changed right away, rather than on its next update
+	trayRefresh chan bool
 
 	stopChannel chan bool
 	version     string
 	verbose     bool
+
+	// set from the command line, to serve runtime profiles over the HTTP API
+	profiling bool
+	startup   *startupTimer
 }
 
 // NewDeej creates a Deej instance
 func NewDeej(logger *zap.SugaredLogger, verbose bool) (*Deej, error) {
 	logger = logger.Named("deej")
+	startup := newStartupTimer()
 
 	notifier, err := NewToastNotifier(logger)
 	if err != nil {
@@ -47,12 +63,17 @@ func NewDeej(logger *zap.SugaredLogger, verbose bool) (*Deej, error) {
 		return nil, fmt.Errorf("create new Config: %w", err)
 	}
 
+	startup.mark("createConfig")
+
 	d := &Deej{
 		logger:      logger,
 		notifier:    notifier,
 		config:      config,
+		trayRefresh: make(chan bool, 1),
 		stopChannel: make(chan bool),
 		verbose:     verbose,
+		supervisor:  newSupervisor(logger),
+		startup:     startup,
 	}
 
 	serial, err := NewSerialIO(d, logger)
@@ -62,12 +83,11 @@ func NewDeej(logger *zap.SugaredLogger, verbose bool) (*Deej, error) {
 	}
 
 	d.serial = serial
+	d.startup.mark("createSerial")
 
-	sessionFinder, err := newSessionFinder(logger)
-	if err != nil {
-		logger.Errorw("Failed to create SessionFinder", "error", err)
-		return nil, fmt.Errorf("create new SessionFinder: %w", err)
-	}
+	sessionFinder := newSessionFinderOrStub(logger)
+
+	d.startup.mark("createSessionFinder")
 
 	sessions, err := newSessionMap(d, logger, sessionFinder)
 	if err != nil {
@@ -76,6 +96,19 @@ func NewDeej(logger *zap.SugaredLogger, verbose bool) (*Deej, error) {
 	}
 
 	d.sessions = sessions
+	d.startup.mark("createSessionMap")
+
+	api, err := newHTTPAPI(d, logger)
+	if err != nil {
+		logger.Errorw("Failed to create HTTP API", "error", err)
+		return nil, fmt.Errorf("create new HTTP API: %w", err)
+	}
+
+	d.api = api
+	d.obs = newOBSClient(d, logger)
+	d.discord = newDiscordClient(d, logger)
+	d.integrations = newIntegrationManager(d, logger)
+	d.relay = newRelay(d, logger)
 
 	logger.Debug("Created deej instance")
 
@@ -92,12 +125,16 @@ func (d *Deej) Initialize() error {
 		return fmt.Errorf("load config during init: %w", err)
 	}
 
+	d.startup.mark("loadConfig")
+
 	// initialize the session map
 	if err := d.sessions.initialize(); err != nil {
 		d.logger.Errorw("Failed to initialize session map", "error", err)
 		return fmt.Errorf("init session map: %w", err)
 	}
 
+	d.startup.mark("initSessionMap")
+
 	// decide whether to run with/without tray
 	if _, noTraySet := os.LookupEnv(envNoTray); noTraySet {
 
@@ -120,6 +157,63 @@ func (d *Deej) SetVersion(version string) {
 	d.version = version
 }
 
+// EnableProfiling causes deej to serve runtime profiles (net/http/pprof) over its HTTP API, if called before
+// Initialize. the HTTP API still needs to be enabled in the config for this to do anything
+func (d *Deej) EnableProfiling() {
+	d.profiling = true
+}
+
+// RecordSerialTraffic causes deej to record everything the device sends to the given file, with timestamps,
+// if called before Initialize
+func (d *Deej) RecordSerialTraffic(path string) {
+	d.serial.recordPath = path
+}
+
+// ReplaySerialTraffic causes deej to play back a recording made with RecordSerialTraffic at its original timing,
+// instead of connecting to a device, if called before Initialize
+func (d *Deej) ReplaySerialTraffic(path string) {
+	d.serial.replayPath = path
+}
+
+// SubscribeToSliderMoveEvents returns a channel that receives an event whenever a slider moves,
+// whether it's a physical one or a virtual one
+func (d *Deej) SubscribeToSliderMoveEvents() chan SliderMoveEvent {
+	return d.serial.SubscribeToSliderMoveEvents()
+}
+
+// SubscribeToButtonPressEvents returns a channel that receives an event whenever a button's value changes
+func (d *Deej) SubscribeToButtonPressEvents() chan ButtonPressEvent {
+	return d.serial.SubscribeToButtonPressEvents()
+}
+
+// UnsubscribeFromSliderMoveEvents stops sending slider move events to a channel returned by
+// SubscribeToSliderMoveEvents, and closes it
+func (d *Deej) UnsubscribeFromSliderMoveEvents(ch chan SliderMoveEvent) {
+	d.serial.UnsubscribeFromSliderMoveEvents(ch)
+}
+
+// UnsubscribeFromButtonPressEvents stops sending button events to a channel returned by
+// SubscribeToButtonPressEvents, and closes it
+func (d *Deej) UnsubscribeFromButtonPressEvents(ch chan ButtonPressEvent) {
+	d.serial.UnsubscribeFromButtonPressEvents(ch)
+}
+
+// ButtonStats returns how many times a button was pressed so far, and when it was last pressed
+func (d *Deej) ButtonStats(buttonID int) ButtonStats {
+	return d.serial.buttonStats.get(buttonID)
+}
+
+// ButtonToggled returns whether a toggle button (see toggle_mapping) is currently on
+func (d *Deej) ButtonToggled(buttonID int) bool {
+	return d.serial.toggles.get(buttonID)
+}
+
+// RecordingApps returns the apps recording from an input device right now (see recording_indicator),
+// empty when none are
+func (d *Deej) RecordingApps() []string {
+	return d.sessions.recording.recordingApps()
+}
+
 // Verbose returns a boolean indicating whether deej is running in verbose mode
 func (d *Deej) Verbose() bool {
 	return d.verbose
@@ -141,9 +235,42 @@ func (d *Deej) run() {
 	// watch the config file for changes
 	go d.config.WatchConfigFileChanges()
 
+	// restart any module that crashes or hangs from here on
+	go d.supervisor.run()
+
+	// serve the HTTP API, if enabled. failing to do so isn't a reason to stop
+	if err := d.api.start(); err != nil {
+		d.logger.Warnw("Failed to start HTTP API", "error", err)
+		d.notifier.Notify("Can't start HTTP API!",
+			fmt.Sprintf("Failed to listen on %s, check your configuration.", d.config.HTTPAPIListen))
+	}
+
+	d.startup.mark("startHTTPAPI")
+
+	// each integration connects (and retries) on its own time
+	d.integrations.start()
+
+	// serve followers and keep them in sync, if this is a relay leader (followers connect like they would to a device)
+	if err := d.relay.start(); err != nil {
+		d.logger.Warnw("Failed to start relay link", "error", err)
+		d.notifier.Notify("Can't start relay link!",
+			fmt.Sprintf("Failed to listen on %s, check your configuration.", d.config.RelayListen))
+	}
+
+	// connect as soon as the device is plugged in, if the OS lets us know
+	d.serial.watchHotplug()
+
+	// move virtual sliders from the keyboard, if any hotkeys are set up
+	d.serial.hotkeys.update()
+
 	// connect to the arduino for the first time
 	go func() {
-		if err := d.serial.Start(); err != nil {
+		err := d.serial.Start()
+
+		d.startup.mark("firstConnection")
+		d.startup.finish(d.logger)
+
+		if err != nil {
 			d.logger.Warnw("Failed to start first-time serial connection", "error", err)
 
 			// If the port is busy, that's because something else is connected - notify and quit
@@ -156,6 +283,14 @@ func (d *Deej) run() {
 
 				d.signalStop()
 
+				// the device may just not be plugged in yet, in which case we'll connect once it is
+			} else if errors.Is(err, os.ErrNotExist) && d.serial.hotplugWatching {
+				d.logger.Infow("Serial port not found, waiting for it to be plugged in",
+					"comPort", d.config.ConnectionInfo.COMPort)
+
+				d.notifier.Notify(fmt.Sprintf("Waiting for %s...", d.config.ConnectionInfo.COMPort),
+					"deej will connect once your device is plugged in. If it already is, check your configuration.")
+
 				// also notify if the COM port they gave isn't found, maybe their config is wrong
 			} else if errors.Is(err, os.ErrNotExist) {
 				d.logger.Warnw("Provided COM port seems wrong, notifying user and closing",
@@ -165,6 +300,13 @@ func (d *Deej) run() {
 					"This serial port doesn't exist, check your configuration and make sure it's set correctly.")
 
 				d.signalStop()
+
+				// the relay leader may not be up yet, keep trying until it is
+			} else if d.config.ConnectionInfo.Type == connectionTypeRelay {
+				d.logger.Infow("Relay leader not reachable, waiting for it",
+					"leader", d.config.ConnectionInfo.RelayLeader)
+
+				go d.serial.reconnectRelay(d.serial.logger)
 			}
 		}
 	}()
@@ -192,6 +334,11 @@ func (d *Deej) stop() error {
 
 	d.config.StopWatchingConfigFile()
 	d.serial.Stop()
+	d.serial.stopRecording()
+	d.api.stop()
+	d.integrations.stop()
+	d.relay.stop()
+	d.supervisor.stop()
 
 	// release the session map
 	if err := d.sessions.release(); err != nil {
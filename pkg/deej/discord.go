@@ -0,0 +1,108 @@
+package deej
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// buttons can mute and deafen the Discord client itself (rather than pressing its keybinds, which only work while
+// they're set up and Discord isn't fighting another app for them): discord_mute_toggle and discord_deafen_toggle
+// flip whatever Discord says the state is right now. deej talks to the running Discord client over its local RPC
+// socket as the "discord" integration, once discord_client_id and discord_client_secret are set (from an app made
+// at https://discord.com/developers/applications). Discord asks the user to allow deej once every time deej starts.
+// with discord_feedback on, the board is told whenever the state changes, for LEDs: "DSC:<muted>:<deafened>",
+// i.e. "DSC:1:0" while muted but not deafened
+const (
+	actionDiscordMuteToggle   = "discord_mute_toggle"
+	actionDiscordDeafenToggle = "discord_deafen_toggle"
+
+	discordIntegrationName = "discord"
+
+	discordToggleMute   = "mute"
+	discordToggleDeafen = "deafen"
+
+	discordFeedbackInterval = 250 * time.Millisecond
+	discordFeedbackFormat   = "DSC:%d:%d"
+)
+
+// discordVoiceState is whether Discord is muted and deafened, as it last told us
+type discordVoiceState struct {
+	Muted    bool `json:"mute"`
+	Deafened bool `json:"deaf"`
+}
+
+// feedbackLine is what the board is told about the state
+func (s discordVoiceState) feedbackLine() string {
+	return fmt.Sprintf(discordFeedbackFormat, boolToInt(s.Muted), boolToInt(s.Deafened))
+}
+
+func boolToInt(value bool) int {
+	if value {
+		return 1
+	}
+
+	return 0
+}
+
+// controlDiscord flips Discord's mute or deafen in the background, so a slow Discord doesn't hold up the buttons
+func (sio *SerialIO) controlDiscord(logger *zap.SugaredLogger, notifier Notifier, action buttonAction) {
+	go func() {
+		state, err := sio.deej.discord.toggle(action.discordToggle)
+		if err != nil {
+			logger.Warnw("Failed to control Discord", "action", action.name, "error", err)
+			notifier.Notify("Can't control Discord!", fmt.Sprintf("%s didn't go through: %v", action.name, err))
+
+			return
+		}
+
+		logger.Infow("Controlled Discord", "action", action.name, "muted", state.Muted, "deafened", state.Deafened)
+	}()
+}
+
+// runDiscordFeedback sends Discord's voice state to the board whenever it changes, and again after reconnecting
+func (sio *SerialIO) runDiscordFeedback(ctx context.Context, logger *zap.SugaredLogger) {
+	logger = logger.Named("discord_feedback")
+	logger.Debug("Starting Discord feedback")
+
+	lastSentLine := ""
+	ticker := time.NewTicker(discordFeedbackInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Debug("Stopping Discord feedback")
+			return
+
+		case <-ticker.C:
+			if !sio.deej.config.DiscordFeedback {
+				continue
+			}
+
+			// nothing to send until Discord told us how it's doing
+			state, ok := sio.deej.discord.voiceState()
+			if !ok {
+				continue
+			}
+
+			line := state.feedbackLine()
+			if line == lastSentLine {
+				continue
+			}
+
+			if err := sio.SendCommand(line); err != nil {
+				logger.Debugw("Failed to send Discord feedback", "error", err)
+				continue
+			}
+
+			if sio.deej.Verbose() {
+				logger.Debugw("Sent Discord feedback", "line", line)
+			}
+
+			lastSentLine = line
+		}
+	}
+}
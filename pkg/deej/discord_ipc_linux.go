@@ -0,0 +1,43 @@
+//go:build !slim
+// +build !slim
+
+package deej
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+)
+
+// Discord listens on a unix socket in the runtime directory, or the temp directory if there's none. flatpak
+// and snap installs put theirs in a directory of their own under it
+var discordIPCSubdirs = []string{"", "app/com.discordapp.Discord", "snap.discord"}
+
+// openDiscordIPC connects to the first Discord client that's listening
+func openDiscordIPC() (io.ReadWriteCloser, error) {
+	baseDirs := []string{}
+	for _, variable := range []string{"XDG_RUNTIME_DIR", "TMPDIR", "TMP", "TEMP"} {
+		if dir := os.Getenv(variable); dir != "" {
+			baseDirs = append(baseDirs, dir)
+		}
+	}
+
+	baseDirs = append(baseDirs, "/tmp")
+
+	for _, baseDir := range baseDirs {
+		for _, subdir := range discordIPCSubdirs {
+			for socketIdx := 0; socketIdx < discordIPCSockets; socketIdx++ {
+				path := filepath.Join(baseDir, subdir, fmt.Sprintf("discord-ipc-%d", socketIdx))
+
+				conn, err := net.Dial("unix", path)
+				if err == nil {
+					return conn, nil
+				}
+			}
+		}
+	}
+
+	return nil, errDiscordNotRunning
+}
@@ -0,0 +1,110 @@
+//go:build !slim
+// +build !slim
+
+package deej
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"golang.org/x/sys/windows"
+)
+
+// openDiscordIPC connects to the first Discord client that's listening, on one of its named pipes
+func openDiscordIPC() (io.ReadWriteCloser, error) {
+	for pipeIdx := 0; pipeIdx < discordIPCSockets; pipeIdx++ {
+		pipe, err := openDiscordPipe(fmt.Sprintf(`\\.\pipe\discord-ipc-%d`, pipeIdx))
+		if err == nil {
+			return pipe, nil
+		}
+	}
+
+	return nil, errDiscordNotRunning
+}
+
+// discordPipe is a named pipe opened for overlapped I/O. a pipe opened the usual way only does one thing at a
+// time, so writes would wait for the read that's always waiting on Discord
+type discordPipe struct {
+	handle windows.Handle
+
+	closeOnce sync.Once
+}
+
+func openDiscordPipe(name string) (*discordPipe, error) {
+	path, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return nil, fmt.Errorf("convert pipe name: %w", err)
+	}
+
+	handle, err := windows.CreateFile(path,
+		windows.GENERIC_READ|windows.GENERIC_WRITE,
+		0,
+		nil,
+		windows.OPEN_EXISTING,
+		windows.FILE_FLAG_OVERLAPPED,
+		0)
+
+	if err != nil {
+		return nil, fmt.Errorf("open pipe: %w", err)
+	}
+
+	return &discordPipe{handle: handle}, nil
+}
+
+func (p *discordPipe) Read(b []byte) (int, error) {
+	n, err := p.do(windows.ReadFile, b)
+
+	// the rest of the message is there for the next read
+	if err == windows.ERROR_MORE_DATA {
+		err = nil
+	}
+
+	if err == windows.ERROR_BROKEN_PIPE || (err == nil && n == 0 && len(b) > 0) {
+		return n, io.EOF
+	}
+
+	return n, err
+}
+
+func (p *discordPipe) Write(b []byte) (int, error) {
+	return p.do(windows.WriteFile, b)
+}
+
+// Close also ends whichever read or write is still waiting
+func (p *discordPipe) Close() error {
+	err := os.ErrClosed
+
+	p.closeOnce.Do(func() {
+		windows.CancelIoEx(p.handle, nil)
+		err = windows.CloseHandle(p.handle)
+	})
+
+	return err
+}
+
+// do starts a read or write and waits for it to finish, with an event of its own so others can go on meanwhile
+func (p *discordPipe) do(operation func(windows.Handle, []byte, *uint32, *windows.Overlapped) error, b []byte) (int, error) {
+	event, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		return 0, fmt.Errorf("create event: %w", err)
+	}
+
+	defer windows.CloseHandle(event)
+
+	overlapped := &windows.Overlapped{HEvent: event}
+
+	var done uint32
+	err = operation(p.handle, b, &done, overlapped)
+
+	if err == windows.ERROR_IO_PENDING {
+		err = windows.GetOverlappedResult(p.handle, overlapped, &done, true)
+	}
+
+	if err == windows.ERROR_OPERATION_ABORTED || err == windows.ERROR_INVALID_HANDLE {
+		err = os.ErrClosed
+	}
+
+	return int(done), err
+}
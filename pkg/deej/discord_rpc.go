@@ -0,0 +1,492 @@
+//go:build !slim
+// +build !slim
+
+package deej
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// discordClient talks to the Discord client over its local RPC socket, and is the "discord" integration. every
+// message is a frame: an opcode and a length (both little-endian uint32s) followed by that much JSON. after the
+// handshake, deej asks to be authorized (which Discord asks the user about), trades the code it gets for an access
+// token, authenticates with it, and subscribes to voice settings changes so it always knows whether Discord is
+// muted and deafened. the token is kept for as long as deej runs, so reconnecting doesn't ask the user again
+const (
+	discordIPCSockets = 10
+
+	discordOpHandshake = 0
+	discordOpFrame     = 1
+	discordOpClose     = 2
+	discordOpPing      = 3
+	discordOpPong      = 4
+
+	discordRPCVersion = 1
+
+	discordTokenURL       = "https://discord.com/api/oauth2/token"
+	discordTokenTimeout   = 10 * time.Second
+	discordRequestTimeout = 5 * time.Second
+
+	// frames are tiny, anything bigger than this means we lost track of where they start
+	discordMaxFrameSize = 1 << 20
+)
+
+var discordScopes = []string{"rpc", "rpc.voice.read", "rpc.voice.write"}
+
+var (
+	errDiscordNotRunning   = errors.New("Discord isn't running")
+	errDiscordNotConnected = errors.New("not connected to Discord")
+	errDiscordClosed       = errors.New("Discord closed the connection")
+)
+
+// discordMessage is a command sent to Discord, its response, or an event it dispatched
+type discordMessage struct {
+	Command string          `json:"cmd"`
+	Event   string          `json:"evt,omitempty"`
+	Nonce   string          `json:"nonce,omitempty"`
+	Args    interface{}     `json:"args,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+type discordError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type discordClient struct {
+	deej   *Deej
+	logger *zap.SugaredLogger
+
+	lock sync.Locker
+
+	// the current connection, or nil while there's none
+	conn io.ReadWriteCloser
+
+	// commands waiting for an answer, by nonce
+	pending   map[string]chan discordMessage
+	lastNonce int
+
+	// kept across connections, so the user only has to allow deej once
+	accessToken string
+
+	// the voice state Discord last told us about, if it told us at all
+	state      discordVoiceState
+	knownState bool
+}
+
+func newDiscordClient(deej *Deej, logger *zap.SugaredLogger) *discordClient {
+	return &discordClient{
+		deej:    deej,
+		logger:  logger.Named(discordIntegrationName),
+		lock:    &sync.Mutex{},
+		pending: map[string]chan discordMessage{},
+	}
+}
+
+// settings implements configuredIntegration
+func (c *discordClient) settings() (string, bool) {
+	clientID, clientSecret := c.deej.config.DiscordClientID, c.deej.config.DiscordClientSecret
+
+	return clientID + "\n" + clientSecret, clientID != "" && clientSecret != ""
+}
+
+// Run implements Integration
+func (c *discordClient) Run(ctx context.Context, connected func()) error {
+	conn, err := openDiscordIPC()
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	// reading blocks until the connection goes away, so take it away once we're done
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	if err := c.login(conn); err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	c.conn = conn
+	c.lock.Unlock()
+
+	defer c.disconnect()
+
+	// answers only come in while something's reading, so that starts before asking anything
+	received := make(chan error, 1)
+	go func() {
+		for {
+			if err := c.receive(conn); err != nil {
+				received <- err
+				return
+			}
+		}
+	}()
+
+	if _, err := c.send(discordMessage{Command: "SUBSCRIBE", Event: "VOICE_SETTINGS_UPDATE"}, false); err != nil {
+		return fmt.Errorf("subscribe to voice settings: %w", err)
+	}
+
+	if _, err := c.send(discordMessage{Command: "GET_VOICE_SETTINGS"}, true); err != nil {
+		return fmt.Errorf("get voice settings: %w", err)
+	}
+
+	connected()
+
+	return <-received
+}
+
+// login goes through the handshake, and authenticates with a token (getting a new one if there's none yet, or
+// Discord doesn't take the one we have anymore)
+func (c *discordClient) login(conn io.ReadWriteCloser) error {
+	clientID := c.deej.config.DiscordClientID
+
+	handshake := map[string]interface{}{"v": discordRPCVersion, "client_id": clientID}
+	if err := writeDiscordFrame(conn, discordOpHandshake, handshake); err != nil {
+		return fmt.Errorf("send handshake to Discord: %w", err)
+	}
+
+	if _, err := c.await(conn, "", "READY"); err != nil {
+		return fmt.Errorf("wait for Discord to be ready: %w", err)
+	}
+
+	if c.accessToken != "" {
+		err := c.authenticate(conn)
+		if err == nil {
+			return nil
+		}
+
+		c.logger.Debugw("Discord didn't take our access token, authorizing again", "error", err)
+		c.accessToken = ""
+	}
+
+	c.logger.Info("Asking Discord to allow deej, check the Discord window")
+
+	args := map[string]interface{}{"client_id": clientID, "scopes": discordScopes}
+	if err := writeDiscordFrame(conn, discordOpFrame, discordMessage{Command: "AUTHORIZE", Nonce: "authorize", Args: args}); err != nil {
+		return fmt.Errorf("ask Discord for authorization: %w", err)
+	}
+
+	response, err := c.await(conn, "authorize", "")
+	if err != nil {
+		return fmt.Errorf("get authorized by Discord: %w", err)
+	}
+
+	var authorization struct {
+		Code string `json:"code"`
+	}
+
+	if err := json.Unmarshal(response.Data, &authorization); err != nil {
+		return fmt.Errorf("parse Discord authorization: %w", err)
+	}
+
+	if c.accessToken, err = c.exchangeCode(authorization.Code); err != nil {
+		return fmt.Errorf("get Discord access token: %w", err)
+	}
+
+	return c.authenticate(conn)
+}
+
+func (c *discordClient) authenticate(conn io.ReadWriteCloser) error {
+	args := map[string]interface{}{"access_token": c.accessToken}
+	if err := writeDiscordFrame(conn, discordOpFrame, discordMessage{Command: "AUTHENTICATE", Nonce: "authenticate", Args: args}); err != nil {
+		return fmt.Errorf("authenticate to Discord: %w", err)
+	}
+
+	if _, err := c.await(conn, "authenticate", ""); err != nil {
+		return fmt.Errorf("authenticate to Discord: %w", err)
+	}
+
+	c.logger.Debug("Authenticated to Discord")
+
+	return nil
+}
+
+// exchangeCode trades an authorization code for an access token, through Discord's OAuth2 API
+func (c *discordClient) exchangeCode(code string) (string, error) {
+	form := url.Values{
+		"client_id":     {c.deej.config.DiscordClientID},
+		"client_secret": {c.deej.config.DiscordClientSecret},
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+	}
+
+	client := http.Client{Timeout: discordTokenTimeout}
+
+	response, err := client.Post(discordTokenURL, "application/x-www-form-urlencoded", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("send request: %w", err)
+	}
+
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", fmt.Errorf("read response: %w", err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Discord answered with %s (check discord_client_secret)", response.Status)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+
+	if err := json.Unmarshal(body, &token); err != nil || token.AccessToken == "" {
+		return "", errors.New("no access token in Discord's answer")
+	}
+
+	return token.AccessToken, nil
+}
+
+// await reads frames until the one with the given nonce (or event, for those without one) shows up. it's only
+// used while logging in, before anything else is going on
+func (c *discordClient) await(conn io.ReadWriteCloser, nonce string, event string) (discordMessage, error) {
+	for {
+		op, payload, err := readDiscordFrame(conn)
+		if err != nil {
+			return discordMessage{}, err
+		}
+
+		switch op {
+		case discordOpClose:
+			return discordMessage{}, closedByDiscord(payload)
+
+		case discordOpPing:
+			writeDiscordFrame(conn, discordOpPong, json.RawMessage(payload))
+			continue
+		}
+
+		var message discordMessage
+		if err := json.Unmarshal(payload, &message); err != nil {
+			return discordMessage{}, fmt.Errorf("parse message: %w", err)
+		}
+
+		if message.Event == "ERROR" && (nonce == "" || message.Nonce == nonce) {
+			return message, messageError(message)
+		}
+
+		if (nonce != "" && message.Nonce == nonce) || (nonce == "" && message.Event == event) {
+			return message, nil
+		}
+	}
+}
+
+// receive reads a single frame once connected, answering pings, passing responses on to whoever's waiting
+// for them and keeping track of voice settings updates
+func (c *discordClient) receive(conn io.ReadWriteCloser) error {
+	op, payload, err := readDiscordFrame(conn)
+	if err != nil {
+		return fmt.Errorf("read from Discord: %w", err)
+	}
+
+	switch op {
+	case discordOpClose:
+		return closedByDiscord(payload)
+
+	case discordOpPing:
+		c.lock.Lock()
+		defer c.lock.Unlock()
+
+		return writeDiscordFrame(conn, discordOpPong, json.RawMessage(payload))
+	}
+
+	if op != discordOpFrame {
+		return nil
+	}
+
+	var message discordMessage
+	if err := json.Unmarshal(payload, &message); err != nil {
+		c.logger.Debugw("Failed to parse Discord message", "error", err)
+		return nil
+	}
+
+	if message.Command == "DISPATCH" && message.Event == "VOICE_SETTINGS_UPDATE" {
+		c.updateState(message.Data)
+		return nil
+	}
+
+	c.lock.Lock()
+	responses, ok := c.pending[message.Nonce]
+	delete(c.pending, message.Nonce)
+	c.lock.Unlock()
+
+	if ok {
+		responses <- message
+	}
+
+	return nil
+}
+
+func (c *discordClient) updateState(data json.RawMessage) (discordVoiceState, error) {
+	var state discordVoiceState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return state, fmt.Errorf("parse voice settings: %w", err)
+	}
+
+	c.lock.Lock()
+	c.state, c.knownState = state, true
+	c.lock.Unlock()
+
+	return state, nil
+}
+
+// disconnect forgets the current connection, failing every command that's still waiting on it
+func (c *discordClient) disconnect() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.conn = nil
+	c.knownState = false
+
+	for nonce, responses := range c.pending {
+		close(responses)
+		delete(c.pending, nonce)
+	}
+}
+
+// send sends a command to Discord, and if wait is true, waits for its answer. commands that answer with voice
+// settings update what we know about them
+func (c *discordClient) send(message discordMessage, wait bool) (discordVoiceState, error) {
+	c.lock.Lock()
+
+	if c.conn == nil {
+		c.lock.Unlock()
+		return discordVoiceState{}, errDiscordNotConnected
+	}
+
+	c.lastNonce++
+	message.Nonce = strconv.Itoa(c.lastNonce)
+
+	responses := make(chan discordMessage, 1)
+	c.pending[message.Nonce] = responses
+
+	err := writeDiscordFrame(c.conn, discordOpFrame, message)
+	if err != nil || !wait {
+		delete(c.pending, message.Nonce)
+	}
+
+	c.lock.Unlock()
+
+	if err != nil {
+		return discordVoiceState{}, fmt.Errorf("send %s to Discord: %w", message.Command, err)
+	}
+
+	if !wait {
+		return discordVoiceState{}, nil
+	}
+
+	select {
+	case response, ok := <-responses:
+		if !ok {
+			return discordVoiceState{}, errDiscordNotConnected
+		}
+
+		if response.Event == "ERROR" {
+			return discordVoiceState{}, messageError(response)
+		}
+
+		return c.updateState(response.Data)
+
+	case <-time.After(discordRequestTimeout):
+		c.lock.Lock()
+		delete(c.pending, message.Nonce)
+		c.lock.Unlock()
+
+		return discordVoiceState{}, fmt.Errorf("Discord didn't answer %s in time", message.Command)
+	}
+}
+
+// toggle flips Discord's mute or deafen, going by whatever Discord says it is right now, and returns the new state
+func (c *discordClient) toggle(what string) (discordVoiceState, error) {
+	state, err := c.send(discordMessage{Command: "GET_VOICE_SETTINGS"}, true)
+	if err != nil {
+		return state, err
+	}
+
+	args := map[string]interface{}{}
+
+	switch what {
+	case discordToggleMute:
+		args["mute"] = !state.Muted
+	case discordToggleDeafen:
+		args["deaf"] = !state.Deafened
+	}
+
+	return c.send(discordMessage{Command: "SET_VOICE_SETTINGS", Args: args}, true)
+}
+
+// voiceState returns whether Discord is muted and deafened, and false if we don't know
+func (c *discordClient) voiceState() (discordVoiceState, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.state, c.knownState
+}
+
+func writeDiscordFrame(conn io.Writer, op uint32, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("encode frame: %w", err)
+	}
+
+	frame := make([]byte, 8, 8+len(data))
+	binary.LittleEndian.PutUint32(frame[0:4], op)
+	binary.LittleEndian.PutUint32(frame[4:8], uint32(len(data)))
+
+	_, err = conn.Write(append(frame, data...))
+	return err
+}
+
+func readDiscordFrame(conn io.Reader) (uint32, []byte, error) {
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return 0, nil, err
+	}
+
+	op, length := binary.LittleEndian.Uint32(header[0:4]), binary.LittleEndian.Uint32(header[4:8])
+	if length > discordMaxFrameSize {
+		return 0, nil, fmt.Errorf("frame too large (%d bytes)", length)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(conn, payload); err != nil {
+		return 0, nil, err
+	}
+
+	return op, payload, nil
+}
+
+func closedByDiscord(payload []byte) error {
+	var reason discordError
+	if json.Unmarshal(payload, &reason) == nil && reason.Message != "" {
+		return fmt.Errorf("%w: %s (code %d)", errDiscordClosed, reason.Message, reason.Code)
+	}
+
+	return errDiscordClosed
+}
+
+func messageError(message discordMessage) error {
+	var reason discordError
+	json.Unmarshal(message.Data, &reason)
+
+	return fmt.Errorf("Discord refused %s: %s (code %d)", message.Command, reason.Message, reason.Code)
+}
@@ -0,0 +1,90 @@
+package deej
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// display feedback pushes a short label for each slider to the device, so boards with a small
+// display per channel (i.e. OLEDs) can show what each slider controls. the message sent for each
+// slider is user-configurable, and is only re-sent when it changes (due to a mapping or volume change)
+const (
+	displayFeedbackInterval = 500 * time.Millisecond
+
+	displayFormatIndex   = "{index}"
+	displayFormatTargets = "{targets}"
+	displayFormatPercent = "{percent}"
+
+	defaultDisplayFeedbackFormat = "LBL:" + displayFormatIndex + ":" + displayFormatTargets
+)
+
+func (sio *SerialIO) runDisplayFeedback(ctx context.Context, logger *zap.SugaredLogger) {
+	logger = logger.Named("display")
+	logger.Debug("Starting display feedback")
+
+	// the last message sent for each slider
+	lastSentLines := map[int]string{}
+
+	ticker := time.NewTicker(displayFeedbackInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Debug("Stopping display feedback")
+			return
+
+		case <-ticker.C:
+			if !sio.deej.config.DisplayFeedback {
+				continue
+			}
+
+			sliderValues, _ := sio.SliderValues()
+
+			for sliderIdx := range sliderValues {
+				line := sio.buildDisplayFeedbackLine(sliderIdx, sliderValues[sliderIdx])
+
+				if line == lastSentLines[sliderIdx] {
+					continue
+				}
+
+				if err := sio.SendCommand(line); err != nil {
+					logger.Debugw("Failed to send display feedback", "error", err)
+					break
+				}
+
+				if sio.deej.Verbose() {
+					logger.Debugw("Sent display feedback", "line", line)
+				}
+
+				lastSentLines[sliderIdx] = line
+			}
+		}
+	}
+}
+
+func (sio *SerialIO) buildDisplayFeedbackLine(sliderIdx int, sliderValue float32) string {
+
+	// show apps by their friendly names where possible, rather than as raw executable names
+	targetNames := sio.deej.sessions.sliderDisplayNames(sliderIdx)
+
+	volume, ok := sio.deej.sessions.sliderVolume(sliderIdx)
+	if !ok {
+		volume = sliderValue
+	}
+
+	percent := ""
+	if volume >= 0 {
+		percent = strconv.Itoa(int(volume*100 + 0.5))
+	}
+
+	return strings.NewReplacer(
+		displayFormatIndex, strconv.Itoa(sliderIdx+sio.deej.config.MappingIndexBase),
+		displayFormatTargets, strings.Join(targetNames, ","),
+		displayFormatPercent, percent,
+	).Replace(sio.deej.config.DisplayFeedbackFormat)
+}
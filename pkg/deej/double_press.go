@@ -0,0 +1,82 @@
+package deej
+
+import (
+	"sync"
+	"time"
+)
+
+const defaultDoublePressWindow = 300 * time.Millisecond
+
+// buttonTapTracker waits to see whether a tap on a button with double-press actions is followed by another
+// one. a second press within the window is a double press, otherwise the first tap was a single press after all
+type buttonTapTracker struct {
+	pending map[int]*pendingTap
+	lock    sync.Locker
+}
+
+type pendingTap struct {
+	press ButtonPressEvent
+	timer *time.Timer
+}
+
+func newButtonTapTracker() *buttonTapTracker {
+	return &buttonTapTracker{
+		pending: map[int]*pendingTap{},
+		lock:    &sync.Mutex{},
+	}
+}
+
+// wait starts the window for a second tap, calling onExpire (on its own goroutine) if none comes
+func (t *buttonTapTracker) wait(event ButtonPressEvent, window time.Duration, onExpire func(ButtonPressEvent)) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if previous, ok := t.pending[event.ButtonID]; ok {
+		previous.timer.Stop()
+	}
+
+	tap := &pendingTap{press: event}
+	tap.timer = time.AfterFunc(window, func() {
+		t.lock.Lock()
+
+		// a second press may have taken this tap while we were waiting for the lock
+		if t.pending[event.ButtonID] != tap {
+			t.lock.Unlock()
+			return
+		}
+
+		delete(t.pending, event.ButtonID)
+		t.lock.Unlock()
+
+		onExpire(tap.press)
+	})
+
+	t.pending[event.ButtonID] = tap
+}
+
+// second returns whether a press completes a double press, taking the tap it follows
+func (t *buttonTapTracker) second(buttonIdx int) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	tap, ok := t.pending[buttonIdx]
+	if !ok {
+		return false
+	}
+
+	tap.timer.Stop()
+	delete(t.pending, buttonIdx)
+
+	return true
+}
+
+// reset forgets all taps without acting on them
+func (t *buttonTapTracker) reset() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for buttonIdx, tap := range t.pending {
+		tap.timer.Stop()
+		delete(t.pending, buttonIdx)
+	}
+}
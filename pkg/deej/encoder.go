@@ -0,0 +1,56 @@
+package deej
+
+import (
+	"go.uber.org/zap"
+)
+
+// rotary encoders report how many ticks they turned since the last line, instead of an absolute position,
+// i.e. "^+1^-3^0^" for three encoders. each tick moves the encoder's targets by encoder_step. with
+// encoder_acceleration set, turning fast (several ticks in one report) makes each tick count for more
+const encoderLinePrefix = "^"
+
+// EncoderTurnEvent represents a single encoder turn captured by deej
+type EncoderTurnEvent struct {
+	EncoderID int
+	Ticks     int
+}
+
+func (sio *SerialIO) handleEncoders(logger *zap.SugaredLogger, encoderTicks []int) {
+	for encoderIdx, ticks := range encoderTicks {
+		if ticks == 0 {
+			continue
+		}
+
+		event := EncoderTurnEvent{
+			EncoderID: encoderIdx,
+			Ticks:     ticks,
+		}
+
+		if sio.deej.Verbose() {
+			logger.Debugw("Encoder turned", "event", event)
+		}
+
+		sio.turnedEncoder(event)
+	}
+}
+
+func (sio *SerialIO) turnedEncoder(event EncoderTurnEvent) {
+	targets, ok := sio.deej.config.EncoderMapping.get(event.EncoderID)
+	if !ok {
+		return
+	}
+
+	sio.deej.sessions.nudgeTargets(targets, encoderDelta(event.Ticks, sio.deej.config.EncoderStep,
+		sio.deej.config.EncoderAcceleration))
+}
+
+// encoderDelta works out how much a number of ticks moves the volume. without acceleration that's just
+// ticks * step, with it each tick beyond the first adds another acceleration * step per tick
+func encoderDelta(ticks int, step float32, acceleration float32) float32 {
+	magnitude := ticks
+	if magnitude < 0 {
+		magnitude = -magnitude
+	}
+
+	return float32(ticks) * step * (1 + acceleration*float32(magnitude-1))
+}
@@ -0,0 +1,183 @@
+package deej
+
+import (
+	"sync/atomic"
+
+	"go.uber.org/zap"
+)
+
+// every consumer of slider and button events gets its own queue (see queues.go), so a slow consumer never holds up
+// the serial reader or the other consumers, unless the user asked for that with the "block" policy. by default,
+// once a consumer's queue fills up, its oldest event is dropped to make room for the newest one - for sliders,
+// the newest value is the only one that matters anyway. slider queues can also coalesce, keeping a single event
+// per slider: a newer value for a slider that's still waiting replaces the older one where it stands
+const (
+
+	// after the first dropped event, only log every this many more
+	consumerDropLogInterval = 100
+)
+
+type sliderMoveConsumer struct {
+	events   chan SliderMoveEvent
+	policy   string
+	counters *queueCounters
+}
+
+type buttonPressConsumer struct {
+	events   chan ButtonPressEvent
+	policy   string
+	counters *queueCounters
+}
+
+func newSliderMoveConsumer(queues *queueRegistry) *sliderMoveConsumer {
+	settings := queues.settingsFor(queueSliderEvents)
+
+	consumer := &sliderMoveConsumer{
+		events: make(chan SliderMoveEvent, settings.Size),
+		policy: settings.Policy,
+	}
+
+	consumer.counters = queues.open(queueSliderEvents, consumer, func() int { return len(consumer.events) })
+
+	return consumer
+}
+
+func newButtonPressConsumer(queues *queueRegistry) *buttonPressConsumer {
+	settings := queues.settingsFor(queueButtonEvents)
+
+	consumer := &buttonPressConsumer{
+		events: make(chan ButtonPressEvent, settings.Size),
+		policy: settings.Policy,
+	}
+
+	consumer.counters = queues.open(queueButtonEvents, consumer, func() int { return len(consumer.events) })
+
+	return consumer
+}
+
+// offer queues an event according to the consumer's policy. it only blocks if that's the policy. if an event
+// had to be dropped for it, it returns how many were dropped so far, or 0 otherwise. it expects to be the only one
+// adding events to this consumer (which the consumer lock takes care of)
+func (c *sliderMoveConsumer) offer(event SliderMoveEvent) uint64 {
+	if c.policy == queuePolicyCoalesce && len(c.events) > 0 && c.coalesce(event) {
+		atomic.AddUint64(&c.counters.coalesced, 1)
+		return 0
+	}
+
+	select {
+	case c.events <- event:
+		c.counters.queued(len(c.events))
+		return 0
+	default:
+	}
+
+	if c.policy == queuePolicyBlock {
+		atomic.AddUint64(&c.counters.blocked, 1)
+
+		c.events <- event
+		c.counters.queued(len(c.events))
+
+		return 0
+	}
+
+	// make room by dropping the oldest event, unless the consumer just did that for us
+	select {
+	case <-c.events:
+	default:
+	}
+
+	dropped := atomic.AddUint64(&c.counters.dropped, 1)
+
+	select {
+	case c.events <- event:
+		c.counters.queued(len(c.events))
+	default:
+		dropped = atomic.AddUint64(&c.counters.dropped, 1)
+	}
+
+	return dropped
+}
+
+// coalesce replaces the waiting event for the same slider with the given one, keeping its place in line.
+// it returns false if there's no such event, in which case the given one should be queued as usual
+func (c *sliderMoveConsumer) coalesce(event SliderMoveEvent) bool {
+
+	// take everything that's waiting out of the queue. the consumer may take some of it meanwhile, which is fine:
+	// whatever's left goes back in the same order, and is still newer than whatever the consumer took
+	waiting := []SliderMoveEvent{}
+	for len(c.events) > 0 {
+		select {
+		case waitingEvent := <-c.events:
+			waiting = append(waiting, waitingEvent)
+			continue
+		default:
+		}
+
+		break
+	}
+
+	replaced := false
+	for idx := range waiting {
+		if waiting[idx].SliderID == event.SliderID {
+			waiting[idx] = event
+			replaced = true
+		}
+	}
+
+	// there's at least as much room as there was before, since nothing else adds events meanwhile
+	for _, waitingEvent := range waiting {
+		c.events <- waitingEvent
+	}
+
+	return replaced
+}
+
+// offer queues an event according to the consumer's policy. it only blocks if that's the policy. if an event
+// had to be dropped for it, it returns how many were dropped so far, or 0 otherwise
+func (c *buttonPressConsumer) offer(event ButtonPressEvent) uint64 {
+	select {
+	case c.events <- event:
+		c.counters.queued(len(c.events))
+		return 0
+	default:
+	}
+
+	if c.policy == queuePolicyBlock {
+		atomic.AddUint64(&c.counters.blocked, 1)
+
+		c.events <- event
+		c.counters.queued(len(c.events))
+
+		return 0
+	}
+
+	select {
+	case <-c.events:
+	default:
+	}
+
+	dropped := atomic.AddUint64(&c.counters.dropped, 1)
+
+	select {
+	case c.events <- event:
+		c.counters.queued(len(c.events))
+	default:
+		dropped = atomic.AddUint64(&c.counters.dropped, 1)
+	}
+
+	return dropped
+}
+
+func logDroppedEvent(logger *zap.SugaredLogger, kind string, dropped uint64) {
+	if dropped%consumerDropLogInterval == 1 {
+		logger.Warnw("Event consumer is falling behind, dropping its oldest events", "kind", kind, "dropped", dropped)
+	}
+}
+
+// DroppedEvents returns how many slider and button events were dropped so far, because their consumers
+// didn't keep up with them
+func (sio *SerialIO) DroppedEvents() (sliderMoves uint64, buttonPresses uint64) {
+	queues := sio.deej.config.queues
+
+	return queues.dropped(queueSliderEvents), queues.dropped(queueButtonEvents)
+}
@@ -0,0 +1,77 @@
+package deej
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/omriharel/deej/pkg/deej/util"
+)
+
+// buttons can run commands with exec:<command line>, which goes through the shell (cmd.exe on windows, sh on linux)
+// so arguments, quoting and pipes work like they would in a terminal. commands that need their own working directory
+// or shouldn't run forever can be set up under exec_commands instead, and then run with exec:<name>
+const actionExecPrefix = "exec:"
+
+// how much of what a failed command printed ends up in the log
+const execOutputLogLimit = 1024
+
+// ExecCommand is a command set up under exec_commands
+type ExecCommand struct {
+	Command string `mapstructure:"command"`
+	Dir     string `mapstructure:"dir"`
+
+	// in milliseconds, commands still running by then are killed. no limit if left out
+	Timeout int `mapstructure:"timeout"`
+}
+
+var errMissingExecCommand = errors.New("missing command")
+
+// execCommand resolves an exec: action's command, which is either the name of one set up under
+// exec_commands or a command line of its own
+func (cc *CanonicalConfig) execCommand(command string) ExecCommand {
+
+	// viper lowercases every key it reads, so names are matched regardless of case
+	if named, ok := cc.ExecCommands[strings.ToLower(command)]; ok {
+		return named
+	}
+
+	return ExecCommand{Command: command}
+}
+
+// runCommand runs an exec: action's command in the background, so slow scripts don't hold up the buttons
+func (sio *SerialIO) runCommand(logger *zap.SugaredLogger, command string) {
+	execCommand := sio.deej.config.execCommand(command)
+
+	go func() {
+		ctx := context.Background()
+
+		if execCommand.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, time.Duration(execCommand.Timeout)*time.Millisecond)
+			defer cancel()
+		}
+
+		logger.Infow("Running command", "command", execCommand.Command, "dir", execCommand.Dir)
+
+		output, err := util.RunCommand(ctx, execCommand.Command, execCommand.Dir)
+		if err != nil {
+			if len(output) > execOutputLogLimit {
+				output = output[:execOutputLogLimit]
+			}
+
+			logger.Warnw("Command failed",
+				"command", execCommand.Command,
+				"timedOut", ctx.Err() == context.DeadlineExceeded,
+				"error", err,
+				"output", string(output))
+
+			return
+		}
+
+		logger.Debugw("Command finished", "command", execCommand.Command, "output", string(output))
+	}()
+}
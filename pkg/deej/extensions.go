@@ -0,0 +1,194 @@
+package deej
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/thoas/go-funk"
+)
+
+// when deej is used as a library, it can be extended with custom transports (to reach devices deej
+// doesn't know how to talk to), custom button actions and integrations with other programs (see integrations.go).
+// all of them need to be registered before NewDeej is called.
+// see the examples directory at the root of this repository for a maintained reference
+
+// Transport opens a connection to a device for a custom connection type. it gets the connection info from
+// the user's config, and should return a connection that reads (and optionally writes) deej-formatted lines
+type Transport func(info ConnectionInfo) (io.ReadWriteCloser, error)
+
+// ActionRunner performs a custom button action. argument is whatever follows the action's name in the
+// button mapping, i.e. "bar" for "foo:bar" (or empty for just "foo")
+type ActionRunner func(event ButtonPressEvent, argument string) error
+
+// deej's own actions are prefixed with these, so custom actions can't use them as their name
+var reservedActionNames = []string{
+	"deej",
+	deejActionPauseToggle,
+	strings.TrimSuffix(actionCycleOutputPrefix, ":"),
+	strings.TrimSuffix(actionCycleOutputsPrefix, ":"),
+	strings.TrimSuffix(actionSwitchOutputPrefix, ":"),
+	strings.TrimSuffix(actionSystemSoundsPrefix, ":"),
+	strings.TrimSuffix(actionRoutePrefix, ":"),
+	strings.TrimSuffix(actionExecPrefix, ":"),
+	strings.TrimSuffix(actionHTTPPrefix, ":"),
+	strings.TrimSuffix(actionOBSScenePrefix, ":"),
+	strings.TrimSuffix(actionOBSMutePrefix, ":"),
+	strings.TrimSuffix(actionOBSUnmutePrefix, ":"),
+	strings.TrimSuffix(actionOBSMuteTogglePrefix, ":"),
+	actionOBSRecordToggle,
+	actionOBSStreamToggle,
+	actionDiscordMuteToggle,
+	actionDiscordDeafenToggle,
+	strings.TrimSuffix(actionMediaPrefix, ":"),
+	actionMediaPlayPauseAlias,
+	actionMediaNextAlias,
+	actionMediaPreviousAlias,
+	actionSystemLock,
+	actionSystemSleep,
+	actionSystemDisplayOff,
+	strings.TrimSuffix(actionLaunchPrefix, ":"),
+	strings.TrimSuffix(actionTypePrefix, ":"),
+	strings.TrimSuffix(actionMacroPrefix, ":"),
+	strings.TrimSuffix(actionProfilePrefix, ":"),
+	strings.TrimSuffix(actionRecordMacroPrefix, ":"),
+	strings.TrimSuffix(actionDelayPrefix, ":"),
+	strings.TrimSuffix(actionMicPrefix, ":"),
+	strings.TrimSuffix(actionCycleSliderTargetPrefix, ":"),
+	strings.TrimSuffix(actionMutePrefix, ":"),
+	strings.TrimSuffix(actionUnmutePrefix, ":"),
+	strings.TrimSuffix(actionMuteTogglePrefix, ":"),
+	strings.TrimSuffix(actionVolumeUpPrefix, ":"),
+	strings.TrimSuffix(actionVolumeDownPrefix, ":"),
+}
+
+var (
+	extensionLock    sync.Mutex
+	customTransports = map[string]Transport{}
+	customActions    = map[string]ActionRunner{}
+	integrations     = map[string]Integration{}
+)
+
+// RegisterTransport makes a custom connection type available for use as connection_type in the config
+func RegisterTransport(connectionType string, open Transport) error {
+	connectionType = strings.ToLower(connectionType)
+
+	if connectionType == "" || open == nil {
+		return fmt.Errorf("register transport %q: name and transport are required", connectionType)
+	}
+
+	if containsFold(builtinConnectionTypes, connectionType) {
+		return fmt.Errorf("register transport %q: built-in connection types can't be replaced", connectionType)
+	}
+
+	extensionLock.Lock()
+	defer extensionLock.Unlock()
+
+	customTransports[connectionType] = open
+
+	return nil
+}
+
+// RegisterAction makes a custom action available for use in button_mapping, as either "<name>" or "<name>:<argument>"
+func RegisterAction(name string, run ActionRunner) error {
+	name = strings.ToLower(name)
+
+	if name == "" || strings.Contains(name, ":") || run == nil {
+		return fmt.Errorf("register action %q: a name without colons and a runner are required", name)
+	}
+
+	if funk.ContainsString(reservedActionNames, name) {
+		return fmt.Errorf("register action %q: name is reserved for deej's own actions", name)
+	}
+
+	extensionLock.Lock()
+	defer extensionLock.Unlock()
+
+	customActions[name] = run
+
+	return nil
+}
+
+// RegisterIntegration adds an integration, which deej keeps connected (unless the user disables it by name)
+func RegisterIntegration(name string, integration Integration) error {
+	name = strings.ToLower(name)
+
+	if name == "" || integration == nil {
+		return fmt.Errorf("register integration %q: name and integration are required", name)
+	}
+
+	extensionLock.Lock()
+	defer extensionLock.Unlock()
+
+	if _, ok := integrations[name]; ok {
+		return fmt.Errorf("register integration %q: already registered", name)
+	}
+
+	integrations[name] = integration
+
+	return nil
+}
+
+// connectionTypes returns all connection types that can be used, built-in ones first
+func connectionTypes() []string {
+	extensionLock.Lock()
+	defer extensionLock.Unlock()
+
+	result := append([]string{}, builtinConnectionTypes...)
+	for connectionType := range customTransports {
+		result = append(result, connectionType)
+	}
+
+	return result
+}
+
+// customActionNames returns the names of all registered custom actions
+func customActionNames() []string {
+	extensionLock.Lock()
+	defer extensionLock.Unlock()
+
+	result := []string{}
+	for name := range customActions {
+		result = append(result, name)
+	}
+
+	return result
+}
+
+// registeredIntegrations returns all registered integrations by name
+func registeredIntegrations() map[string]Integration {
+	extensionLock.Lock()
+	defer extensionLock.Unlock()
+
+	result := map[string]Integration{}
+	for name, integration := range integrations {
+		result[name] = integration
+	}
+
+	return result
+}
+
+func lookupTransport(connectionType string) (Transport, bool) {
+	extensionLock.Lock()
+	defer extensionLock.Unlock()
+
+	open, ok := customTransports[connectionType]
+
+	return open, ok
+}
+
+// lookupAction finds the custom action a button mapping entry refers to, along with its argument
+func lookupAction(mapping string) (ActionRunner, string, bool) {
+	name, argument := mapping, ""
+	if separatorIdx := strings.Index(mapping, ":"); separatorIdx != -1 {
+		name, argument = mapping[:separatorIdx], mapping[separatorIdx+1:]
+	}
+
+	extensionLock.Lock()
+	defer extensionLock.Unlock()
+
+	run, ok := customActions[strings.ToLower(name)]
+
+	return run, argument, ok
+}
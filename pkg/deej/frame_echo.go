@@ -0,0 +1,30 @@
+package deej
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// with echo_frames enabled, every slider and button line deej understands is sent right back to the device as
+// "ECHO:<received>:<handling>:<line>", where <received> is when deej read the line (unix time, in milliseconds)
+// and <handling> is how long deej took to act on it (in microseconds). firmware that keeps track of when it sent
+// each line (a sequence number helps match them up) can then tell how much of the round trip is spent in the
+// cable, drivers and OS, rather than in deej. this is a debugging aid, it roughly doubles the traffic
+const echoFrameFormat = "ECHO:%d:%d:%s"
+
+// echoFrame sends a line back to the device, without holding up the next one. echoes that don't fit in
+// the command queue are dropped
+func (sio *SerialIO) echoFrame(logger *zap.SugaredLogger, line string, receivedAt time.Time) {
+	handling := time.Since(receivedAt)
+	echo := fmt.Sprintf(echoFrameFormat,
+		receivedAt.UnixNano()/int64(time.Millisecond),
+		handling.Microseconds(),
+		strings.TrimRight(line, "\r\n"))
+
+	if !sio.queueCommand(echo) {
+		logger.Debugw("Command queue full, dropping frame echo", "line", line)
+	}
+}
@@ -0,0 +1,120 @@
+package deej
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// the text protocol has no way of telling a garbled line from a real one, which becomes a problem
+// at high baud rates. firmware may instead wrap each line in a compact binary frame:
+//
+//	[0xDE sync] [payload length] [payload: a regular deej line, without CRLF] [CRC-8 of length + payload]
+//
+// deej asks for framing by sending "PROTO:BINARY" right after connecting (if enabled in the config).
+// firmware that doesn't know about framing just keeps sending text lines, which are still accepted - until the
+// first frame comes in. from then on, anything outside of a frame is line noise, and is dropped
+const (
+	frameSyncByte = 0xDE
+
+	binaryProtocolRequest = "PROTO:BINARY"
+
+	// CRC-8 with the 0x07 polynomial, which is cheap enough to compute on any microcontroller
+	frameCRCPolynomial = 0x07
+)
+
+var (
+	errCorruptFrame   = errors.New("corrupt frame")
+	errUnframedOutput = errors.New("output outside of a frame")
+)
+
+// readFrameOrLine reads the next line from the device, whether it arrives as a binary frame or as text.
+// lines taken out of frames get a CRLF appended, so they look exactly like text lines from here on.
+// frames failing their checksum are consumed and reported with errCorruptFrame, which isn't fatal.
+// once framed, whatever comes before the next frame is consumed and reported with errUnframedOutput (neither is that)
+func readFrameOrLine(reader *bufio.Reader, delimiter byte, framed bool) (string, bool, error) {
+	firstByte, err := reader.Peek(1)
+	if err != nil {
+		return "", false, err
+	}
+
+	if firstByte[0] != frameSyncByte && framed {
+		dropped, err := skipToFrame(reader)
+		if err != nil {
+			return "", true, err
+		}
+
+		return "", true, fmt.Errorf("%w: dropped %d bytes", errUnframedOutput, dropped)
+	}
+
+	if firstByte[0] != frameSyncByte {
+		line, err := reader.ReadString(delimiter)
+		return line, false, err
+	}
+
+	// frame header: sync byte and payload length
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return "", true, err
+	}
+
+	// payload and trailing checksum
+	body := make([]byte, int(header[1])+1)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return "", true, err
+	}
+
+	payload := body[:len(body)-1]
+	checksum := body[len(body)-1]
+
+	if expected := frameChecksum(header[1], payload); checksum != expected {
+		return "", true, fmt.Errorf("%w: checksum %#02x, expected %#02x", errCorruptFrame, checksum, expected)
+	}
+
+	return string(payload) + "\r\n", true, nil
+}
+
+// skipToFrame consumes everything up to the next sync byte, and returns how much that was
+func skipToFrame(reader *bufio.Reader) (int, error) {
+	dropped := 0
+
+	for {
+		next, err := reader.Peek(1)
+		if err != nil {
+			return dropped, err
+		}
+
+		if next[0] == frameSyncByte {
+			return dropped, nil
+		}
+
+		reader.Discard(1)
+		dropped++
+	}
+}
+
+// frameChecksum computes the CRC-8 of a frame's length byte followed by its payload
+func frameChecksum(length byte, payload []byte) byte {
+	crc := crc8Update(0, length)
+
+	for _, b := range payload {
+		crc = crc8Update(crc, b)
+	}
+
+	return crc
+}
+
+func crc8Update(crc byte, b byte) byte {
+	crc ^= b
+
+	for bit := 0; bit < 8; bit++ {
+		if crc&0x80 != 0 {
+			crc = crc<<1 ^ frameCRCPolynomial
+		} else {
+			crc <<= 1
+		}
+	}
+
+	return crc
+}
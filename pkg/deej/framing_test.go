@@ -0,0 +1,88 @@
+package deej
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// testFrame wraps a line in a binary frame, the way firmware would
+func testFrame(payload string) string {
+	checksum := frameChecksum(byte(len(payload)), []byte(payload))
+	return string([]byte{frameSyncByte, byte(len(payload))}) + payload + string([]byte{checksum})
+}
+
+func TestFrameChecksum(t *testing.T) {
+
+	// the standard CRC-8 check value: "123456789" comes out as 0xF4, its first byte standing in for the length
+	if checksum := frameChecksum('1', []byte("23456789")); checksum != 0xF4 {
+		t.Errorf("checksum: got %#02x, want 0xf4", checksum)
+	}
+}
+
+func TestReadFrameOrLine(t *testing.T) {
+	corrupt := []byte(testFrame("512|0"))
+	corrupt[len(corrupt)-1]++
+
+	tests := []struct {
+		name          string
+		input         string
+		alreadyFramed bool
+		line          string
+		framed        bool
+		err           error
+	}{
+		{
+			name:  "text lines are read as is",
+			input: "512|0\r\n",
+			line:  "512|0\r\n",
+		},
+		{
+			name:   "frames are unwrapped into text lines",
+			input:  testFrame("512|0~1~"),
+			line:   "512|0~1~\r\n",
+			framed: true,
+		},
+		{
+			name:          "frames keep coming once the device frames its lines",
+			input:         testFrame("512|0"),
+			alreadyFramed: true,
+			line:          "512|0\r\n",
+			framed:        true,
+		},
+		{
+			name:   "frames failing their checksum are dropped",
+			input:  string(corrupt),
+			framed: true,
+			err:    errCorruptFrame,
+		},
+		{
+			name:          "text is noise once the device frames its lines",
+			input:         "512|0\r\n" + testFrame("512|0"),
+			alreadyFramed: true,
+			framed:        true,
+			err:           errUnframedOutput,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reader := bufio.NewReader(bytes.NewBufferString(test.input))
+
+			line, framed, err := readFrameOrLine(reader, '\n', test.alreadyFramed)
+
+			if !errors.Is(err, test.err) {
+				t.Fatalf("error: got %v, want %v", err, test.err)
+			}
+
+			if line != test.line {
+				t.Errorf("line: got %q, want %q", line, test.line)
+			}
+
+			if framed != test.framed {
+				t.Errorf("framed: got %v, want %v", framed, test.framed)
+			}
+		})
+	}
+}
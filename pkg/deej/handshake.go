@@ -0,0 +1,66 @@
+package deej
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// right after connecting, deej asks the device to describe itself by sending "DEEJ?". firmware that
+// supports this replies with its protocol version and how many sliders and buttons it has, i.e. "DEEJ:1:5:4".
+// this lets us size everything up front instead of guessing from the first line, and refuse firmware
+// speaking a protocol we don't understand. firmware that doesn't reply is assumed to be compatible
+const (
+	handshakeProbe = "DEEJ?"
+
+	minSupportedProtocolVersion = 1
+	maxSupportedProtocolVersion = 1
+)
+
+// deviceInfo is what the device told us about itself during the handshake
+type deviceInfo struct {
+	protocolVersion int
+	numSliders      int
+	numButtons      int
+}
+
+func (sio *SerialIO) sendHandshakeProbe(logger *zap.SugaredLogger) {
+
+	// not every connection type can talk back to the device, that's fine
+	if err := sio.SendCommand(handshakeProbe); err != nil {
+		logger.Debugw("Failed to send handshake probe", "error", err)
+	}
+}
+
+// handleHandshakeReply applies what the device reported about itself. it returns an error if
+// the device's firmware is incompatible, in which case the connection shouldn't be used
+func (sio *SerialIO) handleHandshakeReply(logger *zap.SugaredLogger, info deviceInfo) error {
+	logger.Infow("Device identified itself",
+		"protocolVersion", info.protocolVersion,
+		"sliders", info.numSliders,
+		"buttons", info.numButtons)
+
+	if info.protocolVersion < minSupportedProtocolVersion || info.protocolVersion > maxSupportedProtocolVersion {
+		logger.Errorw("Device firmware uses an unsupported protocol version",
+			"protocolVersion", info.protocolVersion,
+			"minSupported", minSupportedProtocolVersion,
+			"maxSupported", maxSupportedProtocolVersion)
+
+		sio.deej.notifier.Notify("Incompatible deej firmware!",
+			fmt.Sprintf("Your device speaks protocol version %d, but this version of deej supports versions %d to %d. "+
+				"Please update deej or your device's firmware.",
+				info.protocolVersion, minSupportedProtocolVersion, maxSupportedProtocolVersion))
+
+		return fmt.Errorf("unsupported protocol version %d", info.protocolVersion)
+	}
+
+	if info.numSliders != sio.lastKnownNumSliders {
+		sio.resizeSliders(logger, info.numSliders)
+	}
+
+	if info.numButtons != sio.lastKnownNumButtons {
+		sio.resizeButtons(logger, info.numButtons)
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package deej
+
+import (
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// deej listens for the OS telling it about serial ports coming and going (WM_DEVICECHANGE on windows,
+// kernel uevents on linux), so it connects the moment the configured port is plugged in, and lets go of
+// it the moment it's unplugged - rather than finding out on the next failed read, which can take a while
+const (
+
+	// a port that was just plugged in isn't always ready right away (drivers loading, udev fixing up
+	// permissions), so connecting is given a few tries
+	hotplugConnectAttempts = 5
+	hotplugConnectDelay    = 300 * time.Millisecond
+)
+
+// deviceChange is a serial port being plugged in or unplugged
+type deviceChange struct {
+	port    string
+	arrived bool
+}
+
+// watchHotplug starts watching for serial ports coming and going, for as long as deej runs.
+// it returns false if the OS won't tell us, in which case nothing changes
+func (sio *SerialIO) watchHotplug() bool {
+	logger := sio.logger.Named("hotplug")
+	changes := make(chan deviceChange)
+
+	if err := watchDeviceChanges(logger, changes); err != nil {
+		logger.Warnw("Can't watch for devices being plugged in, connect manually", "error", err)
+		return false
+	}
+
+	logger.Debug("Watching for devices being plugged in")
+	sio.hotplugWatching = true
+
+	go func() {
+		for change := range changes {
+			sio.handleDeviceChange(logger, change)
+		}
+	}()
+
+	return true
+}
+
+func (sio *SerialIO) handleDeviceChange(logger *zap.SugaredLogger, change deviceChange) {
+	connInfo := sio.deej.config.ConnectionInfo
+
+	// only serial ports come and go like this
+	if sio.replayPath != "" || connInfo.Type != connectionTypeSerial {
+		return
+	}
+
+	if !strings.EqualFold(change.port, connInfo.COMPort) {
+		logger.Debugw("Ignoring unrelated device", "port", change.port, "arrived", change.arrived)
+		return
+	}
+
+	if !change.arrived {
+		if sio.Connected() {
+			logger.Infow("Device unplugged", "port", change.port)
+			sio.handleConnectionLost(logger)
+		}
+
+		return
+	}
+
+	logger.Infow("Device plugged in", "port", change.port)
+
+	for attempt := 1; attempt <= hotplugConnectAttempts; attempt++ {
+		if sio.Connected() {
+			return
+		}
+
+		err := sio.Start()
+		if err == nil {
+			return
+		}
+
+		logger.Debugw("Failed to connect to plugged in device", "attempt", attempt, "error", err)
+		<-time.After(hotplugConnectDelay)
+	}
+
+	logger.Warnw("Giving up on connecting to plugged in device", "port", change.port)
+}
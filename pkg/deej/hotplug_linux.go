@@ -0,0 +1,84 @@
+package deej
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+
+	"go.uber.org/zap"
+	"golang.org/x/sys/unix"
+)
+
+const (
+
+	// the kernel's own uevents, as opposed to the ones udev re-broadcasts once it's done with a device
+	ueventKernelGroup = 1
+
+	ueventBufferSize = 8192
+)
+
+// watchDeviceChanges listens to kernel uevents for tty devices being added and removed, for as long as deej runs
+func watchDeviceChanges(logger *zap.SugaredLogger, changes chan<- deviceChange) error {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_DGRAM, unix.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return fmt.Errorf("create uevent socket: %w", err)
+	}
+
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: ueventKernelGroup}); err != nil {
+		unix.Close(fd)
+		return fmt.Errorf("bind uevent socket: %w", err)
+	}
+
+	go func() {
+		defer unix.Close(fd)
+		defer close(changes)
+
+		buffer := make([]byte, ueventBufferSize)
+
+		for {
+			size, _, err := unix.Recvfrom(fd, buffer, 0)
+			if err == unix.EINTR {
+				continue
+			}
+
+			if err != nil {
+				logger.Warnw("Failed to read uevent", "error", err)
+				return
+			}
+
+			if change, ok := parseUevent(buffer[:size]); ok {
+				changes <- change
+			}
+		}
+	}()
+
+	return nil
+}
+
+// parseUevent picks tty devices being added or removed out of a uevent, which looks like
+// "add@/devices/...\0ACTION=add\0SUBSYSTEM=tty\0DEVNAME=ttyUSB0\0..."
+func parseUevent(message []byte) (deviceChange, bool) {
+	var action, subsystem, devName string
+
+	for _, field := range bytes.Split(message, []byte{0}) {
+		switch {
+		case bytes.HasPrefix(field, []byte("ACTION=")):
+			action = string(field[len("ACTION="):])
+		case bytes.HasPrefix(field, []byte("SUBSYSTEM=")):
+			subsystem = string(field[len("SUBSYSTEM="):])
+		case bytes.HasPrefix(field, []byte("DEVNAME=")):
+			devName = string(field[len("DEVNAME="):])
+		}
+	}
+
+	if subsystem != "tty" || devName == "" || (action != "add" && action != "remove") {
+		return deviceChange{}, false
+	}
+
+	// the kernel leaves out the /dev part
+	if !filepath.IsAbs(devName) {
+		devName = filepath.Join("/dev", devName)
+	}
+
+	return deviceChange{port: devName, arrived: action == "add"}, true
+}
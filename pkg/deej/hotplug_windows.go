@@ -0,0 +1,114 @@
+package deej
+
+import (
+	"errors"
+	"runtime"
+	"syscall"
+	"unsafe"
+
+	"github.com/lxn/win"
+	"go.uber.org/zap"
+	"golang.org/x/sys/windows"
+)
+
+// windows tells every top-level window about serial ports coming and going with WM_DEVICECHANGE, so deej
+// keeps an invisible one around to hear about it. message-only windows don't get these broadcasts
+const (
+	hotplugWindowClass = "deejHotplugWindow"
+
+	dbtDeviceArrival        = 0x8000
+	dbtDeviceRemoveComplete = 0x8004
+	dbtDevTypPort           = 0x3
+)
+
+// devBroadcastHdr is DEV_BROADCAST_HDR. for ports, the port's name follows it (DEV_BROADCAST_PORT)
+type devBroadcastHdr struct {
+	size       uint32
+	deviceType uint32
+	reserved   uint32
+}
+
+// watchDeviceChanges listens for ports being added and removed, for as long as deej runs
+func watchDeviceChanges(logger *zap.SugaredLogger, changes chan<- deviceChange) error {
+	created := make(chan error)
+
+	go func() {
+
+		// a window's messages only ever arrive on the thread that created it
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+
+		defer close(changes)
+
+		// lParam is a pointer for the messages we care about
+		wndProc := func(hwnd win.HWND, msg uint32, wParam uintptr, lParam unsafe.Pointer) uintptr {
+			switch msg {
+			case win.WM_DEVICECHANGE:
+				if change, ok := parseDeviceBroadcast(wParam, lParam); ok {
+
+					// don't hold up whoever's broadcasting
+					go func() { changes <- change }()
+				}
+
+				return 1
+
+			case win.WM_DESTROY:
+				win.PostQuitMessage(0)
+				return 0
+			}
+
+			return win.DefWindowProc(hwnd, msg, wParam, uintptr(lParam))
+		}
+
+		className, _ := syscall.UTF16PtrFromString(hotplugWindowClass)
+		instance := win.GetModuleHandle(nil)
+
+		windowClass := win.WNDCLASSEX{
+			LpfnWndProc:   syscall.NewCallback(wndProc),
+			HInstance:     instance,
+			LpszClassName: className,
+		}
+		windowClass.CbSize = uint32(unsafe.Sizeof(windowClass))
+
+		if win.RegisterClassEx(&windowClass) == 0 {
+			created <- errors.New("register window class")
+			return
+		}
+
+		// never shown
+		hwnd := win.CreateWindowEx(0, className, className, win.WS_OVERLAPPED, 0, 0, 0, 0, 0, 0, instance, nil)
+		if hwnd == 0 {
+			created <- errors.New("create window")
+			return
+		}
+
+		created <- nil
+
+		var msg win.MSG
+		for win.GetMessage(&msg, 0, 0, 0) > 0 {
+			win.TranslateMessage(&msg)
+			win.DispatchMessage(&msg)
+		}
+
+		logger.Debug("Stopped watching for devices")
+	}()
+
+	return <-created
+}
+
+// parseDeviceBroadcast picks ports being added or removed out of a WM_DEVICECHANGE message
+func parseDeviceBroadcast(wParam uintptr, lParam unsafe.Pointer) (deviceChange, bool) {
+	if (wParam != dbtDeviceArrival && wParam != dbtDeviceRemoveComplete) || lParam == nil {
+		return deviceChange{}, false
+	}
+
+	header := (*devBroadcastHdr)(lParam)
+	if header.deviceType != dbtDevTypPort {
+		return deviceChange{}, false
+	}
+
+	// i.e. "COM4"
+	name := windows.UTF16PtrToString((*uint16)(unsafe.Pointer(uintptr(lParam) + unsafe.Sizeof(*header))))
+
+	return deviceChange{port: name, arrived: wParam == dbtDeviceArrival}, true
+}
@@ -0,0 +1,553 @@
+//go:build !slim
+// +build !slim
+
+package deej
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// httpAPI is an optional local HTTP server that lets scripts and other tools talk to deej. since anything that can
+// reach it could change the config, every request is checked before it's handled: its Host has to name this server
+// (an IP address, localhost or the host it listens on - never a domain that merely resolves to it, which is how
+// DNS rebinding gets web pages in), requests that change anything have to come from the same origin, and those (along
+// with reading the config) need the install's token, in an X-Deej-Token header. the tray opens the web UI with it
+type httpAPI struct {
+	deej   *Deej
+	logger *zap.SugaredLogger
+
+	listenAddress string
+	server        *http.Server
+
+	// the port actually listened on, and the token requests have to come with
+	port  string
+	token string
+}
+
+const (
+	httpAPISlidersPath   = "/api/sliders"
+	httpAPISessionsPath  = "/api/sessions"
+	httpAPIConfigPath    = "/api/config"
+	httpAPIDevicePath    = "/api/device"
+	httpAPIButtonsPath   = "/api/buttons"
+	httpAPIRecordingPath = "/api/recording"
+	httpAPIQueuesPath    = "/api/queues"
+
+	httpAPIIntegrationsPath = "/api/integrations"
+	httpAPIReconnectSuffix  = "/reconnect"
+
+	// only served with --pprof
+	httpAPIProfilingPath = "/debug/pprof/"
+
+	httpAPIShutdownTimeout = 2 * time.Second
+
+	httpAPITokenHeader = "X-Deej-Token"
+)
+
+type sliderValuesResponse struct {
+	Physical []float32          `json:"physical"`
+	Virtual  map[string]float32 `json:"virtual"`
+}
+
+type sessionResponse struct {
+	Key         string  `json:"key"`
+	DisplayName string  `json:"displayName"`
+	IconPath    string  `json:"iconPath,omitempty"`
+	Volume      float32 `json:"volume"`
+}
+
+type deviceResponse struct {
+	Connected       bool    `json:"connected"`
+	AudioAvailable  bool    `json:"audioAvailable"`
+	PingSupported   bool    `json:"pingSupported"`
+	PongsMissed     int     `json:"pongsMissed"`
+	PingRoundTripMs float64 `json:"pingRoundTripMs"`
+
+	DroppedSliderMoves   uint64 `json:"droppedSliderMoves"`
+	DroppedButtonPresses uint64 `json:"droppedButtonPresses"`
+}
+
+type buttonStatsResponse struct {
+	Button    int       `json:"button"`
+	Presses   uint64    `json:"presses"`
+	LastPress time.Time `json:"lastPress"`
+
+	// only set for toggle buttons
+	Toggled *bool `json:"toggled,omitempty"`
+}
+
+type recordingResponse struct {
+	Recording bool     `json:"recording"`
+	Apps      []string `json:"apps"`
+}
+
+type setIntegrationRequest struct {
+	Enabled bool `json:"enabled"`
+}
+
+type configResponse struct {
+	Sections []ConfigSection        `json:"sections"`
+	Settings map[string]interface{} `json:"settings"`
+	Editable []string               `json:"editable"`
+}
+
+type setSliderRequest struct {
+	Value float32 `json:"value"`
+}
+
+func newHTTPAPI(deej *Deej, logger *zap.SugaredLogger) (*httpAPI, error) {
+	logger = logger.Named("http_api")
+
+	api := &httpAPI{
+		deej:   deej,
+		logger: logger,
+	}
+
+	logger.Debug("Created HTTP API instance")
+
+	// respond to config changes
+	api.setupOnConfigReload()
+
+	// the server only ever stops on its own if something went wrong, in which case it's worth another try
+	deej.supervisor.supervise(moduleHTTPAPI, 0, func(chan bool) {
+		api.stop()
+
+		if err := api.start(); err != nil {
+			deej.supervisor.reportFailure(moduleHTTPAPI, err)
+		}
+	})
+
+	return api, nil
+}
+
+// start begins serving, if the user enabled the API by giving it an address to listen on
+func (api *httpAPI) start() error {
+	api.listenAddress = api.deej.config.HTTPAPIListen
+
+	if api.listenAddress == "" {
+		if api.deej.profiling {
+			api.logger.Warn("Profiling requested, but the HTTP API isn't enabled (set http_api_listen to serve profiles)")
+		}
+
+		api.logger.Debug("No listen address configured, not starting HTTP API")
+		return nil
+	}
+
+	token, err := api.deej.config.httpAPIToken()
+	if err != nil {
+		api.logger.Warnw("Failed to get HTTP API token", "error", err)
+		return fmt.Errorf("get http api token: %w", err)
+	}
+
+	listener, err := net.Listen("tcp", api.listenAddress)
+	if err != nil {
+		api.logger.Warnw("Failed to listen for HTTP API requests", "address", api.listenAddress, "error", err)
+		return fmt.Errorf("listen for http api requests: %w", err)
+	}
+
+	_, api.port, _ = net.SplitHostPort(listener.Addr().String())
+	api.token = token
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(httpAPISlidersPath, api.handleSliders)
+	mux.HandleFunc(httpAPISlidersPath+"/", api.handleSlider)
+	mux.HandleFunc(httpAPISessionsPath, api.handleSessions)
+	mux.HandleFunc(httpAPIConfigPath, api.handleConfig)
+	mux.HandleFunc(httpAPIDevicePath, api.handleDevice)
+	mux.HandleFunc(httpAPIButtonsPath, api.handleButtons)
+	mux.HandleFunc(httpAPIRecordingPath, api.handleRecording)
+	mux.HandleFunc(httpAPIQueuesPath, api.handleQueues)
+	mux.HandleFunc(httpAPIIntegrationsPath, api.handleIntegrations)
+	mux.HandleFunc(httpAPIIntegrationsPath+"/", api.handleIntegration)
+	mux.HandleFunc("/", api.handleWebUI)
+
+	if api.deej.profiling {
+		mux.HandleFunc(httpAPIProfilingPath, pprof.Index)
+		mux.HandleFunc(httpAPIProfilingPath+"cmdline", pprof.Cmdline)
+		mux.HandleFunc(httpAPIProfilingPath+"profile", pprof.Profile)
+		mux.HandleFunc(httpAPIProfilingPath+"symbol", pprof.Symbol)
+		mux.HandleFunc(httpAPIProfilingPath+"trace", pprof.Trace)
+
+		api.logger.Infow("Serving runtime profiles", "path", httpAPIProfilingPath)
+	}
+
+	api.server = &http.Server{Handler: api.guard(mux)}
+
+	go func() {
+		defer api.deej.supervisor.guard(moduleHTTPAPI)
+
+		if err := api.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			api.logger.Warnw("HTTP API server stopped unexpectedly", "error", err)
+			api.deej.supervisor.reportFailure(moduleHTTPAPI, err)
+		}
+	}()
+
+	api.logger.Infow("Serving HTTP API", "address", listener.Addr())
+
+	return nil
+}
+
+// guard turns away requests that don't name this server, cross-origin requests that change anything, and
+// requests without the token that need it
+func (api *httpAPI) guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !api.allowedHost(r.Host) {
+			api.logger.Debugw("Refusing HTTP API request for another host", "host", r.Host, "path", r.URL.Path)
+			http.Error(w, "unknown host", http.StatusForbidden)
+			return
+		}
+
+		changes := r.Method != http.MethodGet && r.Method != http.MethodHead
+
+		if origin := r.Header.Get("Origin"); changes && origin != "" {
+			if parsed, err := url.Parse(origin); err != nil || parsed.Host != r.Host {
+				api.logger.Debugw("Refusing cross-origin HTTP API request", "origin", origin, "path", r.URL.Path)
+				http.Error(w, "cross-origin requests aren't allowed", http.StatusForbidden)
+				return
+			}
+		}
+
+		needsToken := changes || r.URL.Path == httpAPIConfigPath || strings.HasPrefix(r.URL.Path, httpAPIProfilingPath)
+
+		given := r.Header.Get(httpAPITokenHeader)
+		if needsToken && subtle.ConstantTimeCompare([]byte(given), []byte(api.token)) != 1 {
+			http.Error(w, "missing or wrong "+httpAPITokenHeader, http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedHost returns whether a request's Host header names this server
+func (api *httpAPI) allowedHost(host string) bool {
+	hostname, port, err := net.SplitHostPort(host)
+	if err != nil {
+		hostname, port = host, "80"
+	}
+
+	if port != api.port {
+		return false
+	}
+
+	if strings.EqualFold(hostname, "localhost") || net.ParseIP(strings.Trim(hostname, "[]")) != nil {
+		return true
+	}
+
+	listenHost, _, _ := net.SplitHostPort(api.listenAddress)
+
+	return listenHost != "" && strings.EqualFold(hostname, listenHost)
+}
+
+// webUIAddress returns where to open the web UI, token included, and false if the HTTP API isn't running
+func (api *httpAPI) webUIAddress() (string, bool) {
+	if api.server == nil {
+		return "", false
+	}
+
+	host, _, _ := net.SplitHostPort(api.listenAddress)
+	if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+		host = "127.0.0.1"
+	}
+
+	// the token goes after the #, so it's never sent along with a request (or written to a server log)
+	return fmt.Sprintf("http://%s/#token=%s", net.JoinHostPort(host, api.port), api.token), true
+}
+
+func (api *httpAPI) stop() {
+	if api.server == nil {
+		return
+	}
+
+	api.logger.Debug("Shutting down HTTP API")
+
+	ctx, cancel := context.WithTimeout(context.Background(), httpAPIShutdownTimeout)
+	defer cancel()
+
+	if err := api.server.Shutdown(ctx); err != nil {
+		api.logger.Warnw("Failed to shut down HTTP API gracefully", "error", err)
+	}
+
+	api.server = nil
+}
+
+func (api *httpAPI) setupOnConfigReload() {
+	configReloadedChannel := api.deej.config.SubscribeToChanges()
+
+	go func() {
+		for {
+			select {
+			case <-configReloadedChannel:
+				if api.deej.config.HTTPAPIListen != api.listenAddress {
+					api.logger.Info("Detected change in HTTP API listen address, restarting server")
+					api.stop()
+
+					if err := api.start(); err != nil {
+						api.logger.Warnw("Failed to restart HTTP API after address change", "error", err)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// GET /api/sliders - current values of all physical and virtual sliders
+func (api *httpAPI) handleSliders(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	physical, virtual := api.deej.serial.SliderValues()
+
+	response := sliderValuesResponse{
+		Physical: physical,
+		Virtual:  map[string]float32{},
+	}
+
+	indexBase := api.deej.config.MappingIndexBase
+	for sliderID, value := range virtual {
+		response.Virtual[strconv.Itoa(sliderID+indexBase)] = value
+	}
+
+	api.writeJSON(w, response)
+}
+
+// PUT/POST /api/sliders/<index> with {"value": 0.5} - moves a virtual slider
+func (api *httpAPI) handleSlider(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut && r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// slider indexes in the API are the same as in the user's config
+	sliderIdx, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, httpAPISlidersPath+"/"))
+	if err != nil {
+		http.Error(w, "invalid slider index", http.StatusBadRequest)
+		return
+	}
+
+	request := setSliderRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	sliderID := sliderIdx - api.deej.config.MappingIndexBase
+
+	if err := api.deej.serial.SetVirtualSliderValue(sliderID, request.Value); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/sessions - all audio sessions deej currently knows about, with their names and icons
+func (api *httpAPI) handleSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	response := []sessionResponse{}
+
+	for _, session := range api.deej.sessions.snapshot() {
+		response = append(response, sessionResponse{
+			Key:         session.Key(),
+			DisplayName: session.DisplayName(),
+			IconPath:    session.IconPath(),
+			Volume:      session.GetVolume(),
+		})
+	}
+
+	api.writeJSON(w, response)
+}
+
+// GET /api/device - the device's connection state, and how it's been answering pings
+func (api *httpAPI) handleDevice(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	ping := api.deej.serial.PingStats()
+	droppedSliderMoves, droppedButtonPresses := api.deej.serial.DroppedEvents()
+
+	api.writeJSON(w, deviceResponse{
+		Connected:       api.deej.serial.Connected(),
+		AudioAvailable:  api.deej.sessions.audioAvailable(),
+		PingSupported:   ping.Supported,
+		PongsMissed:     ping.MissedPongs,
+		PingRoundTripMs: float64(ping.LastRoundTrip) / float64(time.Millisecond),
+
+		DroppedSliderMoves:   droppedSliderMoves,
+		DroppedButtonPresses: droppedButtonPresses,
+	})
+}
+
+// GET /api/buttons - how many times each button was pressed so far, when it was last pressed, and whether toggle buttons are on
+func (api *httpAPI) handleButtons(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	response := []buttonStatsResponse{}
+
+	// button indexes in the API are the same as in the user's config
+	for _, stats := range api.deej.serial.ButtonStats() {
+		entry := buttonStatsResponse{
+			Button:    stats.ButtonID + api.deej.config.MappingIndexBase,
+			Presses:   stats.Presses,
+			LastPress: stats.LastPress,
+		}
+
+		if _, ok := api.deej.config.ToggleMapping.get(stats.ButtonID); ok {
+			toggled := api.deej.ButtonToggled(stats.ButtonID)
+			entry.Toggled = &toggled
+		}
+
+		response = append(response, entry)
+	}
+
+	api.writeJSON(w, response)
+}
+
+// GET /api/recording - whether any app is recording from an input device, and which (see recording_indicator)
+func (api *httpAPI) handleRecording(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	apps := api.deej.RecordingApps()
+
+	api.writeJSON(w, recordingResponse{
+		Recording: len(apps) > 0,
+		Apps:      apps,
+	})
+}
+
+// GET /api/queues - how each queue is set up, and how it's been doing
+func (api *httpAPI) handleQueues(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	api.writeJSON(w, api.deej.QueueStats())
+}
+
+// GET /api/integrations - how each integration is doing
+func (api *httpAPI) handleIntegrations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	api.writeJSON(w, api.deej.IntegrationStatuses())
+}
+
+// PUT /api/integrations/<name> with {"enabled": false} - turns an integration off (or back on), saving it to the config
+// POST /api/integrations/<name>/reconnect - drops an integration's connection and connects it again right away
+func (api *httpAPI) handleIntegration(w http.ResponseWriter, r *http.Request) {
+	name := strings.ToLower(strings.TrimPrefix(r.URL.Path, httpAPIIntegrationsPath+"/"))
+
+	var err error
+
+	switch {
+	case r.Method == http.MethodPost && strings.HasSuffix(name, httpAPIReconnectSuffix):
+		err = api.deej.integrations.reconnect(strings.TrimSuffix(name, httpAPIReconnectSuffix))
+
+	case r.Method == http.MethodPut:
+		request := setIntegrationRequest{}
+		if decodeErr := json.NewDecoder(r.Body).Decode(&request); decodeErr != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		err = api.deej.integrations.setEnabled(name, request.Enabled)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if errors.Is(err, errUnknownIntegration) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GET /api/config - all user settings, along with how to group them for display
+// PUT /api/config with {"key": value, ...} - validates and saves the given settings, which then apply right away
+func (api *httpAPI) handleConfig(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		api.writeJSON(w, configResponse{
+			Sections: configSections,
+			Settings: api.deej.config.UserSettings(),
+			Editable: EditableUserSettings(),
+		})
+
+	case http.MethodPut:
+		settings := map[string]interface{}{}
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := api.deej.config.UpdateUserSettings(settings); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// GET / - the configuration UI
+func (api *httpAPI) handleWebUI(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if _, err := w.Write([]byte(webUIPage)); err != nil {
+		api.logger.Warnw("Failed to write web UI", "error", err)
+	}
+}
+
+func (api *httpAPI) writeJSON(w http.ResponseWriter, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		api.logger.Warnw("Failed to write HTTP API response", "error", err)
+	}
+}
@@ -0,0 +1,467 @@
+package deej
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/thoas/go-funk"
+	"go.uber.org/zap"
+)
+
+// integrations connect deej to other programs. deej's own are OBS and Discord (see builtinIntegrations), and whoever
+// embeds deej can register more with RegisterIntegration. the MQTT connection isn't one - it's how deej reaches the
+// board, and shows up in the device status like any other connection - and neither are webhooks (i.e. to Home
+// Assistant), which are one-off requests with nothing to stay connected to. there's no MIDI integration. each one
+// runs in a loop of its own: when it can't connect or loses its connection, it's retried after a delay that grows
+// with every failure, without affecting the others. the tray, the HTTP API and the web UI show how each one is doing,
+// and let users reconnect them right away or turn them off (which is saved to disabled_integrations in the config)
+const (
+	integrationStateNotSetUp   = "not set up"
+	integrationStateDisabled   = "disabled"
+	integrationStateConnecting = "connecting"
+	integrationStateConnected  = "connected"
+	integrationStateRetrying   = "retrying"
+
+	minIntegrationRetryDelay = time.Second
+	maxIntegrationRetryDelay = time.Minute
+
+	// an integration that stays connected this long is healthy again, and its retry delay starts over
+	integrationHealthyUptime = time.Minute
+)
+
+var (
+	errUnknownIntegration      = errors.New("unknown integration")
+	errIntegrationDisconnected = errors.New("disconnected")
+)
+
+// Integration connects deej to another program
+type Integration interface {
+
+	// Run connects and keeps the integration going until ctx is done. it should call connected once it's
+	// up, and return an error as soon as it fails to connect or loses its connection
+	Run(ctx context.Context, connected func()) error
+}
+
+// configuredIntegration is one of deej's own integrations, which only runs once the user set it up (i.e. told it
+// where to connect) so it doesn't keep retrying for everyone else. settings returns what it's set up with, and
+// whether it's set up at all - it's reconnected whenever they change
+type configuredIntegration interface {
+	Integration
+	settings() (string, bool)
+}
+
+// IntegrationStatus is how an integration is doing right now
+type IntegrationStatus struct {
+	Name      string     `json:"name"`
+	State     string     `json:"state"`
+	Error     string     `json:"error,omitempty"`
+	NextRetry *time.Time `json:"nextRetry,omitempty"`
+}
+
+type integrationManager struct {
+	deej   *Deej
+	logger *zap.SugaredLogger
+
+	runners map[string]*integrationRunner
+
+	stopChannel chan bool
+}
+
+// integrationRunner keeps a single integration going
+type integrationRunner struct {
+	name        string
+	integration Integration
+	logger      *zap.SugaredLogger
+
+	lock sync.Locker
+
+	enabled    bool
+	setUp      bool
+	settings   string
+	state      string
+	lastError  error
+	nextRetry  time.Time
+	retryDelay time.Duration
+
+	// cancels the current attempt, and whether that was on purpose (so it isn't counted as a failure)
+	cancel      context.CancelFunc
+	interrupted bool
+
+	// pokes the loop out of waiting, i.e. to reconnect right away
+	wake chan bool
+}
+
+func newIntegrationManager(deej *Deej, logger *zap.SugaredLogger) *integrationManager {
+	logger = logger.Named("integrations")
+
+	manager := &integrationManager{
+		deej:        deej,
+		logger:      logger,
+		runners:     map[string]*integrationRunner{},
+		stopChannel: make(chan bool),
+	}
+
+	// deej's own integrations come first, others can't take their names
+	all := builtinIntegrations(deej)
+	for name, integration := range registeredIntegrations() {
+		if _, ok := all[name]; ok {
+			logger.Warnw("Integration name is taken by one of deej's own, ignoring it", "integration", name)
+			continue
+		}
+
+		all[name] = integration
+	}
+
+	for name, integration := range all {
+		manager.runners[name] = &integrationRunner{
+			name:        name,
+			integration: integration,
+			logger:      logger.Named(name),
+			lock:        &sync.Mutex{},
+			setUp:       true,
+			state:       integrationStateDisabled,
+			retryDelay:  minIntegrationRetryDelay,
+			wake:        make(chan bool, 1),
+		}
+	}
+
+	logger.Debugw("Created integration manager", "integrations", manager.names())
+
+	return manager
+}
+
+// start runs every integration that isn't disabled, and keeps them in line with the config from then on
+func (im *integrationManager) start() {
+	if len(im.runners) == 0 {
+		return
+	}
+
+	im.applyConfig()
+
+	for _, runner := range im.runners {
+		go runner.run(im.stopChannel)
+	}
+
+	configReloadedChannel := im.deej.config.SubscribeToChanges()
+
+	go func() {
+		for range configReloadedChannel {
+			im.applyConfig()
+		}
+	}()
+}
+
+// stop takes every integration down
+func (im *integrationManager) stop() {
+	close(im.stopChannel)
+}
+
+func (im *integrationManager) applyConfig() {
+	for name, runner := range im.runners {
+		if configured, ok := runner.integration.(configuredIntegration); ok {
+			runner.setSettings(configured.settings())
+		}
+
+		runner.setEnabled(!funk.ContainsString(im.deej.config.DisabledIntegrations, name))
+	}
+}
+
+// names returns the names of all integrations, in order
+func (im *integrationManager) names() []string {
+	names := []string{}
+	for name := range im.runners {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// statuses returns how each integration is doing, in order
+func (im *integrationManager) statuses() []IntegrationStatus {
+	statuses := []IntegrationStatus{}
+	for _, name := range im.names() {
+		statuses = append(statuses, im.runners[name].status())
+	}
+
+	return statuses
+}
+
+// reconnect drops an integration's connection (if it has one) and connects it again right away
+func (im *integrationManager) reconnect(name string) error {
+	runner, ok := im.runners[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", errUnknownIntegration, name)
+	}
+
+	im.logger.Infow("Reconnecting integration", "integration", name)
+	runner.reconnect()
+
+	return nil
+}
+
+// setEnabled turns an integration on or off by saving it to the user's config, which takes effect once it's reloaded
+func (im *integrationManager) setEnabled(name string, enabled bool) error {
+	if _, ok := im.runners[name]; !ok {
+		return fmt.Errorf("%w: %s", errUnknownIntegration, name)
+	}
+
+	disabled := funk.FilterString(im.deej.config.DisabledIntegrations, func(disabledName string) bool {
+		return disabledName != name
+	})
+
+	if !enabled {
+		disabled = append(disabled, name)
+	}
+
+	im.logger.Infow("Saving integration state", "integration", name, "enabled", enabled)
+
+	if err := im.deej.config.UpdateUserSettings(map[string]interface{}{configKeyDisabledIntegrations: disabled}); err != nil {
+		return fmt.Errorf("save integration state: %w", err)
+	}
+
+	return nil
+}
+
+func (r *integrationRunner) run(stop chan bool) {
+	for {
+		r.lock.Lock()
+		enabled := r.enabled
+		r.lock.Unlock()
+
+		if !enabled {
+			select {
+			case <-r.wake:
+				continue
+			case <-stop:
+				return
+			}
+		}
+
+		// whatever woke us up before is taken care of by this attempt
+		select {
+		case <-r.wake:
+		default:
+		}
+
+		err := r.attempt(stop)
+
+		// deej is stopping, which isn't the integration's fault
+		select {
+		case <-stop:
+			return
+		default:
+		}
+
+		r.lock.Lock()
+
+		interrupted := r.interrupted
+		r.interrupted = false
+		r.cancel = nil
+
+		// turned off or reconnected on purpose, go again right away
+		if interrupted || !r.enabled {
+			r.lock.Unlock()
+			continue
+		}
+
+		delay := r.fail(err)
+
+		r.lock.Unlock()
+
+		select {
+		case <-time.After(delay):
+		case <-r.wake:
+		case <-stop:
+			return
+		}
+	}
+}
+
+// attempt runs the integration once, until it fails or is stopped
+func (r *integrationRunner) attempt(stop chan bool) (err error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	r.lock.Lock()
+	r.cancel = cancel
+	r.state = integrationStateConnecting
+	r.lock.Unlock()
+
+	// deej stopping takes the integration down with it
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	// a crashing integration is just another failure, it shouldn't take deej down
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			crashlogPath, crashlogErr := writeCrashlog(recovered)
+			if crashlogErr != nil {
+				r.logger.Warnw("Failed to write crashlog for crashed integration", "error", crashlogErr)
+			}
+
+			r.logger.Errorw("Integration crashed", "error", recovered, "crashlogPath", crashlogPath)
+			err = fmt.Errorf("panic: %v", recovered)
+		}
+	}()
+
+	connectedAt := time.Time{}
+
+	r.logger.Debug("Connecting integration")
+
+	err = r.integration.Run(ctx, func() {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+
+		connectedAt = time.Now()
+		r.state = integrationStateConnected
+		r.lastError = nil
+
+		r.logger.Info("Integration connected")
+	})
+
+	if err == nil {
+		err = errIntegrationDisconnected
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if !connectedAt.IsZero() && time.Since(connectedAt) >= integrationHealthyUptime {
+		r.retryDelay = minIntegrationRetryDelay
+	}
+
+	return err
+}
+
+// fail marks the integration as failed, and returns how long to wait before retrying. assumes the lock is held
+func (r *integrationRunner) fail(err error) time.Duration {
+	delay := r.retryDelay
+
+	r.state = integrationStateRetrying
+	r.lastError = err
+	r.nextRetry = time.Now().Add(delay)
+
+	r.logger.Warnw("Integration failed, retrying soon", "error", err, "delay", delay)
+
+	if r.retryDelay *= 2; r.retryDelay > maxIntegrationRetryDelay {
+		r.retryDelay = maxIntegrationRetryDelay
+	}
+
+	return delay
+}
+
+func (r *integrationRunner) setEnabled(enabled bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	// an integration that isn't set up has nothing to run, whatever the user says
+	enabled = enabled && r.setUp
+
+	if r.enabled == enabled {
+		return
+	}
+
+	r.logger.Infow("Integration state changed", "enabled", enabled)
+
+	r.enabled = enabled
+	r.retryDelay = minIntegrationRetryDelay
+
+	if !enabled {
+		r.state = integrationStateDisabled
+		if !r.setUp {
+			r.state = integrationStateNotSetUp
+		}
+
+		r.lastError = nil
+		r.interruptLocked()
+	}
+
+	r.poke()
+}
+
+// setSettings takes in what a configured integration is set up with, and reconnects it if that changed
+// while it's running. whether it runs at all is up to the setEnabled call that follows
+func (r *integrationRunner) setSettings(settings string, setUp bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	changed := settings != r.settings
+
+	r.settings = settings
+	r.setUp = setUp
+
+	if !setUp && !r.enabled {
+		r.state = integrationStateNotSetUp
+	} else if setUp && r.state == integrationStateNotSetUp {
+		r.state = integrationStateDisabled
+	}
+
+	if changed && setUp && r.enabled {
+		r.logger.Info("Integration settings changed, reconnecting")
+
+		r.retryDelay = minIntegrationRetryDelay
+		r.interruptLocked()
+		r.poke()
+	}
+}
+
+func (r *integrationRunner) reconnect() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.retryDelay = minIntegrationRetryDelay
+	r.interruptLocked()
+	r.poke()
+}
+
+// assumes the lock is held
+func (r *integrationRunner) interruptLocked() {
+	if r.cancel != nil {
+		r.interrupted = true
+		r.cancel()
+	}
+}
+
+func (r *integrationRunner) poke() {
+	select {
+	case r.wake <- true:
+	default:
+	}
+}
+
+func (r *integrationRunner) status() IntegrationStatus {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	status := IntegrationStatus{
+		Name:  r.name,
+		State: r.state,
+	}
+
+	if r.lastError != nil {
+		status.Error = r.lastError.Error()
+	}
+
+	if r.state == integrationStateRetrying {
+		nextRetry := r.nextRetry
+		status.NextRetry = &nextRetry
+	}
+
+	return status
+}
+
+// IntegrationStatuses returns how each registered integration is doing
+func (d *Deej) IntegrationStatuses() []IntegrationStatus {
+	return d.integrations.statuses()
+}
@@ -0,0 +1,12 @@
+//go:build !slim
+// +build !slim
+
+package deej
+
+// builtinIntegrations returns deej's own integrations by name, which slim builds leave out
+func builtinIntegrations(deej *Deej) map[string]Integration {
+	return map[string]Integration{
+		obsIntegrationName:     deej.obs,
+		discordIntegrationName: deej.discord,
+	}
+}
@@ -0,0 +1,127 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// keys deej doesn't have a name for, or that sit somewhere else on the user's keyboard layout, can be named in a
+// file of their own (key_map_file), without waiting for a new deej release. each entry in it names a key and says
+// which one it is: the name of one of deej's keys (PLAY: MEDIA_PLAY_PAUSE), a windows virtual key code
+// ("vk:0xB7"), or the code the OS itself uses for it (a scan code on windows, a keycode on linux). the user's keys
+// take precedence over deej's own ones, and the file is reloaded along with the config whenever it changes
+const keyMapVirtualKeyPrefix = "vk:"
+
+var errVirtualKeysUnsupported = errors.New("virtual key codes are only supported on windows")
+
+// loadKeyMap reads the user's key map file (if they have one) and makes its keys available, then makes sure
+// changes to it are picked up. this has to happen before any mapping that may use those keys is read
+func (cc *CanonicalConfig) loadKeyMap() {
+	cc.watchKeyMapFile(cc.KeyMapFile)
+
+	if cc.KeyMapFile == "" {
+		setUserKeyCodes(map[string]int{})
+		return
+	}
+
+	keyMap := viper.New()
+	keyMap.SetConfigFile(cc.KeyMapFile)
+	keyMap.SetConfigType(configType)
+
+	if err := keyMap.ReadInConfig(); err != nil {
+		cc.logger.Warnw("Failed to read key map file, leaving its keys out", "path", cc.KeyMapFile, "error", err)
+		cc.notifier.Notify("Can't read key map!",
+			fmt.Sprintf("Please make sure %s exists and is in a valid YAML format.", cc.KeyMapFile))
+
+		setUserKeyCodes(map[string]int{})
+		return
+	}
+
+	codes := map[string]int{}
+	invalid := 0
+
+	for name, value := range keyMap.AllSettings() {
+
+		// viper lowercases every key, but key names are written in uppercase everywhere else
+		name = trimKeyNamePrefix(strings.ToUpper(name))
+
+		code, err := parseKeyMapValue(value)
+		if err != nil {
+			cc.logger.Warnw("Invalid key map entry, ignoring it", "path", cc.KeyMapFile, "key", name, "error", err)
+			invalid++
+
+			continue
+		}
+
+		codes[name] = code
+	}
+
+	setUserKeyCodes(codes)
+	cc.logger.Infow("Loaded key map", "path", cc.KeyMapFile, "keys", len(codes))
+
+	if invalid > 0 {
+		cc.notifier.Notify("Invalid key map!",
+			fmt.Sprintf("%d of the keys in %s were left out. Check the logs for details.", invalid, cc.KeyMapFile))
+	}
+}
+
+// parseKeyMapValue resolves what a key map entry says its key is into a key code
+func parseKeyMapValue(value interface{}) (int, error) {
+	text := strings.TrimSpace(cast.ToString(value))
+
+	switch {
+	case text == "":
+		return 0, errors.New("missing key code")
+
+	case strings.HasPrefix(strings.ToLower(text), keyMapVirtualKeyPrefix):
+		virtualKey, err := strconv.ParseInt(text[len(keyMapVirtualKeyPrefix):], 0, 32)
+		if err != nil || virtualKey <= 0 {
+			return 0, fmt.Errorf("invalid virtual key code: %q", text)
+		}
+
+		return virtualKeyCode(int(virtualKey))
+	}
+
+	// yaml already reads hex (0x1E) and plain numbers as such, strings that look like them are fine too
+	if code, err := strconv.ParseInt(text, 0, 32); err == nil {
+		if code <= 0 {
+			return 0, fmt.Errorf("invalid key code: %d", code)
+		}
+
+		return int(code), nil
+	}
+
+	// only deej's own keys can be referred to by name, so the order of the file doesn't matter
+	if code, ok := lookupBuiltinKey(trimKeyNamePrefix(strings.ToUpper(text))); ok {
+		return code, nil
+	}
+
+	return 0, fmt.Errorf("unknown key %q", text)
+}
+
+// watchKeyMapFile reloads the config whenever the key map file at the given path changes, and stops watching
+// whichever one it watched before
+func (cc *CanonicalConfig) watchKeyMapFile(path string) {
+	if path == cc.keyMapWatchPath {
+		return
+	}
+
+	if cc.keyMapWatcher != nil {
+		cc.logger.Debugw("Stopping key map file watcher", "path", cc.keyMapWatchPath)
+
+		cc.keyMapWatcher.Close()
+		cc.keyMapWatcher = nil
+	}
+
+	cc.keyMapWatchPath = path
+	if path == "" {
+		return
+	}
+
+	cc.keyMapWatcher = cc.watchFileForReloads(path, "key map file")
+}
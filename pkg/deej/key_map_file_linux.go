@@ -0,0 +1,6 @@
+package deej
+
+// virtual key codes are a windows thing, linux keys are only known by their keycode
+func virtualKeyCode(virtualKey int) (int, error) {
+	return 0, errVirtualKeysUnsupported
+}
@@ -0,0 +1,6 @@
+package deej
+
+// virtualKeyCode returns the key code keybd_event presses the given virtual key with
+func virtualKeyCode(virtualKey int) (int, error) {
+	return virtualKey + keybdVirtualKeyOffset, nil
+}
@@ -0,0 +1,260 @@
+package deej
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/micmonay/keybd_event"
+)
+
+// every key deej can press, by name. buttons press them as is (TAB, F5) or along with modifier keys (CTRL+SHIFT+F5,
+// WIN+L), and keys: slider targets and macros name them the same way. a name may also start with KEY_ (KEY_A), or
+// with VK_ like it used to have to (VK_MEDIA_PLAY_PAUSE) - both stand for the same key as the name without it
+const (
+	keyNamePrefix       = "KEY_"
+	legacyKeyNamePrefix = "VK_"
+)
+
+// https://github.com/micmonay/keybd_event/blob/master/keybd_windows.go
+var keyCodes = map[string]int{
+	"A": keybd_event.VK_A,
+	"B": keybd_event.VK_B,
+	"C": keybd_event.VK_C,
+	"D": keybd_event.VK_D,
+	"E": keybd_event.VK_E,
+	"F": keybd_event.VK_F,
+	"G": keybd_event.VK_G,
+	"H": keybd_event.VK_H,
+	"I": keybd_event.VK_I,
+	"J": keybd_event.VK_J,
+	"K": keybd_event.VK_K,
+	"L": keybd_event.VK_L,
+	"M": keybd_event.VK_M,
+	"N": keybd_event.VK_N,
+	"O": keybd_event.VK_O,
+	"P": keybd_event.VK_P,
+	"Q": keybd_event.VK_Q,
+	"R": keybd_event.VK_R,
+	"S": keybd_event.VK_S,
+	"T": keybd_event.VK_T,
+	"U": keybd_event.VK_U,
+	"V": keybd_event.VK_V,
+	"W": keybd_event.VK_W,
+	"X": keybd_event.VK_X,
+	"Y": keybd_event.VK_Y,
+	"Z": keybd_event.VK_Z,
+
+	"0": keybd_event.VK_0,
+	"1": keybd_event.VK_1,
+	"2": keybd_event.VK_2,
+	"3": keybd_event.VK_3,
+	"4": keybd_event.VK_4,
+	"5": keybd_event.VK_5,
+	"6": keybd_event.VK_6,
+	"7": keybd_event.VK_7,
+	"8": keybd_event.VK_8,
+	"9": keybd_event.VK_9,
+
+	"F1":  keybd_event.VK_F1,
+	"F2":  keybd_event.VK_F2,
+	"F3":  keybd_event.VK_F3,
+	"F4":  keybd_event.VK_F4,
+	"F5":  keybd_event.VK_F5,
+	"F6":  keybd_event.VK_F6,
+	"F7":  keybd_event.VK_F7,
+	"F8":  keybd_event.VK_F8,
+	"F9":  keybd_event.VK_F9,
+	"F10": keybd_event.VK_F10,
+	"F11": keybd_event.VK_F11,
+	"F12": keybd_event.VK_F12,
+	"F13": keybd_event.VK_F13,
+	"F14": keybd_event.VK_F14,
+	"F15": keybd_event.VK_F15,
+	"F16": keybd_event.VK_F16,
+	"F17": keybd_event.VK_F17,
+	"F18": keybd_event.VK_F18,
+	"F19": keybd_event.VK_F19,
+	"F20": keybd_event.VK_F20,
+	"F21": keybd_event.VK_F21,
+	"F22": keybd_event.VK_F22,
+	"F23": keybd_event.VK_F23,
+	"F24": keybd_event.VK_F24,
+
+	"ESC":        keybd_event.VK_ESC,
+	"TAB":        keybd_event.VK_TAB,
+	"ENTER":      keybd_event.VK_ENTER,
+	"SPACE":      keybd_event.VK_SPACE,
+	"BACKSPACE":  keybd_event.VK_BACKSPACE,
+	"INSERT":     keybd_event.VK_INSERT,
+	"DELETE":     keybd_event.VK_DELETE,
+	"HOME":       keybd_event.VK_HOME,
+	"END":        keybd_event.VK_END,
+	"PAGEUP":     keybd_event.VK_PAGEUP,
+	"PAGEDOWN":   keybd_event.VK_PAGEDOWN,
+	"UP":         keybd_event.VK_UP,
+	"DOWN":       keybd_event.VK_DOWN,
+	"LEFT":       keybd_event.VK_LEFT,
+	"RIGHT":      keybd_event.VK_RIGHT,
+	"CAPSLOCK":   keybd_event.VK_CAPSLOCK,
+	"NUMLOCK":    keybd_event.VK_NUMLOCK,
+	"SCROLLLOCK": keybd_event.VK_SCROLLLOCK,
+	"PAUSE":      keybd_event.VK_PAUSE,
+
+	"MINUS":      keybd_event.VK_MINUS,
+	"EQUAL":      keybd_event.VK_EQUAL,
+	"LEFTBRACE":  keybd_event.VK_LEFTBRACE,
+	"RIGHTBRACE": keybd_event.VK_RIGHTBRACE,
+	"SEMICOLON":  keybd_event.VK_SEMICOLON,
+	"APOSTROPHE": keybd_event.VK_APOSTROPHE,
+	"GRAVE":      keybd_event.VK_GRAVE,
+	"BACKSLASH":  keybd_event.VK_BACKSLASH,
+	"COMMA":      keybd_event.VK_COMMA,
+	"DOT":        keybd_event.VK_DOT,
+	"SLASH":      keybd_event.VK_SLASH,
+
+	"KP0":        keybd_event.VK_KP0,
+	"KP1":        keybd_event.VK_KP1,
+	"KP2":        keybd_event.VK_KP2,
+	"KP3":        keybd_event.VK_KP3,
+	"KP4":        keybd_event.VK_KP4,
+	"KP5":        keybd_event.VK_KP5,
+	"KP6":        keybd_event.VK_KP6,
+	"KP7":        keybd_event.VK_KP7,
+	"KP8":        keybd_event.VK_KP8,
+	"KP9":        keybd_event.VK_KP9,
+	"KPMINUS":    keybd_event.VK_KPMINUS,
+	"KPPLUS":     keybd_event.VK_KPPLUS,
+	"KPDOT":      keybd_event.VK_KPDOT,
+	"KPASTERISK": keybd_event.VK_KPASTERISK,
+
+	"MEDIA_NEXT_TRACK":    keybd_event.VK_MEDIA_NEXT_TRACK,
+	"MEDIA_PREV_TRACK":    keybd_event.VK_MEDIA_PREV_TRACK,
+	"MEDIA_STOP":          keybd_event.VK_MEDIA_STOP,
+	"MEDIA_PLAY_PAUSE":    keybd_event.VK_MEDIA_PLAY_PAUSE,
+	"LAUNCH_MEDIA_SELECT": keybd_event.VK_LAUNCH_MEDIA_SELECT,
+	"VOLUME_MUTE":         keybd_event.VK_VOLUME_MUTE,
+	"VOLUME_DOWN":         keybd_event.VK_VOLUME_DOWN,
+	"VOLUME_UP":           keybd_event.VK_VOLUME_UP,
+	"BROWSER_BACK":        keybd_event.VK_BROWSER_BACK,
+	"BROWSER_FORWARD":     keybd_event.VK_BROWSER_FORWARD,
+	"BROWSER_REFRESH":     keybd_event.VK_BROWSER_REFRESH,
+	"BROWSER_STOP":        keybd_event.VK_BROWSER_STOP,
+	"BROWSER_SEARCH":      keybd_event.VK_BROWSER_SEARCH,
+	"BROWSER_FAVORITES":   keybd_event.VK_BROWSER_FAVORITES,
+	"BROWSER_HOME":        keybd_event.VK_BROWSER_HOME,
+}
+
+// other names people tend to use for some keys
+var keyAliases = map[string]string{
+	"ESCAPE":   "ESC",
+	"RETURN":   "ENTER",
+	"DEL":      "DELETE",
+	"INS":      "INSERT",
+	"PGUP":     "PAGEUP",
+	"PGDN":     "PAGEDOWN",
+	"PERIOD":   "DOT",
+	"BACKTICK": "GRAVE",
+}
+
+// keys the user named in their key map file (see key_map_file.go), which take precedence over deej's own
+var (
+	userKeyCodes     = map[string]int{}
+	userKeyCodesLock sync.Mutex
+)
+
+// setUserKeyCodes replaces the keys from the user's key map file
+func setUserKeyCodes(codes map[string]int) {
+	userKeyCodesLock.Lock()
+	defer userKeyCodesLock.Unlock()
+
+	userKeyCodes = codes
+}
+
+// KEY_MAPS is the short list of keys buttons could press before any key could be named.
+//
+// Deprecated: every key in it (and many more) can be looked up by name now, in any case and with or without
+// the VK_ prefix. it's only kept around for code that still refers to it
+var KEY_MAPS = map[string]int{
+	// https://github.com/micmonay/keybd_event/blob/master/keybd_windows.go
+	"VK_MEDIA_NEXT_TRACK":    keybd_event.VK_MEDIA_NEXT_TRACK,
+	"VK_MEDIA_PREV_TRACK":    keybd_event.VK_MEDIA_PREV_TRACK,
+	"VK_MEDIA_STOP":          keybd_event.VK_MEDIA_STOP,
+	"VK_MEDIA_PLAY_PAUSE":    keybd_event.VK_MEDIA_PLAY_PAUSE,
+	"VK_LAUNCH_MEDIA_SELECT": keybd_event.VK_LAUNCH_MEDIA_SELECT,
+	"VK_VOLUME_MUTE":         keybd_event.VK_VOLUME_MUTE,
+	"VK_VOLUME_DOWN":         keybd_event.VK_VOLUME_DOWN,
+	"VK_VOLUME_UP":           keybd_event.VK_VOLUME_UP,
+	"VK_BROWSER_BACK":        keybd_event.VK_BROWSER_BACK,
+	"VK_BROWSER_FORWARD":     keybd_event.VK_BROWSER_FORWARD,
+	"VK_BROWSER_REFRESH":     keybd_event.VK_BROWSER_REFRESH,
+	"VK_BROWSER_STOP":        keybd_event.VK_BROWSER_STOP,
+	"VK_BROWSER_SEARCH":      keybd_event.VK_BROWSER_SEARCH,
+	"VK_BROWSER_FAVORITES":   keybd_event.VK_BROWSER_FAVORITES,
+	"VK_BROWSER_HOME":        keybd_event.VK_BROWSER_HOME,
+}
+
+// lookupKey returns the code of the key with the given name (in any case), with or without a KEY_ or VK_ prefix
+func lookupKey(name string) (int, bool) {
+	userKeyCodesLock.Lock()
+	defer userKeyCodesLock.Unlock()
+
+	name = trimKeyNamePrefix(strings.ToUpper(name))
+
+	if code, ok := userKeyCodes[name]; ok {
+		return code, true
+	}
+
+	return lookupBuiltinKey(name)
+}
+
+// lookupBuiltinKey returns the code of one of deej's own keys, by its name (without a prefix) or an alias
+func lookupBuiltinKey(name string) (int, bool) {
+	if alias, ok := keyAliases[name]; ok {
+		name = alias
+	}
+
+	code, ok := keyCodes[name]
+
+	return code, ok
+}
+
+// trimKeyNamePrefix returns the key name without its KEY_ or VK_ prefix, if it has one
+func trimKeyNamePrefix(name string) string {
+	for _, prefix := range []string{keyNamePrefix, legacyKeyNamePrefix} {
+		if strings.HasPrefix(name, prefix) {
+			return strings.TrimPrefix(name, prefix)
+		}
+	}
+
+	return name
+}
+
+// allKeyCodes returns every key deej knows, the user's own ones included
+func allKeyCodes() map[string]int {
+	userKeyCodesLock.Lock()
+	defer userKeyCodesLock.Unlock()
+
+	result := map[string]int{}
+	for name, code := range keyCodes {
+		result[name] = code
+	}
+
+	for name, code := range userKeyCodes {
+		result[name] = code
+	}
+
+	return result
+}
+
+// keyNames lists every key a button can press, in order
+func keyNames() []string {
+	names := []string{}
+	for name := range allKeyCodes() {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
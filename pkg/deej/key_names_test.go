@@ -0,0 +1,88 @@
+package deej
+
+import (
+	"testing"
+
+	"github.com/micmonay/keybd_event"
+)
+
+func TestLookupKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		userKeys map[string]int
+		code     int
+		ok       bool
+	}{
+		{name: "F5", code: keybd_event.VK_F5, ok: true},
+		{name: "f5", code: keybd_event.VK_F5, ok: true},
+		{name: "KEY_F5", code: keybd_event.VK_F5, ok: true},
+		{name: "VK_MEDIA_PLAY_PAUSE", code: keybd_event.VK_MEDIA_PLAY_PAUSE, ok: true},
+		{name: "vk_media_play_pause", code: keybd_event.VK_MEDIA_PLAY_PAUSE, ok: true},
+		{name: "escape", code: keybd_event.VK_ESC, ok: true},
+		{name: "KEY_RETURN", code: keybd_event.VK_ENTER, ok: true},
+		{name: "HYPER"},
+		{name: "KEY_"},
+		{name: "HYPER", userKeys: map[string]int{"HYPER": 200}, code: 200, ok: true},
+		{name: "F5", userKeys: map[string]int{"F5": 201}, code: 201, ok: true},
+	}
+
+	defer setUserKeyCodes(map[string]int{})
+
+	for _, test := range tests {
+		if test.userKeys == nil {
+			test.userKeys = map[string]int{}
+		}
+
+		setUserKeyCodes(test.userKeys)
+
+		code, ok := lookupKey(test.name)
+		if code != test.code || ok != test.ok {
+			t.Errorf("%q (user keys %v): got (%d, %v), want (%d, %v)", test.name, test.userKeys, code, ok, test.code, test.ok)
+		}
+	}
+}
+
+func TestParseKeyCombo(t *testing.T) {
+	tests := []struct {
+		name  string
+		want  buttonAction
+		combo bool
+	}{
+		{
+			name:  "CTRL+SHIFT+F5",
+			want:  buttonAction{kind: buttonActionKey, keyCode: keybd_event.VK_F5, ctrl: true, shift: true},
+			combo: true,
+		},
+		{
+			name:  "WIN+l",
+			want:  buttonAction{kind: buttonActionKey, keyCode: keybd_event.VK_L, win: true},
+			combo: true,
+		},
+		{
+			name:  "ALT+KEY_TAB",
+			want:  buttonAction{kind: buttonActionKey, keyCode: keybd_event.VK_TAB, alt: true},
+			combo: true,
+		},
+		{name: "F5"},
+		{name: "HYPER+F5"},
+		{name: "CTRL+HYPER"},
+	}
+
+	for _, test := range tests {
+		action, combo := parseKeyCombo(buttonAction{name: test.name})
+		if combo != test.combo {
+			t.Errorf("%q: got combo %v, want %v", test.name, combo, test.combo)
+			continue
+		}
+
+		if !combo {
+			continue
+		}
+
+		if action.kind != test.want.kind || action.keyCode != test.want.keyCode ||
+			action.ctrl != test.want.ctrl || action.shift != test.want.shift ||
+			action.alt != test.want.alt || action.win != test.want.win {
+			t.Errorf("%q: got %+v, want %+v", test.name, action, test.want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/micmonay/keybd_event"
+)
+
+// some things can only be controlled with hotkeys (i.e. a TV behind an HDMI-CEC helper). mapping a slider to
+// "keys:VOLUME_DOWN/VOLUME_UP" turns its movement into key presses instead: one press of the first
+// key for every slider_key_step it moves down, and one of the second key for every step it moves up.
+// there's no telling where the other side's volume is, so the first move after startup only takes note
+// of where the slider is
+const (
+	keyTargetPrefix    = "keys:"
+	keyTargetSeparator = "/"
+
+	defaultSliderKeyStep = 0.02
+
+	// a slider slammed from one end to the other shouldn't press a key more often than this
+	maxKeyPressesPerMove = 50
+)
+
+type keyTarget struct {
+	downKey string
+	upKey   string
+}
+
+func isKeyTarget(target string) bool {
+	return strings.HasPrefix(strings.ToLower(target), keyTargetPrefix)
+}
+
+// parseKeyTarget reads the down and up keys out of a "keys:" target
+func parseKeyTarget(target string) (keyTarget, error) {
+	keys := strings.Split(strings.ToUpper(target[len(keyTargetPrefix):]), keyTargetSeparator)
+	if len(keys) != 2 {
+		return keyTarget{}, fmt.Errorf("expected %s<down key>%s<up key>, got %q", keyTargetPrefix, keyTargetSeparator, target)
+	}
+
+	for _, key := range keys {
+		if _, ok := lookupKey(key); !ok {
+			return keyTarget{}, fmt.Errorf("unknown key %q", key)
+		}
+	}
+
+	return keyTarget{downKey: keys[0], upKey: keys[1]}, nil
+}
+
+// keyStepper remembers where each slider was when it last pressed keys for a target
+type keyStepper struct {
+	lock    sync.Locker
+	anchors map[string]float32
+}
+
+func newKeyStepper() *keyStepper {
+	return &keyStepper{
+		lock:    &sync.Mutex{},
+		anchors: map[string]float32{},
+	}
+}
+
+// steps returns how many whole steps the slider moved since its anchor (negative when it moved down),
+// and moves the anchor along by that many steps
+func (s *keyStepper) steps(sliderID int, target string, value float32, step float32) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	key := fmt.Sprintf("%d:%s", sliderID, target)
+
+	anchor, ok := s.anchors[key]
+	if !ok {
+		s.anchors[key] = value
+		return 0
+	}
+
+	// leave a little room for float rounding, so that moving by exactly one step counts
+	steps := int((value - anchor) / step * 1.0001)
+	s.anchors[key] = anchor + float32(steps)*step
+
+	return steps
+}
+
+// applyKeyTarget presses a key target's keys as many times as the slider moved steps
+func (m *sessionMap) applyKeyTarget(sliderID int, target string, percentValue float32) error {
+	keys, err := parseKeyTarget(target)
+	if err != nil {
+		return fmt.Errorf("parse key target: %w", err)
+	}
+
+	steps := m.keySteps.steps(sliderID, target, percentValue, m.deej.config.SliderKeyStep)
+	if steps == 0 {
+		return nil
+	}
+
+	key := keys.upKey
+	if steps < 0 {
+		key = keys.downKey
+		steps = -steps
+	}
+
+	if steps > maxKeyPressesPerMove {
+		steps = maxKeyPressesPerMove
+	}
+
+	m.logger.Debugw("Pressing keys for slider", "slider", sliderID, "key", key, "times", steps)
+
+	return pressKey(key, steps)
+}
+
+// pressKey presses (and releases) one of the keys deej knows a number of times
+func pressKey(key string, times int) error {
+	code, ok := lookupKey(key)
+	if !ok {
+		return errors.New("key not found")
+	}
+
+	kb, err := keybd_event.NewKeyBonding()
+	if err != nil {
+		return fmt.Errorf("create key bonding: %w", err)
+	}
+
+	kb.SetKeys(code)
+
+	for i := 0; i < times; i++ {
+		if err := kb.Launching(); err != nil {
+			return fmt.Errorf("press key: %w", err)
+		}
+	}
+
+	return nil
+}
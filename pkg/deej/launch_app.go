@@ -0,0 +1,59 @@
+package deej
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/omriharel/deej/pkg/deej/util"
+)
+
+// buttons can bring up an app with launch:<path>. if the app is already running, its window comes to the front
+// (restored if it was minimized), otherwise deej starts it. apps that were closed to the tray have no window to
+// bring up, so they're started again too - which is how most of them bring their window back anyway
+const actionLaunchPrefix = "launch:"
+
+var errMissingLaunchPath = errors.New("missing path")
+
+// parseLaunchAction returns the path in a launch: action, without any quotes around it
+func parseLaunchAction(value string) (string, error) {
+	path := strings.Trim(strings.TrimSpace(value), `"'`)
+	if path == "" {
+		return "", errMissingLaunchPath
+	}
+
+	return path, nil
+}
+
+// launchOrFocus brings the app at path to the front, or starts it if it isn't running
+func (sio *SerialIO) launchOrFocus(logger *zap.SugaredLogger, path string) {
+	executable := filepath.Base(path)
+
+	focused, err := util.FocusProcessWindow(executable)
+	if err != nil {
+		logger.Warnw("Failed to bring app to the front", "executable", executable, "error", err)
+
+		// it's running, starting it again would only make things worse
+		if focused {
+			return
+		}
+	} else if focused {
+		logger.Infow("Brought app to the front", "executable", executable)
+		return
+	}
+
+	logger.Infow("Launching app", "path", path)
+
+	commandLine := path
+
+	// the shell would split the path on spaces otherwise, while cmd.exe's start gets it as a single argument
+	if util.Linux() {
+		commandLine = "'" + strings.ReplaceAll(path, "'", `'\''`) + "'"
+	}
+
+	if err := util.LaunchDetached(commandLine); err != nil {
+		logger.Warnw("Failed to launch app", "path", path, "error", err)
+	}
+}
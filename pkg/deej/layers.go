@@ -0,0 +1,207 @@
+package deej
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/spf13/cast"
+	"go.uber.org/zap"
+)
+
+// layer buttons work like a keyboard's shift key: while one is held, the other buttons fire the actions the layer
+// maps them to instead of their usual ones. that way a box with a few buttons can do two or three times as much.
+// layer buttons don't do anything on their own, and buttons the layer doesn't map keep doing their usual thing
+
+// buttonLayerMap holds the actions each layer maps buttons to, by layer button index and then by button index
+type buttonLayerMap struct {
+	m    map[int]map[int][]buttonAction
+	lock sync.Locker
+}
+
+// layerMapFromConfig also returns every mapping entry that couldn't be resolved into an action
+func layerMapFromConfig(userMapping map[string]interface{}, indexBase int) (*buttonLayerMap, []error) {
+	resultMap := &buttonLayerMap{
+		m:    make(map[int]map[int][]buttonAction),
+		lock: &sync.Mutex{},
+	}
+
+	problems := []error{}
+
+	for layerIdxString, rawButtons := range userMapping {
+		layerIdx, err := strconv.Atoi(layerIdxString)
+		if err != nil {
+			continue
+		}
+
+		buttons := map[int][]buttonAction{}
+
+		for buttonIdxString, rawActions := range cast.ToStringMap(rawButtons) {
+			buttonIdx, err := strconv.Atoi(buttonIdxString)
+			if err != nil || buttonIdx == layerIdx {
+				continue
+			}
+
+			actions, buttonProblems := parseButtonActions(buttonActionEntries(rawActions))
+			for _, problem := range buttonProblems {
+				problems = append(problems, fmt.Errorf("layer %d button %d: %w", layerIdx, buttonIdx, problem))
+			}
+
+			buttons[buttonIdx-indexBase] = actions
+		}
+
+		resultMap.m[layerIdx-indexBase] = buttons
+	}
+
+	return resultMap, problems
+}
+
+// isLayer returns whether a button is a layer button
+func (m *buttonLayerMap) isLayer(buttonIdx int) bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	_, ok := m.m[buttonIdx]
+	return ok
+}
+
+// get returns the actions a layer maps a button to
+func (m *buttonLayerMap) get(layerIdx int, buttonIdx int) ([]buttonAction, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	actions, ok := m.m[layerIdx][buttonIdx]
+	return actions, ok
+}
+
+// layerTracker keeps track of which layer buttons are held, and which buttons were pressed on a layer
+type layerTracker struct {
+
+	// held layer buttons, the most recently pressed one last
+	held []int
+
+	// buttons that fired a layer's actions, so their release belongs to the layer too
+	layered map[int]bool
+
+	lock sync.Locker
+}
+
+func newLayerTracker() *layerTracker {
+	return &layerTracker{
+		held:    []int{},
+		layered: map[int]bool{},
+		lock:    &sync.Mutex{},
+	}
+}
+
+func (t *layerTracker) hold(layerIdx int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.held = append(t.removeHeld(layerIdx), layerIdx)
+}
+
+func (t *layerTracker) release(layerIdx int) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.held = t.removeHeld(layerIdx)
+}
+
+// removeHeld returns the held layers without the given one, the lock must be held
+func (t *layerTracker) removeHeld(layerIdx int) []int {
+	result := []int{}
+	for _, held := range t.held {
+		if held != layerIdx {
+			result = append(result, held)
+		}
+	}
+
+	return result
+}
+
+// active returns the layer the buttons are on right now, if any
+func (t *layerTracker) active() (int, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if len(t.held) == 0 {
+		return 0, false
+	}
+
+	return t.held[len(t.held)-1], true
+}
+
+func (t *layerTracker) setLayered(buttonIdx int, layered bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if layered {
+		t.layered[buttonIdx] = true
+	} else {
+		delete(t.layered, buttonIdx)
+	}
+}
+
+func (t *layerTracker) isLayered(buttonIdx int) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.layered[buttonIdx]
+}
+
+// reset forgets every held layer, for when the buttons' state starts over
+func (t *layerTracker) reset() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.held = []int{}
+	t.layered = map[int]bool{}
+}
+
+// handleLayerButton returns whether it took care of a button event, which then shouldn't be handled as usual
+func (sio *SerialIO) handleLayerButton(logger *zap.SugaredLogger, buttonEvent ButtonPressEvent) bool {
+	layers := sio.deej.config.LayerMapping
+	buttonIdx := buttonEvent.ButtonID
+
+	pressed := buttonEvent.PreviousValue == 0 && buttonEvent.ButtonValue != 0
+	released := buttonEvent.PreviousValue > 0 && buttonEvent.ButtonValue == 0
+
+	if layers.isLayer(buttonIdx) {
+		if pressed {
+			logger.Debugw("Layer button held", "layer", buttonIdx)
+			sio.layers.hold(buttonIdx)
+		} else if released {
+			sio.layers.release(buttonIdx)
+		}
+
+		return true
+	}
+
+	// whatever else happens to a button pressed on a layer (including its release) is the layer's
+	if !pressed {
+		if released && sio.layers.isLayered(buttonIdx) {
+			sio.layers.setLayered(buttonIdx, false)
+			return true
+		}
+
+		return sio.layers.isLayered(buttonIdx)
+	}
+
+	layerIdx, ok := sio.layers.active()
+	if !ok {
+		return false
+	}
+
+	actions, ok := layers.get(layerIdx, buttonIdx)
+	if !ok {
+		return false
+	}
+
+	logger.Debugw("Button pressed on a layer", "layer", layerIdx, "button", buttonIdx)
+
+	sio.layers.setLayered(buttonIdx, true)
+	sio.runButtonActions(logger, buttonEvent, actions)
+
+	return true
+}
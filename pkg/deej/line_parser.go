@@ -0,0 +1,342 @@
+package deej
+
+import (
+	"strings"
+)
+
+// the line parser recognizes every kind of line a device reports, and reads its values in a single pass.
+// with the default separators, these are "512|1023|0" (sliders), "~1~0~" (buttons), "512|1023|0~1~0~1"
+// (both at once), "^+1^-3^" (encoders) and "#2#" (selector position), along with the device's answers to what
+// deej sent it ("PONG", "ACK"/"NACK:<reason>" and "DEEJ:1:5:4") and the "@17:" sequence number any line may
+// start with. it works on the line's bytes directly
+// and keeps its value buffers between lines, so parsing a line doesn't allocate - this runs for every single
+// line, which adds up at high baud rates. lines always end with CRLF by the time they're parsed,
+// regardless of what the device actually sent
+type lineKind int
+
+const (
+	lineUnknown lineKind = iota
+	lineSliders
+	lineButtons
+	lineCombined
+	lineEncoders
+	lineSelector
+	linePong
+	lineCommandAck
+	lineHandshake
+)
+
+// how many digits each kind of value may have
+const (
+	maxSliderValueDigits   = 5
+	maxEncoderTicksDigits  = 3
+	maxSelectorValueDigits = 2
+	maxSequenceDigits      = 3
+	maxHandshakeDigits     = 3
+)
+
+// the device's answers to deej's own commands, and what lines are numbered with
+const (
+	pongLine             = "PONG"
+	commandAckLine       = "ACK"
+	commandNackLine      = "NACK"
+	commandReasonPrefix  = ":"
+	handshakeReplyPrefix = "DEEJ:"
+	handshakeSeparator   = ":"
+	sequencePrefix       = "@"
+	sequenceSeparator    = ":"
+)
+
+type lineParser struct {
+	sliderSeparator string
+	buttonPrefix    string
+
+	// values read from the last parsed line, only valid until the next one is parsed
+	sliderValues     []int
+	buttonValues     []int
+	encoderTicks     []int
+	selectorPosition int
+	commandAck       commandAck
+	device           deviceInfo
+}
+
+func newLineParser(sliderSeparator string, buttonPrefix string) *lineParser {
+	return &lineParser{
+		sliderSeparator: sliderSeparator,
+		buttonPrefix:    buttonPrefix,
+		sliderValues:    make([]int, 0, 16),
+		buttonValues:    make([]int, 0, 16),
+		encoderTicks:    make([]int, 0, 16),
+	}
+}
+
+// parse works out what kind of line this is and reads its values, or returns lineUnknown for garbage
+func (p *lineParser) parse(line string) lineKind {
+	if !strings.HasSuffix(line, "\r\n") {
+		return lineUnknown
+	}
+
+	line = line[:len(line)-2]
+
+	p.sliderValues = p.sliderValues[:0]
+	p.buttonValues = p.buttonValues[:0]
+	p.encoderTicks = p.encoderTicks[:0]
+
+	// the device's answers come first, in case the configured separators start with one of their letters
+	if line == pongLine {
+		return linePong
+	}
+
+	if p.parseCommandAck(line) {
+		return lineCommandAck
+	}
+
+	if strings.HasPrefix(line, handshakeReplyPrefix) {
+		if p.parseHandshakeReply(line) {
+			return lineHandshake
+		}
+
+		return lineUnknown
+	}
+
+	if strings.HasPrefix(line, p.buttonPrefix) {
+		if p.parseButtons(line, true) {
+			return lineButtons
+		}
+
+		return lineUnknown
+	}
+
+	if strings.HasPrefix(line, encoderLinePrefix) {
+		if p.parseEncoders(line) {
+			return lineEncoders
+		}
+
+		return lineUnknown
+	}
+
+	if strings.HasPrefix(line, selectorLinePrefix) {
+		if p.parseSelector(line) {
+			return lineSelector
+		}
+
+		return lineUnknown
+	}
+
+	return p.parseSliders(line)
+}
+
+// parseSliders reads "512|1023|0", optionally followed by a separator, or by buttons ("~1~0") for combined lines
+func (p *lineParser) parseSliders(line string) lineKind {
+	idx := 0
+
+	for {
+		value, digits := readDigits(line[idx:], maxSliderValueDigits)
+		if digits == 0 {
+			return lineUnknown
+		}
+
+		p.sliderValues = append(p.sliderValues, value)
+		idx += digits
+
+		if idx == len(line) {
+			return lineSliders
+		}
+
+		rest := line[idx:]
+
+		if strings.HasPrefix(rest, p.sliderSeparator) {
+			idx += len(p.sliderSeparator)
+
+			// some firmware leaves a separator after the last value, which is harmless
+			if idx == len(line) {
+				return lineSliders
+			}
+
+			continue
+		}
+
+		// the closing prefix is optional here, since the line can't be mistaken for anything else anyway
+		if strings.HasPrefix(rest, p.buttonPrefix) && p.parseButtons(rest, false) {
+			return lineCombined
+		}
+
+		return lineUnknown
+	}
+}
+
+// parseButtons reads "~1~0~", where each value is a single digit. the closing prefix may be optional
+func (p *lineParser) parseButtons(line string, closingPrefixRequired bool) bool {
+	idx := 0
+
+	for {
+		if !strings.HasPrefix(line[idx:], p.buttonPrefix) {
+
+			// reaching the end right after a value is only fine if the line doesn't need to be closed
+			return idx == len(line) && !closingPrefixRequired && len(p.buttonValues) > 0
+		}
+
+		idx += len(p.buttonPrefix)
+
+		if idx == len(line) {
+			return len(p.buttonValues) > 0
+		}
+
+		value, digits := readDigits(line[idx:], 1)
+		if digits == 0 {
+			return false
+		}
+
+		p.buttonValues = append(p.buttonValues, value)
+		idx += digits
+	}
+}
+
+// parseEncoders reads "^+1^-3^0^", where each value may have a sign
+func (p *lineParser) parseEncoders(line string) bool {
+	idx := len(encoderLinePrefix)
+
+	for {
+		negative := false
+
+		if idx < len(line) && (line[idx] == '+' || line[idx] == '-') {
+			negative = line[idx] == '-'
+			idx++
+		}
+
+		value, digits := readDigits(line[idx:], maxEncoderTicksDigits)
+		if digits == 0 {
+			return false
+		}
+
+		if negative {
+			value = -value
+		}
+
+		p.encoderTicks = append(p.encoderTicks, value)
+		idx += digits
+
+		if !strings.HasPrefix(line[idx:], encoderLinePrefix) {
+			return false
+		}
+
+		idx += len(encoderLinePrefix)
+
+		if idx == len(line) {
+			return true
+		}
+	}
+}
+
+// parseSelector reads "#2#"
+func (p *lineParser) parseSelector(line string) bool {
+	line = line[len(selectorLinePrefix):]
+
+	value, digits := readDigits(line, maxSelectorValueDigits)
+	if digits == 0 || line[digits:] != selectorLinePrefix {
+		return false
+	}
+
+	p.selectorPosition = value
+
+	return true
+}
+
+// parseCommandAck reads "ACK" or "NACK", each optionally followed by ":<reason>"
+func (p *lineParser) parseCommandAck(line string) bool {
+	var rest string
+
+	switch {
+	case strings.HasPrefix(line, commandAckLine):
+		p.commandAck.accepted = true
+		rest = line[len(commandAckLine):]
+	case strings.HasPrefix(line, commandNackLine):
+		p.commandAck.accepted = false
+		rest = line[len(commandNackLine):]
+	default:
+		return false
+	}
+
+	if rest == "" {
+		p.commandAck.reason = ""
+		return true
+	}
+
+	if !strings.HasPrefix(rest, commandReasonPrefix) {
+		return false
+	}
+
+	p.commandAck.reason = rest[len(commandReasonPrefix):]
+
+	return true
+}
+
+// parseHandshakeReply reads "DEEJ:1:5:4", the protocol version followed by how many sliders and buttons there are
+func (p *lineParser) parseHandshakeReply(line string) bool {
+	line = line[len(handshakeReplyPrefix):]
+
+	values := [3]int{}
+	for idx := range values {
+		if idx > 0 {
+			if !strings.HasPrefix(line, handshakeSeparator) {
+				return false
+			}
+
+			line = line[len(handshakeSeparator):]
+		}
+
+		value, digits := readDigits(line, maxHandshakeDigits)
+		if digits == 0 {
+			return false
+		}
+
+		values[idx] = value
+		line = line[digits:]
+	}
+
+	if line != "" {
+		return false
+	}
+
+	p.device = deviceInfo{
+		protocolVersion: values[0],
+		numSliders:      values[1],
+		numButtons:      values[2],
+	}
+
+	return true
+}
+
+// parseSequencePrefix splits "@17:512|1023" into its sequence number and the rest of the line
+func (p *lineParser) parseSequencePrefix(line string) (int, string, bool) {
+	if !strings.HasPrefix(line, sequencePrefix) {
+		return 0, line, false
+	}
+
+	sequence, digits := readDigits(line[len(sequencePrefix):], maxSequenceDigits)
+	rest := line[len(sequencePrefix)+digits:]
+
+	if digits == 0 || sequence >= sequenceModulo || !strings.HasPrefix(rest, sequenceSeparator) {
+		return 0, line, false
+	}
+
+	return sequence, rest[len(sequenceSeparator):], true
+}
+
+// readDigits reads a number of up to maxDigits digits from the start of s, and returns it along with
+// how many digits it had. a number with more than maxDigits digits counts as no number at all
+func readDigits(s string, maxDigits int) (int, int) {
+	value := 0
+	digits := 0
+
+	for digits < len(s) && s[digits] >= '0' && s[digits] <= '9' {
+		if digits == maxDigits {
+			return 0, 0
+		}
+
+		value = value*10 + int(s[digits]-'0')
+		digits++
+	}
+
+	return value, digits
+}
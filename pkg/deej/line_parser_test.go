@@ -0,0 +1,129 @@
+package deej
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// a typical line from a board with five sliders and four buttons, reported together
+const benchmarkLine = "512|1023|0|77|1000~1~0~0~1\r\n"
+
+func TestLineParserDeviceAnswers(t *testing.T) {
+	tests := []struct {
+		line       string
+		kind       lineKind
+		commandAck commandAck
+		device     deviceInfo
+	}{
+		{line: "PONG\r\n", kind: linePong},
+		{line: "ACK\r\n", kind: lineCommandAck, commandAck: commandAck{accepted: true}},
+		{line: "NACK\r\n", kind: lineCommandAck, commandAck: commandAck{accepted: false}},
+		{line: "NACK:busy now\r\n", kind: lineCommandAck, commandAck: commandAck{reason: "busy now"}},
+		{line: "DEEJ:1:5:4\r\n", kind: lineHandshake, device: deviceInfo{protocolVersion: 1, numSliders: 5, numButtons: 4}},
+		{line: "PONG", kind: lineUnknown},
+		{line: "PONGS\r\n", kind: lineUnknown},
+		{line: "ACKED\r\n", kind: lineUnknown},
+		{line: "DEEJ:1:5\r\n", kind: lineUnknown},
+		{line: "DEEJ:1:5:4:2\r\n", kind: lineUnknown},
+		{line: "DEEJ:1000:5:4\r\n", kind: lineUnknown},
+	}
+
+	parser := newLineParser(defaultSliderSeparator, defaultButtonPrefix)
+
+	for _, test := range tests {
+		kind := parser.parse(test.line)
+		if kind != test.kind {
+			t.Errorf("%q: got kind %d, want %d", test.line, kind, test.kind)
+			continue
+		}
+
+		if kind == lineCommandAck && parser.commandAck != test.commandAck {
+			t.Errorf("%q: got %+v, want %+v", test.line, parser.commandAck, test.commandAck)
+		}
+
+		if kind == lineHandshake && parser.device != test.device {
+			t.Errorf("%q: got %+v, want %+v", test.line, parser.device, test.device)
+		}
+	}
+}
+
+func BenchmarkLineParser(b *testing.B) {
+	parser := newLineParser(defaultSliderSeparator, defaultButtonPrefix)
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if parser.parse(benchmarkLine) != lineCombined {
+			b.Fatal("line not recognized")
+		}
+	}
+}
+
+// BenchmarkLineProcessing times everything the read loop does with a line: stripping its sequence number,
+// recognizing it and handling its values or the device's answer
+func BenchmarkLineProcessing(b *testing.B) {
+	sio, _ := newTestSerialIO(b, "")
+	sio.sequence = newSequenceTracker()
+	logger := zap.NewNop().Sugar()
+
+	// sequenced like real firmware would, with values that change from one line to the next
+	lines := make([]string, 0, sequenceModulo)
+	for sequence := 0; sequence < sequenceModulo; sequence++ {
+		switch sequence % 4 {
+		case 0:
+			lines = append(lines, "@"+strconv.Itoa(sequence)+":PONG\r\n")
+		case 1:
+			lines = append(lines, "@"+strconv.Itoa(sequence)+":ACK\r\n")
+		default:
+			lines = append(lines, "@"+strconv.Itoa(sequence)+":"+strconv.Itoa(sequence*4)+"|1023|0|77|1000~1~0~0~1\r\n")
+		}
+	}
+
+	receivedAt := time.Now()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if !sio.processLine(logger, lines[i%len(lines)], receivedAt) {
+			b.Fatal("connection given up")
+		}
+	}
+}
+
+// BenchmarkLineRegexes parses the same line the way deej used to, for comparison:
+// matching it against each line pattern in turn, then splitting it up and converting each value
+func BenchmarkLineRegexes(b *testing.B) {
+	button := regexp.MustCompile(`^~\d(~\d)*~\r\n$`)
+	encoder := regexp.MustCompile(`^\^[+-]?\d{1,3}(\^[+-]?\d{1,3})*\^\r\n$`)
+	selector := regexp.MustCompile(`^#\d{1,2}#\r\n$`)
+	combined := regexp.MustCompile(`^\d{1,5}(\|\d{1,5})*(~\d)+(~)?\r\n$`)
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		line := benchmarkLine
+
+		if button.MatchString(line) || encoder.MatchString(line) || selector.MatchString(line) || !combined.MatchString(line) {
+			b.Fatal("line not recognized")
+		}
+
+		prefixIdx := strings.Index(line, defaultButtonPrefix)
+		sliderLine := line[:prefixIdx] + "\r\n"
+		buttonLine := strings.TrimSuffix(line[prefixIdx:], "\r\n") + defaultButtonPrefix + "\r\n"
+
+		for _, value := range strings.Split(strings.TrimSuffix(sliderLine, "\r\n"), defaultSliderSeparator) {
+			strconv.Atoi(value)
+		}
+
+		buttonLine = strings.Trim(strings.TrimSuffix(buttonLine, "\r\n"), defaultButtonPrefix)
+		for _, value := range strings.Split(buttonLine, defaultButtonPrefix) {
+			strconv.Atoi(value)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package deej
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/omriharel/deej/pkg/deej/util"
+)
+
+// the "logging" section of the config picks where logs go (any number of sinks) and what they look like.
+// unlike everything else in there, it's read once when deej starts, since the logger has to exist before
+// the rest of the config is loaded - changing it takes a restart
+const (
+	logSinkFile     = "file"     // logs/deej-latest-run.log
+	logSinkStderr   = "stderr"   // the terminal deej was started from
+	logSinkJournald = "journald" // the systemd journal (linux only)
+	logSinkEventLog = "eventlog" // the windows event log, under the "deej" source (windows only)
+
+	logFormatConsole = "console"
+	logFormatJSON    = "json"
+
+	// how deej shows up in the journal and the event log
+	systemLogIdentifier = "deej"
+)
+
+type loggingConfig struct {
+	sinks  []string
+	format string
+	level  string
+}
+
+// readLoggingConfig reads just the logging section from the user's config, if there is one
+func readLoggingConfig() (loggingConfig, bool) {
+	userConfig := viper.New()
+	userConfig.SetConfigFile(userConfigFilepath)
+
+	if err := userConfig.ReadInConfig(); err != nil {
+		return loggingConfig{}, false
+	}
+
+	// same as the rest of the config, so a shared file can pick journald on linux and the event log on windows
+	if overrides := userConfig.GetStringMap(runtime.GOOS); len(overrides) > 0 {
+		userConfig.MergeConfigMap(overrides)
+	}
+
+	if !userConfig.IsSet(configKeyLogging) {
+		return loggingConfig{}, false
+	}
+
+	return loggingConfig{
+		sinks:  userConfig.GetStringSlice(configKeyLoggingSinks),
+		format: strings.ToLower(userConfig.GetString(configKeyLoggingFormat)),
+		level:  strings.ToLower(userConfig.GetString(configKeyLoggingLevel)),
+	}, true
+}
+
+// applyLoggingConfig adjusts the logger's config to the user's logging settings. sinks that zap can't write
+// to on its own (the system logs), or that need entries to look different (the log file), are returned as
+// extra cores, along with any settings that were wrong
+func applyLoggingConfig(loggerConfig *zap.Config, logging loggingConfig) ([]zapcore.Core, []error) {
+	problems := []error{}
+
+	if logging.level != "" {
+		var level zapcore.Level
+		if err := level.UnmarshalText([]byte(logging.level)); err != nil {
+			problems = append(problems, fmt.Errorf("unknown level %q", logging.level))
+		} else {
+			loggerConfig.Level = zap.NewAtomicLevelAt(level)
+		}
+	}
+
+	switch logging.format {
+	case "", logFormatConsole:
+		loggerConfig.Encoding = logFormatConsole
+	case logFormatJSON:
+		loggerConfig.Encoding = logFormatJSON
+
+		// padding and colors only make sense to people reading along. callers aren't logged either way,
+		// but unlike the console encoder, the json one doesn't check for that
+		loggerConfig.EncoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+		loggerConfig.EncoderConfig.EncodeName = zapcore.FullNameEncoder
+		loggerConfig.EncoderConfig.CallerKey = ""
+	default:
+		problems = append(problems, fmt.Errorf("unknown format %q, expected %q or %q", logging.format, logFormatConsole, logFormatJSON))
+	}
+
+	// no sinks listed means the usual ones for this build type
+	if len(logging.sinks) == 0 {
+		return nil, problems
+	}
+
+	outputPaths := []string{}
+	extraCores := []zapcore.Core{}
+
+	for _, sink := range logging.sinks {
+		switch sink = strings.ToLower(sink); sink {
+		case logSinkFile:
+			if err := util.EnsureDirExists(logDirectory); err != nil {
+				problems = append(problems, fmt.Errorf("ensure log directory exists: %w", err))
+				continue
+			}
+
+			write, _, err := zap.Open(filepath.Join(logDirectory, logFilename))
+			if err != nil {
+				problems = append(problems, fmt.Errorf("open %s sink: %w", sink, err))
+				continue
+			}
+
+			extraCores = append(extraCores, zapcore.NewCore(fileLogEncoder(*loggerConfig), write, loggerConfig.Level))
+
+		case logSinkStderr:
+			outputPaths = append(outputPaths, logSinkStderr)
+
+		default:
+			write, err := openSystemLog(sink)
+			if err != nil {
+				problems = append(problems, fmt.Errorf("open %s sink: %w", sink, err))
+				continue
+			}
+
+			extraCores = append(extraCores, newSystemLogCore(systemLogEncoder(*loggerConfig), loggerConfig.Level, write))
+		}
+	}
+
+	// logging nowhere at all is never what anyone wants
+	if len(outputPaths) == 0 && len(extraCores) == 0 {
+		outputPaths = append(outputPaths, logSinkStderr)
+	}
+
+	loggerConfig.OutputPaths = outputPaths
+
+	return extraCores, problems
+}
+
+// fileLogEncoder is the logger's own encoder without colors, which make a mess of log files. the file gets
+// an encoder of its own, so stderr keeps its colors
+func fileLogEncoder(loggerConfig zap.Config) zapcore.Encoder {
+	encoderConfig := loggerConfig.EncoderConfig
+	encoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+
+	if loggerConfig.Encoding == logFormatJSON {
+		return zapcore.NewJSONEncoder(encoderConfig)
+	}
+
+	return zapcore.NewConsoleEncoder(encoderConfig)
+}
+
+// systemLogEncoder leaves out the time and level, since system logs keep track of both on their own
+func systemLogEncoder(loggerConfig zap.Config) zapcore.Encoder {
+	encoderConfig := loggerConfig.EncoderConfig
+	encoderConfig.TimeKey = ""
+	encoderConfig.LevelKey = ""
+	encoderConfig.EncodeName = zapcore.FullNameEncoder
+
+	if loggerConfig.Encoding == logFormatJSON {
+		return zapcore.NewJSONEncoder(encoderConfig)
+	}
+
+	return zapcore.NewConsoleEncoder(encoderConfig)
+}
+
+// systemLogWriter sends a single, already formatted entry to a system log
+type systemLogWriter func(level zapcore.Level, message string) error
+
+// systemLogCore is a zap core for system logs, which (unlike files) need to know each entry's level
+type systemLogCore struct {
+	zapcore.LevelEnabler
+
+	encoder zapcore.Encoder
+	write   systemLogWriter
+}
+
+func newSystemLogCore(encoder zapcore.Encoder, level zapcore.LevelEnabler, write systemLogWriter) *systemLogCore {
+	return &systemLogCore{
+		LevelEnabler: level,
+		encoder:      encoder,
+		write:        write,
+	}
+}
+
+// With implements zapcore.Core
+func (c *systemLogCore) With(fields []zapcore.Field) zapcore.Core {
+	encoder := c.encoder.Clone()
+	for _, field := range fields {
+		field.AddTo(encoder)
+	}
+
+	return newSystemLogCore(encoder, c.LevelEnabler, c.write)
+}
+
+// Check implements zapcore.Core
+func (c *systemLogCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+
+	return checked
+}
+
+// Write implements zapcore.Core
+func (c *systemLogCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	encoded, err := c.encoder.EncodeEntry(entry, fields)
+	if err != nil {
+		return fmt.Errorf("encode log entry: %w", err)
+	}
+
+	defer encoded.Free()
+
+	return c.write(entry.Level, strings.TrimSuffix(encoded.String(), "\n"))
+}
+
+// Sync implements zapcore.Core, system logs take care of their own buffering
+func (c *systemLogCore) Sync() error {
+	return nil
+}
@@ -0,0 +1,50 @@
+package deej
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const journaldSocketPath = "/run/systemd/journal/socket"
+
+// syslog priorities, as the journal understands them
+var journaldPriorities = map[zapcore.Level]int{
+	zapcore.DebugLevel:  7,
+	zapcore.InfoLevel:   6,
+	zapcore.WarnLevel:   4,
+	zapcore.ErrorLevel:  3,
+	zapcore.DPanicLevel: 2,
+	zapcore.PanicLevel:  2,
+	zapcore.FatalLevel:  2,
+}
+
+func openSystemLog(sink string) (systemLogWriter, error) {
+	if sink != logSinkJournald {
+		return nil, fmt.Errorf("unknown sink (expected %q, %q or %q)", logSinkFile, logSinkStderr, logSinkJournald)
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journaldSocketPath, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("connect to journald: %w", err)
+	}
+
+	return func(level zapcore.Level, message string) error {
+		var entry bytes.Buffer
+
+		fmt.Fprintf(&entry, "PRIORITY=%d\nSYSLOG_IDENTIFIER=%s\n", journaldPriorities[level], systemLogIdentifier)
+
+		// the journal's native protocol takes values with newlines in them (i.e. stack traces)
+		// as the field's name, its length and then the value itself
+		entry.WriteString("MESSAGE\n")
+		binary.Write(&entry, binary.LittleEndian, uint64(len(message)))
+		entry.WriteString(message)
+		entry.WriteString("\n")
+
+		_, err := conn.Write(entry.Bytes())
+		return err
+	}, nil
+}
@@ -0,0 +1,35 @@
+package deej
+
+import (
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// the event log wants an event id with every entry, but deej doesn't tell its entries apart that way
+const eventLogEventID = 1
+
+func openSystemLog(sink string) (systemLogWriter, error) {
+	if sink != logSinkEventLog {
+		return nil, fmt.Errorf("unknown sink (expected %q, %q or %q)", logSinkFile, logSinkStderr, logSinkEventLog)
+	}
+
+	// this works without registering deej as an event source (which needs admin rights), the event
+	// viewer just adds a note about not finding a description for the event next to each entry
+	log, err := eventlog.Open(systemLogIdentifier)
+	if err != nil {
+		return nil, fmt.Errorf("open event log: %w", err)
+	}
+
+	return func(level zapcore.Level, message string) error {
+		switch {
+		case level >= zapcore.ErrorLevel:
+			return log.Error(eventLogEventID, message)
+		case level == zapcore.WarnLevel:
+			return log.Warning(eventLogEventID, message)
+		default:
+			return log.Info(eventLogEventID, message)
+		}
+	}, nil
+}
@@ -32,7 +32,7 @@ func NewLogger(buildType string) (*zap.SugaredLogger, error) {
 		loggerConfig = zap.NewProductionConfig()
 
 		loggerConfig.OutputPaths = []string{filepath.Join(logDirectory, logFilename)}
-		loggerConfig.Encoding = "console"
+		loggerConfig.Encoding = logFormatConsole
 
 		// development: debug and above, log to stderr only, colorful
 	} else {
@@ -52,7 +52,18 @@ func NewLogger(buildType string) (*zap.SugaredLogger, error) {
 		enc.AppendString(fmt.Sprintf("%-27s", s))
 	}
 
-	logger, err := loggerConfig.Build()
+	// the user may pick where logs go and what they look like, otherwise the above is what they get
+	var extraCores []zapcore.Core
+	var problems []error
+
+	if logging, ok := readLoggingConfig(); ok {
+		extraCores, problems = applyLoggingConfig(&loggerConfig, logging)
+	}
+
+	logger, err := loggerConfig.Build(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(append([]zapcore.Core{core}, extraCores...)...)
+	}))
+
 	if err != nil {
 		return nil, fmt.Errorf("create zap logger: %w", err)
 	}
@@ -60,5 +71,10 @@ func NewLogger(buildType string) (*zap.SugaredLogger, error) {
 	// no reason not to use the sugared logger - it's fast enough for anything we're gonna do
 	sugar := logger.Sugar()
 
+	// these could only be reported once there's somewhere to report them to
+	for _, problem := range problems {
+		sugar.Named("logger").Warnw("Invalid logging setting, ignoring it", "error", problem)
+	}
+
 	return sugar, nil
 }
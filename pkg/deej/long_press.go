@@ -0,0 +1,84 @@
+package deej
+
+import (
+	"sync"
+	"time"
+)
+
+const defaultLongPressThreshold = 500 * time.Millisecond
+
+// buttonHoldTracker times how long buttons with long-press actions are held. such buttons don't act when
+// pressed, since there's no way to tell a tap from a hold yet: a hold fires the long-press actions as soon
+// as it reaches the threshold, while a tap fires the regular actions when the button is released
+type buttonHoldTracker struct {
+	holds map[int]*buttonHold
+	lock  sync.Locker
+}
+
+type buttonHold struct {
+	press ButtonPressEvent
+	timer *time.Timer
+	fired bool
+}
+
+func newButtonHoldTracker() *buttonHoldTracker {
+	return &buttonHoldTracker{
+		holds: map[int]*buttonHold{},
+		lock:  &sync.Mutex{},
+	}
+}
+
+// press starts timing a hold, calling onHold (on its own goroutine) if the button is still held after threshold
+func (t *buttonHoldTracker) press(event ButtonPressEvent, threshold time.Duration, onHold func(ButtonPressEvent)) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if previous, ok := t.holds[event.ButtonID]; ok {
+		previous.timer.Stop()
+	}
+
+	hold := &buttonHold{press: event}
+	hold.timer = time.AfterFunc(threshold, func() {
+		t.lock.Lock()
+
+		// the button may have been released while we were waiting for the lock
+		if t.holds[event.ButtonID] != hold {
+			t.lock.Unlock()
+			return
+		}
+
+		hold.fired = true
+		t.lock.Unlock()
+
+		onHold(hold.press)
+	})
+
+	t.holds[event.ButtonID] = hold
+}
+
+// release stops timing a hold, and returns the press that started it if it was a tap
+func (t *buttonHoldTracker) release(buttonIdx int) (ButtonPressEvent, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	hold, ok := t.holds[buttonIdx]
+	if !ok {
+		return ButtonPressEvent{}, false
+	}
+
+	hold.timer.Stop()
+	delete(t.holds, buttonIdx)
+
+	return hold.press, !hold.fired
+}
+
+// reset forgets all holds without acting on them, i.e. when the device goes away mid-press
+func (t *buttonHoldTracker) reset() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for buttonIdx, hold := range t.holds {
+		hold.timer.Stop()
+		delete(t.holds, buttonIdx)
+	}
+}
@@ -0,0 +1,190 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// record_macro:<button> records a macro for a button, far easier than writing one by hand: press the recording
+// button once, then press the keys (and the device's buttons) that make up the macro, and press it again to stop -
+// or wait for macro_record_time to pass. the recording is saved under macros as recorded_<button>, and that
+// button's mapping is replaced with running it. keys can only be recorded on windows, and only ones deej knows
+// the name of (see "deej actions"). elsewhere, only the device's buttons make it into the macro
+const (
+	actionRecordMacroPrefix = "record_macro:"
+	recordedMacroNameFormat = "recorded_%d"
+
+	defaultMacroRecordTime = 10 * time.Second
+)
+
+var (
+	errInvalidRecordMacroButton = errors.New("expected a button index")
+	errKeyRecordingUnsupported  = errors.New("recording keys isn't supported on this platform")
+)
+
+// macroRecorder collects a macro's steps while it's being recorded
+type macroRecorder struct {
+	logger *zap.SugaredLogger
+	lock   sync.Locker
+
+	recording bool
+
+	// the button the macro is for (as the user counts them), and what it does so far
+	buttonIdx int
+	steps     []string
+
+	// stops watching the keyboard, if we are
+	stopKeys func()
+
+	// ends the recording once it's been going for long enough. each recording has a generation of its own,
+	// so a timer that fires just as its recording is stopped by hand can't end the next one
+	timer      *time.Timer
+	generation int
+}
+
+func newMacroRecorder(logger *zap.SugaredLogger) *macroRecorder {
+	return &macroRecorder{
+		logger: logger.Named("macro_recorder"),
+		lock:   &sync.Mutex{},
+	}
+}
+
+// start begins recording a macro for the given button, and calls onTimeout with the recording's generation
+// once it's been going for duration. it returns false if the keyboard can't be recorded, just the device's buttons
+func (r *macroRecorder) start(buttonIdx int, duration time.Duration, onTimeout func(generation int)) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.recording = true
+	r.buttonIdx = buttonIdx
+	r.steps = []string{}
+	r.generation++
+
+	stopKeys, err := watchKeys(r.addKey)
+	if err != nil {
+		r.logger.Infow("Can't record keys, only recording the device's buttons", "error", err)
+	}
+
+	r.stopKeys = stopKeys
+
+	generation := r.generation
+	r.timer = time.AfterFunc(duration, func() { onTimeout(generation) })
+
+	r.logger.Infow("Started recording macro", "button", buttonIdx, "duration", duration)
+
+	return err == nil
+}
+
+// stop ends the recording, and returns which button it was for and what it recorded. it returns false if there
+// was nothing to stop, or if a generation is given (0 for any) and the current recording isn't that one
+func (r *macroRecorder) stop(generation int) (int, []string, bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if !r.recording || (generation != 0 && generation != r.generation) {
+		return 0, nil, false
+	}
+
+	r.recording = false
+	r.timer.Stop()
+
+	if r.stopKeys != nil {
+		r.stopKeys()
+		r.stopKeys = nil
+	}
+
+	r.logger.Infow("Stopped recording macro", "button", r.buttonIdx, "steps", r.steps)
+
+	return r.buttonIdx, r.steps, true
+}
+
+// capture takes note of the given actions, if a macro's being recorded. recording controls aren't part of it
+func (r *macroRecorder) capture(actions []buttonAction) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if !r.recording {
+		return
+	}
+
+	for _, action := range actions {
+		if action.kind != buttonActionRecordMacro {
+			r.steps = append(r.steps, action.name)
+		}
+	}
+}
+
+// addKey takes note of a key pressed on the keyboard (along with any modifiers held down)
+func (r *macroRecorder) addKey(modifiers []string, key string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if !r.recording {
+		return
+	}
+
+	step := strings.Join(append(modifiers, key), keyComboSeparator)
+	r.steps = append(r.steps, step)
+
+	r.logger.Debugw("Recorded key", "key", step)
+}
+
+// recordMacro starts recording a macro for the given button, or stops and saves the one being recorded
+func (sio *SerialIO) recordMacro(logger *zap.SugaredLogger, notifier Notifier, buttonIdx int) {
+	if recordedButtonIdx, steps, ok := sio.macroRecorder.stop(0); ok {
+		sio.saveRecordedMacro(logger, notifier, recordedButtonIdx, steps)
+		return
+	}
+
+	recordTime := sio.deej.config.MacroRecordTime
+
+	recordingKeys := sio.macroRecorder.start(buttonIdx, recordTime, func(generation int) {
+		if recordedButtonIdx, steps, ok := sio.macroRecorder.stop(generation); ok {
+			sio.saveRecordedMacro(logger, notifier, recordedButtonIdx, steps)
+		}
+	})
+
+	// don't leave people typing away at a recording that can't hear them
+	if !recordingKeys {
+		notifier.Notify("Recording macro (device buttons only)",
+			fmt.Sprintf("Keys can't be recorded here. Press the device's buttons for button %d, then press this button again (or wait %d seconds).",
+				buttonIdx, int(recordTime.Seconds())))
+
+		return
+	}
+
+	notifier.Notify("Recording macro",
+		fmt.Sprintf("Press the keys for button %d, then press this button again (or wait %d seconds).",
+			buttonIdx, int(recordTime.Seconds())))
+}
+
+// saveRecordedMacro writes a recorded macro to the config, and maps its button to it
+func (sio *SerialIO) saveRecordedMacro(logger *zap.SugaredLogger, notifier Notifier, buttonIdx int, steps []string) {
+	if len(steps) == 0 {
+		notifier.Notify("Nothing recorded", fmt.Sprintf("Button %d keeps doing what it did.", buttonIdx))
+		return
+	}
+
+	name := fmt.Sprintf(recordedMacroNameFormat, buttonIdx)
+
+	err := sio.deej.config.UpdateUserSettings(map[string]interface{}{
+		configKeyMacros + "." + name:                           steps,
+		configKeyButtonMapping + "." + strconv.Itoa(buttonIdx): actionMacroPrefix + name,
+	})
+
+	if err != nil {
+		logger.Warnw("Failed to save recorded macro", "macro", name, "error", err)
+		notifier.Notify("Can't save macro!", "Please check deej's logs for more details.")
+
+		return
+	}
+
+	logger.Infow("Saved recorded macro", "macro", name, "button", buttonIdx, "steps", len(steps))
+	notifier.Notify("Macro recorded", fmt.Sprintf("Button %d now plays back %d steps.", buttonIdx, len(steps)))
+}
@@ -0,0 +1,6 @@
+package deej
+
+// there's no telling which keys are pressed without root (or an X extension deej doesn't link against)
+func watchKeys(onKey func(modifiers []string, key string)) (func(), error) {
+	return nil, errKeyRecordingUnsupported
+}
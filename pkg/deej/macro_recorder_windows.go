@@ -0,0 +1,120 @@
+package deej
+
+import (
+	"syscall"
+	"time"
+)
+
+// keys are recorded by asking windows which ones are down every so often, rather than hooking into the keyboard.
+// that's plenty fast for people pressing keys, and doesn't need a message loop of its own
+const (
+	keyWatchInterval = 10 * time.Millisecond
+
+	// keybd_event tells virtual key codes apart from scan codes by adding this to them
+	keybdVirtualKeyOffset = 0xFFF
+
+	mapVKScanCodeToVK = 1
+	keyStateDown      = 0x8000
+
+	vkShift   = 0x10
+	vkControl = 0x11
+	vkMenu    = 0x12
+	vkLWin    = 0x5B
+	vkRWin    = 0x5C
+)
+
+var (
+	procGetAsyncKeyState = syscall.NewLazyDLL("user32.dll").NewProc("GetAsyncKeyState")
+	procMapVirtualKey    = syscall.NewLazyDLL("user32.dll").NewProc("MapVirtualKeyW")
+)
+
+func watchKeys(onKey func(modifiers []string, key string)) (func(), error) {
+	if err := procGetAsyncKeyState.Find(); err != nil {
+		return nil, err
+	}
+
+	keys := recordableKeys()
+	stop := make(chan bool)
+
+	go func() {
+		ticker := time.NewTicker(keyWatchInterval)
+		defer ticker.Stop()
+
+		// keys that were already down when recording started don't count until they're pressed again
+		down := map[uintptr]bool{}
+		for virtualKey := range keys {
+			down[virtualKey] = keyDown(virtualKey)
+		}
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			for virtualKey, name := range keys {
+				pressed := keyDown(virtualKey)
+				if pressed && !down[virtualKey] {
+					onKey(heldModifiers(), name)
+				}
+
+				down[virtualKey] = pressed
+			}
+		}
+	}()
+
+	return func() { close(stop) }, nil
+}
+
+// recordableKeys returns the name of each key deej knows, by its virtual key code
+func recordableKeys() map[uintptr]string {
+	keys := map[uintptr]string{}
+
+	for name, code := range allKeyCodes() {
+		var virtualKey uintptr
+		if code >= keybdVirtualKeyOffset {
+			virtualKey = uintptr(code - keybdVirtualKeyOffset)
+		} else {
+			virtualKey, _, _ = procMapVirtualKey.Call(uintptr(code), mapVKScanCodeToVK)
+		}
+
+		if virtualKey == 0 {
+			continue
+		}
+
+		// several names may stand for the same key, stick with the same one every time
+		if existing, ok := keys[virtualKey]; !ok || name < existing {
+			keys[virtualKey] = name
+		}
+	}
+
+	return keys
+}
+
+func heldModifiers() []string {
+	modifiers := []string{}
+
+	if keyDown(vkControl) {
+		modifiers = append(modifiers, keyModifierCtrl)
+	}
+
+	if keyDown(vkShift) {
+		modifiers = append(modifiers, keyModifierShift)
+	}
+
+	if keyDown(vkMenu) {
+		modifiers = append(modifiers, keyModifierAlt)
+	}
+
+	if keyDown(vkLWin) || keyDown(vkRWin) {
+		modifiers = append(modifiers, keyModifierWin)
+	}
+
+	return modifiers
+}
+
+func keyDown(virtualKey uintptr) bool {
+	state, _, _ := procGetAsyncKeyState.Call(virtualKey)
+	return state&keyStateDown != 0
+}
@@ -0,0 +1,61 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// macros are named lists of button actions, set up under macros and run with macro:<name>. their steps run one
+// after the other, just like a button's own actions do (see action_steps.go), so the same steps can be shared
+// between buttons. they can be written by hand, or recorded with record_macro: (see macro_recorder.go)
+const actionMacroPrefix = "macro:"
+
+var (
+	errMissingMacroName = errors.New("missing macro name")
+	errNestedMacro      = errors.New("macros can't run or record other macros")
+)
+
+// macrosFromConfig resolves each macro's steps, keyed by lowercased name. steps that can't be resolved are left out
+func macrosFromConfig(userMacros map[string][]string) (map[string][]buttonAction, []error) {
+	macros := map[string][]buttonAction{}
+	problems := []error{}
+
+	for name, steps := range userMacros {
+		actions, stepProblems := parseButtonActions(steps)
+
+		for _, problem := range stepProblems {
+			problems = append(problems, fmt.Errorf("macro %s: %w", name, problem))
+		}
+
+		// a macro running itself (or one that runs it) would never end
+		validActions := []buttonAction{}
+		for _, action := range actions {
+			if action.kind == buttonActionMacro || action.kind == buttonActionRecordMacro {
+				problems = append(problems, fmt.Errorf("macro %s: %q: %w", name, action.name, errNestedMacro))
+				continue
+			}
+
+			validActions = append(validActions, action)
+		}
+
+		macros[strings.ToLower(name)] = validActions
+	}
+
+	return macros, problems
+}
+
+// runMacro performs each of a macro's steps in turn, and returns false if it was cancelled along the way
+func (sio *SerialIO) runMacro(logger *zap.SugaredLogger, notifier Notifier, buttonEvent ButtonPressEvent, name string, cancel chan bool) bool {
+	steps, ok := sio.deej.config.Macros[name]
+	if !ok {
+		logger.Warnw("Button runs a macro that isn't set up under macros", "macro", name)
+		return true
+	}
+
+	logger.Debugw("Running macro", "macro", name, "steps", len(steps))
+
+	return sio.performSteps(logger, notifier, buttonEvent, steps, cancel)
+}
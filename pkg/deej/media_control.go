@@ -0,0 +1,91 @@
+package deej
+
+import (
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// media:play_pause, media:next and media:previous control whichever media player is active through the OS' own
+// media API where there is one (MPRIS on linux, where media keys often go nowhere depending on the desktop), and
+// press the matching media key otherwise - or whenever the API has no player to control, so nothing changes for
+// anyone whose media keys already worked. a player that doesn't answer in time may still get to it, so it's left at
+// that rather than toggled twice. play_pause, next and previous work as well, without the prefix
+const (
+	actionMediaPrefix = "media:"
+
+	actionMediaPlayPause = actionMediaPrefix + "play_pause"
+	actionMediaNext      = actionMediaPrefix + "next"
+	actionMediaPrevious  = actionMediaPrefix + "previous"
+
+	actionMediaPlayPauseAlias = "play_pause"
+	actionMediaNextAlias      = "next"
+	actionMediaPreviousAlias  = "previous"
+
+	// a player that's hung (or a bus that's busy) shouldn't hold up the buttons for long
+	mediaControlTimeout = 500 * time.Millisecond
+)
+
+// mediaCommand is what a media action asks the player to do through the media API, and the key it falls back to
+type mediaCommand struct {
+	method      string
+	fallbackKey string
+}
+
+var mediaCommands = map[string]mediaCommand{
+	actionMediaPlayPause: {method: "PlayPause", fallbackKey: "MEDIA_PLAY_PAUSE"},
+	actionMediaNext:      {method: "Next", fallbackKey: "MEDIA_NEXT_TRACK"},
+	actionMediaPrevious:  {method: "Previous", fallbackKey: "MEDIA_PREV_TRACK"},
+
+	actionMediaPlayPauseAlias: {method: "PlayPause", fallbackKey: "MEDIA_PLAY_PAUSE"},
+	actionMediaNextAlias:      {method: "Next", fallbackKey: "MEDIA_NEXT_TRACK"},
+	actionMediaPreviousAlias:  {method: "Previous", fallbackKey: "MEDIA_PREV_TRACK"},
+}
+
+var (
+	errNoMediaAPI      = errors.New("no media API on this OS")
+	errNoMediaPlayer   = errors.New("no media player running")
+	errMediaAPITimeout = errors.New("media API didn't answer in time")
+)
+
+// controlMedia has the active player carry out a media action, pressing its media key instead if that can't be done
+func (sio *SerialIO) controlMedia(logger *zap.SugaredLogger, keys *keyPresser, action buttonAction) {
+	err := controlMediaPlayerWithin("", action.mediaMethod, mediaControlTimeout)
+	if err == nil {
+		logger.Infow("Controlled media player", "action", action.name)
+		return
+	}
+
+	// the player may still carry it out, pressing the key too would do it twice
+	if err == errMediaAPITimeout {
+		logger.Warnw("Media player didn't answer in time, not pressing media key", "action", action.name)
+		return
+	}
+
+	if err != errNoMediaAPI {
+		logger.Debugw("Failed to control media player, pressing media key instead", "action", action.name, "error", err)
+	}
+
+	if err := keys.press(action); err != nil {
+		logger.Warnw("Failed to press media key", "action", action.name, "error", err)
+	}
+}
+
+// controlMediaPlayerWithin gives the media API up to timeout to carry out the command, on the named player or the
+// active one if player is empty. a call that's still going after that is left to finish (or not) in the background,
+// so callers shouldn't fall back to anything else on errMediaAPITimeout
+func controlMediaPlayerWithin(player string, method string, timeout time.Duration) error {
+	result := make(chan error, 1)
+
+	go func() {
+		result <- controlMediaPlayer(player, method)
+	}()
+
+	select {
+	case err := <-result:
+		return err
+	case <-time.After(timeout):
+		return errMediaAPITimeout
+	}
+}
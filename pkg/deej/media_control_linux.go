@@ -0,0 +1,71 @@
+package deej
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/godbus/dbus"
+)
+
+// media players on linux take commands over MPRIS, each under a D-Bus name of its own. the active one is
+// the one that's playing, or else one that's paused, or else whichever showed up first. a player asked for by
+// name (i.e. spotify, or spotify.exe) is whichever one's D-Bus name has that in it
+const (
+	mprisNamePrefix     = "org.mpris.MediaPlayer2."
+	mprisObjectPath     = "/org/mpris/MediaPlayer2"
+	mprisPlayerIface    = "org.mpris.MediaPlayer2.Player"
+	mprisPlaybackStatus = mprisPlayerIface + ".PlaybackStatus"
+)
+
+var mprisStatusRanks = map[string]int{"Playing": 0, "Paused": 1}
+
+func controlMediaPlayer(player string, method string) error {
+	conn, err := dbus.SessionBus()
+	if err != nil {
+		return fmt.Errorf("connect to session bus: %w", err)
+	}
+
+	var names []string
+	if err := conn.BusObject().Call("org.freedesktop.DBus.ListNames", 0).Store(&names); err != nil {
+		return fmt.Errorf("list D-Bus names: %w", err)
+	}
+
+	activePlayer, activeRank := "", len(mprisStatusRanks)+1
+
+	for _, name := range names {
+		if !strings.HasPrefix(name, mprisNamePrefix) || !mprisNameMatches(name, player) {
+			continue
+		}
+
+		rank := len(mprisStatusRanks)
+		if status, err := conn.Object(name, mprisObjectPath).GetProperty(mprisPlaybackStatus); err == nil {
+			if statusRank, ok := mprisStatusRanks[fmt.Sprint(status.Value())]; ok {
+				rank = statusRank
+			}
+		}
+
+		if rank < activeRank {
+			activePlayer, activeRank = name, rank
+		}
+	}
+
+	if activePlayer == "" {
+		return errNoMediaPlayer
+	}
+
+	if err := conn.Object(activePlayer, mprisObjectPath).Call(mprisPlayerIface+"."+method, 0).Err; err != nil {
+		return fmt.Errorf("call %s on %s: %w", method, strings.TrimPrefix(activePlayer, mprisNamePrefix), err)
+	}
+
+	return nil
+}
+
+// mprisNameMatches returns whether a player's D-Bus name is that of the given player, if one is given at all
+func mprisNameMatches(name string, player string) bool {
+	player = strings.TrimSuffix(strings.ToLower(player), ".exe")
+	if player == "" {
+		return true
+	}
+
+	return strings.Contains(strings.ToLower(strings.TrimPrefix(name, mprisNamePrefix)), player)
+}
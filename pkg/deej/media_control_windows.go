@@ -0,0 +1,6 @@
+package deej
+
+// windows delivers media keys to the active player reliably, so they're all media actions need
+func controlMediaPlayer(player string, method string) error {
+	return errNoMediaAPI
+}
@@ -0,0 +1,67 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// the mic button action mutes the default input device itself, through the audio backend (unlike pressing
+// Win+Alt+K, which only does anything with PowerToys or a call app listening for it). toggling always asks
+// the device whether it's muted first, so muting it from somewhere else never leaves the button out of sync
+const actionMicPrefix = "mic:"
+
+var errNoMic = errors.New("no default input device")
+
+// setMicMute mutes, unmutes or toggles the default input device, returning whether it's now muted
+func (m *sessionMap) setMicMute(mode string) (bool, error) {
+	muted, err := m.trySetMicMute(mode)
+
+	// the default input device may have changed (or shown up) since sessions were last acquired
+	if err != nil {
+		m.logger.Debugw("Failed to mute input device, refreshing sessions and retrying", "error", err)
+
+		// force == true, this only happens when a mic mute action fails, which is rare enough
+		m.refreshSessions(true)
+
+		muted, err = m.trySetMicMute(mode)
+	}
+
+	return muted, err
+}
+
+func (m *sessionMap) trySetMicMute(mode string) (bool, error) {
+	sessions, ok := m.get(inputSessionName)
+	if !ok || len(sessions) == 0 {
+		return false, errNoMic
+	}
+
+	muted := false
+
+	for _, session := range sessions {
+		mutable, ok := session.(mutableSession)
+		if !ok {
+			continue
+		}
+
+		mute := mode == muteModeMute || (mode == muteModeToggle && !mutable.GetMute())
+		if err := mutable.SetMute(mute); err != nil {
+			return false, fmt.Errorf("set input device mute: %w", err)
+		}
+
+		muted = mute
+	}
+
+	return muted, nil
+}
+
+func (sio *SerialIO) muteMic(logger *zap.SugaredLogger, mode string) {
+	muted, err := sio.deej.sessions.setMicMute(mode)
+	if err != nil {
+		logger.Warnw("Failed to mute input device", "mode", mode, "error", err)
+		return
+	}
+
+	logger.Infow("Changed input device mute", "muted", muted)
+}
@@ -0,0 +1,155 @@
+package deej
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/omriharel/deej/pkg/deej/util"
+)
+
+// MissingTargetRule decides what happens when a slider moves but its target isn't running.
+// by default nothing does, but users can choose to be notified (once per absence), have deej launch
+// the app (using Command, or the target's own name if it's empty), or have the slider's value buffered
+// and applied as soon as the app shows up
+type MissingTargetRule struct {
+	Target  string `mapstructure:"target"`
+	Action  string `mapstructure:"action"`
+	Command string `mapstructure:"command"`
+}
+
+const (
+	missingTargetIgnore = "ignore"
+	missingTargetNotify = "notify"
+	missingTargetLaunch = "launch"
+	missingTargetBuffer = "buffer"
+
+	// sliders move many times a second, and apps take a while to start
+	missingTargetLaunchCooldown = 30 * time.Second
+)
+
+var missingTargetActions = []string{
+	missingTargetIgnore,
+	missingTargetNotify,
+	missingTargetLaunch,
+	missingTargetBuffer,
+}
+
+// missingTargetHandler applies the user's missing target rules
+type missingTargetHandler struct {
+	sessions *sessionMap
+	logger   *zap.SugaredLogger
+
+	// targets the user was already notified about, until they show up
+	notified map[string]bool
+
+	// when each target was last launched
+	launched map[string]time.Time
+
+	// volumes waiting for their targets to show up
+	buffered map[string]float32
+
+	lock sync.Locker
+}
+
+func newMissingTargetHandler(sessions *sessionMap, logger *zap.SugaredLogger) *missingTargetHandler {
+	return &missingTargetHandler{
+		sessions: sessions,
+		logger:   logger.Named("missing_targets"),
+		notified: map[string]bool{},
+		launched: map[string]time.Time{},
+		buffered: map[string]float32{},
+		lock:     &sync.Mutex{},
+	}
+}
+
+func (h *missingTargetHandler) rule(target string) (MissingTargetRule, bool) {
+	for _, rule := range h.sessions.deej.config.MissingTargets {
+		if rule.Target == target {
+			return rule, true
+		}
+	}
+
+	return MissingTargetRule{}, false
+}
+
+// handle is called whenever a slider moves while one of its (resolved) targets isn't running
+func (h *missingTargetHandler) handle(target string, value float32) {
+	rule, ok := h.rule(target)
+	if !ok {
+		return
+	}
+
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	switch rule.Action {
+	case missingTargetNotify:
+		if h.notified[target] {
+			return
+		}
+
+		h.notified[target] = true
+		h.logger.Infow("Notifying about missing target", "target", target)
+
+		h.sessions.deej.notifier.Notify(fmt.Sprintf("%s isn't running", target),
+			"It's mapped to a slider you just moved, but deej can't find it.")
+
+	case missingTargetLaunch:
+		if time.Since(h.launched[target]) < missingTargetLaunchCooldown {
+			return
+		}
+
+		h.launched[target] = time.Now()
+
+		command := rule.Command
+		if command == "" {
+			command = target
+		}
+
+		h.logger.Infow("Launching missing target", "target", target, "command", command)
+
+		if err := util.LaunchDetached(command); err != nil {
+			h.logger.Warnw("Failed to launch missing target", "target", target, "error", err)
+		}
+
+		// once it's up, it should come up at the slider's volume rather than full blast
+		h.buffered[target] = value
+
+	case missingTargetBuffer:
+		h.buffered[target] = value
+	}
+}
+
+// found is called whenever a slider finds its target, which resets anything pending for it
+func (h *missingTargetHandler) found(target string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	delete(h.notified, target)
+	delete(h.buffered, target)
+}
+
+// applyBuffered applies buffered volumes to any targets that have shown up since they were buffered
+func (h *missingTargetHandler) applyBuffered() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	for target, value := range h.buffered {
+		sessions, ok := h.sessions.find(target)
+		if !ok {
+			continue
+		}
+
+		h.logger.Infow("Applying buffered volume to target", "target", target, "volume", value)
+
+		for _, session := range sessions {
+			h.sessions.applier.set(session, h.sessions.autoMix.adjust(session.Key(), value))
+		}
+
+		delete(h.buffered, target)
+		delete(h.notified, target)
+	}
+}
@@ -0,0 +1,158 @@
+package deej
+
+import (
+	"math"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/omriharel/deej/pkg/deej/util"
+)
+
+// noiseCalibrator implements the "auto" noise reduction level. instead of having users guess between
+// low/default/high, it watches each slider while it sits idle for the first few minutes, measures how much
+// its readings jitter, and derives a per-slider threshold from that. the results are persisted to the
+// internal preferences file, so calibration only needs to happen once per device
+type noiseCalibrator struct {
+	logger *zap.SugaredLogger
+	config *CanonicalConfig
+
+	started    time.Time
+	finished   bool
+	numSliders int
+
+	// recent raw readings per slider, used to tell "idle" from "being moved"
+	windows [][]float32
+
+	// the largest idle jitter observed so far, per slider
+	jitter []float64
+
+	thresholds map[int]float64
+}
+
+const (
+	noiseReductionAuto = "auto"
+
+	// how long to keep measuring before settling on (and saving) the thresholds
+	noiseCalibrationDuration = 3 * time.Minute
+
+	// the amount of consecutive readings to consider at a time (about a second's worth)
+	noiseCalibrationWindowSize = 20
+
+	// readings spread wider than this mean the slider is being moved, not jittering
+	noiseCalibrationMaxIdleSpread = 0.06
+
+	// keep auto-tuned thresholds within sane bounds, roughly from "low" to beyond "high"
+	minAutoNoiseThreshold = 0.01
+	maxAutoNoiseThreshold = 0.05
+
+	// thresholds are set a bit above the worst jitter seen, to leave some room
+	autoNoiseThresholdMargin = 0.005
+)
+
+func newNoiseCalibrator(logger *zap.SugaredLogger, config *CanonicalConfig) *noiseCalibrator {
+	return &noiseCalibrator{
+		logger: logger.Named("noise_calibration"),
+		config: config,
+	}
+}
+
+// reset prepares the calibrator for a device with the given amount of sliders, reusing
+// previously saved thresholds if they cover all of them
+func (nc *noiseCalibrator) reset(numSliders int) {
+	nc.numSliders = numSliders
+	nc.started = time.Now()
+	nc.finished = false
+	nc.windows = make([][]float32, numSliders)
+	nc.jitter = make([]float64, numSliders)
+	nc.thresholds = map[int]float64{}
+
+	saved := nc.config.NoiseThresholds
+	if len(saved) == numSliders {
+		complete := true
+
+		for sliderIdx := 0; sliderIdx < numSliders; sliderIdx++ {
+			threshold, ok := saved[sliderIdx]
+			if !ok {
+				complete = false
+				break
+			}
+
+			nc.thresholds[sliderIdx] = threshold
+		}
+
+		if complete {
+			nc.logger.Infow("Using saved noise thresholds", "thresholds", nc.thresholds)
+			nc.finished = true
+			return
+		}
+	}
+
+	nc.logger.Infow("Calibrating noise thresholds, try not to touch your sliders for a bit",
+		"duration", noiseCalibrationDuration,
+		"sliders", numSliders)
+
+	nc.thresholds = map[int]float64{}
+}
+
+// observe feeds a raw (un-normalized) reading into the calibration, if it's still running
+func (nc *noiseCalibrator) observe(sliderIdx int, value float32) {
+	if nc.finished || sliderIdx >= nc.numSliders {
+		return
+	}
+
+	window := append(nc.windows[sliderIdx], value)
+	if len(window) < noiseCalibrationWindowSize {
+		nc.windows[sliderIdx] = window
+		return
+	}
+
+	// a full window - see how far apart its readings are, and start the next one
+	nc.windows[sliderIdx] = nil
+
+	minValue, maxValue := window[0], window[0]
+	for _, v := range window {
+		minValue = float32(math.Min(float64(minValue), float64(v)))
+		maxValue = float32(math.Max(float64(maxValue), float64(v)))
+	}
+
+	spread := float64(maxValue - minValue)
+
+	// the user moved this slider during the window, so it tells us nothing about noise
+	if spread > noiseCalibrationMaxIdleSpread {
+		return
+	}
+
+	if spread > nc.jitter[sliderIdx] {
+		nc.jitter[sliderIdx] = spread
+	}
+
+	// apply the updated threshold right away, no need to wait for the calibration to finish
+	nc.thresholds[sliderIdx] = math.Min(maxAutoNoiseThreshold,
+		math.Max(minAutoNoiseThreshold, nc.jitter[sliderIdx]+autoNoiseThresholdMargin))
+
+	if time.Since(nc.started) >= noiseCalibrationDuration && len(nc.thresholds) == nc.numSliders {
+		nc.finish()
+	}
+}
+
+// threshold returns the difference threshold to use for the given slider
+func (nc *noiseCalibrator) threshold(sliderIdx int) float64 {
+	if threshold, ok := nc.thresholds[sliderIdx]; ok {
+		return threshold
+	}
+
+	// not measured yet, go with the middle ground
+	return util.NoiseReductionThreshold("")
+}
+
+func (nc *noiseCalibrator) finish() {
+	nc.finished = true
+	nc.windows = nil
+
+	nc.logger.Infow("Finished calibrating noise thresholds", "thresholds", nc.thresholds)
+
+	if err := nc.config.SaveNoiseThresholds(nc.thresholds); err != nil {
+		nc.logger.Warnw("Failed to save noise thresholds", "error", err)
+	}
+}
@@ -3,8 +3,8 @@ package deej
 import (
 	"os"
 	"path/filepath"
+	"time"
 
-	"github.com/gen2brain/beeep"
 	"go.uber.org/zap"
 
 	"github.com/omriharel/deej/pkg/deej/icon"
@@ -16,6 +16,24 @@ type Notifier interface {
 	Notify(title string, message string)
 }
 
+// Notification is a notification with its own icon and duration, rather than the usual ones
+type Notification struct {
+	Title   string
+	Message string
+
+	// path to an image file, or empty for deej's own icon
+	Icon string
+
+	// how long it stays up, or 0 to leave that to the OS (which is what windows always does)
+	Duration time.Duration
+}
+
+// DetailedNotifier is a Notifier that can also send Notifications. Notifiers that aren't get just their title and message
+type DetailedNotifier interface {
+	Notifier
+	NotifyWith(notification Notification)
+}
+
 // ToastNotifier provides toast notifications for Windows
 type ToastNotifier struct {
 	logger *zap.SugaredLogger
@@ -33,6 +51,25 @@ func NewToastNotifier(logger *zap.SugaredLogger) (*ToastNotifier, error) {
 
 // Notify sends a toast notification (or falls back to other types of notification for older Windows versions)
 func (tn *ToastNotifier) Notify(title string, message string) {
+	tn.NotifyWith(Notification{Title: title, Message: message})
+}
+
+// NotifyWith sends a toast notification with its own icon and duration
+func (tn *ToastNotifier) NotifyWith(notification Notification) {
+	if notification.Icon == "" {
+		notification.Icon = tn.appIconPath()
+	}
+
+	tn.logger.Infow("Sending toast notification", "title", notification.Title, "message", notification.Message)
+
+	// send the actual notification
+	if err := sendNotification(notification); err != nil {
+		tn.logger.Errorw("Failed to send toast notification", "error", err)
+	}
+}
+
+// appIconPath returns where deej's icon is unpacked for notifications to use
+func (tn *ToastNotifier) appIconPath() string {
 
 	// we need to unpack deej.ico somewhere to remain portable. we already have it as bytes so it should be fine
 	appIconPath := filepath.Join(os.TempDir(), "deej.ico")
@@ -54,10 +91,5 @@ func (tn *ToastNotifier) Notify(title string, message string) {
 		}
 	}
 
-	tn.logger.Infow("Sending toast notification", "title", title, "message", message)
-
-	// send the actual notification
-	if err := beeep.Notify(title, message, appIconPath); err != nil {
-		tn.logger.Errorw("Failed to send toast notification", "error", err)
-	}
+	return appIconPath
 }
@@ -0,0 +1,32 @@
+package deej
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+
+	"github.com/gen2brain/beeep"
+)
+
+// beeep can't tell the notification daemon how long to show a notification for, but notify-send can
+func sendNotification(notification Notification) error {
+	if notification.Duration > 0 {
+		if notifySend, err := exec.LookPath("notify-send"); err == nil {
+			err := exec.Command(notifySend,
+				"-i", notification.Icon,
+				"-t", strconv.FormatInt(notification.Duration.Milliseconds(), 10),
+				notification.Title,
+				notification.Message).Run()
+
+			if err == nil {
+				return nil
+			}
+		}
+	}
+
+	if err := beeep.Notify(notification.Title, notification.Message, notification.Icon); err != nil {
+		return fmt.Errorf("send notification: %w", err)
+	}
+
+	return nil
+}
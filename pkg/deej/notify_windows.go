@@ -0,0 +1,16 @@
+package deej
+
+import (
+	"fmt"
+
+	"github.com/gen2brain/beeep"
+)
+
+// toasts stay up for as long as windows decides, so there's nothing to do with the duration
+func sendNotification(notification Notification) error {
+	if err := beeep.Notify(notification.Title, notification.Message, notification.Icon); err != nil {
+		return fmt.Errorf("send notification: %w", err)
+	}
+
+	return nil
+}
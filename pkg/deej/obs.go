@@ -0,0 +1,81 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// buttons can control OBS through obs-websocket (built into OBS 28 and up): obs_scene:<scene> switches scenes,
+// obs_mute:<source>, obs_unmute:<source> and obs_mute_toggle:<source> mute and unmute an audio source, and
+// obs_record_toggle and obs_stream_toggle start or stop recording and streaming. deej connects once
+// obs_websocket_url is set (along with obs_websocket_password, if OBS asks for one), as the "obs" integration -
+// so it keeps reconnecting while OBS isn't running, and shows how it's doing like any other integration
+const (
+	actionOBSScenePrefix      = "obs_scene:"
+	actionOBSMutePrefix       = "obs_mute:"
+	actionOBSUnmutePrefix     = "obs_unmute:"
+	actionOBSMuteTogglePrefix = "obs_mute_toggle:"
+	actionOBSRecordToggle     = "obs_record_toggle"
+	actionOBSStreamToggle     = "obs_stream_toggle"
+
+	obsIntegrationName = "obs"
+)
+
+var errMissingOBSName = errors.New("missing scene or source name")
+
+// obsRequest is a request for obs-websocket, along with its data
+type obsRequest struct {
+	requestType string
+	data        map[string]interface{}
+}
+
+// parseOBSAction resolves an OBS action into the request it sends. scene and source names are matched by OBS
+// as they are, so they're left exactly as written
+func parseOBSAction(entry string) (obsRequest, error) {
+	switch entry {
+	case actionOBSRecordToggle:
+		return obsRequest{requestType: "ToggleRecord"}, nil
+	case actionOBSStreamToggle:
+		return obsRequest{requestType: "ToggleStream"}, nil
+	}
+
+	separatorIdx := strings.Index(entry, ":")
+	if separatorIdx == -1 {
+		return obsRequest{}, errUnknownButtonAction
+	}
+
+	prefix, name := entry[:separatorIdx+1], strings.TrimSpace(entry[separatorIdx+1:])
+	if name == "" {
+		return obsRequest{}, errMissingOBSName
+	}
+
+	switch prefix {
+	case actionOBSScenePrefix:
+		return obsRequest{requestType: "SetCurrentProgramScene", data: map[string]interface{}{"sceneName": name}}, nil
+	case actionOBSMutePrefix:
+		return obsRequest{requestType: "SetInputMute", data: map[string]interface{}{"inputName": name, "inputMuted": true}}, nil
+	case actionOBSUnmutePrefix:
+		return obsRequest{requestType: "SetInputMute", data: map[string]interface{}{"inputName": name, "inputMuted": false}}, nil
+	case actionOBSMuteTogglePrefix:
+		return obsRequest{requestType: "ToggleInputMute", data: map[string]interface{}{"inputName": name}}, nil
+	}
+
+	return obsRequest{}, errUnknownButtonAction
+}
+
+// controlOBS sends an OBS action's request in the background, so a slow OBS doesn't hold up the buttons
+func (sio *SerialIO) controlOBS(logger *zap.SugaredLogger, notifier Notifier, action buttonAction) {
+	go func() {
+		if err := sio.deej.obs.call(action.obs); err != nil {
+			logger.Warnw("Failed to control OBS", "action", action.name, "error", err)
+			notifier.Notify("Can't control OBS!", fmt.Sprintf("%s didn't go through: %v", action.name, err))
+
+			return
+		}
+
+		logger.Infow("Controlled OBS", "action", action.name)
+	}()
+}
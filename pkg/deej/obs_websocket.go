@@ -0,0 +1,284 @@
+//go:build !slim
+// +build !slim
+
+package deej
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// obsClient talks to OBS over obs-websocket (protocol version 5), and is the "obs" integration. it identifies
+// itself without subscribing to any events, since all deej does is send requests and wait for their answers
+const (
+	obsRPCVersion = 1
+
+	obsOpHello           = 0
+	obsOpIdentify        = 1
+	obsOpIdentified      = 2
+	obsOpRequest         = 6
+	obsOpRequestResponse = 7
+
+	// the close code OBS uses when the password is wrong
+	obsCloseAuthenticationFailed = 4009
+
+	obsConnectTimeout = 5 * time.Second
+	obsRequestTimeout = 5 * time.Second
+)
+
+var (
+	errOBSNotConnected      = errors.New("not connected to OBS")
+	errOBSPasswordNeeded    = errors.New("OBS asks for a password, but obs_websocket_password isn't set")
+	errOBSPasswordIncorrect = errors.New("OBS didn't accept obs_websocket_password")
+)
+
+type obsMessage struct {
+	Op   int             `json:"op"`
+	Data json.RawMessage `json:"d"`
+}
+
+type obsHello struct {
+	Authentication *struct {
+		Challenge string `json:"challenge"`
+		Salt      string `json:"salt"`
+	} `json:"authentication"`
+}
+
+type obsResponse struct {
+	RequestID     string `json:"requestId"`
+	RequestStatus struct {
+		Result  bool   `json:"result"`
+		Code    int    `json:"code"`
+		Comment string `json:"comment"`
+	} `json:"requestStatus"`
+}
+
+type obsClient struct {
+	deej   *Deej
+	logger *zap.SugaredLogger
+
+	lock sync.Locker
+
+	// the current connection, or nil while there's none
+	conn *websocket.Conn
+
+	// requests waiting for an answer, by ID
+	pending       map[string]chan obsResponse
+	lastRequestID int
+}
+
+func newOBSClient(deej *Deej, logger *zap.SugaredLogger) *obsClient {
+	return &obsClient{
+		deej:    deej,
+		logger:  logger.Named(obsIntegrationName),
+		lock:    &sync.Mutex{},
+		pending: map[string]chan obsResponse{},
+	}
+}
+
+// settings implements configuredIntegration
+func (c *obsClient) settings() (string, bool) {
+	url, password := c.deej.config.OBSWebSocketURL, c.deej.config.OBSWebSocketPassword
+
+	return url + "\n" + password, url != ""
+}
+
+// Run implements Integration
+func (c *obsClient) Run(ctx context.Context, connected func()) error {
+	url, password := c.deej.config.OBSWebSocketURL, c.deej.config.OBSWebSocketPassword
+
+	dialer := websocket.Dialer{HandshakeTimeout: obsConnectTimeout}
+
+	conn, _, err := dialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return fmt.Errorf("connect to OBS: %w", err)
+	}
+
+	defer conn.Close()
+
+	// reading blocks until the connection goes away, so take it away once we're done
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	if err := c.identify(conn, password); err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	c.conn = conn
+	c.lock.Unlock()
+
+	defer c.disconnect()
+
+	connected()
+
+	for {
+		var message obsMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			return fmt.Errorf("read from OBS: %w", err)
+		}
+
+		if message.Op != obsOpRequestResponse {
+			continue
+		}
+
+		var response obsResponse
+		if err := json.Unmarshal(message.Data, &response); err != nil {
+			c.logger.Debugw("Failed to parse OBS response", "error", err)
+			continue
+		}
+
+		c.lock.Lock()
+		responses, ok := c.pending[response.RequestID]
+		delete(c.pending, response.RequestID)
+		c.lock.Unlock()
+
+		if ok {
+			responses <- response
+		}
+	}
+}
+
+// identify goes through obs-websocket's handshake: OBS says hello (asking for a password if it has one set),
+// and deej identifies itself
+func (c *obsClient) identify(conn *websocket.Conn, password string) error {
+	conn.SetReadDeadline(time.Now().Add(obsConnectTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	var message obsMessage
+	if err := conn.ReadJSON(&message); err != nil {
+		return fmt.Errorf("wait for OBS to say hello: %w", err)
+	}
+
+	if message.Op != obsOpHello {
+		return fmt.Errorf("unexpected message from OBS instead of hello (op %d)", message.Op)
+	}
+
+	var hello obsHello
+	if err := json.Unmarshal(message.Data, &hello); err != nil {
+		return fmt.Errorf("parse OBS hello: %w", err)
+	}
+
+	identify := map[string]interface{}{
+		"rpcVersion":         obsRPCVersion,
+		"eventSubscriptions": 0,
+	}
+
+	if hello.Authentication != nil {
+		if password == "" {
+			return errOBSPasswordNeeded
+		}
+
+		identify["authentication"] = obsAuthentication(password, hello.Authentication.Salt, hello.Authentication.Challenge)
+	}
+
+	if err := conn.WriteJSON(map[string]interface{}{"op": obsOpIdentify, "d": identify}); err != nil {
+		return fmt.Errorf("identify to OBS: %w", err)
+	}
+
+	if err := conn.ReadJSON(&message); err != nil {
+		if websocket.IsCloseError(err, obsCloseAuthenticationFailed) {
+			return errOBSPasswordIncorrect
+		}
+
+		return fmt.Errorf("wait for OBS to identify us: %w", err)
+	}
+
+	if message.Op != obsOpIdentified {
+		return fmt.Errorf("unexpected message from OBS while identifying (op %d)", message.Op)
+	}
+
+	c.logger.Debug("Identified to OBS")
+
+	return nil
+}
+
+// obsAuthentication answers OBS' challenge, proving we know its password without sending it
+func obsAuthentication(password string, salt string, challenge string) string {
+	secret := sha256.Sum256([]byte(password + salt))
+	answer := sha256.Sum256([]byte(base64.StdEncoding.EncodeToString(secret[:]) + challenge))
+
+	return base64.StdEncoding.EncodeToString(answer[:])
+}
+
+// disconnect forgets the current connection, failing every request that's still waiting on it
+func (c *obsClient) disconnect() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.conn = nil
+
+	for requestID, responses := range c.pending {
+		close(responses)
+		delete(c.pending, requestID)
+	}
+}
+
+// call sends a request to OBS, and waits for it to go through
+func (c *obsClient) call(request obsRequest) error {
+	c.lock.Lock()
+
+	if c.conn == nil {
+		c.lock.Unlock()
+		return errOBSNotConnected
+	}
+
+	c.lastRequestID++
+	requestID := strconv.Itoa(c.lastRequestID)
+
+	responses := make(chan obsResponse, 1)
+	c.pending[requestID] = responses
+
+	data := map[string]interface{}{
+		"requestType": request.requestType,
+		"requestId":   requestID,
+	}
+
+	if request.data != nil {
+		data["requestData"] = request.data
+	}
+
+	err := c.conn.WriteJSON(map[string]interface{}{"op": obsOpRequest, "d": data})
+	if err != nil {
+		delete(c.pending, requestID)
+	}
+
+	c.lock.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("send request to OBS: %w", err)
+	}
+
+	select {
+	case response, ok := <-responses:
+		if !ok {
+			return errOBSNotConnected
+		}
+
+		if !response.RequestStatus.Result {
+			return fmt.Errorf("OBS refused %s: %s (code %d)",
+				request.requestType, response.RequestStatus.Comment, response.RequestStatus.Code)
+		}
+
+		return nil
+
+	case <-time.After(obsRequestTimeout):
+		c.lock.Lock()
+		delete(c.pending, requestID)
+		c.lock.Unlock()
+
+		return fmt.Errorf("OBS didn't answer %s in time", request.requestType)
+	}
+}
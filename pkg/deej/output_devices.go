@@ -0,0 +1,159 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// the cycleout button action switches the default output device to the next one in a list of the user's
+// choosing, i.e. "cycleout:list=[Speakers,Headphones,HDMI]". devices are matched by (part of) their name,
+// ignoring case, and ones that aren't connected right now are skipped. the newly selected device is announced
+// with a notification, and on the device's display when display feedback is enabled.
+// "cycle_outputs:[Speakers,Headphones]" does the same, and "switch_output:Headphones" always picks that one device
+const (
+	actionCycleOutputPrefix  = "cycleout:"
+	actionCycleOutputsPrefix = "cycle_outputs:"
+	actionSwitchOutputPrefix = "switch_output:"
+	cycleOutputListPrefix    = "list="
+
+	outputDeviceDisplayFormat = "OUT:%s"
+)
+
+var errOutputSwitchingUnsupported = errors.New("switching output devices isn't supported here")
+
+// parseCycleOutputList reads the device names out of a cycleout action's argument
+func parseCycleOutputList(argument string) ([]string, error) {
+	if !strings.HasPrefix(argument, cycleOutputListPrefix) {
+		return nil, fmt.Errorf("expected %q, got %q", cycleOutputListPrefix+"[...]", argument)
+	}
+
+	list := strings.TrimPrefix(argument, cycleOutputListPrefix)
+	if !strings.HasPrefix(list, "[") || !strings.HasSuffix(list, "]") {
+		return nil, fmt.Errorf("device list must be in square brackets: %q", list)
+	}
+
+	names := []string{}
+
+	for _, name := range strings.Split(strings.Trim(list, "[]"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, errors.New("device list is empty")
+	}
+
+	return names, nil
+}
+
+// parseSwitchOutputAction reads the device name out of a switch_output action's argument
+func parseSwitchOutputAction(argument string) ([]string, error) {
+	name := strings.TrimSpace(argument)
+	if name == "" {
+		return nil, errors.New("no device given")
+	}
+
+	return []string{name}, nil
+}
+
+// matchOutputDevice finds the device a user-given name refers to, preferring an exact match
+func matchOutputDevice(devices []OutputDevice, name string) (OutputDevice, bool) {
+	name = strings.ToLower(name)
+
+	for _, device := range devices {
+		if strings.ToLower(device.Name) == name {
+			return device, true
+		}
+	}
+
+	for _, device := range devices {
+		if strings.Contains(strings.ToLower(device.Name), name) {
+			return device, true
+		}
+	}
+
+	return OutputDevice{}, false
+}
+
+// cycleOutputDevice makes the device after the current default one (out of the given names) the new default,
+// and returns it. if the current default isn't one of them, the first connected one is picked
+func (m *sessionMap) cycleOutputDevice(names []string) (OutputDevice, error) {
+	switcher, ok := m.sessionFinder.(outputDeviceSwitcher)
+	if !ok {
+		return OutputDevice{}, errOutputSwitchingUnsupported
+	}
+
+	devices, err := switcher.OutputDevices()
+	if err != nil {
+		return OutputDevice{}, fmt.Errorf("list output devices: %w", err)
+	}
+
+	currentID, err := switcher.DefaultOutputDevice()
+	if err != nil {
+		return OutputDevice{}, fmt.Errorf("get default output device: %w", err)
+	}
+
+	candidates := []OutputDevice{}
+	seen := map[string]bool{}
+
+	for _, name := range names {
+		device, ok := matchOutputDevice(devices, name)
+		if !ok {
+			m.logger.Debugw("Listed output device isn't connected, skipping it", "name", name)
+			continue
+		}
+
+		if !seen[device.ID] {
+			seen[device.ID] = true
+			candidates = append(candidates, device)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return OutputDevice{}, errors.New("none of the listed output devices are connected")
+	}
+
+	next := candidates[0]
+
+	for idx, candidate := range candidates {
+		if candidate.ID == currentID {
+			next = candidates[(idx+1)%len(candidates)]
+			break
+		}
+	}
+
+	if next.ID == currentID {
+		return next, nil
+	}
+
+	if err := switcher.SetDefaultOutputDevice(next.ID); err != nil {
+		return OutputDevice{}, fmt.Errorf("set default output device: %w", err)
+	}
+
+	// the master session now belongs to a different device
+	m.refreshSessions(true)
+
+	return next, nil
+}
+
+func (sio *SerialIO) cycleOutputDevice(logger *zap.SugaredLogger, notifier Notifier, names []string) {
+	device, err := sio.deej.sessions.cycleOutputDevice(names)
+	if err != nil {
+		logger.Warnw("Failed to cycle output device", "devices", names, "error", err)
+		return
+	}
+
+	logger.Infow("Switched output device", "device", device.Name)
+
+	notifier.Notify("Output device changed", device.Name)
+
+	if sio.deej.config.DisplayFeedback {
+		if err := sio.SendCommand(fmt.Sprintf(outputDeviceDisplayFormat, device.Name)); err != nil {
+			logger.Debugw("Failed to show output device on display", "error", err)
+		}
+	}
+}
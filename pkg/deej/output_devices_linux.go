@@ -0,0 +1,54 @@
+package deej
+
+import (
+	"fmt"
+
+	"github.com/jfreymuth/pulse/proto"
+)
+
+func (sf *paSessionFinder) OutputDevices() ([]OutputDevice, error) {
+	request := proto.GetSinkInfoList{}
+	reply := proto.GetSinkInfoListReply{}
+
+	if err := sf.client.Request(&request, &reply); err != nil {
+		return nil, fmt.Errorf("get sink list: %w", err)
+	}
+
+	devices := []OutputDevice{}
+
+	for _, info := range reply {
+
+		// sinks are identified by their name, but shown to users by their description
+		name := info.SinkName
+		if description, ok := info.Properties["device.description"]; ok {
+			name = description.String()
+		}
+
+		devices = append(devices, OutputDevice{ID: info.SinkName, Name: name})
+	}
+
+	return devices, nil
+}
+
+func (sf *paSessionFinder) DefaultOutputDevice() (string, error) {
+	request := proto.GetServerInfo{}
+	reply := proto.GetServerInfoReply{}
+
+	if err := sf.client.Request(&request, &reply); err != nil {
+		return "", fmt.Errorf("get server info: %w", err)
+	}
+
+	return reply.DefaultSinkName, nil
+}
+
+func (sf *paSessionFinder) SetDefaultOutputDevice(id string) error {
+	request := proto.SetDefaultSink{SinkName: id}
+
+	if err := sf.client.Request(&request, nil); err != nil {
+		return fmt.Errorf("set default sink: %w", err)
+	}
+
+	sf.logger.Debugw("Changed default output device", "id", id)
+
+	return nil
+}
@@ -0,0 +1,165 @@
+package deej
+
+import (
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	ole "github.com/go-ole/go-ole"
+	wca "github.com/moutend/go-wca"
+)
+
+// windows has no public API for changing the default audio device. the (undocumented) IPolicyConfig
+// interface is what the sound control panel itself uses, and has been stable since vista
+var (
+	clsidPolicyConfig = ole.NewGUID("{870af99c-171d-4f9e-af0d-e63df40c2bc9}")
+	iidPolicyConfig   = ole.NewGUID("{f8679f50-850a-41cf-9c72-430f290290c8}")
+)
+
+type policyConfigVtbl struct {
+	ole.IUnknownVtbl
+	GetMixFormat          uintptr
+	GetDeviceFormat       uintptr
+	ResetDeviceFormat     uintptr
+	SetDeviceFormat       uintptr
+	GetProcessingPeriod   uintptr
+	SetProcessingPeriod   uintptr
+	GetShareMode          uintptr
+	SetShareMode          uintptr
+	GetPropertyValue      uintptr
+	SetPropertyValue      uintptr
+	SetDefaultEndpoint    uintptr
+	SetEndpointVisibility uintptr
+}
+
+// the default device is set separately for each of these roles, users expect all of them to follow
+var defaultDeviceRoles = []uint32{wca.EConsole, wca.EMultimedia, wca.ECommunications}
+
+func (sf *wcaSessionFinder) OutputDevices() ([]OutputDevice, error) {
+	if err := sf.coInitialize(); err != nil {
+		return nil, err
+	}
+	defer ole.CoUninitialize()
+
+	if err := sf.getDeviceEnumerator(); err != nil {
+		return nil, fmt.Errorf("get device enumerator: %w", err)
+	}
+
+	var deviceCollection *wca.IMMDeviceCollection
+
+	if err := sf.mmDeviceEnumerator.EnumAudioEndpoints(wca.ERender, wca.DEVICE_STATE_ACTIVE, &deviceCollection); err != nil {
+		return nil, fmt.Errorf("enumerate active output endpoints: %w", err)
+	}
+	defer deviceCollection.Release()
+
+	var deviceCount uint32
+
+	if err := deviceCollection.GetCount(&deviceCount); err != nil {
+		return nil, fmt.Errorf("get device count from device collection: %w", err)
+	}
+
+	devices := []OutputDevice{}
+
+	for deviceIdx := uint32(0); deviceIdx < deviceCount; deviceIdx++ {
+		var endpoint *wca.IMMDevice
+
+		if err := deviceCollection.Item(deviceIdx, &endpoint); err != nil {
+			return nil, fmt.Errorf("get device %d from device collection: %w", deviceIdx, err)
+		}
+
+		device, err := sf.outputDevice(endpoint)
+		endpoint.Release()
+
+		if err != nil {
+			return nil, fmt.Errorf("describe device %d: %w", deviceIdx, err)
+		}
+
+		devices = append(devices, device)
+	}
+
+	return devices, nil
+}
+
+func (sf *wcaSessionFinder) outputDevice(endpoint *wca.IMMDevice) (OutputDevice, error) {
+	var id string
+
+	if err := endpoint.GetId(&id); err != nil {
+		return OutputDevice{}, fmt.Errorf("get device id: %w", err)
+	}
+
+	var propertyStore *wca.IPropertyStore
+
+	if err := endpoint.OpenPropertyStore(wca.STGM_READ, &propertyStore); err != nil {
+		return OutputDevice{}, fmt.Errorf("open property store: %w", err)
+	}
+	defer propertyStore.Release()
+
+	value := &wca.PROPVARIANT{}
+
+	if err := propertyStore.GetValue(&wca.PKEY_Device_FriendlyName, value); err != nil {
+		return OutputDevice{}, fmt.Errorf("get friendly name: %w", err)
+	}
+
+	return OutputDevice{ID: id, Name: value.String()}, nil
+}
+
+func (sf *wcaSessionFinder) DefaultOutputDevice() (string, error) {
+	if err := sf.coInitialize(); err != nil {
+		return "", err
+	}
+	defer ole.CoUninitialize()
+
+	if err := sf.getDeviceEnumerator(); err != nil {
+		return "", fmt.Errorf("get device enumerator: %w", err)
+	}
+
+	var endpoint *wca.IMMDevice
+
+	if err := sf.mmDeviceEnumerator.GetDefaultAudioEndpoint(wca.ERender, wca.EConsole, &endpoint); err != nil {
+		return "", fmt.Errorf("call GetDefaultAudioEndpoint (out): %w", err)
+	}
+	defer endpoint.Release()
+
+	var id string
+
+	if err := endpoint.GetId(&id); err != nil {
+		return "", fmt.Errorf("get device id: %w", err)
+	}
+
+	return id, nil
+}
+
+func (sf *wcaSessionFinder) SetDefaultOutputDevice(id string) error {
+	if err := sf.coInitialize(); err != nil {
+		return err
+	}
+	defer ole.CoUninitialize()
+
+	policyConfig, err := ole.CreateInstance(clsidPolicyConfig, iidPolicyConfig)
+	if err != nil {
+		return fmt.Errorf("create policy config instance: %w", err)
+	}
+	defer policyConfig.Release()
+
+	deviceID, err := syscall.UTF16PtrFromString(id)
+	if err != nil {
+		return fmt.Errorf("convert device id: %w", err)
+	}
+
+	vtable := (*policyConfigVtbl)(unsafe.Pointer(policyConfig.RawVTable))
+
+	for _, role := range defaultDeviceRoles {
+		hr, _, _ := syscall.Syscall(vtable.SetDefaultEndpoint, 3,
+			uintptr(unsafe.Pointer(policyConfig)),
+			uintptr(unsafe.Pointer(deviceID)),
+			uintptr(role))
+
+		if hr != 0 {
+			return fmt.Errorf("set default endpoint (role %d): %w", role, ole.NewError(hr))
+		}
+	}
+
+	sf.logger.Debugw("Changed default output device", "id", id)
+
+	return nil
+}
@@ -39,19 +39,9 @@ func (d *Deej) recoverFromPanic() {
 	}
 
 	// if we got here, we're recovering from a panic!
-	now := time.Now()
-
-	// that would suck
-	if err := util.EnsureDirExists(logDirectory); err != nil {
-		panic(fmt.Errorf("ensure crashlog dir exists: %w", err))
-	}
-
-	crashlogBytes := bytes.NewBufferString(fmt.Sprintf(crashMessage, now.Format(crashlogTimestampFormat), r, debug.Stack()))
-	crashlogPath := filepath.Join(logDirectory, fmt.Sprintf(crashlogFilename, now.Format(crashlogTimestampFormat)))
-
-	// that would REALLY suck
-	if err := ioutil.WriteFile(crashlogPath, crashlogBytes.Bytes(), os.ModePerm); err != nil {
-		panic(fmt.Errorf("can't even write the crashlog file contents: %w", err))
+	crashlogPath, err := writeCrashlog(r)
+	if err != nil {
+		panic(err)
 	}
 
 	d.logger.Errorw("Encountered and logged panic, crashing",
@@ -66,3 +56,23 @@ func (d *Deej) recoverFromPanic() {
 	d.logger.Errorw("Quitting", "exitCode", 1)
 	os.Exit(1)
 }
+
+// writeCrashlog saves the details of a panic (including the current stack trace) to a new crashlog file
+func writeCrashlog(r interface{}) (string, error) {
+	now := time.Now()
+
+	// that would suck
+	if err := util.EnsureDirExists(logDirectory); err != nil {
+		return "", fmt.Errorf("ensure crashlog dir exists: %w", err)
+	}
+
+	crashlogBytes := bytes.NewBufferString(fmt.Sprintf(crashMessage, now.Format(crashlogTimestampFormat), r, debug.Stack()))
+	crashlogPath := filepath.Join(logDirectory, fmt.Sprintf(crashlogFilename, now.Format(crashlogTimestampFormat)))
+
+	// that would REALLY suck
+	if err := ioutil.WriteFile(crashlogPath, crashlogBytes.Bytes(), os.ModePerm); err != nil {
+		return "", fmt.Errorf("can't even write the crashlog file contents: %w", err)
+	}
+
+	return crashlogPath, nil
+}
@@ -0,0 +1,123 @@
+package deej
+
+import (
+	"sync"
+)
+
+// deej:panic is meant for a big red button: it silences every app (and the default output) right away, and drops
+// anything buttons were about to do. until deej:restore is pressed, nothing deej does changes any volume, so a bumped
+// slider can't bring the noise back. deej:restore puts every volume back where it was before the panic
+const (
+	deejActionPanic   = "deej:panic"
+	deejActionRestore = "deej:restore"
+)
+
+// panicState remembers the volumes from before a panic, by session key. nil while not panicked
+type panicState struct {
+	volumes map[string]float32
+	lock    sync.Locker
+}
+
+func newPanicState() *panicState {
+	return &panicState{lock: &sync.Mutex{}}
+}
+
+func (p *panicState) active() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.volumes != nil
+}
+
+// panicMute silences every output session, and keeps deej from changing any volume until restoreFromPanic
+func (m *sessionMap) panicMute() {
+	m.panic.lock.Lock()
+	defer m.panic.lock.Unlock()
+
+	if m.panic.volumes != nil {
+		m.logger.Debug("Already panicked")
+		return
+	}
+
+	// from here on the applier takes no new writes, forget whatever it had waiting too
+	m.panic.volumes = map[string]float32{}
+	m.applier.discard()
+
+	muted := 0
+
+	for _, session := range m.snapshot() {
+
+		// devices other than the default output are either inputs, or are silent once their apps are
+		if _, ok := session.(*masterSession); ok && session.Key() != masterSessionName {
+			continue
+		}
+
+		if _, ok := m.panic.volumes[session.Key()]; !ok {
+			m.panic.volumes[session.Key()] = session.GetVolume()
+		}
+
+		// this can't wait for the applier
+		if err := session.SetVolume(0); err != nil {
+			m.logger.Warnw("Failed to mute session", "session", session.Key(), "error", err)
+			continue
+		}
+
+		muted++
+	}
+
+	m.logger.Infow("Panic! Muted everything", "sessions", muted)
+}
+
+// restoreFromPanic puts every volume back where it was before the panic
+func (m *sessionMap) restoreFromPanic() {
+	m.panic.lock.Lock()
+	volumes := m.panic.volumes
+	m.panic.volumes = nil
+	m.panic.lock.Unlock()
+
+	if volumes == nil {
+		m.logger.Debug("Not panicked, nothing to restore")
+		return
+	}
+
+	for key, volume := range volumes {
+		sessions, ok := m.find(key)
+		if !ok {
+			continue
+		}
+
+		for _, session := range sessions {
+			m.applier.set(session, volume)
+		}
+	}
+
+	m.logger.Infow("Restored volumes from before the panic", "sessions", len(volumes))
+}
+
+func (sio *SerialIO) panicButton(notifier Notifier) {
+	sio.deej.sessions.panicMute()
+
+	// running steps and macros stop right away, before they get to press anything else
+	sio.steps.reset()
+
+	// this may run from one of the very timers being stopped, which hold their locks meanwhile
+	go sio.cancelPendingButtonActions()
+
+	notifier.Notify("Panic!", "Everything is muted. Press the restore button to bring it back.")
+	sio.deej.refreshTray()
+}
+
+func (sio *SerialIO) restoreButton() {
+	sio.deej.sessions.restoreFromPanic()
+	sio.deej.refreshTray()
+}
+
+// cancelPendingButtonActions drops every button action waiting to fire: repeats, long presses, presses held back
+// to tell taps and chords apart, and whatever comes after a delay step
+func (sio *SerialIO) cancelPendingButtonActions() {
+	sio.holds.reset()
+	sio.taps.reset()
+	sio.repeats.reset()
+	sio.chords.reset()
+	sio.steps.reset()
+}
@@ -0,0 +1,60 @@
+package deej
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// deej_pause_toggle takes deej out of the way for a while, i.e. to set volumes from the OS mixer without a noisy
+// slider fighting back: until it's pressed again, sliders are ignored and deej doesn't change any volume. unlike
+// deej:panic nothing is muted, and nothing is put back once deej resumes - sliders take over again as they move
+const deejActionPauseToggle = "deej_pause_toggle"
+
+type pauseState struct {
+	paused bool
+	lock   sync.Locker
+}
+
+func newPauseState() *pauseState {
+	return &pauseState{lock: &sync.Mutex{}}
+}
+
+func (p *pauseState) active() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.paused
+}
+
+// toggle pauses or resumes, and returns whether it's now paused
+func (p *pauseState) toggle() bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.paused = !p.paused
+
+	return p.paused
+}
+
+// togglePause pauses deej, or resumes it if it already is. volume writes still waiting are dropped when pausing
+func (m *sessionMap) togglePause() bool {
+	paused := m.pause.toggle()
+	if paused {
+		m.applier.discard()
+	}
+
+	return paused
+}
+
+func (sio *SerialIO) pauseButton(logger *zap.SugaredLogger, notifier Notifier) {
+	if sio.deej.sessions.togglePause() {
+		logger.Info("Paused, ignoring sliders")
+		notifier.Notify("deej paused", "Sliders are ignored until you press the pause button again.")
+	} else {
+		logger.Info("Resumed, sliders are back")
+		notifier.Notify("deej resumed", "Sliders control volumes again once they move.")
+	}
+
+	sio.deej.refreshTray()
+}
@@ -0,0 +1,145 @@
+package deej
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// with ping_interval set, deej periodically sends "PING" to the device. firmware that supports it answers
+// with "PONG", which tells an idle device (silent, but answering) apart from a hung one. once a device has
+// answered at least once, missing several pongs in a row counts as the serial module hanging, so the
+// supervisor renews the connection. firmware that never answers is left alone
+const (
+	pingCommand = "PING"
+
+	maxMissedPongs = 3
+)
+
+var errDeviceNotAnswering = errors.New("device stopped answering pings")
+
+// pingTracker keeps track of pings sent to the current connection's device, and its answers
+type pingTracker struct {
+	lock sync.Locker
+
+	// whether the device ever answered a ping
+	supported bool
+
+	lastSent     time.Time
+	awaitingPong bool
+
+	missedPongs   int
+	lastRoundTrip time.Duration
+}
+
+// PingStats describes how the device has been answering pings
+type PingStats struct {
+	Supported     bool
+	MissedPongs   int
+	LastRoundTrip time.Duration
+}
+
+func newPingTracker() *pingTracker {
+	return &pingTracker{
+		lock: &sync.Mutex{},
+	}
+}
+
+// reset forgets everything about the previous connection's device
+func (t *pingTracker) reset() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.supported = false
+	t.awaitingPong = false
+	t.missedPongs = 0
+	t.lastRoundTrip = 0
+}
+
+// sent records a ping going out, and returns how many pings in a row went unanswered before it
+func (t *pingTracker) sent() int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.awaitingPong {
+		t.missedPongs++
+	}
+
+	t.awaitingPong = true
+	t.lastSent = time.Now()
+
+	return t.missedPongs
+}
+
+func (t *pingTracker) received() time.Duration {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.supported = true
+	t.awaitingPong = false
+	t.missedPongs = 0
+	t.lastRoundTrip = time.Since(t.lastSent)
+
+	return t.lastRoundTrip
+}
+
+func (t *pingTracker) stats() PingStats {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return PingStats{
+		Supported:     t.supported,
+		MissedPongs:   t.missedPongs,
+		LastRoundTrip: t.lastRoundTrip,
+	}
+}
+
+// PingStats returns how the connected device has been answering pings
+func (sio *SerialIO) PingStats() PingStats {
+	return sio.ping.stats()
+}
+
+func (sio *SerialIO) runPing(ctx context.Context, logger *zap.SugaredLogger) {
+	interval := sio.deej.config.PingInterval
+	if interval <= 0 {
+		return
+	}
+
+	logger = logger.Named("ping")
+	logger.Debugw("Starting device pings", "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Debug("Stopping device pings")
+			return
+
+		case <-ticker.C:
+			missed := sio.ping.sent()
+
+			if missed >= maxMissedPongs && sio.ping.stats().Supported {
+				logger.Warnw("Device stopped answering pings", "missedPongs", missed)
+				sio.deej.supervisor.reportFailure(moduleSerial, errDeviceNotAnswering)
+				return
+			}
+
+			if err := sio.SendCommand(pingCommand); err != nil {
+				logger.Debugw("Failed to send ping", "error", err)
+			}
+		}
+	}
+}
+
+func (sio *SerialIO) handlePong(logger *zap.SugaredLogger) {
+	roundTrip := sio.ping.received()
+
+	if sio.deej.Verbose() {
+		logger.Debugw("Device answered ping", "roundTrip", roundTrip)
+	}
+}
@@ -0,0 +1,131 @@
+package deej
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// profile feedback keeps boards with per-button displays or LEDs in sync with whatever profile or bank is active.
+// the board is told which one it is (along with the profile's color), and gets a label for each button: the one set
+// under button_labels, or its actions if there's none. like display feedback, lines are only re-sent when they change,
+// so switching profiles sends whatever's different, and everything is sent again when the board reconnects
+const (
+	profileFeedbackInterval = 500 * time.Millisecond
+
+	// the active profile, its bank offset and its color
+	profileFeedbackProfileFormat = "PRF:%s:%d:%s"
+
+	// a button's index and label
+	profileFeedbackButtonFormat = "BTN:%d:%s"
+
+	// key for the profile line among the button lines sent
+	profileFeedbackProfileKey = -1
+)
+
+func (sio *SerialIO) runProfileFeedback(ctx context.Context, logger *zap.SugaredLogger) {
+	logger = logger.Named("profile_feedback")
+	logger.Debug("Starting profile feedback")
+
+	// the last line sent for each button, and for the profile
+	lastSentLines := map[int]string{}
+
+	ticker := time.NewTicker(profileFeedbackInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Debug("Stopping profile feedback")
+			return
+
+		case <-ticker.C:
+			if !sio.deej.config.ProfileFeedback {
+				continue
+			}
+
+			lines := sio.buildProfileFeedbackLines()
+
+			// the profile goes first, so boards know what the button labels that follow belong to
+			keys := []int{}
+			for key := range lines {
+				keys = append(keys, key)
+			}
+
+			sort.Ints(keys)
+
+			for _, key := range keys {
+				line := lines[key]
+				if line == lastSentLines[key] {
+					continue
+				}
+
+				if err := sio.SendCommand(line); err != nil {
+					logger.Debugw("Failed to send profile feedback", "error", err)
+					break
+				}
+
+				if sio.deej.Verbose() {
+					logger.Debugw("Sent profile feedback", "line", line)
+				}
+
+				lastSentLines[key] = line
+			}
+		}
+	}
+}
+
+// buildProfileFeedbackLines returns the line for the active profile, and one for each button the board has
+// or that's mapped. buttons without anything to do get an empty label, to clear whatever the last profile left
+func (sio *SerialIO) buildProfileFeedbackLines() map[int]string {
+	config := sio.deej.config
+	profileName, bankOffset := config.ActiveProfile()
+
+	lines := map[int]string{
+		profileFeedbackProfileKey: fmt.Sprintf(profileFeedbackProfileFormat, profileName, bankOffset, config.ProfileColor),
+	}
+
+	numButtons := sio.lastKnownNumButtons
+	if highest := config.ButtonMapping.highestIndex(); highest >= numButtons {
+		numButtons = highest + 1
+	}
+
+	for buttonIdx := range config.ButtonLabels {
+		if buttonIdx >= numButtons {
+			numButtons = buttonIdx + 1
+		}
+	}
+
+	for buttonIdx := 0; buttonIdx < numButtons; buttonIdx++ {
+		label, ok := config.ButtonLabels[buttonIdx]
+		if !ok {
+			actions, _ := config.ButtonMapping.get(buttonIdx)
+			label = strings.Join(buttonActionNames(actions), ",")
+		}
+
+		lines[buttonIdx] = fmt.Sprintf(profileFeedbackButtonFormat, buttonIdx+config.MappingIndexBase, label)
+	}
+
+	return lines
+}
+
+// buttonLabelsFromConfig reads button_labels, which users may count from 1 like the mappings
+func buttonLabelsFromConfig(userLabels map[string]string, indexBase int) map[int]string {
+	labels := map[int]string{}
+
+	for buttonIdxString, label := range userLabels {
+		buttonIdx, err := strconv.Atoi(buttonIdxString)
+		if err != nil {
+			continue
+		}
+
+		labels[buttonIdx-indexBase] = label
+	}
+
+	return labels
+}
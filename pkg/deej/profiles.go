@@ -0,0 +1,209 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// profiles are alternative sets of slider and button mappings, defined under "profiles" in the config.
+// while a profile is active, its mappings replace the regular ones. slider banks shift the slider mapping
+// instead, so that with a bank offset of 5, the first slider controls whatever slider 5 is mapped to.
+// a rotary selector switch reporting its position (i.e. "#2#") can switch between profiles and banks,
+// with each position mapped to a profile name, "bank:<offset>" or "default" in selector_mapping.
+// buttons can do the same with profile:<name> (or profile:bank:<offset>, profile:default)
+const (
+	defaultProfileName = "default"
+	bankOffsetPrefix   = "bank:"
+
+	selectorLinePrefix = "#"
+
+	actionProfilePrefix = "profile:"
+)
+
+var errMissingProfileName = errors.New("missing profile name")
+
+type profile struct {
+	sliderMapping *sliderMap
+	buttonMapping *buttonMap
+
+	// for profile feedback
+	color        string
+	buttonLabels map[int]string
+}
+
+// loadProfiles reads all profiles and the selector mapping, and re-applies whichever profile is active.
+// it expects the regular mappings to have just been loaded, and returns any button actions that couldn't be resolved
+func (cc *CanonicalConfig) loadProfiles() []error {
+	cc.baseSliderMapping = cc.SliderMapping
+	cc.baseButtonMapping = cc.ButtonMapping
+	cc.baseProfileColor = cc.userConfig.GetString(configKeyProfileColor)
+	cc.baseButtonLabels = buttonLabelsFromConfig(cc.userConfig.GetStringMapString(configKeyButtonLabels), cc.MappingIndexBase)
+
+	problems := []error{}
+
+	cc.Profiles = map[string]*profile{}
+	for name := range cc.userConfig.GetStringMap(configKeyProfiles) {
+		prefix := fmt.Sprintf("%s.%s.", configKeyProfiles, name)
+
+		buttonMapping, buttonProblems := buttonMapFromConfigs(
+			cc.userConfig.GetStringMapStringSlice(prefix+configKeyButtonMapping),
+			cc.MappingIndexBase,
+		)
+
+		for _, problem := range buttonProblems {
+			problems = append(problems, fmt.Errorf("profile %s: %w", name, problem))
+		}
+
+		cc.Profiles[name] = &profile{
+			sliderMapping: sliderMapFromConfigs(
+				cc.userConfig.GetStringMapStringSlice(prefix+configKeySliderMapping),
+				nil,
+				cc.MappingIndexBase,
+			),
+			buttonMapping: buttonMapping,
+			color:         cc.userConfig.GetString(prefix + configKeyProfileColor),
+			buttonLabels: buttonLabelsFromConfig(
+				cc.userConfig.GetStringMapString(prefix+configKeyButtonLabels),
+				cc.MappingIndexBase,
+			),
+		}
+	}
+
+	cc.SelectorMapping = map[int]string{}
+	for positionString, value := range cc.userConfig.GetStringMapString(configKeySelectorMapping) {
+		position, err := strconv.Atoi(positionString)
+		if err != nil {
+			continue
+		}
+
+		cc.SelectorMapping[position-cc.MappingIndexBase] = strings.ToLower(value)
+	}
+
+	// the active profile may no longer exist, in which case we're back to the regular mappings
+	if _, ok := cc.Profiles[cc.activeProfile]; !ok && cc.activeProfile != defaultProfileName {
+		cc.activeProfile = defaultProfileName
+	}
+
+	cc.profileLock.Lock()
+	defer cc.profileLock.Unlock()
+
+	cc.sliderTargetOverrides = map[int][]string{}
+	cc.applyProfile()
+
+	return problems
+}
+
+// applyProfile sets the effective mappings according to the active profile and bank offset. expects profileLock to be held
+func (cc *CanonicalConfig) applyProfile() {
+	sliderMapping, buttonMapping := cc.baseSliderMapping, cc.baseButtonMapping
+	color, buttonLabels := cc.baseProfileColor, cc.baseButtonLabels
+
+	if active, ok := cc.Profiles[cc.activeProfile]; ok {
+		sliderMapping, buttonMapping = active.sliderMapping, active.buttonMapping
+		color, buttonLabels = active.color, active.buttonLabels
+	}
+
+	if cc.activeBankOffset != 0 {
+		sliderMapping = sliderMapping.shifted(cc.activeBankOffset)
+	}
+
+	if len(cc.sliderTargetOverrides) > 0 {
+		sliderMapping = sliderMapping.overridden(cc.sliderTargetOverrides)
+	}
+
+	cc.SliderMapping = sliderMapping
+	cc.ButtonMapping = buttonMapping
+	cc.ProfileColor = color
+	cc.ButtonLabels = buttonLabels
+}
+
+// SelectProfile activates a profile by name ("default" for the regular mappings) along with a slider bank offset.
+// components are notified just like when the config file changes, so everything picks up the new mappings
+func (cc *CanonicalConfig) SelectProfile(name string, bankOffset int) error {
+	name = strings.ToLower(name)
+
+	if _, ok := cc.Profiles[name]; !ok && name != defaultProfileName {
+		return fmt.Errorf("unknown profile: %s", name)
+	}
+
+	if name == cc.activeProfile && bankOffset == cc.activeBankOffset {
+		return nil
+	}
+
+	cc.logger.Infow("Switching profile", "profile", name, "bankOffset", bankOffset)
+
+	cc.profileLock.Lock()
+	cc.activeProfile = name
+	cc.activeBankOffset = bankOffset
+	cc.applyProfile()
+	cc.profileLock.Unlock()
+
+	cc.onConfigReloaded()
+
+	return nil
+}
+
+// ActiveProfile returns the name of the active profile and the active slider bank offset
+func (cc *CanonicalConfig) ActiveProfile() (string, int) {
+	return cc.activeProfile, cc.activeBankOffset
+}
+
+// parseSelectorValue splits a selector mapping value into a profile name and bank offset
+func parseSelectorValue(value string) (string, int, error) {
+	if !strings.HasPrefix(value, bankOffsetPrefix) {
+		return value, 0, nil
+	}
+
+	offset, err := strconv.Atoi(strings.TrimPrefix(value, bankOffsetPrefix))
+	if err != nil || offset < 0 {
+		return "", 0, fmt.Errorf("invalid bank offset: %s", value)
+	}
+
+	return defaultProfileName, offset, nil
+}
+
+func (sio *SerialIO) handleSelector(logger *zap.SugaredLogger, position int) {
+
+	// firmware may keep reporting the same position, only act when it actually changes
+	if position == sio.lastSelectorPosition {
+		return
+	}
+
+	sio.lastSelectorPosition = position
+
+	value, ok := sio.deej.config.SelectorMapping[position]
+	if !ok {
+		logger.Debugw("Selector moved to unmapped position", "position", position)
+		return
+	}
+
+	name, bankOffset, err := parseSelectorValue(value)
+	if err != nil {
+		logger.Warnw("Invalid selector mapping", "position", position, "error", err)
+		return
+	}
+
+	if err := sio.deej.config.SelectProfile(name, bankOffset); err != nil {
+		logger.Warnw("Failed to switch profile from selector", "position", position, "error", err)
+	}
+}
+
+// switchProfile switches profiles (or banks) for a profile: button action, and lets the user know it did
+func (sio *SerialIO) switchProfile(logger *zap.SugaredLogger, notifier Notifier, name string, bankOffset int) {
+	if err := sio.deej.config.SelectProfile(name, bankOffset); err != nil {
+		logger.Warnw("Failed to switch profile from button", "profile", name, "bankOffset", bankOffset, "error", err)
+		return
+	}
+
+	message := fmt.Sprintf("Now using %s", name)
+	if bankOffset != 0 {
+		message = fmt.Sprintf("Now using %s, with sliders shifted by %d", name, bankOffset)
+	}
+
+	notifier.Notify("Profile switched", message)
+	sio.deej.refreshTray()
+}
@@ -0,0 +1,263 @@
+package deej
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/spf13/viper"
+	"github.com/thoas/go-funk"
+	"go.uber.org/zap"
+)
+
+// everything that passes between deej's parts goes through a bounded queue: lines read from the device, slider and
+// button events on their way to each consumer, config reload notifications and commands on their way to the device.
+// each queue has a size and a policy for when it fills up:
+// - block waits for room, holding up whoever's adding to the queue
+// - drop_oldest makes room by dropping whatever's been waiting longest
+// - coalesce replaces what's already waiting with the newer version of it (i.e. the same slider's older value)
+// - reject refuses whatever's being added (so commands fail right away, rather than being sent late)
+// advanced users can change these under "queues" in the config, which only applies after restarting deej.
+// how each queue is actually doing is counted as it goes, and served by the HTTP API at /api/queues
+const (
+	queueLines         = "lines"
+	queueSliderEvents  = "slider_events"
+	queueButtonEvents  = "button_events"
+	queueConfigReloads = "config_reloads"
+	queueCommands      = "commands"
+
+	queuePolicyBlock      = "block"
+	queuePolicyDropOldest = "drop_oldest"
+	queuePolicyCoalesce   = "coalesce"
+	queuePolicyReject     = "reject"
+)
+
+type queueSettings struct {
+	Size   int    `mapstructure:"size"`
+	Policy string `mapstructure:"policy"`
+}
+
+// how each queue works unless the user says otherwise
+var defaultQueueSettings = map[string]queueSettings{
+	queueLines:         {Size: 0, Policy: queuePolicyBlock},
+	queueSliderEvents:  {Size: 64, Policy: queuePolicyDropOldest},
+	queueButtonEvents:  {Size: 64, Policy: queuePolicyDropOldest},
+	queueConfigReloads: {Size: 0, Policy: queuePolicyBlock},
+	queueCommands:      {Size: 32, Policy: queuePolicyReject},
+}
+
+// which policies make sense for each queue. button events can't be coalesced (every press matters),
+// and neither can lines (they may carry buttons too)
+var supportedQueuePolicies = map[string][]string{
+	queueLines:         {queuePolicyBlock, queuePolicyDropOldest},
+	queueSliderEvents:  {queuePolicyBlock, queuePolicyDropOldest, queuePolicyCoalesce},
+	queueButtonEvents:  {queuePolicyBlock, queuePolicyDropOldest},
+	queueConfigReloads: {queuePolicyBlock, queuePolicyCoalesce},
+	queueCommands:      {queuePolicyBlock, queuePolicyDropOldest, queuePolicyReject},
+}
+
+// QueueStats is how one of deej's queues is set up, and how it's been doing since deej started.
+// queues with several instances (one per event consumer) are added up
+type QueueStats struct {
+	Name      string `json:"name"`
+	Policy    string `json:"policy"`
+	Size      int    `json:"size"`
+	Instances int    `json:"instances"`
+	Queued    int    `json:"queued"`
+	HighWater int64  `json:"highWater"`
+	Enqueued  uint64 `json:"enqueued"`
+	Dropped   uint64 `json:"dropped"`
+	Coalesced uint64 `json:"coalesced"`
+	Rejected  uint64 `json:"rejected"`
+	Blocked   uint64 `json:"blocked"`
+}
+
+// queueCounters keeps score for all instances of a queue
+type queueCounters struct {
+	enqueued  uint64
+	dropped   uint64
+	coalesced uint64
+	rejected  uint64
+	blocked   uint64
+	highWater int64
+}
+
+// queued records an item going into a queue that's now this long
+func (c *queueCounters) queued(length int) {
+	atomic.AddUint64(&c.enqueued, 1)
+
+	for {
+		highWater := atomic.LoadInt64(&c.highWater)
+		if int64(length) <= highWater || atomic.CompareAndSwapInt64(&c.highWater, highWater, int64(length)) {
+			return
+		}
+	}
+}
+
+// queueRegistry knows how each queue is set up, and keeps score of how they're doing
+type queueRegistry struct {
+	logger *zap.SugaredLogger
+
+	settings map[string]queueSettings
+	counters map[string]*queueCounters
+
+	// how long each live instance of each queue currently is
+	lengths map[string]map[interface{}]func() int
+	lock    sync.Locker
+}
+
+func newQueueRegistry(logger *zap.SugaredLogger, userSettings map[string]queueSettings) *queueRegistry {
+	r := &queueRegistry{
+		logger:   logger.Named("queues"),
+		settings: map[string]queueSettings{},
+		counters: map[string]*queueCounters{},
+		lengths:  map[string]map[interface{}]func() int{},
+		lock:     &sync.Mutex{},
+	}
+
+	for name, defaults := range defaultQueueSettings {
+		r.settings[name] = defaults
+		r.counters[name] = &queueCounters{}
+		r.lengths[name] = map[interface{}]func() int{}
+	}
+
+	for name, settings := range userSettings {
+		defaults, ok := defaultQueueSettings[name]
+		if !ok {
+			r.logger.Warnw("Unknown queue specified, ignoring it", "key", configKeyQueues, "queue", name)
+			continue
+		}
+
+		if settings.Policy == "" {
+			settings.Policy = defaults.Policy
+		}
+
+		// only blocking queues can do without room for anything, the others need somewhere to drop or coalesce in
+		if !funk.ContainsString(supportedQueuePolicies[name], settings.Policy) ||
+			settings.Size < 0 ||
+			(settings.Size == 0 && settings.Policy != queuePolicyBlock) {
+
+			r.logger.Warnw("Invalid queue settings specified, using default value",
+				"key", configKeyQueues,
+				"queue", name,
+				"invalidValue", settings,
+				"supportedPolicies", supportedQueuePolicies[name],
+				"defaultValue", defaults)
+
+			continue
+		}
+
+		r.settings[name] = settings
+	}
+
+	return r
+}
+
+// readQueueSettings reads just the queues section from the user's config, since the queues are set up
+// before the rest of the config is loaded
+func readQueueSettings(logger *zap.SugaredLogger) map[string]queueSettings {
+	userConfig := viper.New()
+	userConfig.SetConfigFile(userConfigFilepath)
+
+	if err := userConfig.ReadInConfig(); err != nil {
+		return nil
+	}
+
+	if overrides := userConfig.GetStringMap(runtime.GOOS); len(overrides) > 0 {
+		userConfig.MergeConfigMap(overrides)
+	}
+
+	settings := map[string]queueSettings{}
+	if err := userConfig.UnmarshalKey(configKeyQueues, &settings); err != nil {
+		logger.Warnw("Invalid queue settings specified, using default values", "key", configKeyQueues, "error", err)
+		return nil
+	}
+
+	return settings
+}
+
+// settingsFor returns how a queue is set up
+func (r *queueRegistry) settingsFor(name string) queueSettings {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	return r.settings[name]
+}
+
+// open starts tracking a new instance of a queue by its length, and returns the queue's counters
+func (r *queueRegistry) open(name string, instance interface{}, length func() int) *queueCounters {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.lengths[name][instance] = length
+
+	return r.counters[name]
+}
+
+// countersFor returns a queue's counters, for whoever adds items to it
+func (r *queueRegistry) countersFor(name string) *queueCounters {
+	return r.counters[name]
+}
+
+// close stops tracking an instance of a queue, which no longer counts towards how much is queued
+func (r *queueRegistry) close(name string, instance interface{}) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	delete(r.lengths[name], instance)
+}
+
+// stats returns how every queue is doing, in order
+func (r *queueRegistry) stats() []QueueStats {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	names := []string{}
+	for name := range r.settings {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	result := []QueueStats{}
+	for _, name := range names {
+		counters := r.counters[name]
+
+		stats := QueueStats{
+			Name:      name,
+			Policy:    r.settings[name].Policy,
+			Size:      r.settings[name].Size,
+			Instances: len(r.lengths[name]),
+			HighWater: atomic.LoadInt64(&counters.highWater),
+			Enqueued:  atomic.LoadUint64(&counters.enqueued),
+			Dropped:   atomic.LoadUint64(&counters.dropped),
+			Coalesced: atomic.LoadUint64(&counters.coalesced),
+			Rejected:  atomic.LoadUint64(&counters.rejected),
+			Blocked:   atomic.LoadUint64(&counters.blocked),
+		}
+
+		for _, length := range r.lengths[name] {
+			stats.Queued += length()
+		}
+
+		result = append(result, stats)
+	}
+
+	return result
+}
+
+// dropped returns how many items a queue has dropped so far
+func (r *queueRegistry) dropped(name string) uint64 {
+	return atomic.LoadUint64(&r.countersFor(name).dropped)
+}
+
+func (qs queueSettings) String() string {
+	return fmt.Sprintf("%s/%d", qs.Policy, qs.Size)
+}
+
+// QueueStats returns how each of deej's queues is set up, and how it's been doing
+func (d *Deej) QueueStats() []QueueStats {
+	return d.config.queues.stats()
+}
@@ -0,0 +1,120 @@
+package deej
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/thoas/go-funk"
+	"go.uber.org/zap"
+)
+
+// with recording_indicator enabled, deej keeps an eye out for apps recording from any input device (a call,
+// a voice recorder, OBS...). whether anything is recording is sent to the board for boards with a recording LED,
+// shown in the tray and the HTTP API, and can fire actions of its own when recording starts and stops
+// (i.e. turning on do not disturb)
+const (
+	recordingPollInterval = time.Second
+
+	recordingCommandFormat = "REC:%d"
+)
+
+// recordingWatcher tracks which apps are recording right now
+type recordingWatcher struct {
+	deej   *Deej
+	logger *zap.SugaredLogger
+
+	apps []string
+	lock sync.Locker
+}
+
+func newRecordingWatcher(deej *Deej, logger *zap.SugaredLogger) *recordingWatcher {
+	return &recordingWatcher{
+		deej:   deej,
+		logger: logger.Named("recording"),
+		apps:   []string{},
+		lock:   &sync.Mutex{},
+	}
+}
+
+func (rw *recordingWatcher) run(done chan bool) {
+	defer rw.deej.supervisor.guard(moduleRecording)
+
+	ticker := time.NewTicker(recordingPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		if !rw.deej.config.RecordingIndicator {
+			rw.update([]string{})
+			continue
+		}
+
+		watcher, ok := rw.deej.sessions.sessionFinder.(captureWatcher)
+		if !ok {
+			continue
+		}
+
+		apps, err := watcher.CapturingApps()
+		if err != nil {
+			rw.logger.Debugw("Failed to check which apps are recording", "error", err)
+			continue
+		}
+
+		rw.update(apps)
+	}
+}
+
+// update takes in the apps recording right now, and lets everyone know if recording started or stopped
+func (rw *recordingWatcher) update(apps []string) {
+	apps = funk.UniqString(apps)
+	sort.Strings(apps)
+
+	rw.lock.Lock()
+	wasRecording := len(rw.apps) > 0
+	changed := strings.Join(apps, ",") != strings.Join(rw.apps, ",")
+	rw.apps = apps
+	rw.lock.Unlock()
+
+	if !changed {
+		return
+	}
+
+	recording := len(apps) > 0
+	rw.logger.Infow("Recording apps changed", "apps", apps)
+	rw.deej.refreshTray()
+
+	if recording == wasRecording {
+		return
+	}
+
+	state := 0
+	actions := rw.deej.config.RecordingStoppedActions
+
+	if recording {
+		state = 1
+		actions = rw.deej.config.RecordingStartedActions
+	}
+
+	if err := rw.deej.serial.SendCommand(fmt.Sprintf(recordingCommandFormat, state)); err != nil {
+		rw.logger.Debugw("Failed to send recording state to device", "error", err)
+	}
+
+	// these aren't tied to any button
+	rw.deej.serial.runButtonActions(rw.logger, ButtonPressEvent{ButtonID: -1}, actions)
+}
+
+// recordingApps returns the apps recording right now, empty when none are
+func (rw *recordingWatcher) recordingApps() []string {
+	rw.lock.Lock()
+	defer rw.lock.Unlock()
+
+	return append([]string{}, rw.apps...)
+}
@@ -0,0 +1,51 @@
+package deej
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/jfreymuth/pulse/proto"
+)
+
+func (sf *paSessionFinder) CapturingApps() ([]string, error) {
+	sources := proto.GetSourceInfoListReply{}
+	if err := sf.client.Request(&proto.GetSourceInfoList{}, &sources); err != nil {
+		return nil, fmt.Errorf("get source list: %w", err)
+	}
+
+	// recording what's playing (through a sink's monitor) isn't recording from an input device
+	monitors := map[uint32]bool{}
+	for _, source := range sources {
+		if source.MonitorSourceIndex != proto.Undefined {
+			monitors[source.SourceIndex] = true
+		}
+	}
+
+	outputs := proto.GetSourceOutputInfoListReply{}
+	if err := sf.client.Request(&proto.GetSourceOutputInfoList{}, &outputs); err != nil {
+		return nil, fmt.Errorf("get source output list: %w", err)
+	}
+
+	ownPID := strconv.Itoa(os.Getpid())
+	apps := []string{}
+
+	for _, output := range outputs {
+
+		// paused (corked) streams aren't recording anything right now
+		if output.Corked || monitors[output.SourceIndex] {
+			continue
+		}
+
+		// and deej's own level meters don't count
+		if pid, ok := output.Properties["application.process.id"]; ok && pid.String() == ownPID {
+			continue
+		}
+
+		if name, ok := output.Properties["application.process.binary"]; ok {
+			apps = append(apps, name.String())
+		}
+	}
+
+	return apps, nil
+}
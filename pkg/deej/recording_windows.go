@@ -0,0 +1,130 @@
+package deej
+
+import (
+	"fmt"
+	"unsafe"
+
+	ole "github.com/go-ole/go-ole"
+	ps "github.com/mitchellh/go-ps"
+	wca "github.com/moutend/go-wca"
+)
+
+// go-wca numbers the session states from 0, windows starts from AudioSessionStateInactive = 0
+const audioSessionStateActive = 1
+
+func (sf *wcaSessionFinder) CapturingApps() ([]string, error) {
+	if err := sf.coInitialize(); err != nil {
+		return nil, err
+	}
+	defer ole.CoUninitialize()
+
+	if err := sf.getDeviceEnumerator(); err != nil {
+		return nil, fmt.Errorf("get device enumerator: %w", err)
+	}
+
+	var deviceCollection *wca.IMMDeviceCollection
+
+	if err := sf.mmDeviceEnumerator.EnumAudioEndpoints(wca.ECapture, wca.DEVICE_STATE_ACTIVE, &deviceCollection); err != nil {
+		return nil, fmt.Errorf("enumerate active input endpoints: %w", err)
+	}
+	defer deviceCollection.Release()
+
+	var deviceCount uint32
+
+	if err := deviceCollection.GetCount(&deviceCount); err != nil {
+		return nil, fmt.Errorf("get device count from device collection: %w", err)
+	}
+
+	apps := []string{}
+
+	for deviceIdx := uint32(0); deviceIdx < deviceCount; deviceIdx++ {
+		var endpoint *wca.IMMDevice
+
+		if err := deviceCollection.Item(deviceIdx, &endpoint); err != nil {
+			return nil, fmt.Errorf("get device %d from device collection: %w", deviceIdx, err)
+		}
+
+		deviceApps, err := capturingAppsOf(endpoint)
+		endpoint.Release()
+
+		if err != nil {
+			return nil, fmt.Errorf("check input device %d: %w", deviceIdx, err)
+		}
+
+		apps = append(apps, deviceApps...)
+	}
+
+	return apps, nil
+}
+
+// capturingAppsOf returns the apps with an active session on an input device
+func capturingAppsOf(endpoint *wca.IMMDevice) ([]string, error) {
+	var audioSessionManager2 *wca.IAudioSessionManager2
+
+	if err := endpoint.Activate(wca.IID_IAudioSessionManager2, wca.CLSCTX_ALL, nil, &audioSessionManager2); err != nil {
+		return nil, fmt.Errorf("activate endpoint: %w", err)
+	}
+	defer audioSessionManager2.Release()
+
+	var sessionEnumerator *wca.IAudioSessionEnumerator
+
+	if err := audioSessionManager2.GetSessionEnumerator(&sessionEnumerator); err != nil {
+		return nil, fmt.Errorf("get session enumerator: %w", err)
+	}
+	defer sessionEnumerator.Release()
+
+	var sessionCount int
+
+	if err := sessionEnumerator.GetCount(&sessionCount); err != nil {
+		return nil, fmt.Errorf("get session count: %w", err)
+	}
+
+	apps := []string{}
+
+	for sessionIdx := 0; sessionIdx < sessionCount; sessionIdx++ {
+		var audioSessionControl *wca.IAudioSessionControl
+
+		if err := sessionEnumerator.GetSession(sessionIdx, &audioSessionControl); err != nil {
+			return nil, fmt.Errorf("get session %d from enumerator: %w", sessionIdx, err)
+		}
+
+		pid, active := activeSessionProcess(audioSessionControl)
+		audioSessionControl.Release()
+
+		if !active {
+			continue
+		}
+
+		// the process may have exited since, which is just as good as not recording
+		if process, err := ps.FindProcess(int(pid)); err == nil && process != nil {
+			apps = append(apps, process.Executable())
+		}
+	}
+
+	return apps, nil
+}
+
+// activeSessionProcess returns the pid of a session's process, and whether the session is active right now
+func activeSessionProcess(audioSessionControl *wca.IAudioSessionControl) (uint32, bool) {
+	var state uint32
+
+	if err := audioSessionControl.GetState(&state); err != nil || state != audioSessionStateActive {
+		return 0, false
+	}
+
+	dispatch, err := audioSessionControl.QueryInterface(wca.IID_IAudioSessionControl2)
+	if err != nil {
+		return 0, false
+	}
+
+	audioSessionControl2 := (*wca.IAudioSessionControl2)(unsafe.Pointer(dispatch))
+	defer audioSessionControl2.Release()
+
+	var pid uint32
+
+	if err := audioSessionControl2.GetProcessId(&pid); err != nil || pid == 0 {
+		return 0, false
+	}
+
+	return pid, true
+}
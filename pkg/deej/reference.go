@@ -0,0 +1,60 @@
+package deej
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// syntaxReference is a single entry printed by "deej targets" and "deej actions"
+type syntaxReference struct {
+	syntax      string
+	description string
+	example     string
+}
+
+// targetSyntaxes lists everything a slider can be mapped to, straight from the table targets are resolved by
+func targetSyntaxes() []syntaxReference {
+	result := []syntaxReference{}
+	for _, syntax := range sliderTargetSyntaxes {
+		result = append(result, syntaxReference{syntax.syntax, syntax.description, syntax.example})
+	}
+
+	return result
+}
+
+// actionSyntaxes lists everything a button can be mapped to, straight from the parsers that resolve them
+func actionSyntaxes() []syntaxReference {
+	result := []syntaxReference{}
+	for _, syntax := range buttonActionSyntaxes {
+		result = append(result, syntaxReference{syntax.syntax, syntax.description, syntax.example})
+	}
+
+	return result
+}
+
+// PrintTargets writes every supported slider target syntax to w, with an example of each
+func PrintTargets(w io.Writer) {
+	fmt.Fprintln(w, "Slider targets (slider_mapping):")
+	printSyntaxReference(w, targetSyntaxes())
+}
+
+// PrintActions writes every supported button action syntax to w, with an example of each
+func PrintActions(w io.Writer) {
+	fmt.Fprintln(w, "Button actions (button_mapping, release_mapping, toggle_mapping, layer_mapping, button_value_mapping, long_press_mapping, double_press_mapping, chord_mapping):")
+	printSyntaxReference(w, actionSyntaxes())
+
+	if custom := customActionNames(); len(custom) > 0 {
+		sort.Strings(custom)
+		fmt.Fprintf(w, "\nRegistered custom actions: %s\n", strings.Join(custom, ", "))
+	}
+
+	fmt.Fprintf(w, "\nKeys: %s\n", strings.Join(keyNames(), ", "))
+}
+
+func printSyntaxReference(w io.Writer, entries []syntaxReference) {
+	for _, entry := range entries {
+		fmt.Fprintf(w, "\n  %s\n      %s\n      e.g. %s\n", entry.syntax, entry.description, entry.example)
+	}
+}
@@ -0,0 +1,394 @@
+//go:build !slim
+// +build !slim
+
+package deej
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+)
+
+// relay setups share one device between two (or more) PCs. the leader is the one the device is connected to: it
+// serves a relay link (relay_listen), and passes every line the device sends on to whoever connects to it. followers
+// (relay_leader) use that link as their device, and leave talking back to the device to the leader. the link also
+// keeps every instance in the same state: the active profile, bank and panic mute. the leader sends its state when
+// a follower connects and whenever it changes, and a follower changing its own (from its tray or a button) sends it
+// to the leader, which takes it on and passes it to everyone else. that way every tray, and the device's feedback,
+// shows the same thing no matter where it was changed. anyone who can reach the link gets the device's lines and
+// can change the state, so followers have to show the leader the same relay_token it has
+const (
+	relayPath = "/relay"
+
+	relayTokenHeader = "X-Deej-Relay-Token"
+
+	relayMessageLine  = "line"
+	relayMessageState = "state"
+
+	// how often each side checks whether its state changed
+	relaySyncInterval = 250 * time.Millisecond
+
+	// lines and state waiting to be sent to a single peer, past which lines are dropped
+	relayOutboxSize = 64
+)
+
+// relayState is everything that's kept the same across a relay setup
+type relayState struct {
+	Profile  string `json:"profile"`
+	Bank     int    `json:"bank"`
+	Panicked bool   `json:"panicked"`
+}
+
+type relayMessage struct {
+	Type  string      `json:"type"`
+	Line  string      `json:"line,omitempty"`
+	State *relayState `json:"state,omitempty"`
+}
+
+// relayPeer is the other end of a relay link. messages to it are written by a goroutine of its own,
+// so a slow peer never holds up the device
+type relayPeer struct {
+	conn   *websocket.Conn
+	outbox chan relayMessage
+	logger *zap.SugaredLogger
+}
+
+type relay struct {
+	deej   *Deej
+	logger *zap.SugaredLogger
+
+	// held while comparing, changing or applying the synced state, so each side's own changes
+	// and the other side's can't interleave
+	lock sync.Locker
+
+	// the last state every side agreed on
+	synced relayState
+
+	// the leader's followers, or a follower's leader
+	peers map[*relayPeer]bool
+
+	// only on the leader
+	server        *http.Server
+	listenAddress string
+
+	stopChannel chan bool
+}
+
+func newRelay(deej *Deej, logger *zap.SugaredLogger) *relay {
+	r := &relay{
+		deej:   deej,
+		logger: logger.Named("relay"),
+		lock:   &sync.Mutex{},
+		peers:  map[*relayPeer]bool{},
+	}
+
+	r.logger.Debug("Created relay instance")
+
+	return r
+}
+
+// start serves the relay link if this is the leader, and keeps the synced state up to date in either role
+func (r *relay) start() error {
+	r.stopChannel = make(chan bool)
+
+	go r.runSync(r.stopChannel)
+
+	// listen first, so whatever reacts to config changes sees the server (if any) already set up
+	err := r.listen()
+	r.setupOnConfigReload()
+
+	return err
+}
+
+func (r *relay) stop() {
+	if r.stopChannel != nil {
+		close(r.stopChannel)
+		r.stopChannel = nil
+	}
+
+	r.stopListening()
+}
+
+func (r *relay) listen() error {
+	if r.deej.config.RelayRole != relayRoleLeader {
+		return nil
+	}
+
+	r.listenAddress = r.deej.config.RelayListen
+	if r.listenAddress == "" {
+		r.logger.Warn("Relay leader role set, but relay_listen isn't, not serving relay link")
+		return errors.New("relay: no listen address configured")
+	}
+
+	if r.deej.config.RelayToken == "" {
+		r.logger.Warn("Relay leader role set, but relay_token isn't, not serving relay link")
+		return errors.New("relay: no token configured")
+	}
+
+	listener, err := net.Listen("tcp", r.listenAddress)
+	if err != nil {
+		r.logger.Warnw("Failed to listen for relay followers", "address", r.listenAddress, "error", err)
+		return fmt.Errorf("listen for relay followers: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(relayPath, r.handleFollower)
+
+	r.server = &http.Server{Handler: mux}
+
+	go func() {
+		if err := r.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			r.logger.Warnw("Relay server stopped unexpectedly", "error", err)
+		}
+	}()
+
+	r.logger.Infow("Serving relay link", "address", listener.Addr())
+
+	return nil
+}
+
+func (r *relay) stopListening() {
+	if r.server == nil {
+		return
+	}
+
+	r.logger.Debug("Shutting down relay link")
+
+	// closing the server doesn't close hijacked connections, so say goodbye to every follower too
+	r.server.Close()
+	r.server = nil
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for peer := range r.peers {
+		peer.conn.Close()
+	}
+}
+
+func (r *relay) setupOnConfigReload() {
+	configReloadedChannel := r.deej.config.SubscribeToChanges()
+	stop := r.stopChannel
+
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-configReloadedChannel:
+				leading := r.deej.config.RelayRole == relayRoleLeader
+
+				if (r.server != nil) != leading || (leading && r.deej.config.RelayListen != r.listenAddress) ||
+					(r.server != nil && r.deej.config.RelayToken == "") {
+					r.logger.Info("Detected change in relay role, address or token, restarting relay link")
+					r.stopListening()
+
+					if err := r.listen(); err != nil {
+						r.logger.Warnw("Failed to restart relay link after config change", "error", err)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// handleFollower serves a single follower for as long as it stays connected
+func (r *relay) handleFollower(w http.ResponseWriter, req *http.Request) {
+	token, given := r.deej.config.RelayToken, req.Header.Get(relayTokenHeader)
+	if token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+		r.logger.Warnw("Turned away relay follower with a missing or wrong token", "remote", req.RemoteAddr)
+		http.Error(w, "missing or wrong "+relayTokenHeader, http.StatusUnauthorized)
+
+		return
+	}
+
+	conn, err := websocketUpgrader.Upgrade(w, req, nil)
+	if err != nil {
+		r.logger.Warnw("Failed to upgrade relay connection", "remote", req.RemoteAddr, "error", err)
+		return
+	}
+
+	peer := r.addPeer(conn, "remote", req.RemoteAddr)
+	defer r.removePeer(peer)
+
+	r.logger.Infow("Relay follower connected", "remote", req.RemoteAddr)
+
+	// a new follower takes on whatever the leader is doing right now
+	r.lock.Lock()
+	state := r.currentState()
+	peer.send(relayMessage{Type: relayMessageState, State: &state})
+	r.lock.Unlock()
+
+	for {
+		var message relayMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			r.logger.Infow("Relay follower disconnected", "remote", req.RemoteAddr, "error", err)
+			return
+		}
+
+		// followers only ever have news about their state, which is then everyone's
+		if message.Type == relayMessageState && message.State != nil && r.applyState(*message.State) {
+			r.broadcast(relayMessage{Type: relayMessageState, State: message.State})
+		}
+	}
+}
+
+// forwardLine passes a line the device sent on to every follower
+func (r *relay) forwardLine(line string) {
+	r.broadcast(relayMessage{Type: relayMessageLine, Line: line})
+}
+
+func (r *relay) broadcast(message relayMessage) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for peer := range r.peers {
+		peer.send(message)
+	}
+}
+
+func (r *relay) addPeer(conn *websocket.Conn, keysAndValues ...interface{}) *relayPeer {
+	peer := &relayPeer{
+		conn:   conn,
+		outbox: make(chan relayMessage, relayOutboxSize),
+		logger: r.logger.With(keysAndValues...),
+	}
+
+	go peer.writeMessages()
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.peers[peer] = true
+
+	return peer
+}
+
+func (r *relay) removePeer(peer *relayPeer) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.peers[peer] {
+		delete(r.peers, peer)
+		close(peer.outbox)
+		peer.conn.Close()
+	}
+}
+
+// currentState returns this instance's own state
+func (r *relay) currentState() relayState {
+	profile, bank := r.deej.config.ActiveProfile()
+
+	return relayState{
+		Profile:  profile,
+		Bank:     bank,
+		Panicked: r.deej.sessions.panic.active(),
+	}
+}
+
+// applyState takes on the state another instance sent, and returns whether it changed anything here
+func (r *relay) applyState(state relayState) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.synced = state
+
+	current := r.currentState()
+	if current == state {
+		return false
+	}
+
+	r.logger.Infow("Taking on relayed state", "profile", state.Profile, "bank", state.Bank, "panicked", state.Panicked)
+
+	if current.Profile != state.Profile || current.Bank != state.Bank {
+		if err := r.deej.config.SelectProfile(state.Profile, state.Bank); err != nil {
+			r.logger.Warnw("Failed to switch to relayed profile", "profile", state.Profile, "error", err)
+		}
+	}
+
+	if current.Panicked != state.Panicked {
+		if state.Panicked {
+			r.deej.serial.panicButton(r.deej.notifier)
+		} else {
+			r.deej.serial.restoreButton()
+		}
+	}
+
+	r.deej.refreshTray()
+
+	return true
+}
+
+// runSync sends this instance's state to its peers whenever it changes here
+func (r *relay) runSync(stop chan bool) {
+	ticker := time.NewTicker(relaySyncInterval)
+	defer ticker.Stop()
+
+	r.lock.Lock()
+	r.synced = r.currentState()
+	r.lock.Unlock()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			r.lock.Lock()
+
+			if current := r.currentState(); current != r.synced {
+				r.synced = current
+
+				for peer := range r.peers {
+					peer.send(relayMessage{Type: relayMessageState, State: &current})
+				}
+			}
+
+			r.lock.Unlock()
+		}
+	}
+}
+
+// send queues a message for the peer. state always gets through, lines are dropped if the peer falls behind.
+// assumes the relay's lock is held, so the outbox can't be closed meanwhile
+func (p *relayPeer) send(message relayMessage) {
+	select {
+	case p.outbox <- message:
+		return
+	default:
+	}
+
+	if message.Type == relayMessageLine {
+		return
+	}
+
+	// make room for the state by dropping the oldest line
+	select {
+	case <-p.outbox:
+	default:
+	}
+
+	select {
+	case p.outbox <- message:
+	default:
+		p.logger.Warn("Relay peer is falling behind, dropped state update")
+	}
+}
+
+func (p *relayPeer) writeMessages() {
+	for message := range p.outbox {
+		if err := p.conn.WriteJSON(message); err != nil {
+			p.logger.Debugw("Failed to write relay message", "error", err)
+			p.conn.Close()
+
+			// keep draining until the peer is removed
+			continue
+		}
+	}
+}
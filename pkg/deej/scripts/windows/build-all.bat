@@ -7,3 +7,4 @@ SET "WIN_SCRIPTS_ROOT=%~dp0"
 
 CALL "%WIN_SCRIPTS_ROOT%build-dev.bat"
 CALL "%WIN_SCRIPTS_ROOT%build-release.bat"
+CALL "%WIN_SCRIPTS_ROOT%build-slim.bat"
@@ -0,0 +1,91 @@
+package deej
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+// credentials (i.e. an MQTT password) don't have to be written into config.yaml directly, which makes it
+// safe to share. instead, a setting can refer to a value from secrets.yaml (next to config.yaml) with
+// "secret:<name>", or to an environment variable with "${NAME}" - the two can't be mixed within one value
+const secretReferencePrefix = "secret:"
+
+var environmentReferencePattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// isSecretReference returns whether a setting refers to a secret or an environment variable, rather than
+// holding the value itself
+func isSecretReference(value string) bool {
+	return strings.HasPrefix(value, secretReferencePrefix) ||
+		environmentReferencePattern.ReplaceAllString(value, "") == ""
+}
+
+// getSecretString reads a string setting from the user config, resolving any secret it refers to
+func (cc *CanonicalConfig) getSecretString(key string) string {
+	return cc.resolveSecret(key, cc.userConfig.GetString(key))
+}
+
+func (cc *CanonicalConfig) resolveSecret(key string, value string) string {
+	if strings.HasPrefix(value, secretReferencePrefix) {
+		secretName := strings.TrimPrefix(value, secretReferencePrefix)
+
+		if !cc.secretsConfig.IsSet(secretName) {
+			cc.logger.Warnw("Setting refers to a secret that doesn't exist, leaving it empty",
+				"key", key,
+				"secret", secretName,
+				"secretsFile", secretsConfigFilepath)
+
+			return ""
+		}
+
+		return cc.secretsConfig.GetString(secretName)
+	}
+
+	return environmentReferencePattern.ReplaceAllStringFunc(value, func(reference string) string {
+		variable := environmentReferencePattern.FindStringSubmatch(reference)[1]
+
+		resolved, ok := os.LookupEnv(variable)
+		if !ok {
+			cc.logger.Warnw("Setting refers to an environment variable that isn't set, leaving it empty",
+				"key", key,
+				"variable", variable)
+		}
+
+		return resolved
+	})
+}
+
+// redactedValue stands in for credentials in logs
+const redactedValue = "<redacted>"
+
+// redacted returns a copy of the connection info that's safe to write to logs
+func (ci ConnectionInfo) redacted() ConnectionInfo {
+	if ci.MQTTPassword != "" {
+		ci.MQTTPassword = redactedValue
+	}
+
+	if ci.WebSocketURL != "" {
+		ci.WebSocketURL = redactURL(ci.WebSocketURL)
+	}
+
+	if ci.MQTTBroker != "" {
+		ci.MQTTBroker = redactURL(ci.MQTTBroker)
+	}
+
+	return ci
+}
+
+// redacted returns a copy of the webhook that's safe to write to logs. every header is left out, since any of
+// them may carry a token
+func (w Webhook) redacted() Webhook {
+	w.URL = redactURL(w.URL)
+
+	headers := make(map[string]string, len(w.Headers))
+	for name := range w.Headers {
+		headers[name] = redactedValue
+	}
+
+	w.Headers = headers
+
+	return w
+}
@@ -0,0 +1,131 @@
+package deej
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// firmware may number its lines with a rolling sequence number (0 to 255, then back to 0), i.e. "@17:512|1023|0".
+// deej strips the number before handling the line, and uses it to notice lines that never made it here.
+// that's mostly useful for diagnosing flaky cables and noisy wireless links - with sequence_loss_threshold set,
+// deej also reconnects once too many lines go missing, which is often enough to get a wireless link back in shape
+const (
+	sequenceModulo = 256
+
+	// loss is judged over this many expected lines at a time, so a single hiccup doesn't trigger a reconnect
+	sequenceLossWindow = 200
+
+	// jumps bigger than this are more likely a restarted device than lost lines
+	maxSequenceGap = sequenceModulo / 2
+
+	sequenceReconnectDelay = time.Second
+)
+
+// sequenceTracker keeps track of which sequence number we expect next, and how many lines went missing
+type sequenceTracker struct {
+	expected int
+	synced   bool
+
+	totalReceived int
+	totalDropped  int
+
+	windowReceived int
+	windowDropped  int
+}
+
+func newSequenceTracker() *sequenceTracker {
+	return &sequenceTracker{}
+}
+
+// observe records a received sequence number and returns how many lines were skipped right before it
+func (t *sequenceTracker) observe(sequence int) int {
+	dropped := 0
+
+	if t.synced {
+		gap := (sequence - t.expected + sequenceModulo) % sequenceModulo
+
+		// anything else means the device started counting over, there's no telling what we missed
+		if gap <= maxSequenceGap {
+			dropped = gap
+		}
+	}
+
+	t.expected = (sequence + 1) % sequenceModulo
+	t.synced = true
+
+	t.totalReceived++
+	t.totalDropped += dropped
+	t.windowReceived++
+	t.windowDropped += dropped
+
+	return dropped
+}
+
+// windowLoss returns the percentage of lines lost in the current window once it's complete, and starts a new one
+func (t *sequenceTracker) windowLoss() (float64, bool) {
+	expected := t.windowReceived + t.windowDropped
+	if expected < sequenceLossWindow {
+		return 0, false
+	}
+
+	loss := float64(t.windowDropped) / float64(expected) * 100
+
+	t.windowReceived = 0
+	t.windowDropped = 0
+
+	return loss, true
+}
+
+// checkSequence strips the line's sequence number (if it has one) and accounts for it. it also reports
+// whether the configured loss threshold has been exceeded, in which case the connection should be renewed
+func (sio *SerialIO) checkSequence(logger *zap.SugaredLogger, parser *lineParser, line string) (string, bool) {
+	sequence, line, ok := parser.parseSequencePrefix(line)
+	if !ok {
+		return line, false
+	}
+
+	if dropped := sio.sequence.observe(sequence); dropped > 0 {
+		logger.Warnw("Detected dropped lines",
+			"dropped", dropped,
+			"sequence", sequence,
+			"totalDropped", sio.sequence.totalDropped,
+			"totalReceived", sio.sequence.totalReceived)
+	}
+
+	loss, ok := sio.sequence.windowLoss()
+	if !ok {
+		return line, false
+	}
+
+	logger.Debugw("Line loss over the last window", "lossPercent", loss)
+
+	threshold := sio.deej.config.SequenceLossThreshold
+	if threshold <= 0 || loss < threshold {
+		return line, false
+	}
+
+	logger.Warnw("Line loss exceeded threshold", "lossPercent", loss, "threshold", threshold)
+
+	return line, true
+}
+
+// reconnectAfterLoss drops the current connection and opens a fresh one, hoping it fares better
+func (sio *SerialIO) reconnectAfterLoss(logger *zap.SugaredLogger) {
+	logger.Info("Renewing connection due to line loss")
+	sio.close(logger)
+
+	go func() {
+		<-time.After(sequenceReconnectDelay)
+
+		if sio.Connected() {
+			return
+		}
+
+		if err := sio.Start(); err != nil {
+			logger.Warnw("Failed to renew connection after line loss", "error", err)
+		} else {
+			logger.Debug("Renewed connection successfully")
+		}
+	}()
+}
@@ -0,0 +1,117 @@
+package deej
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSequenceTracker(t *testing.T) {
+	tests := []struct {
+		name      string
+		sequences []int
+		dropped   []int
+	}{
+		{
+			name:      "the first number only syncs up",
+			sequences: []int{17},
+			dropped:   []int{0},
+		},
+		{
+			name:      "lines in order",
+			sequences: []int{0, 1, 2},
+			dropped:   []int{0, 0, 0},
+		},
+		{
+			name:      "skipped numbers are lost lines",
+			sequences: []int{0, 1, 4, 5},
+			dropped:   []int{0, 0, 2, 0},
+		},
+		{
+			name:      "numbers wrap around",
+			sequences: []int{254, 255, 0, 1},
+			dropped:   []int{0, 0, 0, 0},
+		},
+		{
+			name:      "lines lost while wrapping around",
+			sequences: []int{254, 1},
+			dropped:   []int{0, 2},
+		},
+		{
+			name:      "a big jump is a restarted device",
+			sequences: []int{100, 0, 1},
+			dropped:   []int{0, 0, 0},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracker := newSequenceTracker()
+
+			dropped := []int{}
+			for _, sequence := range test.sequences {
+				dropped = append(dropped, tracker.observe(sequence))
+			}
+
+			if !reflect.DeepEqual(dropped, test.dropped) {
+				t.Errorf("dropped: got %v, want %v", dropped, test.dropped)
+			}
+		})
+	}
+}
+
+func TestSequenceWindowLoss(t *testing.T) {
+	tracker := newSequenceTracker()
+
+	// every other line goes missing
+	for sequence := 0; ; sequence += 2 {
+		tracker.observe(sequence % sequenceModulo)
+
+		loss, complete := tracker.windowLoss()
+		if !complete {
+			continue
+		}
+
+		if expected := sequence + 1; expected < sequenceLossWindow {
+			t.Fatalf("window complete after %d lines, want %d", expected, sequenceLossWindow)
+		}
+
+		if loss < 49 || loss > 51 {
+			t.Errorf("window loss: got %.1f%%, want about 50%%", loss)
+		}
+
+		break
+	}
+
+	if _, complete := tracker.windowLoss(); complete {
+		t.Errorf("window loss: a new window should start once one completes")
+	}
+}
+
+func TestParseSequencePrefix(t *testing.T) {
+	tests := []struct {
+		line     string
+		sequence int
+		rest     string
+		ok       bool
+	}{
+		{line: "@17:512|1023\r\n", sequence: 17, rest: "512|1023\r\n", ok: true},
+		{line: "@0:~1~0~\r\n", sequence: 0, rest: "~1~0~\r\n", ok: true},
+		{line: "@255:PONG\r\n", sequence: 255, rest: "PONG\r\n", ok: true},
+		{line: "512|1023\r\n", rest: "512|1023\r\n"},
+		{line: "@256:512\r\n", rest: "@256:512\r\n"},
+		{line: "@1234:512\r\n", rest: "@1234:512\r\n"},
+		{line: "@:512\r\n", rest: "@:512\r\n"},
+		{line: "@17512\r\n", rest: "@17512\r\n"},
+	}
+
+	parser := newLineParser(defaultSliderSeparator, defaultButtonPrefix)
+
+	for _, test := range tests {
+		sequence, rest, ok := parser.parseSequencePrefix(test.line)
+
+		if sequence != test.sequence || rest != test.rest || ok != test.ok {
+			t.Errorf("%q: got (%d, %q, %v), want (%d, %q, %v)",
+				test.line, sequence, rest, ok, test.sequence, test.rest, test.ok)
+		}
+	}
+}
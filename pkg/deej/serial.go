@@ -2,19 +2,18 @@ package deej
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
-	"regexp"
-	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jacobsa/go-serial/serial"
 	"go.uber.org/zap"
 
-	"github.com/micmonay/keybd_event"
-
 	"github.com/omriharel/deej/pkg/deej/util"
 )
 
@@ -26,18 +25,118 @@ type SerialIO struct {
 	deej   *Deej
 	logger *zap.SugaredLogger
 
-	stopChannel chan bool
 	connected   bool
+	connInfo    ConnectionInfo
 	connOptions serial.OpenOptions
 	conn        io.ReadWriteCloser
+	writeLock   sync.Locker
+
+	// every goroutine serving the current connection stops once its context is canceled, which happens
+	// whenever the connection goes away (for whatever reason). connLock makes sure that only happens once
+	connCtx    context.Context
+	cancelConn context.CancelFunc
+	connLock   sync.Locker
+
+	// hotplug, reconnects and config reloads may all try connecting at once, so they take turns
+	startLock sync.Locker
 
 	lastKnownNumSliders        int
 	currentSliderPercentValues []float32
 	lastKnownNumButtons        int
 	currentButtonValues        []int
+	lastSelectorPosition       int
+
+	// only the read loop changes slider values, but anyone may ask for them (i.e. the http api)
+	sliderValuesLock sync.Locker
+
+	// the mismatch between the config and the device the user was last told about, by kind of control.
+	// the counts come around again on every reload and reconnect, and the user only needs to hear about each once
+	warnedMismatches map[string][2]int
+
+	noiseCalibrator *noiseCalibrator
+
+	// sliders that only exist in software, moved through the tray or the HTTP API
+	virtualSliderValues map[int]float32
+	virtualSliderLock   sync.Locker
+	hotkeys             *virtualSliderHotkeys
+
+	// consumers may (un)subscribe from any goroutine, while events are being delivered
+	sliderMoveConsumers []*sliderMoveConsumer
+	buttonMoveConsumers []*buttonPressConsumer
+	consumerLock        sync.Locker
+
+	// outbound commands, and the device's replies to them (if it sends any)
+	commandQueue    chan outboundCommand
+	commandPolicy   string
+	commandCounters *queueCounters
+	commandAcks     chan commandAck
+
+	// built from the configured separators. a config reload swaps in a new one while the read loop runs
+	lineParser atomic.Value // *lineParser
+
+	// reused for every line's events, which consumers get copies of
+	sliderMoveEvents  []SliderMoveEvent
+	buttonPressEvents []ButtonPressEvent
+
+	// counts lines lost on the way, for firmware that numbers them
+	sequence *sequenceTracker
+
+	// keeps track of the device's answers to our pings
+	ping *pingTracker
+
+	// keeps track of when the device last said anything useful
+	silence *silenceTracker
+
+	// tell taps from holds and double presses, for buttons with actions for those
+	holds *buttonHoldTracker
+	taps  *buttonTapTracker
+
+	// keeps firing the actions of held buttons that should repeat
+	repeats *buttonRepeater
+
+	// holds back presses of buttons that are part of a chord
+	chords *chordTracker
+
+	// how often each button was pressed, for scripts
+	buttonStats *buttonStatsTracker
+
+	// which toggle buttons are on
+	toggles *toggleTracker
+
+	// records macros, when asked to
+	macroRecorder *macroRecorder
+
+	// lets delay steps know when to give up waiting
+	steps *stepWaiter
+
+	// watches sliders for signs of broken hardware
+	sliderHealth *sliderHealthMonitor
+
+	// shows the startup notification once, the first time we connect
+	startupNotice *startupNotice
+
+	// keeps bouncing switches from firing their actions several times
+	debouncer *buttonDebouncer
+
+	// which layer buttons are held
+	layers *layerTracker
+
+	// keeps buttons from firing again too soon
+	cooldowns *buttonCooldownTracker
 
-	sliderMoveConsumers []chan SliderMoveEvent
-	buttonMoveConsumers []chan ButtonPressEvent
+	// opens connections to the device, SerialIO itself unless tests say otherwise
+	connFactory connectionFactory
+
+	// whether the OS tells us about serial ports being plugged in, so we can wait for ours
+	hotplugWatching bool
+
+	// set from the command line, to record the device's traffic or replay a recording instead of connecting to it.
+	// the recorder is shared with the read loop, and replays pick up where they left off after reconnecting
+	recordPath     string
+	replayPath     string
+	recorder       *trafficRecorder
+	recorderLock   sync.Locker
+	replayPosition *replayPosition
 }
 
 // SliderMoveEvent represents a single slider move captured by deej
@@ -46,47 +145,262 @@ type SliderMoveEvent struct {
 	PercentValue float32
 }
 
+// ButtonPressEvent represents a single button value change captured by deej
 type ButtonPressEvent struct {
 	ButtonID      int
 	PreviousValue int
 	ButtonValue   int
 }
 
-var expectedLinePattern = regexp.MustCompile(`^\d{1,4}(\|\d{1,4})*\r\n$`)
-var buttonLinePattern = regexp.MustCompile(`^~\d(\~\d)*~\r\n$`) // ~1~ or ~0~ for 1 button values
-
 // NewSerialIO creates a SerialIO instance that uses the provided deej
 // instance's connection info to establish communications with the arduino chip
 func NewSerialIO(deej *Deej, logger *zap.SugaredLogger) (*SerialIO, error) {
 	logger = logger.Named("serial")
 
 	sio := &SerialIO{
-		deej:                deej,
-		logger:              logger,
-		stopChannel:         make(chan bool),
-		connected:           false,
-		conn:                nil,
-		sliderMoveConsumers: []chan SliderMoveEvent{},
-		buttonMoveConsumers: []chan ButtonPressEvent{},
+		deej:                 deej,
+		logger:               logger,
+		connLock:             &sync.Mutex{},
+		startLock:            &sync.Mutex{},
+		sliderValuesLock:     &sync.Mutex{},
+		connected:            false,
+		conn:                 nil,
+		writeLock:            &sync.Mutex{},
+		commandAcks:          make(chan commandAck, 1),
+		virtualSliderValues:  map[int]float32{},
+		virtualSliderLock:    &sync.Mutex{},
+		warnedMismatches:     map[string][2]int{},
+		sliderMoveConsumers:  []*sliderMoveConsumer{},
+		buttonMoveConsumers:  []*buttonPressConsumer{},
+		consumerLock:         &sync.Mutex{},
+		lastSelectorPosition: -1,
+		ping:                 newPingTracker(),
+		silence:              newSilenceTracker(),
+		holds:                newButtonHoldTracker(),
+		taps:                 newButtonTapTracker(),
+		repeats:              newButtonRepeater(),
+		buttonStats:          newButtonStatsTracker(),
+		toggles:              newToggleTracker(),
+		macroRecorder:        newMacroRecorder(logger),
+		startupNotice:        newStartupNotice(),
+		sliderHealth:         newSliderHealthMonitor(),
+		steps:                newStepWaiter(),
+		debouncer:            newButtonDebouncer(),
+		layers:               newLayerTracker(),
+		cooldowns:            newButtonCooldownTracker(),
+		chords:               newChordTracker(),
+		recorderLock:         &sync.Mutex{},
+		replayPosition:       &replayPosition{lock: &sync.Mutex{}},
 	}
 
+	commandSettings := deej.config.queues.settingsFor(queueCommands)
+	sio.commandQueue = make(chan outboundCommand, commandSettings.Size)
+	sio.commandPolicy = commandSettings.Policy
+	sio.commandCounters = deej.config.queues.open(queueCommands, sio, func() int { return len(sio.commandQueue) })
+
+	sio.connFactory = sio
+	sio.noiseCalibrator = newNoiseCalibrator(logger, deej.config)
+	sio.lineParser.Store(newLineParser(deej.config.SliderSeparator, deej.config.ButtonPrefix))
+	sio.hotkeys = newVirtualSliderHotkeys(sio, logger)
+
 	logger.Debug("Created serial i/o instance")
 
 	// respond to config changes
 	sio.setupOnConfigReload()
 
+	// if the read loop crashes or hangs, start over with a fresh connection
+	deej.supervisor.supervise(moduleSerial, serialHangTimeout, func(chan bool) {
+		sio.restart()
+	})
+
 	return sio, nil
 }
 
 // Start attempts to connect to our arduino chip
 func (sio *SerialIO) Start() error {
+	sio.startLock.Lock()
+	defer sio.startLock.Unlock()
 
 	// don't allow multiple concurrent connections
-	if sio.connected {
+	if sio.Connected() {
 		sio.logger.Warn("Already connected, can't start another without closing first")
 		return errors.New("serial: connection already active")
 	}
 
+	sio.connInfo = sio.deej.config.ConnectionInfo
+
+	var err error
+	var connName string
+
+	// start recording with the first connection, and keep going across reconnects
+	sio.recorderLock.Lock()
+	if sio.recordPath != "" && sio.recorder == nil {
+		if sio.recorder, err = newTrafficRecorder(sio.logger, sio.recordPath); err != nil {
+			sio.logger.Warnw("Failed to start recording serial traffic", "error", err)
+		}
+	}
+	sio.recorderLock.Unlock()
+
+	conn, connName, err := sio.connFactory.openConnection(sio.connInfo)
+	if err != nil {
+		return err
+	}
+
+	namedLogger := sio.logger.Named(connName)
+
+	namedLogger.Infow("Connected", "conn", conn)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	sio.open(ctx, cancel, conn)
+
+	// everything we send to the device goes through here, one line at a time
+	go sio.runCommandWriter(ctx, namedLogger)
+
+	// these are sent once the read loop below is running, since they may need acknowledging
+	go func() {
+
+		// ask the device to frame its lines, if the user wants that. firmware that doesn't support it will ignore us
+		if sio.deej.config.BinaryProtocol {
+			if err := sio.SendCommand(binaryProtocolRequest); err != nil {
+				namedLogger.Warnw("Failed to request framed protocol", "error", err)
+			}
+		}
+
+		// find out what we're talking to
+		sio.sendHandshakeProbe(namedLogger)
+	}()
+
+	// keep the device informed about volume and mapping changes, if it wants to know
+	go sio.runVolumeFeedback(ctx, namedLogger)
+	go sio.runDisplayFeedback(ctx, namedLogger)
+	go sio.runProfileFeedback(ctx, namedLogger)
+	go sio.runDiscordFeedback(ctx, namedLogger)
+
+	// let the user know we're up, if they want to
+	if sio.deej.config.StartupNotification {
+		go sio.notifyStartup(ctx, namedLogger)
+	}
+
+	// make sure the device is still with us, if it knows how to tell us
+	sio.ping.reset()
+	go sio.runPing(ctx, namedLogger)
+
+	// and that it hasn't gone quiet on us, if the user wants that
+	sio.silence.reset()
+	go sio.runSilenceWatchdog(ctx, namedLogger)
+
+	// a button held while the previous connection went away was never released
+	sio.cancelPendingButtonActions()
+	sio.debouncer.reset()
+	sio.layers.reset()
+	sio.cooldowns.reset()
+
+	// read lines until the connection goes away
+	go func() {
+		defer sio.deej.supervisor.guard(moduleSerial)
+
+		connReader := bufio.NewReader(conn)
+		lineChannel := sio.readLine(ctx, namedLogger, connReader)
+
+		sio.sequence = newSequenceTracker()
+
+		heartbeatTicker := time.NewTicker(moduleHeartbeatInterval)
+		defer heartbeatTicker.Stop()
+
+		for {
+
+			// if this loop hung and got replaced meanwhile, it should quietly go away once it wakes up
+			if ctx.Err() != nil {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-heartbeatTicker.C:
+				sio.deej.supervisor.heartbeat(moduleSerial)
+			case <-sio.debouncer.settled:
+				sio.applySettledButtons(namedLogger)
+			case line, ok := <-lineChannel:
+
+				// the reader gave up. unless we closed the connection ourselves, the device went away from under us
+				if !ok {
+					if ctx.Err() == nil {
+						sio.handleConnectionLost(namedLogger)
+					}
+
+					return
+				}
+
+				if !sio.processLine(namedLogger, line, time.Now()) {
+					return
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// processLine handles a single line read from the device, and returns false if the connection was given up on
+func (sio *SerialIO) processLine(logger *zap.SugaredLogger, line string, receivedAt time.Time) bool {
+	frame := line
+
+	// a config reload may swap the parser at any point, so stick with the same one for the whole line
+	parser := sio.lineParser.Load().(*lineParser)
+
+	line, lossExceeded := sio.checkSequence(logger, parser, line)
+	if lossExceeded {
+		sio.reconnectAfterLoss(logger)
+		return false
+	}
+
+	// this line is unsanitized, but guaranteed to end with CRLF (readLine makes sure of that, whatever
+	// the device uses). it may also have garbage instead of deej-formatted values, so we must check for that!
+	kind := parser.parse(line)
+
+	switch kind {
+	case lineUnknown:
+		return true
+
+	case linePong:
+		sio.silence.heard()
+		sio.handlePong(logger)
+		return true
+
+	case lineCommandAck:
+		sio.silence.heard()
+		sio.deliverCommandAck(parser.commandAck)
+		return true
+
+	case lineHandshake:
+		sio.silence.heard()
+
+		if err := sio.handleHandshakeReply(logger, parser.device); err != nil {
+			logger.Warnw("Refusing to use device", "error", err)
+			sio.close(logger)
+			return false
+		}
+
+		return true
+	}
+
+	sio.handleLine(logger, parser, kind)
+	sio.silence.heard()
+
+	if sio.deej.config.RelayRole == relayRoleLeader {
+		sio.deej.relay.forwardLine(line)
+	}
+
+	if sio.deej.config.EchoFrames {
+		sio.echoFrame(logger, frame, receivedAt)
+	}
+
+	return true
+}
+
+func (sio *SerialIO) openSerial() (io.ReadWriteCloser, error) {
+
 	// set minimum read size according to platform (0 for windows, 1 for linux)
 	// this prevents a rare bug on windows where serial reads get congested,
 	// resulting in significant lag
@@ -96,8 +410,8 @@ func (sio *SerialIO) Start() error {
 	}
 
 	sio.connOptions = serial.OpenOptions{
-		PortName:        sio.deej.config.ConnectionInfo.COMPort,
-		BaudRate:        uint(sio.deej.config.ConnectionInfo.BaudRate),
+		PortName:        sio.connInfo.COMPort,
+		BaudRate:        uint(sio.connInfo.BaudRate),
 		DataBits:        8,
 		StopBits:        1,
 		MinimumReadSize: uint(minimumReadSize),
@@ -108,55 +422,178 @@ func (sio *SerialIO) Start() error {
 		"baudRate", sio.connOptions.BaudRate,
 		"minReadSize", minimumReadSize)
 
-	var err error
-	sio.conn, err = serial.Open(sio.connOptions)
+	conn, err := serial.Open(sio.connOptions)
 	if err != nil {
 
 		// might need a user notification here, TBD
 		sio.logger.Warnw("Failed to open serial connection", "error", err)
-		return fmt.Errorf("open serial connection: %w", err)
+		return nil, fmt.Errorf("open serial connection: %w", err)
 	}
 
-	namedLogger := sio.logger.Named(strings.ToLower(sio.connOptions.PortName))
-
-	namedLogger.Infow("Connected", "conn", sio.conn)
-	sio.connected = true
+	return conn, nil
+}
 
-	// read lines or await a stop
-	go func() {
-		connReader := bufio.NewReader(sio.conn)
-		lineChannel := sio.readLine(namedLogger, connReader)
+func (sio *SerialIO) openHID() (io.ReadWriteCloser, error) {
+	sio.logger.Debugw("Attempting HID connection",
+		"devicePath", sio.connInfo.HIDDevicePath,
+		"vendorID", sio.connInfo.HIDVendorID,
+		"productID", sio.connInfo.HIDProductID)
 
-		for {
-			select {
-			case <-sio.stopChannel:
-				sio.close(namedLogger)
-			case line := <-lineChannel:
-				sio.handleLine(namedLogger, line)
-			}
-		}
-	}()
+	conn, err := openHID(sio.logger, sio.connInfo)
+	if err != nil {
+		sio.logger.Warnw("Failed to open HID connection", "error", err)
+		return nil, fmt.Errorf("open hid connection: %w", err)
+	}
 
-	return nil
+	return conn, nil
 }
 
-// Stop signals us to shut down our serial connection, if one is active
+// Stop shuts down our serial connection, if one is active. every goroutine serving it is stopped as well
 func (sio *SerialIO) Stop() {
-	if sio.connected {
+
+	// don't stop a connection while it's still being started
+	sio.startLock.Lock()
+	defer sio.startLock.Unlock()
+
+	if sio.Connected() {
 		sio.logger.Debug("Shutting down serial connection")
-		sio.stopChannel <- true
+		sio.close(sio.logger)
 	} else {
 		sio.logger.Debug("Not currently connected, nothing to stop")
 	}
 }
 
-// SubscribeToSliderMoveEvents returns an unbuffered channel that receives
-// a sliderMoveEvent struct every time a slider moves
+// stopRecording finishes up the traffic recording, if there is one
+func (sio *SerialIO) stopRecording() {
+	sio.recorderLock.Lock()
+	defer sio.recorderLock.Unlock()
+
+	if sio.recorder != nil {
+		sio.recorder.close()
+		sio.recorder = nil
+	}
+}
+
+// SubscribeToSliderMoveEvents returns a buffered channel that receives
+// a sliderMoveEvent struct every time a slider moves. if the channel isn't drained fast enough,
+// its oldest events are dropped (unless the slider_events queue is set up otherwise)
 func (sio *SerialIO) SubscribeToSliderMoveEvents() chan SliderMoveEvent {
-	ch := make(chan SliderMoveEvent)
-	sio.sliderMoveConsumers = append(sio.sliderMoveConsumers, ch)
+	consumer := newSliderMoveConsumer(sio.deej.config.queues)
 
-	return ch
+	sio.consumerLock.Lock()
+	sio.sliderMoveConsumers = append(sio.sliderMoveConsumers, consumer)
+	sio.consumerLock.Unlock()
+
+	return consumer.events
+}
+
+// UnsubscribeFromSliderMoveEvents stops delivering slider move events to a channel returned by
+// SubscribeToSliderMoveEvents, and closes it
+func (sio *SerialIO) UnsubscribeFromSliderMoveEvents(ch chan SliderMoveEvent) {
+	sio.consumerLock.Lock()
+	defer sio.consumerLock.Unlock()
+
+	for idx, consumer := range sio.sliderMoveConsumers {
+		if consumer.events == ch {
+			sio.sliderMoveConsumers = append(sio.sliderMoveConsumers[:idx:idx], sio.sliderMoveConsumers[idx+1:]...)
+			sio.deej.config.queues.close(queueSliderEvents, consumer)
+			close(ch)
+
+			return
+		}
+	}
+}
+
+// SubscribeToButtonPressEvents returns a buffered channel that receives a ButtonPressEvent struct
+// every time a button's value changes, whether it's pressed, released or (for pressure-sensitive buttons) pressed harder.
+// if the channel isn't drained fast enough, its oldest events are dropped (unless the button_events queue is set up otherwise)
+func (sio *SerialIO) SubscribeToButtonPressEvents() chan ButtonPressEvent {
+	consumer := newButtonPressConsumer(sio.deej.config.queues)
+
+	sio.consumerLock.Lock()
+	sio.buttonMoveConsumers = append(sio.buttonMoveConsumers, consumer)
+	sio.consumerLock.Unlock()
+
+	return consumer.events
+}
+
+// UnsubscribeFromButtonPressEvents stops delivering button events to a channel returned by
+// SubscribeToButtonPressEvents, and closes it
+func (sio *SerialIO) UnsubscribeFromButtonPressEvents(ch chan ButtonPressEvent) {
+	sio.consumerLock.Lock()
+	defer sio.consumerLock.Unlock()
+
+	for idx, consumer := range sio.buttonMoveConsumers {
+		if consumer.events == ch {
+			sio.buttonMoveConsumers = append(sio.buttonMoveConsumers[:idx:idx], sio.buttonMoveConsumers[idx+1:]...)
+			sio.deej.config.queues.close(queueButtonEvents, consumer)
+			close(ch)
+
+			return
+		}
+	}
+}
+
+// SetVirtualSliderValue moves a virtual slider (one that has no physical counterpart on the device)
+// to the given value between 0.0 and 1.0. this results in a regular slider move event
+func (sio *SerialIO) SetVirtualSliderValue(sliderID int, percentValue float32) error {
+	if !sio.deej.config.isVirtualSlider(sliderID) {
+		return fmt.Errorf("slider %d is not a virtual slider", sliderID+sio.deej.config.MappingIndexBase)
+	}
+
+	if percentValue < 0 || percentValue > 1 {
+		return fmt.Errorf("invalid slider value %.2f, must be between 0.0 and 1.0", percentValue)
+	}
+
+	normalizedScalar := util.NormalizeScalar(percentValue)
+
+	sio.virtualSliderLock.Lock()
+	sio.virtualSliderValues[sliderID] = normalizedScalar
+	sio.virtualSliderLock.Unlock()
+
+	moveEvent := SliderMoveEvent{
+		SliderID:     sliderID,
+		PercentValue: normalizedScalar,
+	}
+
+	sio.logger.Debugw("Virtual slider moved", "event", moveEvent)
+	sio.deliverSliderMoveEvents([]SliderMoveEvent{moveEvent})
+
+	return nil
+}
+
+// virtualSliderValue returns where a virtual slider is, and false if it was never moved
+func (sio *SerialIO) virtualSliderValue(sliderID int) (float32, bool) {
+	sio.virtualSliderLock.Lock()
+	defer sio.virtualSliderLock.Unlock()
+
+	value, ok := sio.virtualSliderValues[sliderID]
+
+	return value, ok
+}
+
+// SliderValues returns the current values of all physical sliders (by index),
+// along with those of all virtual sliders that were moved at least once
+func (sio *SerialIO) SliderValues() ([]float32, map[int]float32) {
+	sio.sliderValuesLock.Lock()
+	physical := make([]float32, len(sio.currentSliderPercentValues))
+	copy(physical, sio.currentSliderPercentValues)
+	sio.sliderValuesLock.Unlock()
+
+	sio.virtualSliderLock.Lock()
+	defer sio.virtualSliderLock.Unlock()
+
+	virtual := make(map[int]float32, len(sio.virtualSliderValues))
+	for sliderID, value := range sio.virtualSliderValues {
+		virtual[sliderID] = value
+	}
+
+	return physical, virtual
+}
+
+// ButtonStats returns how often each button was pressed so far, and when it was last pressed
+func (sio *SerialIO) ButtonStats() []ButtonStats {
+	return sio.buttonStats.all()
 }
 
 func (sio *SerialIO) setupOnConfigReload() {
@@ -168,6 +605,8 @@ func (sio *SerialIO) setupOnConfigReload() {
 		for {
 			select {
 			case <-configReloadedChannel:
+				sio.lineParser.Store(newLineParser(sio.deej.config.SliderSeparator, sio.deej.config.ButtonPrefix))
+				sio.hotkeys.update()
 
 				// make any config reload unset our slider number to ensure process volumes are being re-set
 				// (the next read line will emit SliderMoveEvent instances for all sliders)\
@@ -178,11 +617,11 @@ func (sio *SerialIO) setupOnConfigReload() {
 					<-time.After(stopDelay)
 					sio.lastKnownNumSliders = 0
 					sio.lastKnownNumButtons = 0
+					sio.resendVirtualSliders()
 				}()
 
 				// if connection params have changed, attempt to stop and start the connection
-				if sio.deej.config.ConnectionInfo.COMPort != sio.connOptions.PortName ||
-					uint(sio.deej.config.ConnectionInfo.BaudRate) != sio.connOptions.BaudRate {
+				if sio.deej.config.ConnectionInfo != sio.connInfo {
 
 					sio.logger.Info("Detected change in connection parameters, attempting to renew connection")
 					sio.Stop()
@@ -201,23 +640,164 @@ func (sio *SerialIO) setupOnConfigReload() {
 	}()
 }
 
+// resendVirtualSliders re-emits move events for all virtual sliders that still exist,
+// so they get applied to freshly acquired sessions just like their physical counterparts
+func (sio *SerialIO) resendVirtualSliders() {
+	sio.virtualSliderLock.Lock()
+
+	moveEvents := []SliderMoveEvent{}
+	for sliderID, value := range sio.virtualSliderValues {
+		if !sio.deej.config.isVirtualSlider(sliderID) {
+			delete(sio.virtualSliderValues, sliderID)
+			continue
+		}
+
+		moveEvents = append(moveEvents, SliderMoveEvent{SliderID: sliderID, PercentValue: value})
+	}
+
+	sio.virtualSliderLock.Unlock()
+
+	sio.deliverSliderMoveEvents(moveEvents)
+}
+
+// resendSlider emits a move event for a single slider at its current value, if it has one yet
+func (sio *SerialIO) resendSlider(sliderID int) {
+	physical, virtual := sio.SliderValues()
+
+	value, ok := virtual[sliderID]
+	if sliderID >= 0 && sliderID < len(physical) && physical[sliderID] >= 0 {
+		value, ok = physical[sliderID], true
+	}
+
+	if !ok {
+		return
+	}
+
+	sio.deliverSliderMoveEvents([]SliderMoveEvent{{SliderID: sliderID, PercentValue: value}})
+}
+
+func (sio *SerialIO) handleConnectionLost(logger *zap.SugaredLogger) {
+	logger.Warn("Lost connection to device")
+	sio.close(logger)
+
+	// wireless links come and go, so keep trying to get them back
+	if sio.connInfo.Type == connectionTypeBluetooth {
+		go sio.reconnectBluetooth(logger)
+	}
+
+	if sio.connInfo.Type == connectionTypeRelay {
+		go sio.reconnectRelay(logger)
+	}
+}
+
+// restart replaces the current connection (if any) with a fresh one
+func (sio *SerialIO) restart() {
+	sio.close(sio.logger)
+
+	if err := sio.Start(); err != nil {
+		sio.logger.Warnw("Failed to restart connection", "error", err)
+	}
+}
+
+// Connected returns whether there's an active connection to the device
+func (sio *SerialIO) Connected() bool {
+	sio.connLock.Lock()
+	defer sio.connLock.Unlock()
+
+	return sio.connected
+}
+
+// connection returns the current connection's context, or false if there's no active connection
+func (sio *SerialIO) connection() (context.Context, bool) {
+	sio.connLock.Lock()
+	defer sio.connLock.Unlock()
+
+	if !sio.connected || sio.connCtx == nil {
+		return nil, false
+	}
+
+	return sio.connCtx, true
+}
+
+// open makes a freshly opened connection the current one
+func (sio *SerialIO) open(ctx context.Context, cancel context.CancelFunc, conn io.ReadWriteCloser) {
+	sio.connLock.Lock()
+	defer sio.connLock.Unlock()
+
+	// writers check for a connection while holding the write lock
+	sio.writeLock.Lock()
+	sio.conn = conn
+	sio.connected = true
+	sio.writeLock.Unlock()
+
+	sio.connCtx, sio.cancelConn = ctx, cancel
+	sio.deej.refreshTray()
+}
+
+// close shuts down the current connection and everything serving it. it's safe to call more than once
 func (sio *SerialIO) close(logger *zap.SugaredLogger) {
+	sio.connLock.Lock()
+	defer sio.connLock.Unlock()
+
+	if !sio.connected {
+		return
+	}
+
+	sio.deej.supervisor.idle(moduleSerial)
+
+	// let every goroutine serving this connection know it's going away
+	sio.cancelConn()
+
 	if err := sio.conn.Close(); err != nil {
 		logger.Warnw("Failed to close serial connection", "error", err)
 	} else {
 		logger.Debug("Serial connection closed")
 	}
 
+	// make sure nobody's in the middle of writing to the connection we're discarding
+	sio.writeLock.Lock()
+	defer sio.writeLock.Unlock()
+
 	sio.conn = nil
 	sio.connected = false
+	sio.deej.refreshTray()
 }
 
-func (sio *SerialIO) readLine(logger *zap.SugaredLogger, reader *bufio.Reader) chan string {
-	ch := make(chan string)
+// readLine reads lines from the connection in the background, until reading fails or ctx is canceled.
+// the returned channel is closed once that happens. lines wait in the "lines" queue until they're handled
+func (sio *SerialIO) readLine(ctx context.Context, logger *zap.SugaredLogger, reader *bufio.Reader) chan string {
+	queues := sio.deej.config.queues
+
+	settings := queues.settingsFor(queueLines)
+	ch := make(chan string, settings.Size)
+	counters := queues.open(queueLines, reader, func() int { return len(ch) })
 
 	go func() {
+		framed := false
+		corruptFrames := 0
+
+		// lines end with LF unless the device is set up to end them with CR alone
+		delimiter := byte('\n')
+		if sio.connInfo.LineTerminator == lineTerminatorCR {
+			delimiter = '\r'
+		}
+
 		for {
-			line, err := reader.ReadString('\n')
+			line, isFrame, err := readFrameOrLine(reader, delimiter, framed)
+
+			// a garbled frame is dropped as a whole, no harm done
+			if errors.Is(err, errCorruptFrame) {
+				corruptFrames++
+				logger.Debugw("Dropped corrupt frame", "error", err, "totalDropped", corruptFrames)
+				continue
+			}
+
+			// and so is anything a framed device sends outside of its frames
+			if errors.Is(err, errUnframedOutput) {
+				logger.Debugw("Dropped unframed output", "error", err)
+				continue
+			}
+
 			if err != nil {
 
 				if sio.deej.Verbose() {
@@ -225,144 +805,386 @@ func (sio *SerialIO) readLine(logger *zap.SugaredLogger, reader *bufio.Reader) c
 				}
 
 				// just ignore the line, the read loop will stop after this
+				queues.close(queueLines, reader)
+				close(ch)
 				return
 			}
 
+			// from here on, all lines end with CRLF no matter how the device terminates them
+			line = strings.Trim(line, "\r\n") + "\r\n"
+
+			if isFrame && !framed {
+				logger.Info("Device switched to framed protocol")
+				framed = true
+			}
+
 			if sio.deej.Verbose() {
 				logger.Debugw("Read new line", "line", line)
 			}
 
-			// deliver the line to the channel
-			ch <- line
+			sio.recordLine(line)
+
+			// deliver the line to the channel, unless nobody's listening anymore
+			if !sio.queueLine(ctx, ch, line, settings.Policy, counters) {
+				queues.close(queueLines, reader)
+				close(ch)
+				return
+			}
 		}
 	}()
 
 	return ch
 }
 
-var KEY_MAPS = map[string]int{
-	// https://github.com/micmonay/keybd_event/blob/master/keybd_windows.go
-	"VK_MEDIA_NEXT_TRACK":    keybd_event.VK_MEDIA_NEXT_TRACK,
-	"VK_MEDIA_PREV_TRACK":    keybd_event.VK_MEDIA_PREV_TRACK,
-	"VK_MEDIA_STOP":          keybd_event.VK_MEDIA_STOP,
-	"VK_MEDIA_PLAY_PAUSE":    keybd_event.VK_MEDIA_PLAY_PAUSE,
-	"VK_LAUNCH_MEDIA_SELECT": keybd_event.VK_LAUNCH_MEDIA_SELECT,
-	"VK_VOLUME_MUTE":         keybd_event.VK_VOLUME_MUTE,
-	"VK_VOLUME_DOWN":         keybd_event.VK_VOLUME_DOWN,
-	"VK_VOLUME_UP":           keybd_event.VK_VOLUME_UP,
-	"VK_BROWSER_BACK":        keybd_event.VK_BROWSER_BACK,
-	"VK_BROWSER_FORWARD":     keybd_event.VK_BROWSER_FORWARD,
-	"VK_BROWSER_REFRESH":     keybd_event.VK_BROWSER_REFRESH,
-	"VK_BROWSER_STOP":        keybd_event.VK_BROWSER_STOP,
-	"VK_BROWSER_SEARCH":      keybd_event.VK_BROWSER_SEARCH,
-	"VK_BROWSER_FAVORITES":   keybd_event.VK_BROWSER_FAVORITES,
-	"VK_BROWSER_HOME":        keybd_event.VK_BROWSER_HOME,
+// queueLine hands a line over to the read loop according to the lines queue's policy. it returns false if
+// the read loop is gone, and the line (along with any that would follow it) can't be delivered
+func (sio *SerialIO) queueLine(ctx context.Context, ch chan string, line string, policy string, counters *queueCounters) bool {
+	select {
+	case ch <- line:
+		counters.queued(len(ch))
+		return true
+	case <-ctx.Done():
+		return false
+	default:
+	}
+
+	if policy == queuePolicyDropOldest {
+
+		// the read loop may have taken the oldest one in the meantime, in which case there's room anyway
+		select {
+		case <-ch:
+			atomic.AddUint64(&counters.dropped, 1)
+		default:
+		}
+
+		select {
+		case ch <- line:
+			counters.queued(len(ch))
+		default:
+			atomic.AddUint64(&counters.dropped, 1)
+		}
+
+		return true
+	}
+
+	atomic.AddUint64(&counters.blocked, 1)
+
+	select {
+	case ch <- line:
+		counters.queued(len(ch))
+		return true
+	case <-ctx.Done():
+		return false
+	}
 }
 
-func (sio *SerialIO) kbKeySimple(kb *keybd_event.KeyBonding, key string) error {
+// warnOnMappingMismatch lets the user know when their config refers to sliders or buttons that the
+// device doesn't have. off-by-one mappings are by far the most common first-time setup mistake.
+// the same mismatch is only brought up once, until it's fixed or changes
+func (sio *SerialIO) warnOnMappingMismatch(logger *zap.SugaredLogger, kind string, detected int, highestMapped int) {
+	if highestMapped < detected {
+		delete(sio.warnedMismatches, kind)
+		return
+	}
 
-	key_data, exists := KEY_MAPS[key]
-	if !exists {
-		return errors.New("Key not found")
+	mismatch := [2]int{detected, highestMapped}
+	if last, ok := sio.warnedMismatches[kind]; ok && last == mismatch {
+		return
 	}
 
-	kb.SetKeys(key_data)
+	sio.warnedMismatches[kind] = mismatch
 
-	return nil
+	logger.Warnw("Config maps more controls than the device reports",
+		"kind", kind,
+		"highestMappedIndex", highestMapped,
+		"detectedAmount", detected)
+
+	// speak in the same terms as the user's config
+	indexBase := sio.deej.config.MappingIndexBase
+
+	sio.deej.notifier.Notify(fmt.Sprintf("Config maps %s %d, but device reports %d!", kind, highestMapped+indexBase, detected),
+		fmt.Sprintf("%s indexes start at %d, so the highest usable one is %d. Check your configuration.",
+			strings.Title(kind), indexBase, detected-1+indexBase))
 }
 
+const (
+
+	// reverts the most recent volume change made by deej
+	deejActionUndo = "deej:undo"
+)
+
 func (sio *SerialIO) pressedButton(logger *zap.SugaredLogger, buttonEvent ButtonPressEvent) {
 	bindex := buttonEvent.ButtonID
-	logger.Debugw("pressedButton", "event", buttonEvent, "ButtonMapping.m[bindex]", sio.deej.config.ButtonMapping.m[bindex])
 
-	kb, err := keybd_event.NewKeyBonding()
-	if err != nil {
-		panic(err)
+	// pressure-sensitive buttons may have actions for the specific value they reached, these fire whenever
+	// the button changes to that value. otherwise, the button's regular actions fire when it's first pressed.
+	// going back to 0 is a release, see releasedButton
+	if buttonEvent.ButtonValue > 0 {
+		if actions, ok := sio.deej.config.ButtonValueMapping.get(bindex, buttonEvent.ButtonValue); ok {
+			sio.runButtonActions(logger, buttonEvent, actions)
+			return
+		}
 	}
 
-	for conf_ind, conf_key := range sio.deej.config.ButtonMapping.m[bindex] {
-		// logger.Debugw("pressedButton",
-		// 	"conf_ind", conf_ind,
-		// 	"conf_key", conf_key,
-		// )
-		// https://github.com/micmonay/keybd_event/blob/master/keybd_windows.go#L281
-		// send_key := "VK_MEDIA_PLAY_PAUSE"
-		// KEY_MAPS
-
-		key_err := err
-		if conf_key == "FORCE_REFRESH" {
-			kb.SetKeys(keybd_event.VK_F5)
-			kb.HasCTRL(true)
-		} else if conf_key == "WIN_MIC_MUTE_TOGGLE" {
-			kb.SetKeys(keybd_event.VK_K)
-			kb.HasSuper(true)
-			kb.HasALTGR(true)
-		} else {
-			key_err = sio.kbKeySimple(&kb, conf_key)
-			// logger.Debugw("kbKeySimple", "key_err", key_err)
+	// toggle buttons fire one of their two sets of actions instead
+	if toggle, ok := sio.deej.config.ToggleMapping.get(bindex); ok {
+		sio.toggledButton(logger, buttonEvent, toggle)
+		return
+	}
+
+	// unless it also has long or double press actions, in which case we have to wait and see what the user's doing
+	longPress, _ := sio.deej.config.LongPressMapping.get(bindex)
+	doublePress, _ := sio.deej.config.DoublePressMapping.get(bindex)
+
+	if len(longPress) > 0 || len(doublePress) > 0 {
+		sio.handleButtonGesture(logger, buttonEvent, longPress, doublePress)
+		return
+	}
+
+	// held buttons keep repeating until they're released, if the user wants them to
+	if buttonEvent.PreviousValue > 0 && buttonEvent.ButtonValue == 0 {
+		sio.repeats.stop(bindex)
+		return
+	}
+
+	if buttonEvent.PreviousValue != 0 || buttonEvent.ButtonValue == 0 {
+		return
+	}
+
+	actions, _ := sio.deej.config.ButtonMapping.get(bindex)
+	sio.runButtonActions(logger, buttonEvent, actions)
+
+	if len(actions) > 0 && sio.deej.config.repeatsButton(bindex) {
+		sio.repeats.start(buttonEvent, sio.deej.config.ButtonRepeatDelay, sio.deej.config.ButtonRepeatInterval, func(press ButtonPressEvent) {
+			sio.runButtonActions(logger, press, actions)
+		})
+	}
+}
+
+// releasedButton fires a button's release actions when it's let go of, i.e. to mute a mic that was unmuted on press
+func (sio *SerialIO) releasedButton(logger *zap.SugaredLogger, buttonEvent ButtonPressEvent) {
+	if buttonEvent.PreviousValue <= 0 || buttonEvent.ButtonValue != 0 {
+		return
+	}
+
+	if actions, ok := sio.deej.config.ReleaseMapping.get(buttonEvent.ButtonID); ok {
+		sio.runButtonActions(logger, buttonEvent, actions)
+	}
+
+	// switches may have actions for their resting position too
+	if actions, ok := sio.deej.config.ButtonValueMapping.get(buttonEvent.ButtonID, 0); ok {
+		sio.runButtonActions(logger, buttonEvent, actions)
+	}
+}
+
+// handleButtonGesture tells taps, holds and double presses apart. holds fire the long-press actions once the
+// button's been held long enough, and a second press soon enough after a tap fires the double-press ones
+func (sio *SerialIO) handleButtonGesture(logger *zap.SugaredLogger, buttonEvent ButtonPressEvent, longPress []buttonAction, doublePress []buttonAction) {
+	switch {
+	case buttonEvent.PreviousValue == 0 && buttonEvent.ButtonValue != 0:
+		if len(doublePress) > 0 && sio.taps.second(buttonEvent.ButtonID) {
+			logger.Debugw("Button double pressed", "button", buttonEvent.ButtonID)
+			sio.runButtonActions(logger, buttonEvent, doublePress)
+
+			return
 		}
 
-		if key_err != nil {
-			logger.Debugw("pressedButton invalid key",
-				"conf_ind", conf_ind,
-				"conf_key", conf_key,
-				"key_err", key_err,
-			)
+		// without long-press actions, there's no need to wait for the release to know it's a tap
+		if len(longPress) == 0 {
+			sio.tappedButton(logger, buttonEvent, doublePress)
+			return
 		}
 
+		sio.holds.press(buttonEvent, sio.deej.config.LongPressThreshold, func(press ButtonPressEvent) {
+			logger.Debugw("Button held", "button", press.ButtonID)
+			sio.runButtonActions(logger, press, longPress)
+		})
+
+	case buttonEvent.PreviousValue > 0 && buttonEvent.ButtonValue == 0:
+		if press, tapped := sio.holds.release(buttonEvent.ButtonID); tapped {
+			sio.tappedButton(logger, press, doublePress)
+		}
 	}
+}
 
-	// Press the selected keys
-	err = kb.Launching()
-	if err != nil {
-		panic(err)
+// tappedButton fires the regular actions for a tap. if the button has double-press actions, this waits
+// for the tap window to pass without a second press first, unless the user wants single presses immediately
+func (sio *SerialIO) tappedButton(logger *zap.SugaredLogger, press ButtonPressEvent, doublePress []buttonAction) {
+	actions, _ := sio.deej.config.ButtonMapping.get(press.ButtonID)
+
+	immediate := len(doublePress) == 0 || sio.deej.config.DoublePressImmediate
+	if immediate {
+		sio.runButtonActions(logger, press, actions)
+	}
+
+	if len(doublePress) == 0 {
+		return
 	}
+
+	sio.taps.wait(press, sio.deej.config.DoublePressWindow, func(press ButtonPressEvent) {
+		if !immediate {
+			sio.runButtonActions(logger, press, actions)
+		}
+	})
 }
 
-func (sio *SerialIO) handleButtons(logger *zap.SugaredLogger, line string) {
+func (sio *SerialIO) runButtonActions(logger *zap.SugaredLogger, buttonEvent ButtonPressEvent, actions []buttonAction) {
+	logger.Debugw("pressedButton", "event", buttonEvent, "actions", buttonActionNames(actions))
 
-	// trim the suffix
-	line = strings.TrimSuffix(line, "\r\n")
-	line = strings.TrimSuffix(line, "~")
-	line = strings.Trim(line, "~")
+	// unmapped buttons have nothing to do, don't bother with the keyboard
+	if len(actions) == 0 {
+		return
+	}
 
-	// logger.Debugw("raw button", "event", line)
+	// a macro being recorded takes note of whatever the device's buttons do meanwhile
+	sio.macroRecorder.capture(actions)
 
-	// split on ~, this gives a slice of numerical strings between "0" and "9"
-	splitLine := strings.Split(line, "~")
-	numSliders := len(splitLine)
+	sio.performButtonActions(logger, sio.deej.notifier, buttonEvent, actions)
+}
 
-	// logger.Debugw("raw button data",
-	// 	"splitLine", splitLine,
-	// 	"numSliders", numSliders,
-	// )
+// performSteps does what each of the given actions says in turn, and returns false if it was cancelled along the way
+func (sio *SerialIO) performSteps(logger *zap.SugaredLogger, stepsNotifier Notifier, buttonEvent ButtonPressEvent, actions []buttonAction, cancel chan bool) bool {
+	keys := &keyPresser{}
 
-	// update our slider count, if needed - this will send slider move events for all
-	if numSliders != sio.lastKnownNumButtons {
-		logger.Infow("Detected buttons", "amount", numSliders)
-		sio.lastKnownNumButtons = numSliders
-		sio.currentButtonValues = make([]int, numSliders)
+	for _, action := range actions {
 
-		// reset everything to be an impossible value to force the slider move event later
-		for idx := range sio.currentButtonValues {
-			sio.currentButtonValues[idx] = -1.0
+		// actions with their own OSD show it once they're done, instead of whatever they would show
+		osd, notifier, hasOSD := sio.actionOSD(logger, stepsNotifier, action)
+
+		// a panic (or whatever else drops pending actions) stops steps that are already running, too
+		if stepsCancelled(cancel) {
+			logger.Debugw("Dropping the rest of the button's actions", "event", buttonEvent)
+			return false
 		}
-	}
 
-	// for each slider:
-	moveEvents := []ButtonPressEvent{}
-	for sliderIdx, stringValue := range splitLine {
+		switch action.kind {
 
-		// convert string values to integers ("1023" -> 1023)
-		number, _ := strconv.Atoi(stringValue)
-		number = int(number)
+		// waiting is a step of its own
+		case buttonActionDelay:
+			select {
+			case <-cancel:
+				logger.Debugw("Dropping the rest of the button's actions", "event", buttonEvent)
+				return false
+			case <-time.After(action.delay):
+			}
 
-		// turns out the first line could come out dirty sometimes (i.e. "4558|925|41|643|220")
-		// so let's check the first number for correctness just in case
-		if sliderIdx == 0 && number > 9 {
-			sio.logger.Debugw("Got malformed line from serial, ignoring", "line", line)
-			return
+		// deej's own actions don't involve the keyboard at all
+		case buttonActionUndo:
+			sio.deej.sessions.undoLastChange()
+
+		case buttonActionPanic:
+			sio.panicButton(notifier)
+
+		case buttonActionRestore:
+			sio.restoreButton()
+
+		case buttonActionPauseToggle:
+			sio.pauseButton(logger, notifier)
+
+		case buttonActionCycleOutput:
+			sio.cycleOutputDevice(logger, notifier, action.outputDevices)
+
+		case buttonActionRoute:
+			sio.routeApp(logger, notifier, action.app, action.outputDevices)
+
+		case buttonActionSliderMute:
+			sio.muteSlider(logger, action.sliderIdx, action.muteMode)
+
+		case buttonActionVolumeStep:
+			sio.stepSliderVolume(logger, action.sliderIdx, action.volumeDelta)
+
+		case buttonActionCycleSliderTarget:
+			sio.cycleSliderTarget(logger, notifier, action.sliderIdx, action.targets)
+
+		case buttonActionMicMute:
+			sio.muteMic(logger, action.muteMode)
+
+		case buttonActionExec:
+			sio.runCommand(logger, action.command)
+
+		case buttonActionWebhook:
+			sio.callWebhook(logger, buttonEvent, action.webhook)
+
+		case buttonActionOBS:
+			sio.controlOBS(logger, notifier, action)
+
+		case buttonActionDiscord:
+			sio.controlDiscord(logger, notifier, action)
+
+		case buttonActionLaunch:
+			sio.launchOrFocus(logger, action.path)
+
+		case buttonActionProfile:
+			sio.switchProfile(logger, notifier, action.profile, action.bankOffset)
+
+		case buttonActionMacro:
+			if !sio.runMacro(logger, notifier, buttonEvent, action.macro, cancel) {
+				return false
+			}
+
+		case buttonActionRecordMacro:
+			sio.recordMacro(logger, notifier, action.buttonIdx)
+
+		case buttonActionSystemSounds:
+			if muted, err := sio.deej.sessions.setSystemSoundsMute(action.muteMode); err != nil {
+				logger.Warnw("Failed to mute system sounds", "mode", action.muteMode, "error", err)
+			} else {
+				logger.Infow("Changed system sounds mute", "muted", muted)
+			}
+
+		// so are the ones registered by whoever embeds deej
+		case buttonActionCustom:
+			if err := action.run(buttonEvent, action.argument); err != nil {
+				logger.Warnw("Custom action failed", "action", action.name, "error", err)
+			}
+
+		case buttonActionKey:
+			if err := keys.press(action); err != nil {
+				logger.Warnw("Failed to press key", "key", action.name, "error", err)
+			}
+
+		case buttonActionSystem:
+			sio.controlSystem(logger, notifier, action)
+
+		case buttonActionMedia:
+			sio.controlMedia(logger, keys, action)
+
+		case buttonActionType:
+			for _, keystroke := range action.keystrokes {
+				if stepsCancelled(cancel) {
+					logger.Debugw("Dropping the rest of the button's actions", "event", buttonEvent)
+					return false
+				}
+
+				if err := keys.press(keystroke); err != nil {
+					logger.Warnw("Failed to type text", "action", action.name, "error", err)
+					break
+				}
+			}
+		}
+
+		if hasOSD {
+			sio.showActionOSD(logger, osd)
 		}
+	}
+
+	return true
+}
+
+// handleButtons takes raw button values between 0 and 9
+func (sio *SerialIO) handleButtons(logger *zap.SugaredLogger, values []int) {
+	numSliders := len(values)
+
+	// update our slider count, if needed - this will send slider move events for all
+	if numSliders != sio.lastKnownNumButtons {
+		sio.resizeButtons(logger, numSliders)
+	}
+
+	now := time.Now()
+
+	// for each slider:
+	moveEvents := sio.buttonPressEvents[:0]
+	for sliderIdx, number := range values {
 
 		// logger.Debugw("button info",
 		// 	"sliderIdx", sliderIdx,
@@ -373,6 +1195,12 @@ func (sio *SerialIO) handleButtons(logger *zap.SugaredLogger, line string) {
 		// check if it changes the desired state (could just be a jumpy raw slider value)
 		if sio.currentButtonValues[sliderIdx] != number {
 
+			// the first value a button reports is never a bounce
+			if sio.currentButtonValues[sliderIdx] != -1 &&
+				!sio.debouncer.accept(sliderIdx, number, now, sio.deej.config.buttonDebounceTime(sliderIdx)) {
+				continue
+			}
+
 			moveEvents = append(moveEvents, ButtonPressEvent{
 				ButtonID:      sliderIdx,
 				PreviousValue: sio.currentButtonValues[sliderIdx],
@@ -384,91 +1212,110 @@ func (sio *SerialIO) handleButtons(logger *zap.SugaredLogger, line string) {
 			if sio.deej.Verbose() {
 				logger.Debugw("Button state changed", "event", moveEvents[len(moveEvents)-1])
 			}
+		} else {
+
+			// it bounced right back, so whatever it bounced to doesn't matter
+			sio.debouncer.unchanged(sliderIdx)
 		}
 	}
 
 	for _, moveEvent := range moveEvents {
+
+		// count presses before acting on them, so actions that care see their own press
 		if moveEvent.PreviousValue == 0 && moveEvent.ButtonValue != 0 {
-			sio.pressedButton(logger, moveEvent)
+			sio.buttonStats.pressed(moveEvent.ButtonID, now)
 		}
-	}
 
-	// TODO not properly implemented !!!!!!!
-	// // deliver move events if there are any, towards all potential consumers
-	// if len(moveEvents) > 0 {
-	// 	for _, consumer := range sio.buttonMoveConsumers {
-	// 		for _, moveEvent := range moveEvents {
-	// 			consumer <- moveEvent
-	// 		}
-	// 	}
-	// }
+		if sio.coolingDown(logger, moveEvent, now) {
+			continue
+		}
 
-	return
-}
+		if sio.handleLayerButton(logger, moveEvent) {
+			continue
+		}
 
-func (sio *SerialIO) handleLine(logger *zap.SugaredLogger, line string) {
+		if sio.handleChordButton(logger, moveEvent) {
+			continue
+		}
 
-	if buttonLinePattern.MatchString(line) {
-		sio.handleButtons(logger, line)
-		return
+		sio.pressedButton(logger, moveEvent)
+		sio.releasedButton(logger, moveEvent)
 	}
 
-	// this function receives an unsanitized line which is guaranteed to end with LF,
-	// but most lines will end with CRLF. it may also have garbage instead of
-	// deej-formatted values, so we must check for that! just ignore bad ones
-	if !expectedLinePattern.MatchString(line) {
-		return
-	}
+	// deliver button events if there are any, towards all potential consumers
+	sio.deliverButtonPressEvents(moveEvents)
+	sio.buttonPressEvents = moveEvents
+}
 
-	// trim the suffix
-	line = strings.TrimSuffix(line, "\r\n")
+// handleLine handles the values of a line the parser recognized as reporting sliders, buttons, encoders or the selector
+func (sio *SerialIO) handleLine(logger *zap.SugaredLogger, parser *lineParser, kind lineKind) {
+	switch kind {
+	case lineSliders:
+		sio.handleSliders(logger, parser.sliderValues)
 
-	// split on pipe (|), this gives a slice of numerical strings between "0" and "1023"
-	splitLine := strings.Split(line, "|")
-	numSliders := len(splitLine)
+	case lineButtons:
+		sio.handleButtons(logger, parser.buttonValues)
+
+	// sliders and buttons reported together, which keeps them in sync. handle each as if
+	// it came on its own line, sliders first
+	case lineCombined:
+		sio.handleSliders(logger, parser.sliderValues)
+		sio.handleButtons(logger, parser.buttonValues)
+
+	case lineEncoders:
+		sio.handleEncoders(logger, parser.encoderTicks)
+
+	case lineSelector:
+		sio.handleSelector(logger, parser.selectorPosition)
+	}
+}
+
+// handleSliders takes raw slider values between 0 and the max analog value ("1023" unless configured otherwise)
+func (sio *SerialIO) handleSliders(logger *zap.SugaredLogger, values []int) {
+	numSliders := len(values)
 
 	// update our slider count, if needed - this will send slider move events for all
 	if numSliders != sio.lastKnownNumSliders {
-		logger.Infow("Detected sliders", "amount", numSliders)
-		sio.lastKnownNumSliders = numSliders
-		sio.currentSliderPercentValues = make([]float32, numSliders)
-
-		// reset everything to be an impossible value to force the slider move event later
-		for idx := range sio.currentSliderPercentValues {
-			sio.currentSliderPercentValues[idx] = -1.0
-		}
+		sio.resizeSliders(logger, numSliders)
 	}
 
-	// for each slider:
-	moveEvents := []SliderMoveEvent{}
-	for sliderIdx, stringValue := range splitLine {
+	maxAnalogValue := sio.deej.config.MaxAnalogValue
 
-		// convert string values to integers ("1023" -> 1023)
-		number, _ := strconv.Atoi(stringValue)
+	// for each slider:
+	moveEvents := sio.sliderMoveEvents[:0]
+	for sliderIdx, number := range values {
 
 		// turns out the first line could come out dirty sometimes (i.e. "4558|925|41|643|220")
 		// so let's check the first number for correctness just in case
-		if sliderIdx == 0 && number > 1023 {
-			sio.logger.Debugw("Got malformed line from serial, ignoring", "line", line)
+		if sliderIdx == 0 && number > maxAnalogValue {
+			sio.logger.Debugw("Got malformed line from serial, ignoring", "values", values)
 			return
 		}
 
 		// map the value from raw to a "dirty" float between 0 and 1 (e.g. 0.15451...)
-		dirtyFloat := float32(number) / 1023.0
+		dirtyFloat := float32(number) / float32(maxAnalogValue)
 
 		// normalize it to an actual volume scalar between 0.0 and 1.0 with 2 points of precision
 		normalizedScalar := util.NormalizeScalar(dirtyFloat)
 
+		if sio.deej.config.NoiseReductionLevel == noiseReductionAuto {
+			sio.noiseCalibrator.observe(sliderIdx, dirtyFloat)
+		}
+
+		noiseThreshold := sio.noiseThreshold(sliderIdx)
+
 		// if sliders are inverted, take the complement of 1.0
 		if sio.deej.config.InvertSliders {
 			normalizedScalar = 1 - normalizedScalar
 		}
 
 		// check if it changes the desired state (could just be a jumpy raw slider value)
-		if util.SignificantlyDifferent(sio.currentSliderPercentValues[sliderIdx], normalizedScalar, sio.deej.config.NoiseReductionLevel) {
+		if util.SignificantlyDifferentWithThreshold(sio.currentSliderPercentValues[sliderIdx], normalizedScalar, noiseThreshold) {
 
 			// if it does, update the saved value and create a move event
+			sio.sliderValuesLock.Lock()
 			sio.currentSliderPercentValues[sliderIdx] = normalizedScalar
+			sio.sliderValuesLock.Unlock()
 
 			moveEvents = append(moveEvents, SliderMoveEvent{
 				SliderID:     sliderIdx,
@@ -481,11 +1328,87 @@ func (sio *SerialIO) handleLine(logger *zap.SugaredLogger, line string) {
 		}
 	}
 
-	// deliver move events if there are any, towards all potential consumers
+	// look at the raw values for broken sliders, noise and all
+	sio.checkSliderHealth(logger, values)
+
+	sio.deliverSliderMoveEvents(moveEvents)
+	sio.sliderMoveEvents = moveEvents
+}
+
+// noiseThreshold returns how much a slider's value has to change to count as moving it.
+// auto noise reduction picks its own threshold for each slider
+func (sio *SerialIO) noiseThreshold(sliderIdx int) float64 {
+	if sio.deej.config.NoiseReductionLevel == noiseReductionAuto {
+		return sio.noiseCalibrator.threshold(sliderIdx)
+	}
+
+	return util.NoiseReductionThreshold(sio.deej.config.NoiseReductionLevel)
+}
+
+func (sio *SerialIO) resizeSliders(logger *zap.SugaredLogger, numSliders int) {
+	logger.Infow("Detected sliders", "amount", numSliders)
+	sio.lastKnownNumSliders = numSliders
+	sio.warnOnMappingMismatch(logger, "slider", numSliders,
+		sio.deej.config.SliderMapping.highestIndex(sio.deej.config.VirtualSliders))
+	// reset everything to be an impossible value to force the slider move event later
+	values := make([]float32, numSliders)
+	for idx := range values {
+		values[idx] = -1.0
+	}
+
+	sio.sliderValuesLock.Lock()
+	sio.currentSliderPercentValues = values
+	sio.sliderValuesLock.Unlock()
+
+	if sio.deej.config.NoiseReductionLevel == noiseReductionAuto {
+		sio.noiseCalibrator.reset(numSliders)
+	}
+
+	sio.sliderHealth.reset(numSliders)
+}
+
+func (sio *SerialIO) resizeButtons(logger *zap.SugaredLogger, numButtons int) {
+	logger.Infow("Detected buttons", "amount", numButtons)
+	sio.lastKnownNumButtons = numButtons
+	sio.warnOnMappingMismatch(logger, "button", numButtons, sio.deej.config.ButtonMapping.highestIndex())
+	sio.currentButtonValues = make([]int, numButtons)
+	sio.cancelPendingButtonActions()
+	sio.debouncer.reset()
+	sio.layers.reset()
+	sio.cooldowns.reset()
+
+	// reset everything to be an impossible value to force the button press event later
+	for idx := range sio.currentButtonValues {
+		sio.currentButtonValues[idx] = -1.0
+	}
+}
+
+// deliverSliderMoveEvents sends move events, if there are any, towards all potential consumers
+func (sio *SerialIO) deliverSliderMoveEvents(moveEvents []SliderMoveEvent) {
 	if len(moveEvents) > 0 {
+		sio.consumerLock.Lock()
+		defer sio.consumerLock.Unlock()
+
 		for _, consumer := range sio.sliderMoveConsumers {
 			for _, moveEvent := range moveEvents {
-				consumer <- moveEvent
+				if dropped := consumer.offer(moveEvent); dropped > 0 {
+					logDroppedEvent(sio.logger, "slider move", dropped)
+				}
+			}
+		}
+	}
+}
+
+func (sio *SerialIO) deliverButtonPressEvents(pressEvents []ButtonPressEvent) {
+	if len(pressEvents) > 0 {
+		sio.consumerLock.Lock()
+		defer sio.consumerLock.Unlock()
+
+		for _, consumer := range sio.buttonMoveConsumers {
+			for _, pressEvent := range pressEvents {
+				if dropped := consumer.offer(pressEvent); dropped > 0 {
+					logDroppedEvent(sio.logger, "button press", dropped)
+				}
 			}
 		}
 	}
@@ -0,0 +1,283 @@
+package deej
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// how long the pipeline has to go quiet before a test decides every event has arrived
+const testQuietPeriod = 200 * time.Millisecond
+
+type testNotifier struct{}
+
+func (testNotifier) Notify(title string, message string) {}
+
+// pipeConnection is an in-memory device. whatever the test writes to the other end of the pipe is
+// read by deej, and whatever deej sends the device is thrown away
+type pipeConnection struct {
+	*io.PipeReader
+}
+
+func (pc pipeConnection) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// pipeFactory hands SerialIO a pipeConnection, and keeps the device's end of it for the test
+type pipeFactory struct {
+	device *io.PipeWriter
+}
+
+func (f *pipeFactory) openConnection(info ConnectionInfo) (io.ReadWriteCloser, string, error) {
+	reader, writer := io.Pipe()
+	f.device = writer
+
+	return pipeConnection{reader}, "pipe", nil
+}
+
+// newTestSerialIO sets up just enough of deej to run the line handling pipeline, with config read from
+// the given yaml (on top of the usual defaults) and the device connected through an in-memory pipe
+func newTestSerialIO(t testing.TB, configYAML string) (*SerialIO, *pipeFactory) {
+	t.Helper()
+
+	logger := zap.NewNop().Sugar()
+	config := newTestConfig(t, configYAML)
+
+	if err := config.populateFromVipers(); err != nil {
+		t.Fatalf("populate config: %v", err)
+	}
+
+	d := &Deej{
+		logger:      logger,
+		notifier:    testNotifier{},
+		config:      config,
+		supervisor:  newSupervisor(logger),
+		stopChannel: make(chan bool),
+	}
+
+	sio, err := NewSerialIO(d, logger)
+	if err != nil {
+		t.Fatalf("create serial i/o: %v", err)
+	}
+
+	factory := &pipeFactory{}
+	sio.connFactory = factory
+
+	return sio, factory
+}
+
+// collectEvents gathers slider and button events until none have arrived for a while
+func collectEvents(sliderEvents chan SliderMoveEvent, buttonEvents chan ButtonPressEvent) ([]SliderMoveEvent, []ButtonPressEvent) {
+	sliderMoves := []SliderMoveEvent{}
+	buttonPresses := []ButtonPressEvent{}
+
+	for {
+		select {
+		case event := <-sliderEvents:
+			sliderMoves = append(sliderMoves, event)
+		case event := <-buttonEvents:
+			buttonPresses = append(buttonPresses, event)
+		case <-time.After(testQuietPeriod):
+			return sliderMoves, buttonPresses
+		}
+	}
+}
+
+func TestLinePipeline(t *testing.T) {
+	tests := []struct {
+		name          string
+		config        string
+		lines         []string
+		sliderMoves   []SliderMoveEvent
+		buttonPresses []ButtonPressEvent
+	}{
+		{
+			name:  "slider values map to volume",
+			lines: []string{"0|1023|512"},
+			sliderMoves: []SliderMoveEvent{
+				{SliderID: 0, PercentValue: 0},
+				{SliderID: 1, PercentValue: 1},
+				{SliderID: 2, PercentValue: 0.5},
+			},
+		},
+		{
+			name:   "inverted sliders",
+			config: "invert_sliders: true",
+			lines:  []string{"0|1023"},
+			sliderMoves: []SliderMoveEvent{
+				{SliderID: 0, PercentValue: 1},
+				{SliderID: 1, PercentValue: 0},
+			},
+		},
+		{
+			name:  "lines may end with LF alone",
+			lines: []string{"1023\n"},
+			sliderMoves: []SliderMoveEvent{
+				{SliderID: 0, PercentValue: 1},
+			},
+		},
+		{
+			name:  "malformed lines are ignored",
+			lines: []string{"garbage", "12|ab|3", "~1~x~", "123456", "^+1", "#2", "|", "~"},
+		},
+		{
+			name:  "first value out of range drops the whole line",
+			lines: []string{"4558|925|41"},
+		},
+		{
+			name:   "noise below the default threshold is ignored",
+			config: "max_analog_value: 1000",
+			lines:  []string{"505|505", "525|515", "605|505"},
+			sliderMoves: []SliderMoveEvent{
+				{SliderID: 0, PercentValue: 0.5},
+				{SliderID: 1, PercentValue: 0.5},
+				{SliderID: 0, PercentValue: 0.6},
+			},
+		},
+		{
+			name:   "noise reduction high ignores larger changes",
+			config: "max_analog_value: 1000\nnoise_reduction: high",
+			lines:  []string{"505", "535"},
+			sliderMoves: []SliderMoveEvent{
+				{SliderID: 0, PercentValue: 0.5},
+			},
+		},
+		{
+			name:   "noise reduction low lets smaller changes through",
+			config: "max_analog_value: 1000\nnoise_reduction: low",
+			lines:  []string{"505", "525"},
+			sliderMoves: []SliderMoveEvent{
+				{SliderID: 0, PercentValue: 0.5},
+				{SliderID: 0, PercentValue: 0.52},
+			},
+		},
+		{
+			name:   "small changes still snap to the edges",
+			config: "max_analog_value: 1000",
+			lines:  []string{"25", "5", "985", "1000"},
+			sliderMoves: []SliderMoveEvent{
+				{SliderID: 0, PercentValue: 0.02},
+				{SliderID: 0, PercentValue: 0},
+				{SliderID: 0, PercentValue: 0.98},
+				{SliderID: 0, PercentValue: 1},
+			},
+		},
+		{
+			name:  "button transitions",
+			lines: []string{"~0~0~", "~1~0~", "~1~0~", "~0~1~"},
+			buttonPresses: []ButtonPressEvent{
+				{ButtonID: 0, PreviousValue: -1, ButtonValue: 0},
+				{ButtonID: 1, PreviousValue: -1, ButtonValue: 0},
+				{ButtonID: 0, PreviousValue: 0, ButtonValue: 1},
+				{ButtonID: 0, PreviousValue: 1, ButtonValue: 0},
+				{ButtonID: 1, PreviousValue: 0, ButtonValue: 1},
+			},
+		},
+		{
+			name:  "pressure-sensitive buttons report every value",
+			lines: []string{"~0~", "~3~", "~7~", "~0~"},
+			buttonPresses: []ButtonPressEvent{
+				{ButtonID: 0, PreviousValue: -1, ButtonValue: 0},
+				{ButtonID: 0, PreviousValue: 0, ButtonValue: 3},
+				{ButtonID: 0, PreviousValue: 3, ButtonValue: 7},
+				{ButtonID: 0, PreviousValue: 7, ButtonValue: 0},
+			},
+		},
+		{
+			name:   "bouncing buttons settle on their last value",
+			config: "button_debounce_time: 50",
+			lines:  []string{"~0~", "~1~", "~0~", "~1~", "~0~"},
+			buttonPresses: []ButtonPressEvent{
+				{ButtonID: 0, PreviousValue: -1, ButtonValue: 0},
+				{ButtonID: 0, PreviousValue: 0, ButtonValue: 1},
+				{ButtonID: 0, PreviousValue: 1, ButtonValue: 0},
+			},
+		},
+		{
+			name:  "combined lines",
+			lines: []string{"1023|0~0~", "1023|0~1"},
+			sliderMoves: []SliderMoveEvent{
+				{SliderID: 0, PercentValue: 1},
+				{SliderID: 1, PercentValue: 0},
+			},
+			buttonPresses: []ButtonPressEvent{
+				{ButtonID: 0, PreviousValue: -1, ButtonValue: 0},
+				{ButtonID: 0, PreviousValue: 0, ButtonValue: 1},
+			},
+		},
+		{
+			name:  "sequence numbers are stripped",
+			lines: []string{"@0:1023|0", "@1:~1~"},
+			sliderMoves: []SliderMoveEvent{
+				{SliderID: 0, PercentValue: 1},
+				{SliderID: 1, PercentValue: 0},
+			},
+			buttonPresses: []ButtonPressEvent{
+				{ButtonID: 0, PreviousValue: -1, ButtonValue: 1},
+			},
+		},
+		{
+			name:  "the device's answers aren't values",
+			lines: []string{"PONG", "ACK", "NACK:busy", "@2:PONG"},
+		},
+		{
+			name:   "custom separators",
+			config: "slider_separator: \",\"\nbutton_prefix: \"!\"",
+			lines:  []string{"1023,0", "!1!", "0|1023", "~0~"},
+			sliderMoves: []SliderMoveEvent{
+				{SliderID: 0, PercentValue: 1},
+				{SliderID: 1, PercentValue: 0},
+			},
+			buttonPresses: []ButtonPressEvent{
+				{ButtonID: 0, PreviousValue: -1, ButtonValue: 1},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sio, factory := newTestSerialIO(t, test.config)
+
+			sliderEvents := sio.SubscribeToSliderMoveEvents()
+			buttonEvents := sio.SubscribeToButtonPressEvents()
+
+			if err := sio.Start(); err != nil {
+				t.Fatalf("start: %v", err)
+			}
+
+			defer sio.Stop()
+
+			for _, line := range test.lines {
+				if !strings.HasSuffix(line, "\n") {
+					line += "\r\n"
+				}
+
+				if _, err := io.WriteString(factory.device, line); err != nil {
+					t.Fatalf("write line %q: %v", line, err)
+				}
+			}
+
+			sliderMoves, buttonPresses := collectEvents(sliderEvents, buttonEvents)
+
+			if test.sliderMoves == nil {
+				test.sliderMoves = []SliderMoveEvent{}
+			}
+
+			if test.buttonPresses == nil {
+				test.buttonPresses = []ButtonPressEvent{}
+			}
+
+			if !reflect.DeepEqual(sliderMoves, test.sliderMoves) {
+				t.Errorf("slider moves: got %v, want %v", sliderMoves, test.sliderMoves)
+			}
+
+			if !reflect.DeepEqual(buttonPresses, test.buttonPresses) {
+				t.Errorf("button presses: got %v, want %v", buttonPresses, test.buttonPresses)
+			}
+		})
+	}
+}
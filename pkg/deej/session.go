@@ -17,6 +17,18 @@ type Session interface {
 
 	Key() string
 	Release()
+
+	// DisplayName returns a human-friendly name for the session, meant for UIs and device displays
+	DisplayName() string
+
+	// IconPath returns where to find an icon for the session (a file path, or an icon name on linux).
+	// empty if the audio system doesn't know about one
+	IconPath() string
+}
+
+// muteSession is implemented by sessions that can tell whether they're muted
+type muteSession interface {
+	GetMute() bool
 }
 
 const (
@@ -39,6 +51,10 @@ type baseSession struct {
 
 	// used by String(), needs to be set by child
 	humanReadableDesc string
+
+	// used by DisplayName() and IconPath(), optionally set by child
+	displayName string
+	iconPath    string
 }
 
 func (s *baseSession) Key() string {
@@ -52,3 +68,31 @@ func (s *baseSession) Key() string {
 
 	return strings.ToLower(s.name)
 }
+
+func (s *baseSession) DisplayName() string {
+	if s.displayName != "" {
+		return s.displayName
+	}
+
+	// better than nothing
+	return strings.TrimSuffix(s.name, ".exe")
+}
+
+func (s *baseSession) IconPath() string {
+	return s.iconPath
+}
+
+// masterDisplayName gives the special master, mic and loopback sessions a friendlier name.
+// device sessions are already named after their friendly names, so they're left as is
+func masterDisplayName(key string) string {
+	switch key {
+	case masterSessionName:
+		return "Master volume"
+	case inputSessionName:
+		return "Microphone"
+	case loopbackSessionName:
+		return "Desktop audio capture"
+	}
+
+	return key
+}
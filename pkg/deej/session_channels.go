@@ -0,0 +1,155 @@
+package deej
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// channel targets address a single channel of a multichannel device, i.e. "device:Speakers:FL".
+// this lets builds with surround setups balance their channels with sliders. the device part matches
+// a device session's key either exactly or by its leading part, so "speakers" matches "speakers (realtek audio)".
+// the channel part is one of the channel names below, or a raw 0-based channel index
+const channelTargetPrefix = "device:"
+
+const (
+	channelFrontLeft   = "fl"
+	channelFrontRight  = "fr"
+	channelFrontCenter = "fc"
+	channelLFE         = "lfe"
+	channelBackLeft    = "bl"
+	channelBackRight   = "br"
+	channelSideLeft    = "sl"
+	channelSideRight   = "sr"
+)
+
+// alternative names people tend to use for the same channels
+var channelNameAliases = map[string]string{
+	"rl":  channelBackLeft,
+	"rr":  channelBackRight,
+	"c":   channelFrontCenter,
+	"sub": channelLFE,
+}
+
+// channelVolumeSession is implemented by sessions that can control their channels individually
+type channelVolumeSession interface {
+	Session
+
+	channelCount() int
+
+	// channelIndex maps a (normalized) channel name to its index on this session's device
+	channelIndex(channelName string) (int, bool)
+
+	getChannelVolume(channel int) float32
+	setChannelVolume(channel int, v float32) error
+}
+
+// channelSession adapts a single channel of a multichannel session to the regular Session interface
+type channelSession struct {
+	parent  channelVolumeSession
+	channel int
+
+	// the (lowercase) target this was resolved from, so it can be looked up again later
+	key         string
+	channelName string
+}
+
+func (s *channelSession) GetVolume() float32 {
+	return s.parent.getChannelVolume(s.channel)
+}
+
+func (s *channelSession) SetVolume(v float32) error {
+	return s.parent.setChannelVolume(s.channel, v)
+}
+
+func (s *channelSession) Key() string {
+	return s.key
+}
+
+// the parent owns the underlying audio objects, so there's nothing to release here
+func (s *channelSession) Release() {}
+
+func (s *channelSession) DisplayName() string {
+	return fmt.Sprintf("%s (%s)", s.parent.DisplayName(), strings.ToUpper(s.channelName))
+}
+
+func (s *channelSession) IconPath() string {
+	return s.parent.IconPath()
+}
+
+func (s *channelSession) String() string {
+	return fmt.Sprintf(sessionStringFormat, s.DisplayName(), s.GetVolume())
+}
+
+// parseChannelTarget splits a (lowercase) channel target into its device and channel parts
+func parseChannelTarget(target string) (string, string, bool) {
+	if !strings.HasPrefix(target, channelTargetPrefix) {
+		return "", "", false
+	}
+
+	target = strings.TrimPrefix(target, channelTargetPrefix)
+
+	// device names may contain colons themselves, but channel names never do
+	separatorIdx := strings.LastIndex(target, ":")
+	if separatorIdx <= 0 || separatorIdx == len(target)-1 {
+		return "", "", false
+	}
+
+	channelName := target[separatorIdx+1:]
+	if alias, ok := channelNameAliases[channelName]; ok {
+		channelName = alias
+	}
+
+	return target[:separatorIdx], channelName, true
+}
+
+// channelSessions returns a channel session for each multichannel session matching a channel target
+func (m *sessionMap) channelSessions(target string) ([]Session, bool) {
+	deviceName, channelName, ok := parseChannelTarget(target)
+	if !ok {
+		return nil, false
+	}
+
+	result := []Session{}
+
+	for _, session := range m.snapshot() {
+		multichannel, ok := session.(channelVolumeSession)
+		if !ok {
+			continue
+		}
+
+		key := session.Key()
+		if key != deviceName && !strings.HasPrefix(key, deviceName+" (") {
+			continue
+		}
+
+		channel, ok := resolveChannelIndex(multichannel, channelName)
+		if !ok {
+			m.logger.Debugw("Device doesn't have the requested channel",
+				"device", key,
+				"channel", channelName,
+				"channelCount", multichannel.channelCount())
+
+			continue
+		}
+
+		result = append(result, &channelSession{
+			parent:      multichannel,
+			channel:     channel,
+			key:         target,
+			channelName: channelName,
+		})
+	}
+
+	return result, len(result) > 0
+}
+
+func resolveChannelIndex(session channelVolumeSession, channelName string) (int, bool) {
+
+	// raw channel indexes work for any layout
+	if channel, err := strconv.Atoi(channelName); err == nil {
+		return channel, channel >= 0 && channel < session.channelCount()
+	}
+
+	return session.channelIndex(channelName)
+}
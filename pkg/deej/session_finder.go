@@ -6,3 +6,26 @@ type SessionFinder interface {
 
 	Release() error
 }
+
+// OutputDevice is an audio output device, which can be made the system's default
+type OutputDevice struct {
+	ID   string
+	Name string
+}
+
+// outputDeviceSwitcher is implemented by session finders that can change the default output device
+type outputDeviceSwitcher interface {
+	OutputDevices() ([]OutputDevice, error)
+	DefaultOutputDevice() (string, error)
+	SetDefaultOutputDevice(id string) error
+}
+
+// captureWatcher is implemented by session finders that can tell which apps are recording from an input device
+type captureWatcher interface {
+	CapturingApps() ([]string, error)
+}
+
+// appRouter is implemented by session finders that can move a single app to another output device
+type appRouter interface {
+	RouteSession(session Session, deviceID string) error
+}
@@ -65,6 +65,14 @@ func (sf *paSessionFinder) GetAllSessions() ([]Session, error) {
 		sf.logger.Warnw("Failed to get master audio source session", "error", err)
 	}
 
+	// get the loopback session (the default sink's monitor source)
+	loopback, err := sf.getLoopbackSession()
+	if err == nil {
+		sessions = append(sessions, loopback)
+	} else {
+		sf.logger.Warnw("Failed to get loopback audio session", "error", err)
+	}
+
 	// enumerate sink inputs and add sessions along the way
 	if err := sf.enumerateAndAddSessions(&sessions); err != nil {
 		sf.logger.Warnw("Failed to enumerate audio sessions", "error", err)
@@ -97,7 +105,7 @@ func (sf *paSessionFinder) getMasterSinkSession() (Session, error) {
 	}
 
 	// create the master sink session
-	sink := newMasterSession(sf.sessionLogger, sf.client, reply.SinkIndex, reply.Channels, true)
+	sink := newMasterSession(sf.sessionLogger, sf.client, reply.SinkIndex, reply.Channels, reply.ChannelMap, true, masterSessionName)
 
 	return sink, nil
 }
@@ -114,11 +122,41 @@ func (sf *paSessionFinder) getMasterSourceSession() (Session, error) {
 	}
 
 	// create the master source session
-	source := newMasterSession(sf.sessionLogger, sf.client, reply.SourceIndex, reply.Channels, false)
+	source := newMasterSession(sf.sessionLogger, sf.client, reply.SourceIndex, reply.Channels, reply.ChannelMap, false, inputSessionName)
 
 	return source, nil
 }
 
+// every sink has a monitor source carrying whatever it plays. its volume only affects what recording
+// apps capture from it, so it makes a good "desktop audio" level for streamers
+func (sf *paSessionFinder) getLoopbackSession() (Session, error) {
+	sinkRequest := proto.GetSinkInfo{
+		SinkIndex: proto.Undefined,
+	}
+	sinkReply := proto.GetSinkInfoReply{}
+
+	if err := sf.client.Request(&sinkRequest, &sinkReply); err != nil {
+		sf.logger.Warnw("Failed to get master sink info", "error", err)
+		return nil, fmt.Errorf("get master sink info: %w", err)
+	}
+
+	request := proto.GetSourceInfo{
+		SourceIndex: sinkReply.MonitorSourceIndex,
+	}
+	reply := proto.GetSourceInfoReply{}
+
+	if err := sf.client.Request(&request, &reply); err != nil {
+		sf.logger.Warnw("Failed to get monitor source info", "error", err)
+		return nil, fmt.Errorf("get monitor source info: %w", err)
+	}
+
+	// create the loopback session
+	loopback := newMasterSession(sf.sessionLogger, sf.client, reply.SourceIndex, reply.Channels, reply.ChannelMap,
+		false, loopbackSessionName)
+
+	return loopback, nil
+}
+
 func (sf *paSessionFinder) enumerateAndAddSessions(sessions *[]Session) error {
 	request := proto.GetSinkInputInfoList{}
 	reply := proto.GetSinkInputInfoListReply{}
@@ -138,8 +176,20 @@ func (sf *paSessionFinder) enumerateAndAddSessions(sessions *[]Session) error {
 			continue
 		}
 
+		// these are optional, and only used for showing the session to users
+		var displayName, iconName string
+
+		if appName, ok := info.Properties["application.name"]; ok {
+			displayName = appName.String()
+		}
+
+		if appIcon, ok := info.Properties["application.icon_name"]; ok {
+			iconName = appIcon.String()
+		}
+
 		// create the deej session object
-		newSession := newPASession(sf.sessionLogger, sf.client, info.SinkInputIndex, info.Channels, name.String())
+		newSession := newPASession(sf.sessionLogger, sf.client, info.SinkInputIndex, info.Channels,
+			name.String(), displayName, iconName)
 
 		// add it to our slice
 		*sessions = append(*sessions, newSession)
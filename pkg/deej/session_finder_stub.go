@@ -0,0 +1,60 @@
+package deej
+
+import (
+	"os"
+
+	"go.uber.org/zap"
+)
+
+// when there's no audio subsystem to talk to (a headless box relaying its device elsewhere, a CI runner), deej
+// still starts, with a session finder that never finds anything instead of the real one. the device, transports,
+// button actions and the HTTP API all keep working, there just aren't any volumes for sliders to change
+const (
+
+	// when this is set to anything, deej won't even try to use the audio subsystem
+	envNoAudio = "DEEJ_NO_AUDIO"
+)
+
+type stubSessionFinder struct {
+	logger *zap.SugaredLogger
+
+	// why the real session finder couldn't be used, or nil if the user asked for this
+	cause error
+}
+
+func newStubSessionFinder(logger *zap.SugaredLogger, cause error) *stubSessionFinder {
+	sf := &stubSessionFinder{
+		logger: logger.Named("session_finder"),
+		cause:  cause,
+	}
+
+	sf.logger.Debug("Created stub session finder instance")
+
+	return sf
+}
+
+// newSessionFinderOrStub creates the platform's session finder, or a stub one if that's impossible or unwanted
+func newSessionFinderOrStub(logger *zap.SugaredLogger) SessionFinder {
+	if _, noAudioSet := os.LookupEnv(envNoAudio); noAudioSet {
+		logger.Infow("Running without audio", "reason", "envvar set")
+		return newStubSessionFinder(logger, nil)
+	}
+
+	sessionFinder, err := newSessionFinder(logger)
+	if err != nil {
+		logger.Warnw("Failed to create SessionFinder, running without audio", "error", err)
+		return newStubSessionFinder(logger, err)
+	}
+
+	return sessionFinder
+}
+
+func (sf *stubSessionFinder) GetAllSessions() ([]Session, error) {
+	return []Session{}, nil
+}
+
+func (sf *stubSessionFinder) Release() error {
+	sf.logger.Debug("Released stub session finder instance")
+
+	return nil
+}
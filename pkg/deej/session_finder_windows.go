@@ -58,34 +58,8 @@ func (sf *wcaSessionFinder) GetAllSessions() ([]Session, error) {
 	sessions := []Session{}
 
 	// we must call this every time we're about to list devices, i think. could be wrong
-	if err := ole.CoInitializeEx(0, ole.COINIT_APARTMENTTHREADED); err != nil {
-
-		// if the error is "Incorrect function" that corresponds to 0x00000001,
-		// which represents E_FALSE in COM error handling. this is fine for this function,
-		// and just means that the call was redundant.
-		const eFalse = 1
-		oleError := &ole.OleError{}
-
-		if errors.As(err, &oleError) {
-			if oleError.Code() == eFalse {
-				sf.logger.Warn("CoInitializeEx failed with E_FALSE due to redundant invocation")
-			} else {
-				sf.logger.Warnw("Failed to call CoInitializeEx",
-					"isOleError", true,
-					"error", err,
-					"oleError", oleError)
-
-				return nil, fmt.Errorf("call CoInitializeEx: %w", err)
-			}
-		} else {
-			sf.logger.Warnw("Failed to call CoInitializeEx",
-				"isOleError", false,
-				"error", err,
-				"oleError", nil)
-
-			return nil, fmt.Errorf("call CoInitializeEx: %w", err)
-		}
-
+	if err := sf.coInitialize(); err != nil {
+		return nil, err
 	}
 	defer ole.CoUninitialize()
 
@@ -159,6 +133,41 @@ func (sf *wcaSessionFinder) Release() error {
 	return nil
 }
 
+// coInitialize prepares the calling thread for COM calls. callers should defer ole.CoUninitialize if it succeeds
+func (sf *wcaSessionFinder) coInitialize() error {
+	if err := ole.CoInitializeEx(0, ole.COINIT_APARTMENTTHREADED); err != nil {
+
+		// if the error is "Incorrect function" that corresponds to 0x00000001,
+		// which represents E_FALSE in COM error handling. this is fine for this function,
+		// and just means that the call was redundant.
+		const eFalse = 1
+		oleError := &ole.OleError{}
+
+		if errors.As(err, &oleError) {
+			if oleError.Code() == eFalse {
+				sf.logger.Warn("CoInitializeEx failed with E_FALSE due to redundant invocation")
+			} else {
+				sf.logger.Warnw("Failed to call CoInitializeEx",
+					"isOleError", true,
+					"error", err,
+					"oleError", oleError)
+
+				return fmt.Errorf("call CoInitializeEx: %w", err)
+			}
+		} else {
+			sf.logger.Warnw("Failed to call CoInitializeEx",
+				"isOleError", false,
+				"error", err,
+				"oleError", nil)
+
+			return fmt.Errorf("call CoInitializeEx: %w", err)
+		}
+
+	}
+
+	return nil
+}
+
 func (sf *wcaSessionFinder) getDeviceEnumerator() error {
 
 	// get the IMMDeviceEnumerator (only once)
@@ -366,11 +375,38 @@ func (sf *wcaSessionFinder) enumerateAndAddSessions(sessions *[]Session) error {
 
 		// add it to our slice
 		*sessions = append(*sessions, newSession)
+
+		// capture devices that record whatever's playing also get bound to "loopback", so streamers can
+		// put their desktop audio capture level on a slider without knowing their device's exact name
+		if dataFlow == wca.ECapture && isLoopbackDevice(endpointDescription) {
+			loopbackSession, err := sf.getMasterSession(endpoint, loopbackSessionName, loopbackSessionName)
+			if err != nil {
+				sf.logger.Warnw("Failed to get loopback session for device",
+					"deviceIdx", deviceIdx,
+					"error", err)
+
+				return fmt.Errorf("get device %d loopback session: %w", deviceIdx, err)
+			}
+
+			*sessions = append(*sessions, loopbackSession)
+		}
 	}
 
 	return nil
 }
 
+// windows has no way of telling a loopback capture device apart from a regular one,
+// so go by the (lowercase) names drivers commonly give them
+func isLoopbackDevice(endpointDescription string) bool {
+	for _, name := range []string{"stereo mix", "what u hear", "wave out mix", "loopback"} {
+		if strings.Contains(endpointDescription, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (sf *wcaSessionFinder) enumerateAndAddProcessSessions(
 	endpoint *wca.IMMDevice,
 	endpointFriendlyName string,
@@ -30,9 +30,22 @@ type masterSession struct {
 
 	client *proto.Client
 
-	streamIndex    uint32
-	streamChannels byte
-	isOutput       bool
+	streamIndex      uint32
+	streamChannels   byte
+	streamChannelMap proto.ChannelMap
+	isOutput         bool
+}
+
+// positions as they appear in PulseAudio channel maps
+var paChannelPositions = map[string]byte{
+	channelFrontLeft:   1,
+	channelFrontRight:  2,
+	channelFrontCenter: 3,
+	channelBackLeft:    5,
+	channelBackRight:   6,
+	channelLFE:         7,
+	channelSideLeft:    10,
+	channelSideRight:   11,
 }
 
 func newPASession(
@@ -41,6 +54,8 @@ func newPASession(
 	sinkInputIndex uint32,
 	sinkInputChannels byte,
 	processName string,
+	displayName string,
+	iconName string,
 ) *paSession {
 
 	s := &paSession{
@@ -52,6 +67,8 @@ func newPASession(
 	s.processName = processName
 	s.name = processName
 	s.humanReadableDesc = processName
+	s.displayName = displayName
+	s.iconPath = iconName
 
 	// use a self-identifying session name e.g. deej.sessions.chrome
 	s.logger = logger.Named(s.Key())
@@ -65,28 +82,24 @@ func newMasterSession(
 	client *proto.Client,
 	streamIndex uint32,
 	streamChannels byte,
+	streamChannelMap proto.ChannelMap,
 	isOutput bool,
+	key string,
 ) *masterSession {
 
 	s := &masterSession{
-		client:         client,
-		streamIndex:    streamIndex,
-		streamChannels: streamChannels,
-		isOutput:       isOutput,
-	}
-
-	var key string
-
-	if isOutput {
-		key = masterSessionName
-	} else {
-		key = inputSessionName
+		client:           client,
+		streamIndex:      streamIndex,
+		streamChannels:   streamChannels,
+		streamChannelMap: streamChannelMap,
+		isOutput:         isOutput,
 	}
 
 	s.logger = logger.Named(key)
 	s.master = true
 	s.name = key
 	s.humanReadableDesc = key
+	s.displayName = masterDisplayName(key)
 
 	s.logger.Debugw(sessionCreationLogMessage, "session", s)
 
@@ -125,6 +138,50 @@ func (s *paSession) SetVolume(v float32) error {
 	return nil
 }
 
+func (s *paSession) GetMute() bool {
+	request := proto.GetSinkInputInfo{
+		SinkInputIndex: s.sinkInputIndex,
+	}
+	reply := proto.GetSinkInputInfoReply{}
+
+	if err := s.client.Request(&request, &reply); err != nil {
+		s.logger.Warnw("Failed to get session mute state", "error", err)
+		return false
+	}
+
+	return reply.Muted
+}
+
+func (s *paSession) SetMute(m bool) error {
+	request := proto.SetSinkInputMute{
+		SinkInputIndex: s.sinkInputIndex,
+		Mute:           m,
+	}
+
+	if err := s.client.Request(&request, nil); err != nil {
+		s.logger.Warnw("Failed to set session mute state", "error", err)
+		return fmt.Errorf("adjust session mute: %w", err)
+	}
+
+	s.logger.Debugw("Adjusting session mute", "to", m)
+
+	return nil
+}
+
+// isActive reports whether the session is currently playing (as opposed to being paused, or "corked")
+func (s *paSession) isActive() bool {
+	request := proto.GetSinkInputInfo{
+		SinkInputIndex: s.sinkInputIndex,
+	}
+	reply := proto.GetSinkInputInfoReply{}
+
+	if err := s.client.Request(&request, &reply); err != nil {
+		return false
+	}
+
+	return !reply.Corked
+}
+
 func (s *paSession) Release() {
 	s.logger.Debug("Releasing audio session")
 }
@@ -165,10 +222,83 @@ func (s *masterSession) GetVolume() float32 {
 	return level
 }
 
-func (s *masterSession) SetVolume(v float32) error {
+func (s *masterSession) GetMute() bool {
+	if s.isOutput {
+		reply := proto.GetSinkInfoReply{}
+		if err := s.client.Request(&proto.GetSinkInfo{SinkIndex: s.streamIndex}, &reply); err != nil {
+			s.logger.Warnw("Failed to get session mute state", "error", err)
+			return false
+		}
+
+		return reply.Mute
+	}
+
+	reply := proto.GetSourceInfoReply{}
+	if err := s.client.Request(&proto.GetSourceInfo{SourceIndex: s.streamIndex}, &reply); err != nil {
+		s.logger.Warnw("Failed to get session mute state", "error", err)
+		return false
+	}
+
+	return reply.Mute
+}
+
+func (s *masterSession) SetMute(m bool) error {
 	var request proto.RequestArgs
 
-	volumes := createChannelVolumes(s.streamChannels, v)
+	if s.isOutput {
+		request = &proto.SetSinkMute{
+			SinkIndex: s.streamIndex,
+			Mute:      m,
+		}
+	} else {
+		request = &proto.SetSourceMute{
+			SourceIndex: s.streamIndex,
+			Mute:        m,
+		}
+	}
+
+	if err := s.client.Request(request, nil); err != nil {
+		s.logger.Warnw("Failed to set session mute state", "error", err)
+		return fmt.Errorf("adjust session mute: %w", err)
+	}
+
+	s.logger.Debugw("Adjusting session mute", "to", m)
+
+	return nil
+}
+
+func (s *masterSession) SetVolume(v float32) error {
+	return s.setChannelVolumes(createChannelVolumes(s.streamChannels, v))
+}
+
+func (s *masterSession) channelVolumes() ([]uint32, error) {
+	if s.isOutput {
+		request := proto.GetSinkInfo{
+			SinkIndex: s.streamIndex,
+		}
+		reply := proto.GetSinkInfoReply{}
+
+		if err := s.client.Request(&request, &reply); err != nil {
+			return nil, fmt.Errorf("get sink info: %w", err)
+		}
+
+		return reply.ChannelVolumes, nil
+	}
+
+	request := proto.GetSourceInfo{
+		SourceIndex: s.streamIndex,
+	}
+	reply := proto.GetSourceInfoReply{}
+
+	if err := s.client.Request(&request, &reply); err != nil {
+		return nil, fmt.Errorf("get source info: %w", err)
+	}
+
+	return reply.ChannelVolumes, nil
+}
+
+func (s *masterSession) setChannelVolumes(volumes []uint32) error {
+	var request proto.RequestArgs
 
 	if s.isOutput {
 		request = &proto.SetSinkVolume{
@@ -185,16 +315,62 @@ func (s *masterSession) SetVolume(v float32) error {
 	if err := s.client.Request(request, nil); err != nil {
 		s.logger.Warnw("Failed to set session volume",
 			"error", err,
-			"volume", v)
+			"volumes", volumes)
 
 		return fmt.Errorf("adjust session volume: %w", err)
 	}
 
-	s.logger.Debugw("Adjusting session volume", "to", fmt.Sprintf("%.2f", v))
+	s.logger.Debugw("Adjusting session volume", "to", volumes)
 
 	return nil
 }
 
+func (s *masterSession) channelCount() int {
+	return int(s.streamChannels)
+}
+
+func (s *masterSession) channelIndex(channelName string) (int, bool) {
+	position, ok := paChannelPositions[channelName]
+	if !ok {
+		return 0, false
+	}
+
+	for channel, channelPosition := range s.streamChannelMap {
+		if channelPosition == position {
+			return channel, true
+		}
+	}
+
+	return 0, false
+}
+
+func (s *masterSession) getChannelVolume(channel int) float32 {
+	volumes, err := s.channelVolumes()
+	if err != nil || channel >= len(volumes) {
+		s.logger.Warnw("Failed to get channel volume", "channel", channel, "error", err)
+		return 0
+	}
+
+	return float32(volumes[channel]) / float32(maxVolume)
+}
+
+// only the requested channel changes, the rest keep whatever volume they're at
+func (s *masterSession) setChannelVolume(channel int, v float32) error {
+	volumes, err := s.channelVolumes()
+	if err != nil {
+		s.logger.Warnw("Failed to get channel volumes", "error", err)
+		return fmt.Errorf("get channel volumes: %w", err)
+	}
+
+	if channel >= len(volumes) {
+		return fmt.Errorf("channel %d out of range", channel)
+	}
+
+	volumes[channel] = uint32(v * maxVolume)
+
+	return s.setChannelVolumes(volumes)
+}
+
 func (s *masterSession) Release() {
 	s.logger.Debug("Releasing audio session")
 }
@@ -23,6 +23,23 @@ type sessionMap struct {
 
 	lastSessionRefresh time.Time
 	unmappedSessions   []Session
+
+	history   *volumeHistory
+	autoMix   *autoMixer
+	autoPause *autoPauser
+
+	missingTargets *missingTargetHandler
+	settler        *sliderSettler
+	keySteps       *keyStepper
+	cec            *cecClient
+	applier        *volumeApplier
+	announcer      *volumeAnnouncer
+	recording      *recordingWatcher
+	panic          *panicState
+	pause          *pauseState
+
+	// the output device each app was last routed to
+	appRoutes map[string]string
 }
 
 const (
@@ -30,6 +47,9 @@ const (
 	systemSessionName = "system" // system sounds volume
 	inputSessionName  = "mic"    // microphone input level
 
+	// desktop audio capture level (a "stereo mix" device on windows, the default output's monitor on linux)
+	loopbackSessionName = "loopback"
+
 	// some targets need to be transformed before their correct audio sessions can be accessed.
 	// this prefix identifies those targets to ensure they don't contradict with another similarly-named process
 	specialTargetTransformPrefix = "deej."
@@ -66,8 +86,22 @@ func newSessionMap(deej *Deej, logger *zap.SugaredLogger, sessionFinder SessionF
 		m:             make(map[string][]Session),
 		lock:          &sync.Mutex{},
 		sessionFinder: sessionFinder,
+		history:       newVolumeHistory(),
+		appRoutes:     make(map[string]string),
 	}
 
+	m.autoMix = newAutoMixer(m, logger)
+	m.autoPause = newAutoPauser(m, logger)
+	m.missingTargets = newMissingTargetHandler(m, logger)
+	m.settler = newSliderSettler()
+	m.keySteps = newKeyStepper()
+	m.cec = newCECClient(logger)
+	m.applier = newVolumeApplier(m, logger)
+	m.announcer = newVolumeAnnouncer(deej, logger)
+	m.recording = newRecordingWatcher(deej, logger)
+	m.panic = newPanicState()
+	m.pause = newPauseState()
+
 	logger.Debug("Created session map instance")
 
 	return m, nil
@@ -75,17 +109,70 @@ func newSessionMap(deej *Deej, logger *zap.SugaredLogger, sessionFinder SessionF
 
 func (m *sessionMap) initialize() error {
 	if err := m.getAndAddSessions(); err != nil {
-		m.logger.Warnw("Failed to get all sessions during session map initialization", "error", err)
-		return fmt.Errorf("get all sessions during init: %w", err)
+		m.logger.Warnw("Failed to get all sessions during session map initialization, running without audio", "error", err)
+
+		// there's likely no audio subsystem to talk to, but everything that doesn't need one can still work
+		if err := m.sessionFinder.Release(); err != nil {
+			m.logger.Debugw("Failed to release session finder", "error", err)
+		}
+
+		m.sessionFinder = newStubSessionFinder(m.logger, err)
+
+		if err := m.getAndAddSessions(); err != nil {
+			return fmt.Errorf("get all sessions during init: %w", err)
+		}
+	}
+
+	if stub, ok := m.sessionFinder.(*stubSessionFinder); ok && stub.cause != nil {
+		m.deej.notifier.Notify("Can't reach audio system!",
+			"deej keeps running, but sliders won't change any volume. Check the logs for details.")
 	}
 
+	runApplier := func(done chan bool) {
+		go m.applier.run(done)
+	}
+
+	runApplier(m.deej.supervisor.supervise(moduleVolumeApplier, 0, runApplier))
+
 	m.setupOnConfigReload()
 	m.setupOnSliderMove()
 
+	runAutoMix := func(done chan bool) {
+		go m.autoMix.run(done)
+	}
+
+	runAutoMix(m.deej.supervisor.supervise(moduleAutoMix, autoMixHangTimeout, runAutoMix))
+
+	runAutoPause := func(done chan bool) {
+		go m.autoPause.run(done)
+	}
+
+	runAutoPause(m.deej.supervisor.supervise(moduleAutoPause, 0, runAutoPause))
+
+	watchAppStarts := func(done chan bool) {
+		go m.watchAppStarts(done)
+	}
+
+	watchAppStarts(m.deej.supervisor.supervise(moduleAppStart, 0, watchAppStarts))
+
+	watchRecording := func(done chan bool) {
+		go m.recording.run(done)
+	}
+
+	watchRecording(m.deej.supervisor.supervise(moduleRecording, 0, watchRecording))
+
 	return nil
 }
 
+// audioAvailable returns false if deej is running without audio, with a stub session finder
+func (m *sessionMap) audioAvailable() bool {
+	_, stub := m.sessionFinder.(*stubSessionFinder)
+	return !stub
+}
+
 func (m *sessionMap) release() error {
+	m.cec.stop()
+
 	if err := m.sessionFinder.Release(); err != nil {
 		m.logger.Warnw("Failed to release session finder during session map release", "error", err)
 		return fmt.Errorf("release session finder during release: %w", err)
@@ -119,6 +206,9 @@ func (m *sessionMap) getAndAddSessions() error {
 
 	m.logger.Infow("Got all audio sessions successfully", "sessionMap", m)
 
+	// apps that just showed up may have volumes waiting for them
+	m.missingTargets.applyBuffered()
+
 	return nil
 }
 
@@ -139,14 +229,34 @@ func (m *sessionMap) setupOnConfigReload() {
 func (m *sessionMap) setupOnSliderMove() {
 	sliderEventsChannel := m.deej.serial.SubscribeToSliderMoveEvents()
 
-	go func() {
-		for {
-			select {
-			case event := <-sliderEventsChannel:
-				m.handleSliderMoveEvent(event)
+	// the subscription outlives restarts, so a restarted consumer picks up right where the previous one left off
+	consume := func(done chan bool) {
+		go m.consumeSliderMoves(sliderEventsChannel, done)
+	}
+
+	consume(m.deej.supervisor.supervise(moduleSessionMap, sessionMapHangTimeout, consume))
+}
+
+func (m *sessionMap) consumeSliderMoves(sliderEventsChannel chan SliderMoveEvent, done chan bool) {
+	defer m.deej.supervisor.guard(moduleSessionMap)
+
+	heartbeatTicker := time.NewTicker(moduleHeartbeatInterval)
+	defer heartbeatTicker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-heartbeatTicker.C:
+			m.deej.supervisor.heartbeat(moduleSessionMap)
+		case event := <-sliderEventsChannel:
+			m.handleSliderMoveEvent(event)
+		case <-m.settler.ready:
+			for _, event := range m.settler.settledMoves() {
+				m.applySliderMoveEvent(event)
 			}
 		}
-	}()
+	}
 }
 
 // performance: explain why force == true at every such use to avoid unintended forced refresh spams
@@ -172,8 +282,8 @@ func (m *sessionMap) refreshSessions(force bool) {
 // even when absent from the config. this makes sense for every current feature that uses "unmapped sessions"
 func (m *sessionMap) sessionMapped(session Session) bool {
 
-	// count master/system/mic as mapped
-	if funk.ContainsString([]string{masterSessionName, systemSessionName, inputSessionName}, session.Key()) {
+	// count master/system/mic/loopback as mapped
+	if funk.ContainsString([]string{masterSessionName, systemSessionName, inputSessionName, loopbackSessionName}, session.Key()) {
 		return true
 	}
 
@@ -207,6 +317,23 @@ func (m *sessionMap) sessionMapped(session Session) bool {
 }
 
 func (m *sessionMap) handleSliderMoveEvent(event SliderMoveEvent) {
+	m.announcer.sliderMoved(event)
+
+	// in low-churn mode, nothing happens until the slider stops moving
+	if settleTime := m.deej.config.sliderSettleTime(event.SliderID); settleTime > 0 {
+		m.settler.hold(event, settleTime)
+		return
+	}
+
+	m.applySliderMoveEvent(event)
+}
+
+func (m *sessionMap) applySliderMoveEvent(event SliderMoveEvent) {
+
+	// sliders stay out of it until the panic is over, or while deej is paused
+	if m.panic.active() || m.pause.active() {
+		return
+	}
 
 	// first of all, ensure our session map isn't moldy
 	if m.lastSessionRefresh.Add(maxTimeBetweenSessionRefreshes).Before(time.Now()) {
@@ -222,12 +349,43 @@ func (m *sessionMap) handleSliderMoveEvent(event SliderMoveEvent) {
 		return
 	}
 
+	targetFound, adjustmentFailed := m.applySliderVolume(event.SliderID, targets, event.PercentValue, true)
+
+	// if we still haven't found a target or the volume adjustment failed, maybe look for the target again.
+	// processes could've opened since the last time this slider moved.
+	// if they haven't, the cooldown will take care to not spam it up
+	if !targetFound {
+		m.refreshSessions(false)
+	} else if adjustmentFailed {
+
+		// performance: the reason that forcing a refresh here is okay is that we'll only get here
+		// when a key or cec target failed, which is rare (failed session writes are taken care of by the applier)
+		m.refreshSessions(true)
+	}
+}
+
+// applySliderVolume sets the volume of all sessions matching a slider's targets. it returns whether any
+// matching sessions were found, and whether adjusting any of them failed
+func (m *sessionMap) applySliderVolume(sliderID int, targets []string, percentValue float32, recordHistory bool) (bool, bool) {
 	targetFound := false
 	adjustmentFailed := false
 
 	// for each possible target for this slider...
 	for _, target := range targets {
 
+		// some targets press keys or step the TV's volume instead of touching any session
+		if syntax := sliderTargetSyntaxFor(target); syntax.apply != nil {
+			found, err := syntax.apply(m, sliderID, target, percentValue)
+			if err != nil {
+				m.logger.Warnw("Failed to apply slider target", "target", target, "error", err)
+				adjustmentFailed = true
+			}
+
+			targetFound = targetFound || found
+
+			continue
+		}
+
 		// resolve the target name by cleaning it up and applying any special transformations.
 		// depending on the transformation applied, this can result in more than one target name
 		resolvedTargets := m.resolveTarget(target)
@@ -236,41 +394,165 @@ func (m *sessionMap) handleSliderMoveEvent(event SliderMoveEvent) {
 		for _, resolvedTarget := range resolvedTargets {
 
 			// check the map for matching sessions
-			sessions, ok := m.get(resolvedTarget)
+			sessions, ok := m.find(resolvedTarget)
 
-			// no sessions matching this target - move on
+			// no sessions matching this target - move on, after doing whatever the user wants done about that
 			if !ok {
+				if recordHistory {
+					m.missingTargets.handle(resolvedTarget, percentValue)
+				}
+
 				continue
 			}
 
 			targetFound = true
+			m.missingTargets.found(resolvedTarget)
 
 			// iterate all matching sessions and adjust the volume of each one
 			for _, session := range sessions {
-				if session.GetVolume() != event.PercentValue {
-					if err := session.SetVolume(event.PercentValue); err != nil {
-						m.logger.Warnw("Failed to set target session volume", "error", err)
-						adjustmentFailed = true
+				volume := m.autoMix.adjust(session.Key(), percentValue)
+
+				if previousVolume := m.applier.volume(session); previousVolume != volume {
+					if recordHistory {
+						m.history.record(sliderID, session.Key(), previousVolume)
 					}
+
+					m.applier.set(session, volume)
 				}
 			}
 		}
 	}
 
-	// if we still haven't found a target or the volume adjustment failed, maybe look for the target again.
-	// processes could've opened since the last time this slider moved.
-	// if they haven't, the cooldown will take care to not spam it up
+	return targetFound, adjustmentFailed
+}
+
+// nudgeTargets moves the volume of all sessions matching the given targets by delta, staying within 0.0 to 1.0.
+// inverted controls nudge the other way, same as their sliders move
+func (m *sessionMap) nudgeTargets(targets []string, delta float32) {
+	targetFound := false
+
+	if m.deej.config.InvertSliders {
+		delta = -delta
+	}
+
+	for _, target := range targets {
+		for _, resolvedTarget := range m.resolveTarget(target) {
+			sessions, ok := m.find(resolvedTarget)
+			if !ok {
+				continue
+			}
+
+			targetFound = true
+
+			for _, session := range sessions {
+				volume := util.NormalizeScalar(m.applier.volume(session) + delta)
+				if volume < 0 {
+					volume = 0
+				} else if volume > 1 {
+					volume = 1
+				}
+
+				m.applier.set(session, volume)
+			}
+		}
+	}
+
+	// same as with sliders, the target might have just shown up
 	if !targetFound {
 		m.refreshSessions(false)
-	} else if adjustmentFailed {
+	}
+}
 
-		// performance: the reason that forcing a refresh here is okay is that we'll only get here
-		// when a session's SetVolume call errored, such as in the case of a stale master session
-		// (or another, more catastrophic failure happens)
-		m.refreshSessions(true)
+// undoLastChange reverts the most recent deej-applied volume change (a whole slider gesture)
+func (m *sessionMap) undoLastChange() {
+	step, ok := m.history.pop()
+	if !ok {
+		m.logger.Debug("Nothing to undo")
+		return
+	}
+
+	m.logger.Infow("Undoing last volume change", "sliderID", step.sliderID, "sessions", len(step.previousVolumes))
+
+	for sessionKey, previousVolume := range step.previousVolumes {
+		sessions, ok := m.find(sessionKey)
+		if !ok {
+			continue
+		}
+
+		for _, session := range sessions {
+			m.applier.set(session, previousVolume)
+		}
 	}
 }
 
+// sliderVolume returns the current volume of the first live session mapped to the given slider.
+// the second return value is false if the slider doesn't control any live session right now
+func (m *sessionMap) sliderVolume(sliderID int) (float32, bool) {
+	targets, ok := m.deej.config.SliderMapping.get(sliderID)
+	if !ok {
+		return 0, false
+	}
+
+	for _, target := range targets {
+		for _, resolvedTarget := range m.resolveTarget(target) {
+			if sessions, ok := m.find(resolvedTarget); ok && len(sessions) > 0 {
+
+				// report the slider's own level, not whatever auto-mix reduced it to
+				return m.autoMix.baseline(sessions[0].Key(), m.applier.volume(sessions[0])), true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+// sliderDisplayNames returns friendly names for whatever the given slider controls: the display names of
+// its live sessions, or the configured target names (sans ".exe") for targets with no live session
+func (m *sessionMap) sliderDisplayNames(sliderID int) []string {
+	targets, ok := m.deej.config.SliderMapping.get(sliderID)
+	if !ok {
+		return nil
+	}
+
+	names := []string{}
+
+	for _, target := range targets {
+		found := false
+
+		for _, resolvedTarget := range m.resolveTarget(target) {
+			sessions, ok := m.find(resolvedTarget)
+			if !ok {
+				continue
+			}
+
+			for _, session := range sessions {
+				names = append(names, session.DisplayName())
+				found = true
+			}
+		}
+
+		if !found {
+			names = append(names, strings.TrimSuffix(target, ".exe"))
+		}
+	}
+
+	// several sessions of the same app (i.e. browsers) shouldn't show up more than once
+	return funk.UniqString(names)
+}
+
+// snapshot returns all sessions currently in the map
+func (m *sessionMap) snapshot() []Session {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	sessions := []Session{}
+	for _, value := range m.m {
+		sessions = append(sessions, value...)
+	}
+
+	return sessions
+}
+
 func (m *sessionMap) targetHasSpecialTransform(target string) bool {
 	return strings.HasPrefix(target, specialTargetTransformPrefix)
 }
@@ -345,7 +627,21 @@ func (m *sessionMap) get(key string) ([]Session, bool) {
 	return value, ok
 }
 
+// find looks up the sessions for a resolved target, including ones that only exist as part
+// of another session (such as a single channel of a multichannel device)
+func (m *sessionMap) find(target string) ([]Session, bool) {
+	if strings.HasPrefix(target, channelTargetPrefix) {
+		return m.channelSessions(target)
+	}
+
+	return m.get(target)
+}
+
 func (m *sessionMap) clear() {
+
+	// writes still waiting for these sessions would go to released ones
+	m.applier.discard()
+
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
@@ -4,11 +4,14 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"syscall"
+	"unsafe"
 
 	ole "github.com/go-ole/go-ole"
 	ps "github.com/mitchellh/go-ps"
 	wca "github.com/moutend/go-wca"
 	"go.uber.org/zap"
+	"golang.org/x/sys/windows"
 )
 
 var errNoSuchProcess = errors.New("No such process")
@@ -22,10 +25,24 @@ type wcaSession struct {
 
 	control *wca.IAudioSessionControl2
 	volume  *wca.ISimpleAudioVolume
+	meter   *audioMeterInformation // may be nil, if the session doesn't support metering
 
 	eventCtx *ole.GUID
 }
 
+// go-wca doesn't wrap IAudioMeterInformation, but we only need its first method
+type audioMeterInformation struct {
+	ole.IUnknown
+}
+
+type audioMeterInformationVtbl struct {
+	ole.IUnknownVtbl
+	GetPeakValue uintptr
+}
+
+// sessions peaking above this are considered to be playing something
+const activePeakThreshold = 0.005
+
 type masterSession struct {
 	baseSession
 
@@ -56,6 +73,7 @@ func newWCASession(
 		s.system = true
 		s.name = systemSessionName
 		s.humanReadableDesc = "system sounds"
+		s.displayName = "System sounds"
 	} else {
 
 		// find our session's process name
@@ -77,6 +95,15 @@ func newWCASession(
 		s.processName = process.Executable()
 		s.name = s.processName
 		s.humanReadableDesc = fmt.Sprintf("%s (pid %d)", s.processName, s.pid)
+
+		s.displayName, s.iconPath = sessionAppInfo(logger, control, pid)
+	}
+
+	// metering is only used to tell whether the session is playing, so it's fine to go without it
+	if dispatch, err := control.QueryInterface(wca.IID_IAudioMeterInformation); err == nil {
+		s.meter = (*audioMeterInformation)(unsafe.Pointer(dispatch))
+	} else {
+		logger.Debugw("Failed to get meter for session", "pid", pid, "error", err)
 	}
 
 	// use a self-identifying session name e.g. deej.sessions.chrome
@@ -103,6 +130,7 @@ func newMasterSession(
 	s.master = true
 	s.name = key
 	s.humanReadableDesc = key
+	s.displayName = masterDisplayName(key)
 
 	s.logger.Debugw(sessionCreationLogMessage, "session", s)
 
@@ -143,13 +171,64 @@ func (s *wcaSession) SetVolume(v float32) error {
 	return nil
 }
 
+func (s *wcaSession) GetMute() bool {
+
+	// go-wca's GetMute writes a 4-byte BOOL into a Go bool, so call it ourselves
+	var mute int32
+
+	hr, _, _ := syscall.Syscall(s.volume.VTable().GetMute, 2, uintptr(unsafe.Pointer(s.volume)), uintptr(unsafe.Pointer(&mute)), 0)
+	if hr != 0 {
+		s.logger.Warnw("Failed to get session mute state", "error", ole.NewError(hr))
+		return false
+	}
+
+	return mute != 0
+}
+
+func (s *wcaSession) SetMute(m bool) error {
+	if err := s.volume.SetMute(m, s.eventCtx); err != nil {
+		s.logger.Warnw("Failed to set session mute state", "error", err)
+		return fmt.Errorf("adjust session mute: %w", err)
+	}
+
+	s.logger.Debugw("Adjusting session mute", "to", m)
+
+	return nil
+}
+
 func (s *wcaSession) Release() {
 	s.logger.Debug("Releasing audio session")
 
+	if s.meter != nil {
+		s.meter.Release()
+	}
+
 	s.volume.Release()
 	s.control.Release()
 }
 
+// isActive reports whether the session is currently making any sound
+func (s *wcaSession) isActive() bool {
+	if s.meter == nil {
+		var state uint32
+		if err := s.control.GetState(&state); err != nil {
+			return false
+		}
+
+		return state == wca.AudioSessionStateActive
+	}
+
+	var peak float32
+	vtable := (*audioMeterInformationVtbl)(unsafe.Pointer(s.meter.RawVTable))
+
+	hr, _, _ := syscall.Syscall(vtable.GetPeakValue, 2, uintptr(unsafe.Pointer(s.meter)), uintptr(unsafe.Pointer(&peak)), 0)
+	if hr != 0 {
+		return false
+	}
+
+	return peak > activePeakThreshold
+}
+
 func (s *wcaSession) String() string {
 	return fmt.Sprintf(sessionStringFormat, s.humanReadableDesc, s.GetVolume())
 }
@@ -183,6 +262,36 @@ func (s *masterSession) SetVolume(v float32) error {
 	return nil
 }
 
+func (s *masterSession) GetMute() bool {
+
+	// go-wca's GetMute writes a 4-byte BOOL into a Go bool, so call it ourselves
+	var mute int32
+
+	hr, _, _ := syscall.Syscall(s.volume.VTable().GetMute, 2, uintptr(unsafe.Pointer(s.volume)), uintptr(unsafe.Pointer(&mute)), 0)
+	if hr != 0 {
+		s.logger.Warnw("Failed to get session mute state", "error", ole.NewError(hr))
+		return false
+	}
+
+	return mute != 0
+}
+
+func (s *masterSession) SetMute(m bool) error {
+	if s.stale {
+		s.logger.Warnw("Session expired because default device has changed, triggering session refresh")
+		return errRefreshSessions
+	}
+
+	if err := s.volume.SetMute(m, s.eventCtx); err != nil {
+		s.logger.Warnw("Failed to set session mute state", "error", err)
+		return fmt.Errorf("adjust session mute: %w", err)
+	}
+
+	s.logger.Debugw("Adjusting session mute", "to", m)
+
+	return nil
+}
+
 func (s *masterSession) Release() {
 	s.logger.Debug("Releasing audio session")
 
@@ -193,6 +302,180 @@ func (s *masterSession) String() string {
 	return fmt.Sprintf(sessionStringFormat, s.humanReadableDesc, s.GetVolume())
 }
 
+func (s *masterSession) channelCount() int {
+	var count uint32
+
+	if err := s.volume.GetChannelCount(&count); err != nil {
+		s.logger.Warnw("Failed to get channel count", "error", err)
+		return 0
+	}
+
+	return int(count)
+}
+
+// windows doesn't tell us which speaker each channel belongs to through this interface,
+// but devices with a given channel count use the standard layout for it
+func (s *masterSession) channelIndex(channelName string) (int, bool) {
+	layouts := map[int][]string{
+		2: {channelFrontLeft, channelFrontRight},
+		4: {channelFrontLeft, channelFrontRight, channelBackLeft, channelBackRight},
+		6: {channelFrontLeft, channelFrontRight, channelFrontCenter, channelLFE, channelBackLeft, channelBackRight},
+		8: {channelFrontLeft, channelFrontRight, channelFrontCenter, channelLFE,
+			channelBackLeft, channelBackRight, channelSideLeft, channelSideRight},
+	}
+
+	count := s.channelCount()
+
+	// 5.1 comes in a "side" flavor too, which uses the same channel slots
+	if count == 6 {
+		switch channelName {
+		case channelSideLeft:
+			channelName = channelBackLeft
+		case channelSideRight:
+			channelName = channelBackRight
+		}
+	}
+
+	for channel, name := range layouts[count] {
+		if name == channelName {
+			return channel, true
+		}
+	}
+
+	return 0, false
+}
+
+func (s *masterSession) getChannelVolume(channel int) float32 {
+	var level float32
+
+	if err := s.volume.GetChannelVolumeLevelScalar(uint32(channel), &level); err != nil {
+		s.logger.Warnw("Failed to get channel volume", "channel", channel, "error", err)
+	}
+
+	return level
+}
+
+func (s *masterSession) setChannelVolume(channel int, v float32) error {
+	if s.stale {
+		s.logger.Warnw("Session expired because default device has changed, triggering session refresh")
+		return errRefreshSessions
+	}
+
+	if err := s.volume.SetChannelVolumeLevelScalar(uint32(channel), v, s.eventCtx); err != nil {
+		s.logger.Warnw("Failed to set channel volume",
+			"error", err,
+			"channel", channel,
+			"volume", v)
+
+		return fmt.Errorf("adjust channel volume: %w", err)
+	}
+
+	s.logger.Debugw("Adjusting channel volume", "channel", channel, "to", fmt.Sprintf("%.2f", v))
+
+	return nil
+}
+
 func (s *masterSession) markAsStale() {
 	s.stale = true
 }
+
+// sessionAppInfo figures out a friendly name and an icon for a session's app. apps rarely set these on
+// their audio sessions, so we fall back to the description and icon embedded in the process's executable
+func sessionAppInfo(logger *zap.SugaredLogger, control *wca.IAudioSessionControl2, pid uint32) (string, string) {
+	displayName := sessionControlString(control.VTable().GetDisplayName, control)
+	iconPath := sessionControlString(control.VTable().GetIconPath, control)
+
+	// indirect strings (i.e. "@%SystemRoot%\System32\AudioSrv.Dll,-202") aren't fit for display as is
+	if strings.HasPrefix(displayName, "@") {
+		displayName = ""
+	}
+
+	if displayName != "" && iconPath != "" {
+		return displayName, iconPath
+	}
+
+	executablePath, err := processExecutablePath(pid)
+	if err != nil {
+		logger.Debugw("Failed to get process executable path", "pid", pid, "error", err)
+		return displayName, iconPath
+	}
+
+	if displayName == "" {
+		displayName = executableDescription(executablePath)
+	}
+
+	// icon paths are resource paths, where the executable itself with the default index is the app's main icon
+	if iconPath == "" {
+		iconPath = executablePath + ",0"
+	}
+
+	return displayName, iconPath
+}
+
+// sessionControlString calls one of the session control's string getters. go-wca's own wrappers for these
+// truncate the returned pointer to 32 bits, which crashes on 64-bit builds, so we call them ourselves
+func sessionControlString(method uintptr, control *wca.IAudioSessionControl2) string {
+	var value *uint16
+
+	hr, _, _ := syscall.Syscall(method, 2, uintptr(unsafe.Pointer(control)), uintptr(unsafe.Pointer(&value)), 0)
+	if hr != 0 || value == nil {
+		return ""
+	}
+
+	defer ole.CoTaskMemFree(uintptr(unsafe.Pointer(value)))
+
+	return windows.UTF16PtrToString(value)
+}
+
+func processExecutablePath(pid uint32) (string, error) {
+	process, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return "", fmt.Errorf("open process: %w", err)
+	}
+
+	defer windows.CloseHandle(process)
+
+	buf := make([]uint16, windows.MAX_LONG_PATH)
+	size := uint32(len(buf))
+
+	if err := windows.QueryFullProcessImageName(process, 0, &buf[0], &size); err != nil {
+		return "", fmt.Errorf("query process image name: %w", err)
+	}
+
+	return windows.UTF16ToString(buf[:size]), nil
+}
+
+// executableDescription returns the "file description" from an executable's version info, which is
+// what task manager shows as the app's name. empty if the executable doesn't have one
+func executableDescription(executablePath string) string {
+	size, err := windows.GetFileVersionInfoSize(executablePath, nil)
+	if err != nil || size == 0 {
+		return ""
+	}
+
+	info := make([]byte, size)
+	if err := windows.GetFileVersionInfo(executablePath, 0, size, unsafe.Pointer(&info[0])); err != nil {
+		return ""
+	}
+
+	// string values are stored per language and code page, so look up the first one first
+	var translation *[2]uint16
+	var translationLen uint32
+
+	if err := windows.VerQueryValue(unsafe.Pointer(&info[0]), `\VarFileInfo\Translation`,
+		unsafe.Pointer(&translation), &translationLen); err != nil || translationLen < 4 {
+		return ""
+	}
+
+	subBlock := fmt.Sprintf(`\StringFileInfo\%04x%04x\FileDescription`, translation[0], translation[1])
+
+	var description *uint16
+	var descriptionLen uint32
+
+	if err := windows.VerQueryValue(unsafe.Pointer(&info[0]), subBlock,
+		unsafe.Pointer(&description), &descriptionLen); err != nil || descriptionLen == 0 {
+		return ""
+	}
+
+	return windows.UTF16PtrToString(description)
+}
@@ -0,0 +1,116 @@
+package deej
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// with silence_timeout set, deej expects to hear something from the device at least that often. a board that
+// browned out or whose firmware hung can leave its port open while sending nothing at all, which otherwise
+// looks just like a board whose sliders aren't moving. once the device has been silent for too long, deej
+// lets the user know and has the supervisor renew the connection. only lines deej understands count -
+// garbage from a confused board doesn't keep it alive
+const minSilenceCheckInterval = 250 * time.Millisecond
+
+var errDeviceSilent = errors.New("device went silent")
+
+// silenceTracker keeps track of when the device last sent a valid line
+type silenceTracker struct {
+	lock sync.Locker
+
+	lastHeard time.Time
+
+	// only the first reconnect in a row is worth a notification, a board that stays dead doesn't need more
+	notified bool
+}
+
+func newSilenceTracker() *silenceTracker {
+	return &silenceTracker{
+		lock:      &sync.Mutex{},
+		lastHeard: time.Now(),
+	}
+}
+
+// heard records a valid line from the device
+func (t *silenceTracker) heard() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.lastHeard = time.Now()
+	t.notified = false
+}
+
+// reset starts counting from now, for a fresh connection
+func (t *silenceTracker) reset() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.lastHeard = time.Now()
+}
+
+func (t *silenceTracker) silence() time.Duration {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return time.Since(t.lastHeard)
+}
+
+// shouldNotify returns true the first time it's called since the device was last heard from
+func (t *silenceTracker) shouldNotify() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.notified {
+		return false
+	}
+
+	t.notified = true
+
+	return true
+}
+
+func (sio *SerialIO) runSilenceWatchdog(ctx context.Context, logger *zap.SugaredLogger) {
+	timeout := sio.deej.config.SilenceTimeout
+	if timeout <= 0 {
+		return
+	}
+
+	logger = logger.Named("watchdog")
+	logger.Debugw("Watching for device silence", "timeout", timeout)
+
+	checkInterval := timeout / 4
+	if checkInterval < minSilenceCheckInterval {
+		checkInterval = minSilenceCheckInterval
+	}
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case <-ticker.C:
+			silence := sio.silence.silence()
+			if silence < timeout {
+				continue
+			}
+
+			logger.Warnw("Device went silent, reconnecting", "silence", silence.Round(time.Millisecond))
+
+			if sio.silence.shouldNotify() {
+				sio.deej.notifier.Notify("Device went silent!",
+					fmt.Sprintf("deej hasn't heard from your device in %s, reconnecting.", silence.Round(time.Second)))
+			}
+
+			sio.deej.supervisor.reportFailure(moduleSerial, errDeviceSilent)
+			return
+		}
+	}
+}
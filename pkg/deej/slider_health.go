@@ -0,0 +1,174 @@
+package deej
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// with slider_health_checks on, deej keeps an eye out for sliders that look broken. a working pot always jitters a
+// little, so one whose raw reading hasn't changed at all for slider_stuck_time while the other sliders have most
+// likely lost a wire. one that keeps jumping across a good part of its range by itself is likely a worn out pot, or
+// a wire that's barely making contact. either way the user gets a maintenance notification naming the slider,
+// once until it behaves again. a slider resting at either end of its range (give or take its noise threshold)
+// reads steady without anything being wrong, so it's never taken for stuck
+const (
+	defaultSliderStuckTime = time.Hour
+
+	// a single reading moving this much of the slider's range is a jump. people don't move sliders that fast
+	sliderFlickerJump = 0.25
+
+	// this many jumps within the window means the slider is flickering, not being slammed around by hand
+	sliderFlickerJumps  = 10
+	sliderFlickerWindow = 5 * time.Second
+)
+
+type sliderProblem int
+
+const (
+	sliderStuck sliderProblem = iota
+	sliderFlickering
+)
+
+// sliderHealth is what the monitor remembers about a single slider
+type sliderHealth struct {
+	lastRaw     int
+	lastChanged time.Time
+
+	// whether the slider is resting at either end of its range
+	parked bool
+
+	// when the slider jumped, within the last flicker window
+	jumps []time.Time
+
+	seen            bool
+	stuckNotified   bool
+	flickerNotified bool
+}
+
+// sliderHealthMonitor watches every slider's raw readings for signs of broken hardware
+type sliderHealthMonitor struct {
+	lock    sync.Locker
+	sliders []sliderHealth
+}
+
+func newSliderHealthMonitor() *sliderHealthMonitor {
+	return &sliderHealthMonitor{
+		lock: &sync.Mutex{},
+	}
+}
+
+// reset forgets everything, for a device with the given amount of sliders
+func (m *sliderHealthMonitor) reset(numSliders int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	m.sliders = make([]sliderHealth, numSliders)
+}
+
+// observe takes in a line's raw slider values, and returns the problems that just showed up, by slider index.
+// each problem is only reported once, until the slider behaves again. noiseThreshold gives each slider's
+// noise threshold, as a fraction of its range
+func (m *sliderHealthMonitor) observe(values []int, maxAnalogValue int, noiseThreshold func(sliderIdx int) float64,
+	stuckTime time.Duration, now time.Time) map[int]sliderProblem {
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	problems := map[int]sliderProblem{}
+
+	if len(values) != len(m.sliders) {
+		return problems
+	}
+
+	for sliderIdx, raw := range values {
+		slider := &m.sliders[sliderIdx]
+
+		position, threshold := float64(raw)/float64(maxAnalogValue), noiseThreshold(sliderIdx)
+		slider.parked = position <= threshold || position >= 1-threshold
+
+		if !slider.seen {
+			slider.seen = true
+			slider.lastRaw = raw
+			slider.lastChanged = now
+
+			continue
+		}
+
+		if raw != slider.lastRaw {
+			change := float64(raw-slider.lastRaw) / float64(maxAnalogValue)
+			if change >= sliderFlickerJump || change <= -sliderFlickerJump {
+				slider.jumps = append(slider.jumps, now)
+			}
+
+			slider.lastRaw = raw
+			slider.lastChanged = now
+			slider.stuckNotified = false
+		}
+
+		// only jumps within the window count
+		for len(slider.jumps) > 0 && now.Sub(slider.jumps[0]) > sliderFlickerWindow {
+			slider.jumps = slider.jumps[1:]
+		}
+
+		if len(slider.jumps) >= sliderFlickerJumps && !slider.flickerNotified {
+			slider.flickerNotified = true
+			problems[sliderIdx] = sliderFlickering
+		} else if len(slider.jumps) == 0 {
+			slider.flickerNotified = false
+		}
+	}
+
+	// a slider that hasn't changed is only suspicious if the others did meanwhile - otherwise the whole device may
+	// just be sitting there, or sending the same line over and over
+	for sliderIdx := range m.sliders {
+		slider := &m.sliders[sliderIdx]
+		if slider.stuckNotified || slider.parked || now.Sub(slider.lastChanged) < stuckTime {
+			continue
+		}
+
+		for otherIdx, other := range m.sliders {
+			if otherIdx != sliderIdx && now.Sub(other.lastChanged) < stuckTime {
+				slider.stuckNotified = true
+				problems[sliderIdx] = sliderStuck
+
+				break
+			}
+		}
+	}
+
+	return problems
+}
+
+// checkSliderHealth looks for broken sliders in a line's raw values, and lets the user know about any it finds
+func (sio *SerialIO) checkSliderHealth(logger *zap.SugaredLogger, values []int) {
+	config := sio.deej.config
+	if !config.SliderHealthChecks {
+		return
+	}
+
+	problems := sio.sliderHealth.observe(values, config.MaxAnalogValue, sio.noiseThreshold, config.SliderStuckTime, time.Now())
+
+	for sliderIdx, problem := range problems {
+		userIdx := sliderIdx + config.MappingIndexBase
+
+		switch problem {
+		case sliderStuck:
+			logger.Warnw("Slider reading hasn't changed in a long time, it may be disconnected",
+				"slider", userIdx,
+				"since", config.SliderStuckTime)
+
+			sio.deej.notifier.Notify(fmt.Sprintf("Slider %d may be disconnected!", userIdx),
+				fmt.Sprintf("It's read exactly the same for over %s while other sliders moved. Check its wiring.",
+					config.SliderStuckTime.Round(time.Second)))
+
+		case sliderFlickering:
+			logger.Warnw("Slider keeps jumping around by itself, it may be failing", "slider", userIdx)
+
+			sio.deej.notifier.Notify(fmt.Sprintf("Slider %d may be failing!", userIdx),
+				"It keeps jumping around by itself. Its potentiometer or wiring may need replacing.")
+		}
+	}
+}
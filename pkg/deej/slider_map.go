@@ -20,12 +20,14 @@ func newSliderMap() *sliderMap {
 	}
 }
 
-func sliderMapFromConfigs(userMapping map[string][]string, internalMapping map[string][]string) *sliderMap {
+func sliderMapFromConfigs(userMapping map[string][]string, internalMapping map[string][]string, indexBase int) *sliderMap {
 	resultMap := newSliderMap()
 
-	// copy targets from user config, ignoring empty values
+	// copy targets from user config, ignoring empty values.
+	// users may count their sliders from 1, internally we always count from 0
 	for sliderIdxString, targets := range userMapping {
 		sliderIdx, _ := strconv.Atoi(sliderIdxString)
+		sliderIdx -= indexBase
 
 		resultMap.set(sliderIdx, funk.FilterString(targets, func(s string) bool {
 			return s != ""
@@ -76,6 +78,57 @@ func (m *sliderMap) set(key int, value []string) {
 	m.m[key] = value
 }
 
+// shifted returns a copy of this map with every slider index lowered by offset, dropping sliders that end up below 0
+func (m *sliderMap) shifted(offset int) *sliderMap {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	resultMap := newSliderMap()
+
+	for key, value := range m.m {
+		if key-offset >= 0 {
+			resultMap.m[key-offset] = value
+		}
+	}
+
+	return resultMap
+}
+
+// overridden returns a copy of this map with the given sliders' targets replaced
+func (m *sliderMap) overridden(overrides map[int][]string) *sliderMap {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	resultMap := newSliderMap()
+
+	for key, value := range m.m {
+		resultMap.m[key] = value
+	}
+
+	for key, value := range overrides {
+		resultMap.m[key] = value
+	}
+
+	return resultMap
+}
+
+// highestIndex returns the highest slider index that has any targets mapped to it, or -1 if there are none.
+// the given slider indexes are skipped (useful for virtual sliders, which the device doesn't know about)
+func (m *sliderMap) highestIndex(ignored []int) int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	highest := -1
+
+	for key, value := range m.m {
+		if len(value) > 0 && key > highest && !funk.ContainsInt(ignored, key) {
+			highest = key
+		}
+	}
+
+	return highest
+}
+
 func (m *sliderMap) String() string {
 	m.lock.Lock()
 	defer m.lock.Unlock()
@@ -0,0 +1,115 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// the mute actions mute whatever a slider controls, using the same targets and sessions the slider moves (unlike
+// VK_VOLUME_MUTE, which only ever mutes the default output device). mute:<slider> and unmute:<slider> do just that,
+// mute_toggle:<slider> unmutes the slider's sessions if they're all muted, and mutes them otherwise
+const (
+	actionMutePrefix       = "mute:"
+	actionUnmutePrefix     = "unmute:"
+	actionMuteTogglePrefix = "mute_toggle:"
+)
+
+var sliderMutePrefixes = map[string]string{
+	actionMutePrefix:       muteModeMute,
+	actionUnmutePrefix:     muteModeUnmute,
+	actionMuteTogglePrefix: muteModeToggle,
+}
+
+var errNothingToMute = errors.New("no sessions that can be muted")
+
+// sliderMutePrefix returns the mute action prefix the entry starts with, if any
+func sliderMutePrefix(entry string) (string, bool) {
+	for prefix := range sliderMutePrefixes {
+		if strings.HasPrefix(entry, prefix) {
+			return prefix, true
+		}
+	}
+
+	return "", false
+}
+
+// parseSliderMuteAction returns the mute mode and slider index (as the user counts them) of a mute action
+func parseSliderMuteAction(entry string) (string, int, error) {
+	prefix, ok := sliderMutePrefix(entry)
+	if !ok {
+		return "", 0, fmt.Errorf("expected one of %s", strings.Join(sliderMutePrefixList(), "/"))
+	}
+
+	sliderIdx, err := strconv.Atoi(strings.TrimPrefix(entry, prefix))
+	if err != nil || sliderIdx < 0 {
+		return "", 0, fmt.Errorf("invalid slider index: %q", strings.TrimPrefix(entry, prefix))
+	}
+
+	return sliderMutePrefixes[prefix], sliderIdx, nil
+}
+
+func sliderMutePrefixList() []string {
+	return []string{actionMutePrefix, actionUnmutePrefix, actionMuteTogglePrefix}
+}
+
+// setSliderMute mutes, unmutes or toggles every session the given slider controls, returning whether they're now muted
+func (m *sessionMap) setSliderMute(sliderID int, mode string) (bool, error) {
+	targets, _ := m.deej.config.SliderMapping.get(sliderID)
+
+	sessions := []mutableSession{}
+	seen := map[Session]bool{}
+
+	for _, target := range targets {
+		for _, resolvedTarget := range m.resolveTarget(target) {
+			found, _ := m.find(resolvedTarget)
+
+			for _, session := range found {
+				mutable, ok := session.(mutableSession)
+				if !ok || seen[session] {
+					continue
+				}
+
+				seen[session] = true
+				sessions = append(sessions, mutable)
+			}
+		}
+	}
+
+	if len(sessions) == 0 {
+		return false, errNothingToMute
+	}
+
+	mute := mode == muteModeMute
+	if mode == muteModeToggle {
+		for _, session := range sessions {
+			if !session.GetMute() {
+				mute = true
+				break
+			}
+		}
+	}
+
+	for _, session := range sessions {
+		if err := session.SetMute(mute); err != nil {
+			return false, fmt.Errorf("set slider mute: %w", err)
+		}
+	}
+
+	return mute, nil
+}
+
+func (sio *SerialIO) muteSlider(logger *zap.SugaredLogger, sliderIdx int, mode string) {
+	sliderID := sliderIdx - sio.deej.config.MappingIndexBase
+
+	muted, err := sio.deej.sessions.setSliderMute(sliderID, mode)
+	if err != nil {
+		logger.Warnw("Failed to mute slider", "slider", sliderIdx, "mode", mode, "error", err)
+		return
+	}
+
+	logger.Infow("Changed slider mute", "slider", sliderIdx, "muted", muted)
+}
@@ -0,0 +1,106 @@
+package deej
+
+import (
+	"sync"
+	"time"
+)
+
+// some audio drivers crackle when volumes are written many times in quick succession. with a settle time,
+// moving a slider doesn't touch any volume until the slider stays put for that long, and then only its
+// final value is applied. this can be set for all sliders, or for specific ones
+
+// sliderSettleTime returns how long the given slider needs to stay put before its volume is applied
+func (cc *CanonicalConfig) sliderSettleTime(sliderID int) time.Duration {
+	if settleTime, ok := cc.SliderSettleTimeByID[sliderID]; ok {
+		return settleTime
+	}
+
+	return cc.SliderSettleTime
+}
+
+// settlingSlider is a slider that's still moving: its latest move, and the timer that's waiting for it to stop.
+// every move starts a new timer, and only the one for the latest move may settle it
+type settlingSlider struct {
+	latest     SliderMoveEvent
+	timer      *time.Timer
+	generation int
+	settled    bool
+}
+
+// sliderSettler holds back slider moves until their sliders settle. ready gets a value once any of them did,
+// for the session map to apply their latest moves from its own goroutine
+type sliderSettler struct {
+	sliders map[int]*settlingSlider
+	lock    sync.Locker
+
+	ready chan bool
+}
+
+func newSliderSettler() *sliderSettler {
+	return &sliderSettler{
+		sliders: map[int]*settlingSlider{},
+		lock:    &sync.Mutex{},
+		ready:   make(chan bool, 1),
+	}
+}
+
+// hold holds on to a slider move, until the slider hasn't moved for settleTime
+func (s *sliderSettler) hold(event SliderMoveEvent, settleTime time.Duration) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	slider, ok := s.sliders[event.SliderID]
+	if !ok {
+		slider = &settlingSlider{}
+		s.sliders[event.SliderID] = slider
+	} else {
+
+		// still moving, start waiting all over again. a timer that already fired is left waiting
+		// for the lock, and finds out it's stale once it has it
+		slider.timer.Stop()
+	}
+
+	slider.latest = event
+	slider.settled = false
+	slider.generation++
+
+	generation := slider.generation
+	slider.timer = time.AfterFunc(settleTime, func() {
+		s.settle(event.SliderID, generation)
+	})
+}
+
+// settle marks a slider as settled, if the timer that fired is the slider's latest one
+func (s *sliderSettler) settle(sliderID int, generation int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	slider, ok := s.sliders[sliderID]
+	if !ok || slider.generation != generation {
+		return
+	}
+
+	slider.settled = true
+
+	select {
+	case s.ready <- true:
+	default:
+	}
+}
+
+// settledMoves returns the latest move of every slider that settled, and forgets them
+func (s *sliderSettler) settledMoves() []SliderMoveEvent {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	moves := []SliderMoveEvent{}
+
+	for sliderID, slider := range s.sliders {
+		if slider.settled {
+			moves = append(moves, slider.latest)
+			delete(s.sliders, sliderID)
+		}
+	}
+
+	return moves
+}
@@ -0,0 +1,114 @@
+package deej
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestSliderSettler(t *testing.T) {
+	const settleTime = 30 * time.Millisecond
+
+	tests := []struct {
+		name    string
+		moves   []SliderMoveEvent
+		settled []SliderMoveEvent
+	}{
+		{
+			name:    "a single move settles as is",
+			moves:   []SliderMoveEvent{{SliderID: 0, PercentValue: 0.5}},
+			settled: []SliderMoveEvent{{SliderID: 0, PercentValue: 0.5}},
+		},
+		{
+			name: "only the latest move is applied",
+			moves: []SliderMoveEvent{
+				{SliderID: 0, PercentValue: 0.1},
+				{SliderID: 0, PercentValue: 0.2},
+				{SliderID: 0, PercentValue: 0.3},
+			},
+			settled: []SliderMoveEvent{{SliderID: 0, PercentValue: 0.3}},
+		},
+		{
+			name: "each slider settles on its own",
+			moves: []SliderMoveEvent{
+				{SliderID: 0, PercentValue: 0.1},
+				{SliderID: 1, PercentValue: 0.7},
+				{SliderID: 0, PercentValue: 0.2},
+			},
+			settled: []SliderMoveEvent{
+				{SliderID: 0, PercentValue: 0.2},
+				{SliderID: 1, PercentValue: 0.7},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settler := newSliderSettler()
+
+			for _, move := range test.moves {
+				settler.hold(move, settleTime)
+			}
+
+			// sliders that are still moving aren't applied yet
+			if moves := settler.settledMoves(); len(moves) != 0 {
+				t.Fatalf("settled early: %v", moves)
+			}
+
+			settled := []SliderMoveEvent{}
+			deadline := time.After(10 * settleTime)
+
+			for len(settled) < len(test.settled) {
+				select {
+				case <-settler.ready:
+					settled = append(settled, settler.settledMoves()...)
+				case <-deadline:
+					t.Fatalf("settled: got %v, want %v", settled, test.settled)
+				}
+			}
+
+			sort.Slice(settled, func(i, j int) bool { return settled[i].SliderID < settled[j].SliderID })
+
+			if !reflect.DeepEqual(settled, test.settled) {
+				t.Errorf("settled: got %v, want %v", settled, test.settled)
+			}
+
+			// and once applied, they're forgotten
+			select {
+			case <-settler.ready:
+				if moves := settler.settledMoves(); len(moves) != 0 {
+					t.Errorf("settled again: %v", moves)
+				}
+			case <-time.After(2 * settleTime):
+			}
+		})
+	}
+}
+
+func TestSliderSettlerRestartsWhileMoving(t *testing.T) {
+	const settleTime = 100 * time.Millisecond
+
+	settler := newSliderSettler()
+
+	// keep moving for longer than the settle time, just never holding still for that long
+	for step := 1; step <= 4; step++ {
+		settler.hold(SliderMoveEvent{SliderID: 0, PercentValue: float32(step) / 10}, settleTime)
+		time.Sleep(settleTime / 4)
+
+		if moves := settler.settledMoves(); len(moves) != 0 {
+			t.Fatalf("settled while moving: %v", moves)
+		}
+	}
+
+	select {
+	case <-settler.ready:
+	case <-time.After(10 * settleTime):
+		t.Fatal("never settled")
+	}
+
+	want := []SliderMoveEvent{{SliderID: 0, PercentValue: 0.4}}
+	if moves := settler.settledMoves(); !reflect.DeepEqual(moves, want) {
+		t.Errorf("settled: got %v, want %v", moves, want)
+	}
+}
@@ -0,0 +1,82 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// the cycle_slider_target button action points a slider at the next target in a list with every press, i.e.
+// "cycle_slider_target:4:[spotify.exe,chrome.exe,discord.exe]" has slider 4 control Spotify, then Chrome, then Discord.
+// the chosen target replaces whatever the slider is mapped to (in any profile and bank), until the config file
+// changes. if the slider isn't on one of the listed targets yet, the first one is picked
+const actionCycleSliderTargetPrefix = "cycle_slider_target:"
+
+// parseCycleSliderTargetAction reads the slider index (as the user counts them) and targets out of the action's argument
+func parseCycleSliderTargetAction(argument string) (int, []string, error) {
+	parts := strings.SplitN(argument, ":", 2)
+	if len(parts) != 2 {
+		return 0, nil, fmt.Errorf("expected %q, got %q", "<slider>:[<target>,<target>,...]", argument)
+	}
+
+	sliderIdx, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || sliderIdx < 0 {
+		return 0, nil, fmt.Errorf("invalid slider index: %q", parts[0])
+	}
+
+	list := strings.TrimSpace(parts[1])
+	if !strings.HasPrefix(list, "[") || !strings.HasSuffix(list, "]") {
+		return 0, nil, fmt.Errorf("target list must be in square brackets: %q", list)
+	}
+
+	targets := []string{}
+
+	for _, target := range strings.Split(strings.Trim(list, "[]"), ",") {
+		if target = strings.TrimSpace(target); target != "" {
+			targets = append(targets, target)
+		}
+	}
+
+	if len(targets) == 0 {
+		return 0, nil, errors.New("target list is empty")
+	}
+
+	return sliderIdx, targets, nil
+}
+
+// cycleSliderTarget points a slider at the target after the one it's on right now (out of the given ones),
+// and returns it. nothing else changed, so components aren't told to reload - the caller re-sends just this slider
+func (cc *CanonicalConfig) cycleSliderTarget(sliderID int, targets []string) string {
+	cc.profileLock.Lock()
+	defer cc.profileLock.Unlock()
+
+	next := targets[0]
+
+	if current, ok := cc.SliderMapping.get(sliderID); ok && len(current) == 1 {
+		for idx, target := range targets {
+			if strings.EqualFold(target, current[0]) {
+				next = targets[(idx+1)%len(targets)]
+				break
+			}
+		}
+	}
+
+	cc.sliderTargetOverrides[sliderID] = []string{next}
+	cc.applyProfile()
+
+	return next
+}
+
+func (sio *SerialIO) cycleSliderTarget(logger *zap.SugaredLogger, notifier Notifier, sliderIdx int, targets []string) {
+	target := sio.deej.config.cycleSliderTarget(sliderIdx-sio.deej.config.MappingIndexBase, targets)
+
+	logger.Infow("Changed slider target", "slider", sliderIdx, "target", target)
+
+	// the slider's volume goes to its new target right away
+	sio.resendSlider(sliderIdx - sio.deej.config.MappingIndexBase)
+
+	notifier.Notify("Slider target changed", fmt.Sprintf("Slider %d now controls %s", sliderIdx, target))
+}
@@ -0,0 +1,111 @@
+package deej
+
+import (
+	"fmt"
+	"strings"
+)
+
+// sliderTargetSyntax is a kind of target a slider can be mapped to. most of them resolve to audio sessions, the
+// rest (key presses, the TV) are applied directly instead
+type sliderTargetSyntax struct {
+	syntax      string
+	description string
+	example     string
+
+	matches func(target string) bool
+
+	// for targets that aren't audio sessions, sets them to a slider's value. it returns false if the target
+	// can't be reached at all
+	apply func(m *sessionMap, sliderID int, target string, percentValue float32) (bool, error)
+}
+
+// sliderTargetSyntaxes lists everything a slider can be mapped to. a target is whichever of these matches it first
+var sliderTargetSyntaxes = []sliderTargetSyntax{
+	{
+		syntax:      masterSessionName,
+		description: "the default output device's volume",
+		example:     masterSessionName,
+		matches:     func(target string) bool { return strings.ToLower(target) == masterSessionName },
+	},
+	{
+		syntax:      inputSessionName,
+		description: "the default input device's level",
+		example:     inputSessionName,
+		matches:     func(target string) bool { return strings.ToLower(target) == inputSessionName },
+	},
+	{
+		syntax:      systemSessionName,
+		description: "the volume of system sounds (Windows only)",
+		example:     systemSessionName,
+		matches:     func(target string) bool { return strings.ToLower(target) == systemSessionName },
+	},
+	{
+		syntax:      loopbackSessionName,
+		description: "the level of desktop audio capture (stereo mix, or the output's monitor)",
+		example:     loopbackSessionName,
+		matches:     func(target string) bool { return strings.ToLower(target) == loopbackSessionName },
+	},
+	{
+		syntax:      specialTargetTransformPrefix + specialTargetCurrentWindow,
+		description: "whichever app owns the active window (Windows only)",
+		example:     specialTargetTransformPrefix + specialTargetCurrentWindow,
+		matches: func(target string) bool {
+			return strings.ToLower(target) == specialTargetTransformPrefix+specialTargetCurrentWindow
+		},
+	},
+	{
+		syntax:      specialTargetTransformPrefix + specialTargetAllUnmapped,
+		description: "every app that no other slider is mapped to",
+		example:     specialTargetTransformPrefix + specialTargetAllUnmapped,
+		matches: func(target string) bool {
+			return strings.ToLower(target) == specialTargetTransformPrefix+specialTargetAllUnmapped
+		},
+	},
+	{
+		syntax: channelTargetPrefix + "<device>:<channel>",
+		description: fmt.Sprintf("a single channel of a device, one of %s or a 0-based index", strings.Join([]string{
+			channelFrontLeft, channelFrontRight, channelFrontCenter, channelLFE,
+			channelBackLeft, channelBackRight, channelSideLeft, channelSideRight,
+		}, ", ")),
+		example: channelTargetPrefix + "Speakers:" + channelFrontLeft,
+		matches: func(target string) bool { return strings.HasPrefix(strings.ToLower(target), channelTargetPrefix) },
+	},
+	{
+		syntax:      keyTargetPrefix + "<down key>" + keyTargetSeparator + "<up key>",
+		description: "presses the first key for every step the slider moves down, and the second for every step up",
+		example:     keyTargetPrefix + "VOLUME_DOWN" + keyTargetSeparator + "VOLUME_UP",
+		matches:     isKeyTarget,
+		apply: func(m *sessionMap, sliderID int, target string, percentValue float32) (bool, error) {
+			return true, m.applyKeyTarget(sliderID, target, percentValue)
+		},
+	},
+	{
+		syntax:      cecTargetTV,
+		description: "the TV's volume over HDMI-CEC (needs cec-client)",
+		example:     cecTargetTV,
+		matches:     isCECTarget,
+		apply: func(m *sessionMap, sliderID int, target string, percentValue float32) (bool, error) {
+			return m.applyCECTarget(sliderID, target, percentValue)
+		},
+	},
+
+	// anything else is taken for an app
+	{
+		syntax:      "<process name>",
+		description: "an app's volume, by the name of its process (ignoring case)",
+		example:     "chrome.exe",
+		matches:     func(target string) bool { return true },
+	},
+}
+
+// sliderTargetSyntaxFor returns the kind of target a slider target is
+func sliderTargetSyntaxFor(target string) sliderTargetSyntax {
+	for _, syntax := range sliderTargetSyntaxes {
+		if syntax.matches(target) {
+			return syntax
+		}
+	}
+
+	// the last one takes anything, so this is never reached
+	return sliderTargetSyntaxes[len(sliderTargetSyntaxes)-1]
+}
@@ -0,0 +1,134 @@
+//go:build slim
+// +build slim
+
+package deej
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// slim builds (go build -tags slim) leave out everything that talks to the network: websocket, MQTT and relay
+// connections, webhooks, deej's own integrations (OBS and Discord), and the HTTP API along with its web UI. that's what
+// pulls in most of deej's dependencies, so what's left is a smaller binary that's lighter on old machines - serial
+// (and the other local connections), sessions and buttons, with nothing listening on a port. the pieces below stand
+// in for the ones that were left out, and let the user know when their config asks for one of them
+const slimBuildHint = "this build of deej leaves it out, use a full build instead"
+
+func notInSlimBuild(feature string) error {
+	return fmt.Errorf("%s: %s", feature, slimBuildHint)
+}
+
+func (sio *SerialIO) openWebSocket() (io.ReadWriteCloser, error) {
+	sio.logger.Warnw("Websocket connection requested, but websocket support isn't included", "hint", slimBuildHint)
+	return nil, notInSlimBuild("websocket connections")
+}
+
+func (sio *SerialIO) openMQTT() (io.ReadWriteCloser, error) {
+	sio.logger.Warnw("MQTT connection requested, but MQTT support isn't included", "hint", slimBuildHint)
+	return nil, notInSlimBuild("MQTT connections")
+}
+
+func (sio *SerialIO) openRelay() (io.ReadWriteCloser, error) {
+	sio.logger.Warnw("Relay follower role set, but relay support isn't included", "hint", slimBuildHint)
+	return nil, notInSlimBuild("relay links")
+}
+
+// reconnectRelay has nothing to reconnect to, since a relay link can't be opened in the first place
+func (sio *SerialIO) reconnectRelay(logger *zap.SugaredLogger) {
+	logger.Debug("Not reconnecting relay link, relay support isn't included")
+}
+
+func sendWebhook(webhook Webhook, timeout time.Duration) (int, error) {
+	return 0, notInSlimBuild("webhooks")
+}
+
+// obsClient stands in for the OBS integration, so OBS actions can tell the user why they don't do anything
+type obsClient struct{}
+
+func newOBSClient(deej *Deej, logger *zap.SugaredLogger) *obsClient {
+	return &obsClient{}
+}
+
+func (c *obsClient) call(request obsRequest) error {
+	return notInSlimBuild("OBS control")
+}
+
+// discordClient stands in for the Discord integration, which never connects in slim builds
+type discordClient struct{}
+
+func newDiscordClient(deej *Deej, logger *zap.SugaredLogger) *discordClient {
+	return &discordClient{}
+}
+
+func (c *discordClient) toggle(what string) (discordVoiceState, error) {
+	return discordVoiceState{}, notInSlimBuild("Discord control")
+}
+
+func (c *discordClient) voiceState() (discordVoiceState, bool) {
+	return discordVoiceState{}, false
+}
+
+// builtinIntegrations returns deej's own integrations, which are all left out
+func builtinIntegrations(deej *Deej) map[string]Integration {
+	return map[string]Integration{}
+}
+
+// relay stands in for the relay leader, which never serves a relay link in slim builds
+type relay struct {
+	deej   *Deej
+	logger *zap.SugaredLogger
+}
+
+func newRelay(deej *Deej, logger *zap.SugaredLogger) *relay {
+	return &relay{
+		deej:   deej,
+		logger: logger.Named("relay"),
+	}
+}
+
+func (r *relay) start() error {
+	if r.deej.config.RelayRole == relayRoleLeader {
+		r.logger.Warnw("Relay leader role set, but relay support isn't included", "hint", slimBuildHint)
+		r.deej.notifier.Notify("Relay link not available",
+			"This build of deej doesn't include relay support. Use a full build to share the device.")
+	}
+
+	return nil
+}
+
+func (r *relay) stop() {}
+
+func (r *relay) forwardLine(line string) {}
+
+// httpAPI stands in for the HTTP API, which never listens in slim builds
+type httpAPI struct {
+	deej   *Deej
+	logger *zap.SugaredLogger
+}
+
+func newHTTPAPI(deej *Deej, logger *zap.SugaredLogger) (*httpAPI, error) {
+	return &httpAPI{
+		deej:   deej,
+		logger: logger.Named("http_api"),
+	}, nil
+}
+
+func (api *httpAPI) start() error {
+	if api.deej.config.HTTPAPIListen != "" {
+		api.logger.Warnw("HTTP API listen address set, but the HTTP API isn't included",
+			"address", api.deej.config.HTTPAPIListen,
+			"hint", slimBuildHint)
+	}
+
+	return nil
+}
+
+func (api *httpAPI) stop() {}
+
+func (api *httpAPI) webUIAddress() (string, bool) {
+	return "", false
+}
@@ -0,0 +1,88 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// commandSpeaker reads text out loud with a speech command. speech-dispatcher (spd-say) comes with most desktop
+// distros, and espeak is the usual fallback. each announcement kills the previous one if it's still going
+type commandSpeaker struct {
+	executable string
+	cancelArgs []string
+
+	lock    sync.Locker
+	current *exec.Cmd
+
+	// closed once the current command exited, which is the only way to tell without racing its Wait
+	currentDone chan bool
+}
+
+var errNoSpeechCommand = errors.New("neither spd-say nor espeak found")
+
+func newSpeaker(logger *zap.SugaredLogger) (speaker, error) {
+	candidates := []struct {
+		executable string
+		cancelArgs []string
+	}{
+		{"spd-say", []string{"--cancel"}},
+		{"espeak-ng", nil},
+		{"espeak", nil},
+	}
+
+	for _, candidate := range candidates {
+		path, err := exec.LookPath(candidate.executable)
+		if err != nil {
+			continue
+		}
+
+		logger.Debugw("Using speech command", "path", path)
+
+		return &commandSpeaker{
+			executable: path,
+			cancelArgs: candidate.cancelArgs,
+			lock:       &sync.Mutex{},
+		}, nil
+	}
+
+	return nil, errNoSpeechCommand
+}
+
+func (s *commandSpeaker) speak(text string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.current != nil {
+		select {
+		case <-s.currentDone:
+		default:
+			s.current.Process.Kill()
+		}
+	}
+
+	// speech-dispatcher speaks on its own, so killing our client wouldn't stop it
+	if s.cancelArgs != nil {
+		exec.Command(s.executable, s.cancelArgs...).Run()
+	}
+
+	cmd := exec.Command(s.executable, text)
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("start speech command: %w", err)
+	}
+
+	done := make(chan bool)
+
+	s.current = cmd
+	s.currentDone = done
+
+	go func() {
+		cmd.Wait()
+		close(done)
+	}()
+
+	return nil
+}
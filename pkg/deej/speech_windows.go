@@ -0,0 +1,97 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+
+	ole "github.com/go-ole/go-ole"
+	"github.com/go-ole/go-ole/oleutil"
+	"go.uber.org/zap"
+)
+
+// sapiSpeaker reads text out loud with SAPI, windows' built-in speech synthesizer. COM objects belong to
+// the thread that created them, so a single goroutine locked to its thread does all the talking
+type sapiSpeaker struct {
+	logger *zap.SugaredLogger
+	texts  chan string
+}
+
+const (
+	sapiVoiceProgID = "SAPI.SpVoice"
+
+	// SpeechVoiceSpeakFlags: return right away, and cut off whatever was being said
+	sapiSpeakAsync        = 1
+	sapiPurgeBeforeSpeak  = 2
+	sapiAnnouncementFlags = sapiSpeakAsync | sapiPurgeBeforeSpeak
+)
+
+func newSpeaker(logger *zap.SugaredLogger) (speaker, error) {
+	s := &sapiSpeaker{
+		logger: logger,
+		texts:  make(chan string, 1),
+	}
+
+	ready := make(chan error)
+	go s.run(ready)
+
+	if err := <-ready; err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *sapiSpeaker) run(ready chan error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if err := ole.CoInitializeEx(0, ole.COINIT_APARTMENTTHREADED); err != nil {
+
+		// E_FALSE (1) just means COM was already initialized on this thread
+		oleError := &ole.OleError{}
+		if !errors.As(err, &oleError) || oleError.Code() != 1 {
+			ready <- fmt.Errorf("call CoInitializeEx: %w", err)
+			return
+		}
+	}
+
+	defer ole.CoUninitialize()
+
+	unknown, err := oleutil.CreateObject(sapiVoiceProgID)
+	if err != nil {
+		ready <- fmt.Errorf("create %s: %w", sapiVoiceProgID, err)
+		return
+	}
+
+	defer unknown.Release()
+
+	voice, err := unknown.QueryInterface(ole.IID_IDispatch)
+	if err != nil {
+		ready <- fmt.Errorf("query %s dispatch: %w", sapiVoiceProgID, err)
+		return
+	}
+
+	defer voice.Release()
+
+	ready <- nil
+
+	for text := range s.texts {
+		if _, err := oleutil.CallMethod(voice, "Speak", text, sapiAnnouncementFlags); err != nil {
+			s.logger.Warnw("Failed to speak", "text", text, "error", err)
+		}
+	}
+}
+
+func (s *sapiSpeaker) speak(text string) error {
+
+	// only the latest announcement matters, drop one that's still waiting
+	select {
+	case <-s.texts:
+	default:
+	}
+
+	s.texts <- text
+
+	return nil
+}
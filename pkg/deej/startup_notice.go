@@ -0,0 +1,143 @@
+package deej
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// with startup_notification on, deej lets the user know it's up and what it's talking to once it first connects
+// to the device. that's mostly for setups that start deej along with the PC (or have no screen near the device),
+// where otherwise the only way to tell whether it's running is to move a slider and see if anything happens
+const (
+	// how long to give the device to report its sliders and buttons before telling the user about them
+	startupNotificationWait = 3 * time.Second
+	startupNotificationPoll = 100 * time.Millisecond
+)
+
+// startupNotice makes sure the startup notification is only shown once, rather than on every reconnect
+type startupNotice struct {
+	lock sync.Locker
+	sent bool
+}
+
+func newStartupNotice() *startupNotice {
+	return &startupNotice{
+		lock: &sync.Mutex{},
+	}
+}
+
+// claim returns true the first time it's called, and false from then on
+func (n *startupNotice) claim() bool {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+
+	if n.sent {
+		return false
+	}
+
+	n.sent = true
+
+	return true
+}
+
+// notifyStartup shows the startup notification once the device reported what it has (or it took too long to).
+// if the connection goes away meanwhile, the notification waits for the next one
+func (sio *SerialIO) notifyStartup(ctx context.Context, logger *zap.SugaredLogger) {
+	deadline := time.NewTimer(startupNotificationWait)
+	defer deadline.Stop()
+
+	ticker := time.NewTicker(startupNotificationPoll)
+	defer ticker.Stop()
+
+	waiting := true
+	for waiting && (sio.lastKnownNumSliders == 0 || sio.lastKnownNumButtons == 0) {
+		select {
+		case <-ctx.Done():
+			return
+		case <-deadline.C:
+			logger.Debug("Device didn't report both sliders and buttons in time, notifying with what we have")
+			waiting = false
+		case <-ticker.C:
+		}
+	}
+
+	if !sio.startupNotice.claim() {
+		return
+	}
+
+	message := sio.startupSummary()
+
+	logger.Infow("Showing startup notification", "summary", message)
+	sio.deej.notifier.Notify("deej is running", message)
+}
+
+// startupSummary describes what deej connected to, what the device has and which profile is active, one per line
+func (sio *SerialIO) startupSummary() string {
+	lines := []string{fmt.Sprintf("Connected to %s", describeConnection(sio.connInfo))}
+
+	controls := []string{}
+	if sio.lastKnownNumSliders > 0 {
+		controls = append(controls, pluralize(sio.lastKnownNumSliders, "slider"))
+	}
+
+	if sio.lastKnownNumButtons > 0 {
+		controls = append(controls, pluralize(sio.lastKnownNumButtons, "button"))
+	}
+
+	if len(controls) > 0 {
+		lines = append(lines, strings.Join(controls, ", "))
+	} else {
+		lines = append(lines, "No sliders or buttons reported yet")
+	}
+
+	profile, bankOffset := sio.deej.config.ActiveProfile()
+	line := fmt.Sprintf("Profile: %s", profile)
+	if bankOffset != 0 {
+		line = fmt.Sprintf("%s (bank %d)", line, bankOffset)
+	}
+
+	return strings.Join(append(lines, line), "\n")
+}
+
+// describeConnection names the device (or whatever stands in for it) the way the user set it up
+func describeConnection(info ConnectionInfo) string {
+	switch info.Type {
+	case connectionTypeWebSocket:
+		if info.WebSocketURL != "" {
+			return redactURL(info.WebSocketURL)
+		}
+
+		return fmt.Sprintf("websocket client on %s", info.WebSocketListen)
+	case connectionTypeMQTT:
+		return fmt.Sprintf("MQTT broker %s", redactURL(info.MQTTBroker))
+	case connectionTypeBluetooth:
+		return fmt.Sprintf("bluetooth device %s", info.BluetoothAddress)
+	case connectionTypeHID:
+		if info.HIDDevicePath != "" {
+			return fmt.Sprintf("HID device %s", info.HIDDevicePath)
+		}
+
+		return fmt.Sprintf("HID device %s:%s", info.HIDVendorID, info.HIDProductID)
+	case connectionTypeRelay:
+		return fmt.Sprintf("relay leader %s", info.RelayLeader)
+	case connectionTypeMock:
+		return "simulated device"
+	case connectionTypeSerial, "":
+		return info.COMPort
+	default:
+		return fmt.Sprintf("%s device", info.Type)
+	}
+}
+
+func pluralize(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("%d %s", count, noun)
+	}
+
+	return fmt.Sprintf("%d %ss", count, noun)
+}
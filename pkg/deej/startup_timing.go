@@ -0,0 +1,71 @@
+package deej
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// deej keeps track of how long each part of starting up takes, and logs a breakdown once it's connected
+// (or failed to connect) for the first time. this turns "deej takes forever to start" into something we
+// can actually look into
+type startupTimer struct {
+	lock sync.Locker
+
+	start    time.Time
+	last     time.Time
+	phases   []startupPhase
+	finished bool
+}
+
+type startupPhase struct {
+	name     string
+	duration time.Duration
+}
+
+func newStartupTimer() *startupTimer {
+	now := time.Now()
+
+	return &startupTimer{
+		lock:   &sync.Mutex{},
+		start:  now,
+		last:   now,
+		phases: []startupPhase{},
+	}
+}
+
+// mark records that a phase just ended, having started when the previous one did
+func (t *startupTimer) mark(name string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.finished {
+		return
+	}
+
+	now := time.Now()
+	t.phases = append(t.phases, startupPhase{name: name, duration: now.Sub(t.last)})
+	t.last = now
+}
+
+// finish logs the breakdown. anything marked after this is ignored, so restarts don't count
+func (t *startupTimer) finish(logger *zap.SugaredLogger) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.finished {
+		return
+	}
+
+	t.finished = true
+
+	keysAndValues := []interface{}{}
+	for _, phase := range t.phases {
+		keysAndValues = append(keysAndValues, phase.name, phase.duration.Round(time.Millisecond).String())
+	}
+
+	keysAndValues = append(keysAndValues, "total", t.last.Sub(t.start).Round(time.Millisecond).String())
+
+	logger.Infow("Startup timing", keysAndValues...)
+}
@@ -0,0 +1,239 @@
+package deej
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// the supervisor keeps deej's long-running modules alive. modules send it heartbeats while they're running,
+// and report crashes (recovered panics) and failures to it. a module that crashes, fails or stops sending
+// heartbeats is restarted on its own, without touching the rest of deej. repeated restarts back off
+// exponentially, so a module that keeps failing doesn't spin
+const (
+	supervisorCheckInterval = time.Second
+
+	// modules should send heartbeats at least this often
+	moduleHeartbeatInterval = 2 * time.Second
+
+	minModuleRestartDelay = time.Second
+	maxModuleRestartDelay = time.Minute
+
+	// a module that stays up this long after (re)starting is healthy again, and its backoff starts over
+	moduleHealthyUptime = time.Minute
+)
+
+// supervised module names
+const (
+	moduleSerial     = "serial"
+	moduleSessionMap = "session map"
+	moduleAutoMix    = "auto-mix"
+	moduleAutoPause  = "auto-pause"
+	moduleAppStart   = "app start watcher"
+	moduleRecording  = "recording watcher"
+	moduleHTTPAPI    = "http api"
+
+	moduleVolumeApplier = "volume applier"
+)
+
+// how long each module may go without a heartbeat before it's considered hung. these leave plenty of
+// room for slow but legitimate work, such as re-acquiring all audio sessions
+const (
+	serialHangTimeout     = 15 * time.Second
+	sessionMapHangTimeout = 15 * time.Second
+	autoMixHangTimeout    = 15 * time.Second
+)
+
+// moduleRestarter brings a module back up. done is closed when the module is restarted again,
+// so any goroutine left over from a hung instance knows to quit once it wakes up
+type moduleRestarter func(done chan bool)
+
+type supervisedModule struct {
+	name    string
+	restart moduleRestarter
+	done    chan bool
+
+	// how long the module may go without a heartbeat before it's considered hung, or 0 to only watch for crashes
+	hangTimeout time.Duration
+
+	// modules only need to send heartbeats while active (i.e. the serial module while connected)
+	active        bool
+	lastHeartbeat time.Time
+	startedAt     time.Time
+
+	down         bool
+	restartDelay time.Duration
+	nextRestart  time.Time
+}
+
+type supervisor struct {
+	logger *zap.SugaredLogger
+
+	modules map[string]*supervisedModule
+	lock    sync.Locker
+
+	stopChannel chan bool
+}
+
+func newSupervisor(logger *zap.SugaredLogger) *supervisor {
+	logger = logger.Named("supervisor")
+
+	s := &supervisor{
+		logger:      logger,
+		modules:     map[string]*supervisedModule{},
+		lock:        &sync.Mutex{},
+		stopChannel: make(chan bool),
+	}
+
+	logger.Debug("Created supervisor instance")
+
+	return s
+}
+
+// supervise starts watching a module, and returns the done channel for its first instance
+func (s *supervisor) supervise(name string, hangTimeout time.Duration, restart moduleRestarter) chan bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	module := &supervisedModule{
+		name:          name,
+		restart:       restart,
+		done:          make(chan bool),
+		hangTimeout:   hangTimeout,
+		lastHeartbeat: time.Now(),
+		startedAt:     time.Now(),
+		restartDelay:  minModuleRestartDelay,
+	}
+
+	s.modules[name] = module
+
+	return module.done
+}
+
+// heartbeat tells the supervisor that a module is alive and well
+func (s *supervisor) heartbeat(name string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if module, ok := s.modules[name]; ok {
+		module.active = true
+		module.lastHeartbeat = time.Now()
+	}
+}
+
+// idle tells the supervisor that a module has nothing to do for now, and won't send heartbeats until it does
+func (s *supervisor) idle(name string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if module, ok := s.modules[name]; ok {
+		module.active = false
+	}
+}
+
+// guard should be deferred at the top of every supervised goroutine. it turns a panic into a crash
+// report (and a crashlog) instead of taking all of deej down with it
+func (s *supervisor) guard(name string) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	crashlogPath, err := writeCrashlog(r)
+	if err != nil {
+		s.logger.Warnw("Failed to write crashlog for crashed module", "module", name, "error", err)
+	}
+
+	s.logger.Errorw("Module crashed", "module", name, "error", r, "crashlogPath", crashlogPath)
+	s.reportFailure(name, fmt.Errorf("panic: %v", r))
+}
+
+// reportFailure marks a module as down, to be restarted once its backoff delay passes
+func (s *supervisor) reportFailure(name string, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	module, ok := s.modules[name]
+	if !ok || module.down {
+		return
+	}
+
+	s.markDown(module, err)
+}
+
+// assumes the lock is held
+func (s *supervisor) markDown(module *supervisedModule, err error) {
+	now := time.Now()
+
+	if now.Sub(module.startedAt) >= moduleHealthyUptime {
+		module.restartDelay = minModuleRestartDelay
+	}
+
+	module.down = true
+	module.nextRestart = now.Add(module.restartDelay)
+
+	s.logger.Warnw("Module is down, restarting it soon", "module", module.name, "error", err, "delay", module.restartDelay)
+
+	if module.restartDelay *= 2; module.restartDelay > maxModuleRestartDelay {
+		module.restartDelay = maxModuleRestartDelay
+	}
+}
+
+func (s *supervisor) run() {
+	s.logger.Debug("Starting supervisor")
+
+	ticker := time.NewTicker(supervisorCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopChannel:
+			s.logger.Debug("Stopping supervisor")
+			return
+
+		case <-ticker.C:
+			for _, module := range s.dueForRestart() {
+				s.logger.Infow("Restarting module", "module", module.name)
+				module.restart(module.done)
+			}
+		}
+	}
+}
+
+func (s *supervisor) stop() {
+	close(s.stopChannel)
+}
+
+// dueForRestart marks hung modules as down, and returns the modules that should be restarted right now
+func (s *supervisor) dueForRestart() []*supervisedModule {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	now := time.Now()
+	result := []*supervisedModule{}
+
+	for _, module := range s.modules {
+		if !module.down && module.active && module.hangTimeout > 0 && now.Sub(module.lastHeartbeat) > module.hangTimeout {
+			s.markDown(module, fmt.Errorf("no heartbeat for %s", now.Sub(module.lastHeartbeat).Round(time.Second)))
+		}
+
+		if !module.down || now.Before(module.nextRestart) {
+			continue
+		}
+
+		// let whatever is left of the previous instance know it's been replaced
+		close(module.done)
+
+		module.done = make(chan bool)
+		module.down = false
+		module.active = false
+		module.lastHeartbeat = now
+		module.startedAt = now
+
+		result = append(result, module)
+	}
+
+	return result
+}
@@ -0,0 +1,34 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// lock, sleep and display_off control the PC itself: lock the session (i.e. walking away from it), put it to
+// sleep, or turn the displays off without locking. each OS does these its own way, see controlSystem
+const (
+	actionSystemLock       = "lock"
+	actionSystemSleep      = "sleep"
+	actionSystemDisplayOff = "display_off"
+)
+
+var systemActions = []string{actionSystemLock, actionSystemSleep, actionSystemDisplayOff}
+
+var errNoSystemCommand = errors.New("no command found for this")
+
+// controlSystem carries out a system action in the background, since some of them take a moment to return
+func (sio *SerialIO) controlSystem(logger *zap.SugaredLogger, notifier Notifier, action buttonAction) {
+	go func() {
+		if err := controlSystem(action.name); err != nil {
+			logger.Warnw("Failed to control system", "action", action.name, "error", err)
+			notifier.Notify("Can't control system!", fmt.Sprintf("%s didn't go through: %v", action.name, err))
+
+			return
+		}
+
+		logger.Infow("Controlled system", "action", action.name)
+	}()
+}
@@ -0,0 +1,54 @@
+package deej
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// each system action has a few commands that may do it, tried in order until one is installed and works.
+// logind's are there on most distros, the others cover desktops that don't go through it
+var systemCommands = map[string][][]string{
+	actionSystemLock: {
+		{"loginctl", "lock-session"},
+		{"xdg-screensaver", "lock"},
+		{"gnome-screensaver-command", "--lock"},
+	},
+	actionSystemSleep: {
+		{"systemctl", "suspend"},
+		{"loginctl", "suspend"},
+	},
+	actionSystemDisplayOff: {
+		{"xset", "dpms", "force", "off"},
+		{"kscreen-doctor", "--dpms", "off"},
+	},
+}
+
+func controlSystem(action string) error {
+	candidates, ok := systemCommands[action]
+	if !ok {
+		return errUnknownButtonAction
+	}
+
+	var lastErr error
+
+	for _, candidate := range candidates {
+		path, err := exec.LookPath(candidate[0])
+		if err != nil {
+			continue
+		}
+
+		output, err := exec.Command(path, candidate[1:]...).CombinedOutput()
+		if err == nil {
+			return nil
+		}
+
+		lastErr = fmt.Errorf("%s: %w (%s)", strings.Join(candidate, " "), err, strings.TrimSpace(string(output)))
+	}
+
+	if lastErr != nil {
+		return lastErr
+	}
+
+	return errNoSystemCommand
+}
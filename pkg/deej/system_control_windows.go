@@ -0,0 +1,50 @@
+package deej
+
+import (
+	"fmt"
+	"syscall"
+)
+
+const (
+	hwndBroadcast   = 0xFFFF
+	wmSysCommand    = 0x0112
+	scMonitorPower  = 0xF170
+	monitorPowerOff = 2
+)
+
+var (
+	procLockWorkStation = syscall.NewLazyDLL("user32.dll").NewProc("LockWorkStation")
+	procPostMessage     = syscall.NewLazyDLL("user32.dll").NewProc("PostMessageW")
+	procSetSuspendState = syscall.NewLazyDLL("powrprof.dll").NewProc("SetSuspendState")
+)
+
+// controlSystem locks the workstation, puts it to sleep (not hibernate, unless hibernation replaced sleep in
+// the power settings) or asks every window to turn the monitors off
+func controlSystem(action string) error {
+	var proc *syscall.LazyProc
+	var args []uintptr
+
+	switch action {
+	case actionSystemLock:
+		proc = procLockWorkStation
+	case actionSystemSleep:
+		proc = procSetSuspendState
+		args = []uintptr{0, 0, 0}
+	case actionSystemDisplayOff:
+		proc = procPostMessage
+		args = []uintptr{hwndBroadcast, wmSysCommand, scMonitorPower, monitorPowerOff}
+	default:
+		return errUnknownButtonAction
+	}
+
+	if err := proc.Find(); err != nil {
+		return fmt.Errorf("find %s: %w", proc.Name, err)
+	}
+
+	// these all return zero when they fail
+	if result, _, err := proc.Call(args...); result == 0 {
+		return fmt.Errorf("call %s: %w", proc.Name, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/thoas/go-funk"
+)
+
+// the system_sounds button action mutes windows' system sounds (the sound scheme's event sounds) without touching
+// their volume, i.e. to keep notification dings out of a recording. map it to "system_sounds:toggle", or to
+// "system_sounds:mute" on press and "system_sounds:unmute" on release (see release_mapping) to only mute while held.
+// their volume itself is controlled by mapping a slider to the "system" target
+const (
+	actionSystemSoundsPrefix = "system_sounds:"
+
+	muteModeMute   = "mute"
+	muteModeUnmute = "unmute"
+	muteModeToggle = "toggle"
+)
+
+var muteModes = []string{muteModeMute, muteModeUnmute, muteModeToggle}
+
+var errNoSystemSounds = errors.New("no system sounds session")
+
+// mutableSession is implemented by sessions that can be muted and unmuted
+type mutableSession interface {
+	muteSession
+	SetMute(m bool) error
+}
+
+func parseMuteMode(mode string) (string, error) {
+	mode = strings.ToLower(mode)
+	if !funk.ContainsString(muteModes, mode) {
+		return "", fmt.Errorf("expected one of %s, got %q", strings.Join(muteModes, "/"), mode)
+	}
+
+	return mode, nil
+}
+
+// setSystemSoundsMute mutes, unmutes or toggles the system sounds session, returning whether it's now muted
+func (m *sessionMap) setSystemSoundsMute(mode string) (bool, error) {
+	sessions, ok := m.get(systemSessionName)
+	if !ok {
+		return false, errNoSystemSounds
+	}
+
+	muted := false
+
+	for _, session := range sessions {
+		mutable, ok := session.(mutableSession)
+		if !ok {
+			continue
+		}
+
+		mute := mode == muteModeMute || (mode == muteModeToggle && !mutable.GetMute())
+		if err := mutable.SetMute(mute); err != nil {
+			return false, fmt.Errorf("set system sounds mute: %w", err)
+		}
+
+		muted = mute
+	}
+
+	return muted, nil
+}
@@ -0,0 +1,145 @@
+package deej
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/spf13/cast"
+	"go.uber.org/zap"
+)
+
+// toggle buttons alternate between two sets of actions: the first press toggles the button on and fires the
+// "activate" actions, the next one toggles it off and fires the "deactivate" ones, and so on (i.e. muting an app,
+// then restoring its volume with deej:undo). whether each button is on is tracked here, and shown in the tray
+// and the HTTP API. the keys aren't "on" and "off" since yaml reads those as booleans
+const (
+	toggleKeyOn  = "activate"
+	toggleKeyOff = "deactivate"
+)
+
+type buttonToggle struct {
+	on  []buttonAction
+	off []buttonAction
+}
+
+// buttonToggleMap holds the toggle actions of each button, by button index
+type buttonToggleMap struct {
+	m    map[int]buttonToggle
+	lock sync.Locker
+}
+
+// toggleMapFromConfig also returns every mapping entry that couldn't be resolved into an action
+func toggleMapFromConfig(userMapping map[string]interface{}, indexBase int) (*buttonToggleMap, []error) {
+	resultMap := &buttonToggleMap{
+		m:    make(map[int]buttonToggle),
+		lock: &sync.Mutex{},
+	}
+
+	problems := []error{}
+
+	for buttonIdxString, rawToggle := range userMapping {
+		buttonIdx, err := strconv.Atoi(buttonIdxString)
+		if err != nil {
+			continue
+		}
+
+		sides := cast.ToStringMap(rawToggle)
+
+		on, onProblems := parseButtonActions(buttonActionEntries(sides[toggleKeyOn]))
+		off, offProblems := parseButtonActions(buttonActionEntries(sides[toggleKeyOff]))
+
+		for _, problem := range onProblems {
+			problems = append(problems, fmt.Errorf("button %d %s: %w", buttonIdx, toggleKeyOn, problem))
+		}
+
+		for _, problem := range offProblems {
+			problems = append(problems, fmt.Errorf("button %d %s: %w", buttonIdx, toggleKeyOff, problem))
+		}
+
+		resultMap.m[buttonIdx-indexBase] = buttonToggle{on: on, off: off}
+	}
+
+	return resultMap, problems
+}
+
+func (m *buttonToggleMap) get(buttonIdx int) (buttonToggle, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	toggle, ok := m.m[buttonIdx]
+	return toggle, ok
+}
+
+// toggleTracker remembers which toggle buttons are on. like the press counts, this stays around for as long
+// as deej runs, so reconnecting the device doesn't lose track of what the next press will do
+type toggleTracker struct {
+	states map[int]bool
+	lock   sync.Locker
+}
+
+func newToggleTracker() *toggleTracker {
+	return &toggleTracker{
+		states: map[int]bool{},
+		lock:   &sync.Mutex{},
+	}
+}
+
+// flip switches a button to its other state, and returns the new one
+func (t *toggleTracker) flip(buttonID int) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.states[buttonID] = !t.states[buttonID]
+
+	return t.states[buttonID]
+}
+
+func (t *toggleTracker) get(buttonID int) bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	return t.states[buttonID]
+}
+
+// on returns the indexes of every button that's toggled on right now, in order
+func (t *toggleTracker) on() []int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	result := []int{}
+	for buttonID, on := range t.states {
+		if on {
+			result = append(result, buttonID)
+		}
+	}
+
+	sort.Ints(result)
+
+	return result
+}
+
+// toggledButton fires a toggle button's actions for whichever state it's switching to
+func (sio *SerialIO) toggledButton(logger *zap.SugaredLogger, buttonEvent ButtonPressEvent, toggle buttonToggle) {
+	if buttonEvent.PreviousValue != 0 || buttonEvent.ButtonValue == 0 {
+		return
+	}
+
+	actions := toggle.off
+
+	on := sio.toggles.flip(buttonEvent.ButtonID)
+	if on {
+		actions = toggle.on
+	}
+
+	logger.Debugw("Button toggled", "button", buttonEvent.ButtonID, "on", on)
+
+	sio.runButtonActions(logger, buttonEvent, actions)
+	sio.deej.refreshTray()
+}
+
+// ToggledButtons returns the indexes of every toggle button that's currently on
+func (sio *SerialIO) ToggledButtons() []int {
+	return sio.toggles.on()
+}
@@ -0,0 +1,208 @@
+package deej
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// serial traffic can be recorded to a file (with --record) and later replayed in place of the real device
+// (with --replay), at its original timing. this lets us reproduce reports like "my slider 3 jitters" without
+// the user's hardware. each recorded line holds the milliseconds passed since recording started, a tab,
+// and the line exactly as the device sent it (after framing and line endings were taken care of)
+const (
+	trafficRecordSeparator = "\t"
+
+	replayConnectionName = "replay"
+)
+
+type trafficRecorder struct {
+	logger *zap.SugaredLogger
+
+	file  *os.File
+	start time.Time
+	lock  sync.Locker
+}
+
+func newTrafficRecorder(logger *zap.SugaredLogger, path string) (*trafficRecorder, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create recording file: %w", err)
+	}
+
+	logger = logger.Named("recorder")
+	logger.Infow("Recording serial traffic", "path", path)
+
+	return &trafficRecorder{
+		logger: logger,
+		file:   file,
+		start:  time.Now(),
+		lock:   &sync.Mutex{},
+	}, nil
+}
+
+func (r *trafficRecorder) record(line string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	elapsed := time.Since(r.start).Milliseconds()
+	entry := strconv.FormatInt(elapsed, 10) + trafficRecordSeparator + strings.TrimRight(line, "\r\n") + "\n"
+
+	if _, err := r.file.WriteString(entry); err != nil {
+		r.logger.Warnw("Failed to record line", "error", err)
+	}
+}
+
+// recordLine adds a line the device sent to the recording, if there is one
+func (sio *SerialIO) recordLine(line string) {
+	sio.recorderLock.Lock()
+	defer sio.recorderLock.Unlock()
+
+	if sio.recorder != nil {
+		sio.recorder.record(line)
+	}
+}
+
+func (r *trafficRecorder) close() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if err := r.file.Close(); err != nil {
+		r.logger.Warnw("Failed to close recording file", "error", err)
+		return
+	}
+
+	r.logger.Debug("Stopped recording serial traffic")
+}
+
+// replayConnection plays a recording back as if it came from a device. whatever deej sends it is ignored.
+// once the recording ends the connection stays open (and quiet), so deej's state stays as the recording left it
+type replayConnection struct {
+	linePipe
+
+	logger   *zap.SugaredLogger
+	file     *os.File
+	position *replayPosition
+	closed   chan bool
+}
+
+// replayPosition is how far a replay got, so reconnecting carries on from there rather than starting over
+type replayPosition struct {
+	lock sync.Locker
+
+	// the recorded lines played so far, and the time offset of the last one
+	lines  int
+	offset time.Duration
+}
+
+func (p *replayPosition) get() (int, time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.lines, p.offset
+}
+
+func (p *replayPosition) set(lines int, offset time.Duration) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.lines, p.offset = lines, offset
+}
+
+func openReplay(logger *zap.SugaredLogger, path string, position *replayPosition) (*replayConnection, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open recording file: %w", err)
+	}
+
+	rc := &replayConnection{
+		linePipe: newLinePipe(),
+		logger:   logger.Named(replayConnectionName),
+		file:     file,
+		position: position,
+		closed:   make(chan bool),
+	}
+
+	go rc.play()
+
+	return rc, nil
+}
+
+func (rc *replayConnection) play() {
+	playedLines, playedOffset := rc.position.get()
+	rc.logger.Infow("Replaying recorded serial traffic", "path", rc.file.Name(), "skippedLines", playedLines)
+
+	// lines played before reconnecting are skipped, and the rest keep their timing relative to the last of them
+	start := time.Now().Add(-playedOffset)
+	scanner := bufio.NewScanner(rc.file)
+	lineNumber := 0
+
+	for scanner.Scan() {
+		lineNumber++
+		if lineNumber <= playedLines {
+			continue
+		}
+
+		offset, line, err := parseTrafficRecord(scanner.Text())
+		if err != nil {
+			rc.logger.Warnw("Skipping invalid recorded line", "lineNumber", lineNumber, "error", err)
+			continue
+		}
+
+		select {
+		case <-rc.closed:
+			return
+		case <-time.After(time.Until(start.Add(offset))):
+		}
+
+		if err := rc.deliver([]byte(line)); err != nil {
+			return
+		}
+
+		rc.position.set(lineNumber, offset)
+	}
+
+	if err := scanner.Err(); err != nil {
+		rc.logger.Warnw("Failed to read recording file", "error", err)
+	}
+
+	rc.logger.Infow("Finished replaying recorded serial traffic", "lines", lineNumber)
+}
+
+// parseTrafficRecord splits a recorded line into its time offset and the line itself
+func parseTrafficRecord(record string) (time.Duration, string, error) {
+	parts := strings.SplitN(record, trafficRecordSeparator, 2)
+	if len(parts) != 2 {
+		return 0, "", fmt.Errorf("missing %q separator", trafficRecordSeparator)
+	}
+
+	elapsed, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("parse time offset: %w", err)
+	}
+
+	return time.Duration(elapsed) * time.Millisecond, parts[1], nil
+}
+
+// Write implements io.Writer, there's no device to send anything to
+func (rc *replayConnection) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// Close implements io.Closer
+func (rc *replayConnection) Close() error {
+	close(rc.closed)
+	rc.file.Close()
+
+	return rc.close()
+}
+
+func (rc *replayConnection) String() string {
+	return fmt.Sprintf("<replay: %s>", rc.file.Name())
+}
@@ -1,28 +1,53 @@
 package deej
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/getlantern/systray"
+	"go.uber.org/zap"
 
 	"github.com/omriharel/deej/pkg/deej/icon"
 	"github.com/omriharel/deej/pkg/deej/util"
 )
 
+// how often the tray tooltip's summary is refreshed (it only actually changes when something's different)
+const trayTooltipInterval = time.Second
+
 func (d *Deej) initializeTray(onDone func()) {
 	logger := d.logger.Named("tray")
 
 	onReady := func() {
 		logger.Debug("Tray instance ready")
+		d.startup.mark("startTray")
 
 		systray.SetTemplateIcon(icon.DeejLogo, icon.DeejLogo)
 		systray.SetTitle("deej")
 		systray.SetTooltip("deej")
 
+		// shows whether the device is connected, at a glance
+		connectionStatus := systray.AddMenuItem(trayConnectionStatus(false), "")
+		connectionStatus.Disable()
+		systray.AddSeparator()
+
 		editConfig := systray.AddMenuItem("Edit configuration", "Open config file with notepad")
 		editConfig.SetIcon(icon.EditConfig)
 
+		openWebUI := systray.AddMenuItem("Open configuration UI", "Open the web configuration UI (needs http_api_listen)")
+
 		refreshSessions := systray.AddMenuItem("Re-scan audio sessions", "Manually refresh audio sessions if something's stuck")
 		refreshSessions.SetIcon(icon.RefreshSessions)
 
+		undoVolumeChange := systray.AddMenuItem("Undo last volume change", "Revert the most recent volume change made by deej")
+
+		d.addVirtualSliderMenuItems(logger)
+		d.addIntegrationMenuItems(logger)
+
+		// keep the tooltip up to date with what's going on
+		go d.runTooltipUpdates(connectionStatus)
+
 		if d.version != "" {
 			systray.AddSeparator()
 			versionInfo := systray.AddMenuItem(d.version, "")
@@ -56,6 +81,26 @@ func (d *Deej) initializeTray(onDone func()) {
 						logger.Warnw("Failed to open config file for editing", "error", err)
 					}
 
+				// open the web UI, token and all
+				case <-openWebUI.ClickedCh:
+					logger.Info("Open web UI menu item clicked, opening it in the browser")
+
+					address, ok := d.api.webUIAddress()
+					if !ok {
+						d.notifier.Notify("Configuration UI not available",
+							"Set http_api_listen in the config (i.e. to 127.0.0.1:8600) to use it.")
+						continue
+					}
+
+					browser, argument := "explorer.exe", address
+					if util.Linux() {
+						browser, argument = "xdg-open", "'"+address+"'"
+					}
+
+					if err := util.OpenExternal(logger, browser, argument); err != nil {
+						logger.Warnw("Failed to open web UI", "error", err)
+					}
+
 				// refresh sessions
 				case <-refreshSessions.ClickedCh:
 					logger.Info("Refresh sessions menu item clicked, triggering session map refresh")
@@ -63,6 +108,12 @@ func (d *Deej) initializeTray(onDone func()) {
 					// performance: the reason that forcing a refresh here is okay is that users can't spam the
 					// right-click -> select-this-option sequence at a rate that's meaningful to performance
 					d.sessions.refreshSessions(true)
+
+				// undo last volume change
+				case <-undoVolumeChange.ClickedCh:
+					logger.Info("Undo menu item clicked, reverting last volume change")
+
+					d.sessions.undoLastChange()
 				}
 			}
 		}()
@@ -80,6 +131,215 @@ func (d *Deej) initializeTray(onDone func()) {
 	systray.Run(onReady, onExit)
 }
 
+// virtual sliders get a submenu with a few preset levels each. note that this menu is only
+// built once, so adding or removing virtual sliders requires restarting deej to show up here
+func (d *Deej) addVirtualSliderMenuItems(logger *zap.SugaredLogger) {
+	if len(d.config.VirtualSliders) == 0 {
+		return
+	}
+
+	presetLevels := []float32{0, 0.25, 0.5, 0.75, 1}
+
+	systray.AddSeparator()
+	virtualSliders := systray.AddMenuItem("Virtual sliders", "Adjust sliders that don't exist on your device")
+
+	for _, sliderID := range d.config.VirtualSliders {
+		sliderTitle := fmt.Sprintf("Slider %d", sliderID+d.config.MappingIndexBase)
+		if targetNames := d.sessions.sliderDisplayNames(sliderID); len(targetNames) > 0 {
+			sliderTitle = fmt.Sprintf("%s (%s)", sliderTitle, strings.Join(targetNames, ", "))
+		}
+
+		sliderItem := virtualSliders.AddSubMenuItem(sliderTitle, "")
+
+		for _, level := range presetLevels {
+			levelItem := sliderItem.AddSubMenuItem(fmt.Sprintf("%.0f%%", level*100), "")
+
+			go func(sliderID int, level float32) {
+				for range levelItem.ClickedCh {
+					logger.Infow("Virtual slider menu item clicked", "sliderID", sliderID, "level", level)
+
+					if err := d.serial.SetVirtualSliderValue(sliderID, level); err != nil {
+						logger.Warnw("Failed to move virtual slider", "error", err)
+					}
+				}
+			}(sliderID, level)
+		}
+	}
+}
+
+// integrations get a submenu showing how each one is doing, with a way to reconnect it or turn it off and on.
+// like the virtual sliders menu, it's only built once (integrations are all registered before deej starts anyway)
+func (d *Deej) addIntegrationMenuItems(logger *zap.SugaredLogger) {
+	names := d.integrations.names()
+	if len(names) == 0 {
+		return
+	}
+
+	systray.AddSeparator()
+	integrationsItem := systray.AddMenuItem("Integrations", "See how deej's integrations are doing")
+
+	for _, name := range names {
+		integrationItem := integrationsItem.AddSubMenuItem(name, "")
+		reconnectItem := integrationItem.AddSubMenuItem("Reconnect", "Connect again right away")
+		toggleItem := integrationItem.AddSubMenuItem("Turn off", "")
+
+		go func(name string) {
+			ticker := time.NewTicker(trayTooltipInterval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-ticker.C:
+					status := d.integrations.runners[name].status()
+					integrationItem.SetTitle(fmt.Sprintf("%s: %s", name, status.State))
+
+					switch status.State {
+					case integrationStateNotSetUp:
+						toggleItem.SetTitle("Turn on")
+						toggleItem.Disable()
+						reconnectItem.Disable()
+					case integrationStateDisabled:
+						toggleItem.SetTitle("Turn on")
+						toggleItem.Enable()
+						reconnectItem.Disable()
+					default:
+						toggleItem.SetTitle("Turn off")
+						toggleItem.Enable()
+						reconnectItem.Enable()
+					}
+
+				case <-reconnectItem.ClickedCh:
+					logger.Infow("Reconnect integration menu item clicked", "integration", name)
+
+					if err := d.integrations.reconnect(name); err != nil {
+						logger.Warnw("Failed to reconnect integration", "error", err)
+					}
+
+				case <-toggleItem.ClickedCh:
+					enable := d.integrations.runners[name].status().State == integrationStateDisabled
+					logger.Infow("Toggle integration menu item clicked", "integration", name, "enable", enable)
+
+					if err := d.integrations.setEnabled(name, enable); err != nil {
+						logger.Warnw("Failed to toggle integration", "error", err)
+					}
+				}
+			}
+		}(name)
+	}
+}
+
+// runTooltipUpdates keeps the tray tooltip showing a short summary of deej's state,
+// and the tray's title and connection status item showing whether the device is connected
+func (d *Deej) runTooltipUpdates(connectionStatus *systray.MenuItem) {
+	ticker := time.NewTicker(trayTooltipInterval)
+	defer ticker.Stop()
+
+	lastTooltip := ""
+	lastConnected, first := false, true
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-d.trayRefresh:
+		}
+
+		if connected := d.serial.Connected(); connected != lastConnected || first {
+			connectionStatus.SetTitle(trayConnectionStatus(connected))
+
+			title := "deej"
+			if !connected {
+				title = "deej (not connected)"
+			}
+
+			systray.SetTitle(title)
+			lastConnected, first = connected, false
+		}
+
+		// only bother the tray when something actually changed
+		if tooltip := d.trayTooltip(); tooltip != lastTooltip {
+			systray.SetTooltip(tooltip)
+			lastTooltip = tooltip
+		}
+	}
+}
+
+func trayConnectionStatus(connected bool) string {
+	if connected {
+		return "Connected"
+	}
+
+	return "Not connected"
+}
+
+// trayTooltip builds a compact summary of deej's state, one fact per line
+func (d *Deej) trayTooltip() string {
+	lines := []string{"deej"}
+
+	lines = append(lines, trayConnectionStatus(d.serial.Connected()))
+
+	if profile, bankOffset := d.config.ActiveProfile(); profile != defaultProfileName || bankOffset != 0 {
+		line := fmt.Sprintf("Profile: %s", profile)
+		if bankOffset != 0 {
+			line = fmt.Sprintf("%s (bank %d)", line, bankOffset)
+		}
+
+		lines = append(lines, line)
+	}
+
+	if d.sessions.panic.active() {
+		lines = append(lines, "PANIC: everything muted")
+	}
+
+	if d.sessions.pause.active() {
+		lines = append(lines, "Paused: sliders ignored")
+	}
+
+	down := []string{}
+	for _, status := range d.IntegrationStatuses() {
+		if status.State == integrationStateRetrying {
+			down = append(down, status.Name)
+		}
+	}
+
+	if len(down) > 0 {
+		lines = append(lines, fmt.Sprintf("Integrations down: %s", strings.Join(down, ", ")))
+	}
+
+	if apps := d.RecordingApps(); len(apps) > 0 {
+		lines = append(lines, fmt.Sprintf("Recording: %s", strings.Join(apps, ", ")))
+	}
+
+	if toggled := d.serial.ToggledButtons(); len(toggled) > 0 {
+		names := make([]string, len(toggled))
+		for idx, buttonID := range toggled {
+			names[idx] = strconv.Itoa(buttonID + d.config.MappingIndexBase)
+		}
+
+		lines = append(lines, fmt.Sprintf("Toggled on: %s", strings.Join(names, ", ")))
+	}
+
+	if sessions, ok := d.sessions.get(masterSessionName); ok && len(sessions) > 0 {
+		lines = append(lines, fmt.Sprintf("Master: %.0f%%", sessions[0].GetVolume()*100))
+	}
+
+	if sessions, ok := d.sessions.get(inputSessionName); ok && len(sessions) > 0 {
+		if mic, ok := sessions[0].(muteSession); ok && mic.GetMute() {
+			lines = append(lines, "Mic muted")
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// refreshTray updates the tray right away, for changes the user should see immediately (like the device
+// being unplugged). it never blocks, and does nothing when running without a tray
+func (d *Deej) refreshTray() {
+	select {
+	case d.trayRefresh <- true:
+	default:
+	}
+}
+
 func (d *Deej) stopTray() {
 	d.logger.Debug("Quitting tray")
 	systray.Quit()
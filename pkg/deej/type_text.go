@@ -0,0 +1,106 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// type:<text> types out text by pressing the keys that make it up, one after the other, holding shift down for
+// uppercase letters and symbols. the keys are the ones a US keyboard layout types the text with, so other layouts
+// may end up with different symbols. newlines and tabs in the text press ENTER and TAB
+const actionTypePrefix = "type:"
+
+var errMissingTypeText = errors.New("missing text to type")
+
+// typedKey is how a single character is typed: which key, and whether shift is held down meanwhile
+type typedKey struct {
+	key   string
+	shift bool
+}
+
+// the characters a US keyboard layout types, other than letters and digits
+var typedSymbols = map[rune]typedKey{
+	' ':  {"SPACE", false},
+	'\n': {"ENTER", false},
+	'\t': {"TAB", false},
+
+	'-':  {"MINUS", false},
+	'=':  {"EQUAL", false},
+	'[':  {"LEFTBRACE", false},
+	']':  {"RIGHTBRACE", false},
+	';':  {"SEMICOLON", false},
+	'\'': {"APOSTROPHE", false},
+	'`':  {"GRAVE", false},
+	'\\': {"BACKSLASH", false},
+	',':  {"COMMA", false},
+	'.':  {"DOT", false},
+	'/':  {"SLASH", false},
+
+	'_': {"MINUS", true},
+	'+': {"EQUAL", true},
+	'{': {"LEFTBRACE", true},
+	'}': {"RIGHTBRACE", true},
+	':': {"SEMICOLON", true},
+	'"': {"APOSTROPHE", true},
+	'~': {"GRAVE", true},
+	'|': {"BACKSLASH", true},
+	'<': {"COMMA", true},
+	'>': {"DOT", true},
+	'?': {"SLASH", true},
+
+	'!': {"1", true},
+	'@': {"2", true},
+	'#': {"3", true},
+	'$': {"4", true},
+	'%': {"5", true},
+	'^': {"6", true},
+	'&': {"7", true},
+	'*': {"8", true},
+	'(': {"9", true},
+	')': {"0", true},
+}
+
+// typedKeyFor returns how a character is typed, and false if there's no key for it
+func typedKeyFor(char rune) (typedKey, bool) {
+	switch {
+	case char >= 'a' && char <= 'z':
+		return typedKey{key: strings.ToUpper(string(char))}, true
+	case char >= 'A' && char <= 'Z':
+		return typedKey{key: string(char), shift: true}, true
+	case char >= '0' && char <= '9':
+		return typedKey{key: string(char)}, true
+	}
+
+	typed, ok := typedSymbols[char]
+
+	return typed, ok
+}
+
+// parseTypeAction resolves a type:<text> action's text into the key presses that type it
+func parseTypeAction(entry string) ([]buttonAction, error) {
+	text := strings.TrimPrefix(entry, actionTypePrefix)
+	if text == "" {
+		return nil, errMissingTypeText
+	}
+
+	keystrokes := []buttonAction{}
+
+	for _, char := range text {
+		typed, ok := typedKeyFor(char)
+		if !ok {
+			return nil, fmt.Errorf("can't type %q, only characters on a US keyboard are supported", char)
+		}
+
+		keyCode, _ := lookupBuiltinKey(typed.key)
+
+		keystrokes = append(keystrokes, buttonAction{
+			name:    string(char),
+			kind:    buttonActionKey,
+			keyCode: keyCode,
+			shift:   typed.shift,
+		})
+	}
+
+	return keystrokes, nil
+}
@@ -1,14 +1,18 @@
 package util
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"math"
 	"os"
 	"os/exec"
 	"os/signal"
 	"runtime"
+	"strings"
 	"syscall"
 
+	"github.com/mitchellh/go-ps"
 	"go.uber.org/zap"
 )
 
@@ -39,7 +43,7 @@ func Linux() bool {
 // SetupCloseHandler creates a 'listener' on a new goroutine which will notify the
 // program if it receives an interrupt from the OS
 func SetupCloseHandler() chan os.Signal {
-	c := make(chan os.Signal)
+	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 
 	return c
@@ -52,6 +56,37 @@ func GetCurrentWindowProcessNames() ([]string, error) {
 	return getCurrentWindowProcessNames()
 }
 
+// FocusProcessWindow brings the main window of a running app with the given executable name (i.e. "Spotify.exe")
+// to the front, restoring it if it's minimized. it returns false if there's no such window, which means
+// the app isn't running (or is, but only in the tray). if it's running but can't be brought up, it returns true
+// along with the error. on Linux, this needs xdotool
+func FocusProcessWindow(executable string) (bool, error) {
+	return focusProcessWindow(executable)
+}
+
+// findProcessIDs returns the IDs of every running process with the given executable name, ignoring case
+func findProcessIDs(executable string) (map[int]bool, error) {
+	processes, err := ps.Processes()
+	if err != nil {
+		return nil, fmt.Errorf("list processes: %w", err)
+	}
+
+	pids := map[int]bool{}
+	for _, process := range processes {
+		if strings.EqualFold(process.Executable(), executable) {
+			pids[process.Pid()] = true
+		}
+	}
+
+	return pids, nil
+}
+
+// AttachParentConsole makes os.Stdout write to the console deej was started from, if any.
+// Release builds on Windows are GUI apps, which otherwise have nowhere to print to
+func AttachParentConsole() error {
+	return attachParentConsole()
+}
+
 // OpenExternal spawns a detached window with the provided command and argument
 func OpenExternal(logger *zap.SugaredLogger, cmd string, arg string) error {
 
@@ -75,14 +110,83 @@ func OpenExternal(logger *zap.SugaredLogger, cmd string, arg string) error {
 	return nil
 }
 
+// LaunchDetached starts the given command line (i.e. an app's executable path) without waiting for it to finish
+func LaunchDetached(commandLine string) error {
+
+	// start's first quoted argument is the window title, hence the empty one
+	execCommandArgs := []string{"cmd.exe", "/C", "start", "", commandLine}
+	if Linux() {
+		execCommandArgs = []string{"/bin/sh", "-c", commandLine}
+	}
+
+	command := exec.Command(execCommandArgs[0], execCommandArgs[1:]...)
+
+	if err := command.Start(); err != nil {
+		return fmt.Errorf("start detached proc: %w", err)
+	}
+
+	// reap it whenever it's done, we don't care how that goes
+	go command.Wait()
+
+	return nil
+}
+
+// RunCommand runs the given command line through the shell in dir (deej's own if empty), until it's done or ctx is.
+// it returns everything the command printed, even when it fails. when ctx is done first, whatever the shell started
+// is killed along with it - those would otherwise keep running, and keep us waiting on their output
+func RunCommand(ctx context.Context, commandLine string, dir string) ([]byte, error) {
+	execCommandArgs := []string{"cmd.exe", "/C", commandLine}
+	if Linux() {
+		execCommandArgs = []string{"/bin/sh", "-c", commandLine}
+	}
+
+	command := exec.Command(execCommandArgs[0], execCommandArgs[1:]...)
+	command.Dir = dir
+
+	output := &bytes.Buffer{}
+	command.Stdout = output
+	command.Stderr = output
+
+	tree, err := startProcessTree(command)
+	if err != nil {
+		return nil, fmt.Errorf("start command: %w", err)
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+
+	go func() {
+		defer close(stopped)
+
+		select {
+		case <-ctx.Done():
+			tree.kill()
+		case <-done:
+		}
+	}()
+
+	err = command.Wait()
+
+	close(done)
+	<-stopped
+	tree.release()
+
+	if err != nil {
+		return output.Bytes(), fmt.Errorf("run command: %w", err)
+	}
+
+	return output.Bytes(), nil
+}
+
 // NormalizeScalar "trims" the given float32 to 2 points of precision (e.g. 0.15442 -> 0.15)
 // This is used both for windows core audio volume levels and for cleaning up slider level values from serial
 func NormalizeScalar(v float32) float32 {
 	return float32(math.Floor(float64(v)*100) / 100.0)
 }
 
-// SignificantlyDifferent returns true if there's a significant enough volume difference between two given values
-func SignificantlyDifferent(old float32, new float32, noiseReductionLevel string) bool {
+// NoiseReductionThreshold returns the minimum volume difference that counts as an actual slider
+// move for the given noise reduction level (as provided in the config)
+func NoiseReductionThreshold(noiseReductionLevel string) float64 {
 
 	const (
 		noiseReductionHigh = "high"
@@ -107,6 +211,16 @@ func SignificantlyDifferent(old float32, new float32, noiseReductionLevel string
 		break
 	}
 
+	return significantDifferenceThreshold
+}
+
+// SignificantlyDifferent returns true if there's a significant enough volume difference between two given values
+func SignificantlyDifferent(old float32, new float32, noiseReductionLevel string) bool {
+	return SignificantlyDifferentWithThreshold(old, new, NoiseReductionThreshold(noiseReductionLevel))
+}
+
+// SignificantlyDifferentWithThreshold is like SignificantlyDifferent, but takes the difference threshold directly
+func SignificantlyDifferentWithThreshold(old float32, new float32, significantDifferenceThreshold float64) bool {
 	if math.Abs(float64(old-new)) >= significantDifferenceThreshold {
 		return true
 	}
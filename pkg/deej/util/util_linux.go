@@ -2,8 +2,80 @@ package util
 
 import (
 	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
 )
 
+// the kernel only keeps this many characters of a process' name
+const maxProcessNameLength = 15
+
+func attachParentConsole() error {
+	return nil
+}
+
 func getCurrentWindowProcessNames() ([]string, error) {
 	return nil, errors.New("Not implemented")
 }
+
+func focusProcessWindow(executable string) (bool, error) {
+	if len(executable) > maxProcessNameLength {
+		executable = executable[:maxProcessNameLength]
+	}
+
+	pids, err := findProcessIDs(executable)
+	if err != nil || len(pids) == 0 {
+		return false, err
+	}
+
+	xdotool, err := exec.LookPath("xdotool")
+	if err != nil {
+		return true, fmt.Errorf("find xdotool: %w", err)
+	}
+
+	for pid := range pids {
+
+		// this fails if the process has no visible windows, in which case one of the others might
+		output, err := exec.Command(xdotool, "search", "--onlyvisible", "--pid", strconv.Itoa(pid)).Output()
+		if err != nil {
+			continue
+		}
+
+		windows := strings.Fields(string(output))
+		if len(windows) == 0 {
+			continue
+		}
+
+		// there's a window to bring up, so the app is running whether or not that works
+		if err := exec.Command(xdotool, "windowactivate", windows[0]).Run(); err != nil {
+			return true, fmt.Errorf("activate window: %w", err)
+		}
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// processTree is a command started in a process group of its own, so everything it starts can be killed with it
+type processTree struct {
+	pgid int
+}
+
+func startProcessTree(command *exec.Cmd) (*processTree, error) {
+	command.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	if err := command.Start(); err != nil {
+		return nil, err
+	}
+
+	return &processTree{pgid: command.Process.Pid}, nil
+}
+
+func (tree *processTree) kill() {
+	syscall.Kill(-tree.pgid, syscall.SIGKILL)
+}
+
+func (tree *processTree) release() {}
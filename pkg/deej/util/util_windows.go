@@ -1,13 +1,18 @@
 package util
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
 	"syscall"
 	"time"
 	"unsafe"
 
 	"github.com/lxn/win"
 	"github.com/mitchellh/go-ps"
+	"golang.org/x/sys/windows"
 )
 
 const (
@@ -94,3 +99,135 @@ func getCurrentWindowProcessNames() ([]string, error) {
 	lastGetCurrentWindowResult = result
 	return result, nil
 }
+
+// used with AttachConsole, to attach to whichever process started us
+const attachParentProcess = ^uintptr(0)
+
+var procAttachConsole = syscall.NewLazyDLL("kernel32.dll").NewProc("AttachConsole")
+
+func attachParentConsole() error {
+	if ok, _, err := procAttachConsole.Call(attachParentProcess); ok == 0 {
+		return fmt.Errorf("attach to parent console: %w", err)
+	}
+
+	// the standard handles were set up before we had a console, so they go nowhere
+	console, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("open console output: %w", err)
+	}
+
+	os.Stdout = console
+
+	return nil
+}
+
+var procEnumWindows = syscall.NewLazyDLL("user32.dll").NewProc("EnumWindows")
+
+// windowSearch is what enumWindowsCallback looks for, and where it puts what it found
+type windowSearch struct {
+	pids  map[int]bool
+	found win.HWND
+}
+
+// callbacks are never freed, so there's just the one
+var enumWindowsCallback = syscall.NewCallback(func(hwnd win.HWND, search *windowSearch) uintptr {
+	var pid uint32
+	win.GetWindowThreadProcessId(hwnd, &pid)
+
+	// only the app's own top-level windows, not the dialogs and tool windows they own
+	if search.pids[int(pid)] && win.IsWindowVisible(hwnd) && win.GetWindow(hwnd, win.GW_OWNER) == 0 {
+		search.found = hwnd
+
+		// stop iterating
+		return 0
+	}
+
+	return 1
+})
+
+func focusProcessWindow(executable string) (bool, error) {
+	pids, err := findProcessIDs(executable)
+	if err != nil || len(pids) == 0 {
+		return false, err
+	}
+
+	search := &windowSearch{pids: pids}
+	procEnumWindows.Call(enumWindowsCallback, uintptr(unsafe.Pointer(search)))
+
+	if search.found == 0 {
+		return false, nil
+	}
+
+	if win.IsIconic(search.found) {
+		win.ShowWindow(search.found, win.SW_RESTORE)
+	}
+
+	// windows only lets whoever owns the foreground window hand it over, so borrow its input for a moment. that's
+	// attached to this thread, so the goroutine has to stay on it until it's detached again
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	foregroundThread := win.GetWindowThreadProcessId(win.GetForegroundWindow(), nil)
+	currentThread := win.GetCurrentThreadId()
+
+	if foregroundThread != currentThread {
+		win.AttachThreadInput(int32(currentThread), int32(foregroundThread), true)
+		defer win.AttachThreadInput(int32(currentThread), int32(foregroundThread), false)
+	}
+
+	win.BringWindowToTop(search.found)
+
+	if !win.SetForegroundWindow(search.found) {
+		return true, errors.New("bring window to the front: refused by windows")
+	}
+
+	return true, nil
+}
+
+// processTree is a command started in a job object of its own, so everything it starts can be killed with it
+type processTree struct {
+	job     windows.Handle
+	process *os.Process
+}
+
+func startProcessTree(command *exec.Cmd) (*processTree, error) {
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create job object: %w", err)
+	}
+
+	if err := command.Start(); err != nil {
+		windows.CloseHandle(job)
+		return nil, err
+	}
+
+	// whatever the shell manages to start before this isn't part of the job, but it has to read the command line first
+	process, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE, false, uint32(command.Process.Pid))
+	if err == nil {
+		err = windows.AssignProcessToJobObject(job, process)
+		windows.CloseHandle(process)
+	}
+
+	// it still runs, but only the shell itself can be killed
+	if err != nil {
+		windows.CloseHandle(job)
+		job = 0
+	}
+
+	return &processTree{job: job, process: command.Process}, nil
+}
+
+func (tree *processTree) kill() {
+	if tree.job == 0 {
+		tree.process.Kill()
+		return
+	}
+
+	windows.TerminateJobObject(tree.job, 1)
+}
+
+func (tree *processTree) release() {
+	if tree.job != 0 {
+		windows.CloseHandle(tree.job)
+	}
+}
@@ -0,0 +1,155 @@
+package deej
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// virtual sliders can be moved with keyboard shortcuts too, from whichever app has focus. virtual_slider_hotkeys maps
+// a key, or a key along with modifier keys (named the same way as for buttons), to a virtual slider and how far to
+// move it: "CTRL+ALT+UP": "4:+5" turns slider 4 up by 5%. keys are picked up the same way macros record them, so
+// this only works where recording keys does (windows, for now)
+const (
+
+	// slider values get floored to whole percent, and most of those are a hair under theirs as a float32
+	virtualSliderHotkeyRounding = 0.001
+)
+
+// virtualSliderStep is what a hotkey does to a virtual slider, in whole percent
+type virtualSliderStep struct {
+	sliderID int
+	percent  int
+}
+
+// virtualSliderHotkey is a key, along with the modifier keys held down with it
+type virtualSliderHotkey struct {
+	keyCode               int
+	ctrl, shift, alt, win bool
+}
+
+// parseVirtualSliderHotkey resolves a key name or combo (i.e. CTRL+ALT+UP) into a hotkey
+func parseVirtualSliderHotkey(name string) (virtualSliderHotkey, bool) {
+	name = strings.ToUpper(strings.TrimSpace(name))
+
+	action, ok := parseKeyCombo(buttonAction{name: name})
+	if !ok {
+		action.keyCode, ok = lookupKey(name)
+	}
+
+	return virtualSliderHotkey{
+		keyCode: action.keyCode,
+		ctrl:    action.ctrl,
+		shift:   action.shift,
+		alt:     action.alt,
+		win:     action.win,
+	}, ok
+}
+
+// parseVirtualSliderStep reads the slider index (as the user counts them) and step in percent out of "4:+5"
+func parseVirtualSliderStep(value string) (int, int, error) {
+	parts := strings.SplitN(value, ":", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("expected %q, got %q", "<slider>:<+/-percent>", value)
+	}
+
+	sliderIdx, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || sliderIdx < 0 {
+		return 0, 0, fmt.Errorf("invalid slider index: %q", parts[0])
+	}
+
+	percent, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(parts[1]), "+"))
+	if err != nil || percent == 0 || percent < -100 || percent > 100 {
+		return 0, 0, fmt.Errorf("invalid step: %q", parts[1])
+	}
+
+	return sliderIdx, percent, nil
+}
+
+// virtualSliderHotkeys watches the keyboard for as long as any hotkeys are configured
+type virtualSliderHotkeys struct {
+	sio    *SerialIO
+	logger *zap.SugaredLogger
+
+	lock     sync.Locker
+	stopKeys func()
+
+	// so an unsupported platform is only brought up once
+	warned bool
+}
+
+func newVirtualSliderHotkeys(sio *SerialIO, logger *zap.SugaredLogger) *virtualSliderHotkeys {
+	return &virtualSliderHotkeys{
+		sio:    sio,
+		logger: logger.Named("hotkeys"),
+		lock:   &sync.Mutex{},
+	}
+}
+
+// update starts watching the keyboard if there are hotkeys to watch for, and stops once there aren't
+func (h *virtualSliderHotkeys) update() {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	configured := len(h.sio.deej.config.VirtualSliderHotkeys) > 0
+
+	if !configured && h.stopKeys != nil {
+		h.logger.Debug("No more virtual slider hotkeys, no longer watching keys")
+
+		h.stopKeys()
+		h.stopKeys = nil
+	}
+
+	if !configured || h.stopKeys != nil {
+		return
+	}
+
+	stopKeys, err := watchKeys(h.pressed)
+	if err != nil {
+		if !h.warned {
+			h.warned = true
+			h.logger.Warnw("Can't watch keys, virtual slider hotkeys won't work", "error", err)
+		}
+
+		return
+	}
+
+	h.logger.Debugw("Watching keys for virtual slider hotkeys", "hotkeys", len(h.sio.deej.config.VirtualSliderHotkeys))
+	h.stopKeys = stopKeys
+}
+
+// pressed moves whichever virtual slider the pressed key (if it's a hotkey) is for
+func (h *virtualSliderHotkeys) pressed(modifiers []string, key string) {
+	hotkey, ok := parseVirtualSliderHotkey(strings.Join(append(modifiers, key), keyComboSeparator))
+	if !ok {
+		return
+	}
+
+	step, ok := h.sio.deej.config.VirtualSliderHotkeys[hotkey]
+	if !ok {
+		return
+	}
+
+	// a virtual slider that was never moved starts out at 0
+	current, _ := h.sio.virtualSliderValue(step.sliderID)
+
+	percent := int(math.Round(float64(current)*100)) + step.percent
+	if percent < 0 {
+		percent = 0
+	} else if percent > 100 {
+		percent = 100
+	}
+
+	value := float32(math.Min(float64(percent)/100+virtualSliderHotkeyRounding, 1))
+
+	if err := h.sio.SetVirtualSliderValue(step.sliderID, value); err != nil {
+		h.logger.Warnw("Failed to move virtual slider", "slider", step.sliderID, "error", err)
+		return
+	}
+
+	h.logger.Debugw("Moved virtual slider with a hotkey", "slider", step.sliderID, "value", value)
+}
@@ -0,0 +1,137 @@
+package deej
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// volume writes don't go straight to the audio API, they go through the applier instead. those calls (COM
+// on windows, pulseaudio on linux) can be slow, and making them right where slider moves are handled means
+// a slow one holds up reading the device. the applier makes them from its own goroutine, and only keeps the
+// newest volume waiting for each session: a slider swept across its whole range while a write is in flight
+// turns into a single write for wherever it ended up, instead of a backlog of stale ones
+type volumeApplier struct {
+	logger   *zap.SugaredLogger
+	sessions *sessionMap
+
+	pending map[volumeWriteKey]volumeWrite
+	lock    sync.Locker
+
+	// held while writes are being made, so sessions aren't released from under them
+	applyLock sync.Locker
+
+	wake chan bool
+}
+
+// volumeWriteKey tells which writes replace each other. channel sessions are created anew every time
+// they're looked up, so those are told apart by the session and channel they stand for instead
+type volumeWriteKey struct {
+	session Session
+	channel int
+}
+
+type volumeWrite struct {
+	session Session
+	volume  float32
+}
+
+func newVolumeApplier(sessions *sessionMap, logger *zap.SugaredLogger) *volumeApplier {
+	return &volumeApplier{
+		logger:    logger.Named("applier"),
+		sessions:  sessions,
+		pending:   map[volumeWriteKey]volumeWrite{},
+		lock:      &sync.Mutex{},
+		applyLock: &sync.Mutex{},
+		wake:      make(chan bool, 1),
+	}
+}
+
+func writeKeyFor(session Session) volumeWriteKey {
+	if channel, ok := session.(*channelSession); ok {
+		return volumeWriteKey{session: channel.parent, channel: channel.channel}
+	}
+
+	return volumeWriteKey{session: session, channel: -1}
+}
+
+// set queues a volume for a session, replacing whatever was still waiting for it. during a panic or while deej is
+// paused, nothing is queued
+func (a *volumeApplier) set(session Session, volume float32) {
+	if a.sessions.panic.active() || a.sessions.pause.active() {
+		return
+	}
+
+	a.lock.Lock()
+	a.pending[writeKeyFor(session)] = volumeWrite{session: session, volume: volume}
+	a.lock.Unlock()
+
+	select {
+	case a.wake <- true:
+	default:
+	}
+}
+
+// volume returns the volume a session is about to have, which is its current one unless a write is waiting
+func (a *volumeApplier) volume(session Session) float32 {
+	a.lock.Lock()
+	write, ok := a.pending[writeKeyFor(session)]
+	a.lock.Unlock()
+
+	if ok {
+		return write.volume
+	}
+
+	return session.GetVolume()
+}
+
+// discard forgets every waiting write, and waits for any writes in flight to finish. the session
+// map calls this before releasing its sessions
+func (a *volumeApplier) discard() {
+	a.applyLock.Lock()
+	defer a.applyLock.Unlock()
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.pending = map[volumeWriteKey]volumeWrite{}
+}
+
+func (a *volumeApplier) run(done chan bool) {
+	defer a.sessions.deej.supervisor.guard(moduleVolumeApplier)
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-a.wake:
+			if failed := a.applyPending(); failed {
+
+				// same as before the applier existed, a failed write usually means a stale session
+				a.sessions.refreshSessions(true)
+			}
+		}
+	}
+}
+
+// applyPending makes every waiting write, and returns whether any of them failed
+func (a *volumeApplier) applyPending() bool {
+	a.applyLock.Lock()
+	defer a.applyLock.Unlock()
+
+	a.lock.Lock()
+	writes := a.pending
+	a.pending = map[volumeWriteKey]volumeWrite{}
+	a.lock.Unlock()
+
+	failed := false
+
+	for _, write := range writes {
+		if err := write.session.SetVolume(write.volume); err != nil {
+			a.logger.Warnw("Failed to set session volume", "session", write.session.Key(), "error", err)
+			failed = true
+		}
+	}
+
+	return failed
+}
@@ -0,0 +1,90 @@
+package deej
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// volume feedback periodically tells the device what each slider's targets are actually set to,
+// including changes made from outside deej (such as the OS mixer). this lets motorized faders and
+// LED rings stay in sync. values are sent in the same raw range the device reports its sliders in,
+// so firmware can compare them directly to its own readings, i.e. "VOL:512|1023|0"
+const (
+	volumeFeedbackInterval = 250 * time.Millisecond
+	volumeFeedbackPrefix   = "VOL:"
+)
+
+func (sio *SerialIO) runVolumeFeedback(ctx context.Context, logger *zap.SugaredLogger) {
+	logger = logger.Named("feedback")
+	logger.Debug("Starting volume feedback")
+
+	lastSentLine := ""
+	ticker := time.NewTicker(volumeFeedbackInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Debug("Stopping volume feedback")
+			return
+
+		case <-ticker.C:
+			if !sio.deej.config.VolumeFeedback {
+				continue
+			}
+
+			line := sio.buildVolumeFeedbackLine()
+
+			// only bother the device when something actually changed
+			if line == "" || line == lastSentLine {
+				continue
+			}
+
+			if err := sio.SendCommand(line); err != nil {
+				logger.Debugw("Failed to send volume feedback", "error", err)
+				continue
+			}
+
+			if sio.deej.Verbose() {
+				logger.Debugw("Sent volume feedback", "line", line)
+			}
+
+			lastSentLine = line
+		}
+	}
+}
+
+func (sio *SerialIO) buildVolumeFeedbackLine() string {
+	sliderValues, _ := sio.SliderValues()
+	if len(sliderValues) == 0 {
+		return ""
+	}
+
+	rawValues := make([]string, len(sliderValues))
+
+	for sliderIdx, sliderValue := range sliderValues {
+
+		// sliders without any live targets simply report back their own position, so nothing moves
+		volume, ok := sio.deej.sessions.sliderVolume(sliderIdx)
+		if !ok {
+			volume = sliderValue
+		}
+
+		// sliders that haven't reported anything yet can't be fed back either
+		if volume < 0 {
+			return ""
+		}
+
+		if sio.deej.config.InvertSliders {
+			volume = 1 - volume
+		}
+
+		rawValues[sliderIdx] = strconv.Itoa(int(volume*float32(sio.deej.config.MaxAnalogValue) + 0.5))
+	}
+
+	return volumeFeedbackPrefix + strings.Join(rawValues, "|")
+}
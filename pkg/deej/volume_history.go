@@ -0,0 +1,86 @@
+package deej
+
+import (
+	"sync"
+	"time"
+)
+
+// volumeHistory remembers what deej-applied volume changes replaced, so they can be undone
+// (i.e. after accidentally bumping a slider). a single slider gesture produces many small changes,
+// so consecutive changes from the same slider are grouped together into one undoable step
+type volumeHistory struct {
+	steps []*volumeHistoryStep
+	lock  sync.Locker
+}
+
+type volumeHistoryStep struct {
+	sliderID    int
+	lastChanged time.Time
+
+	// session key -> volume before this step began
+	previousVolumes map[string]float32
+}
+
+const (
+
+	// changes from the same slider that are this close to each other belong to the same gesture
+	volumeHistoryGestureWindow = time.Second
+
+	// nobody is going to undo further back than this
+	maxVolumeHistorySteps = 20
+)
+
+func newVolumeHistory() *volumeHistory {
+	return &volumeHistory{
+		steps: []*volumeHistoryStep{},
+		lock:  &sync.Mutex{},
+	}
+}
+
+// record notes that a slider is about to change a session's volume from previousVolume
+func (h *volumeHistory) record(sliderID int, sessionKey string, previousVolume float32) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	now := time.Now()
+
+	var step *volumeHistoryStep
+	if len(h.steps) > 0 {
+		step = h.steps[len(h.steps)-1]
+	}
+
+	// start a new step unless this continues the latest gesture
+	if step == nil || step.sliderID != sliderID || step.lastChanged.Add(volumeHistoryGestureWindow).Before(now) {
+		step = &volumeHistoryStep{
+			sliderID:        sliderID,
+			previousVolumes: map[string]float32{},
+		}
+
+		h.steps = append(h.steps, step)
+		if len(h.steps) > maxVolumeHistorySteps {
+			h.steps = h.steps[1:]
+		}
+	}
+
+	step.lastChanged = now
+
+	// only the volume from before the gesture started matters
+	if _, ok := step.previousVolumes[sessionKey]; !ok {
+		step.previousVolumes[sessionKey] = previousVolume
+	}
+}
+
+// pop removes and returns the most recent step, if there is one
+func (h *volumeHistory) pop() (*volumeHistoryStep, bool) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	if len(h.steps) == 0 {
+		return nil, false
+	}
+
+	step := h.steps[len(h.steps)-1]
+	h.steps = h.steps[:len(h.steps)-1]
+
+	return step, true
+}
@@ -0,0 +1,77 @@
+package deej
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// volume_up:<slider>:<step> and volume_down:<slider>:<step> nudge whatever a slider controls up or down by step
+// percent (5 if left out), the same way an encoder tick does. unlike VK_VOLUME_UP and VK_VOLUME_DOWN, which only
+// ever change the default output device, this works for any target - an app, a group, or the current window.
+// the slider itself doesn't move, so its next move sets the volume back to wherever it is
+const (
+	actionVolumeUpPrefix   = "volume_up:"
+	actionVolumeDownPrefix = "volume_down:"
+
+	defaultVolumeStepPercent = 5
+)
+
+// volumeStepPrefix returns the volume step action prefix the entry starts with, if any
+func volumeStepPrefix(entry string) (string, bool) {
+	for _, prefix := range []string{actionVolumeUpPrefix, actionVolumeDownPrefix} {
+		if strings.HasPrefix(entry, prefix) {
+			return prefix, true
+		}
+	}
+
+	return "", false
+}
+
+// parseVolumeStepAction returns the slider index (as the user counts them) of a volume step action,
+// and how much it moves the volume by (negative for volume_down)
+func parseVolumeStepAction(entry string) (int, float32, error) {
+	prefix, ok := volumeStepPrefix(entry)
+	if !ok {
+		return 0, 0, fmt.Errorf("expected %s or %s", actionVolumeUpPrefix, actionVolumeDownPrefix)
+	}
+
+	parts := strings.Split(strings.TrimPrefix(entry, prefix), ":")
+	if len(parts) > 2 {
+		return 0, 0, fmt.Errorf("expected <slider>:<step>, got %q", strings.TrimPrefix(entry, prefix))
+	}
+
+	sliderIdx, err := strconv.Atoi(parts[0])
+	if err != nil || sliderIdx < 0 {
+		return 0, 0, fmt.Errorf("invalid slider index: %q", parts[0])
+	}
+
+	stepPercent := float64(defaultVolumeStepPercent)
+	if len(parts) == 2 {
+		stepPercent, err = strconv.ParseFloat(parts[1], 32)
+		if err != nil || stepPercent <= 0 || stepPercent > 100 {
+			return 0, 0, fmt.Errorf("invalid step (expected a percentage above 0, up to 100): %q", parts[1])
+		}
+	}
+
+	delta := float32(stepPercent / 100)
+	if prefix == actionVolumeDownPrefix {
+		delta = -delta
+	}
+
+	return sliderIdx, delta, nil
+}
+
+func (sio *SerialIO) stepSliderVolume(logger *zap.SugaredLogger, sliderIdx int, delta float32) {
+	targets, ok := sio.deej.config.SliderMapping.get(sliderIdx - sio.deej.config.MappingIndexBase)
+	if !ok {
+		logger.Warnw("Button steps the volume of a slider that isn't mapped", "slider", sliderIdx)
+		return
+	}
+
+	logger.Debugw("Stepping slider volume", "slider", sliderIdx, "delta", delta)
+
+	sio.deej.sessions.nudgeTargets(targets, delta)
+}
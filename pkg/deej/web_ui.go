@@ -0,0 +1,239 @@
+//go:build !slim
+// +build !slim
+
+package deej
+
+// webUIPage is the configuration UI served by the HTTP API at its root. it's deliberately a single
+// dependency-free page: settings are rendered from /api/config, grouped into its sections, and saved back to it.
+// simple values get regular inputs, while mappings and lists are edited as JSON. integrations (if there are any)
+// are listed above the settings, refreshing every few seconds, with buttons to reconnect and turn them off or on.
+// the API token comes after the # in the page's address (the tray opens it that way), and is kept for the session
+const webUIPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>deej configuration</title>
+<style>
+	body { font-family: sans-serif; max-width: 760px; margin: 2em auto; padding: 0 1em; color: #222; }
+	h1 { font-weight: normal; }
+	fieldset { border: 1px solid #ccc; border-radius: 4px; margin-bottom: 1.5em; }
+	legend { font-weight: bold; padding: 0 .5em; }
+	label { display: block; margin: .6em 0; }
+	label span { display: inline-block; width: 14em; vertical-align: top; font-family: monospace; }
+	input[type=text], textarea { width: 24em; }
+	textarea { height: 6em; font-family: monospace; }
+	#status { margin-left: 1em; }
+	.error { color: #b00; }
+	#integrations table { border-collapse: collapse; width: 100%; }
+	#integrations td { padding: .3em .5em; border-bottom: 1px solid #eee; }
+</style>
+</head>
+<body>
+<h1>deej configuration</h1>
+<fieldset id="integrations" hidden><legend>Integrations</legend><table></table></fieldset>
+<form id="config"></form>
+<button id="save">Save and apply</button><span id="status"></span>
+<script>
+var form = document.getElementById("config");
+var statusEl = document.getElementById("status");
+var original = {};
+
+var tokenMatch = /token=([0-9a-f]+)/.exec(location.hash);
+if (tokenMatch) {
+	sessionStorage.setItem("deejToken", tokenMatch[1]);
+	history.replaceState(null, "", location.pathname);
+}
+
+function request(path, options) {
+	options = options || {};
+	options.headers = options.headers || {};
+	options.headers["X-Deej-Token"] = sessionStorage.getItem("deejToken") || "";
+
+	return fetch(path, options).then(function (response) {
+		if (response.status === 401) {
+			statusEl.className = "error";
+			statusEl.textContent = "Open this page from deej's tray menu (Open configuration UI) to use it.";
+		}
+
+		return response;
+	});
+}
+
+function isComplex(value) {
+	return value !== null && typeof value === "object";
+}
+
+function addField(parent, key, value, editable) {
+	var label = document.createElement("label");
+	var name = document.createElement("span");
+	name.textContent = key;
+	label.appendChild(name);
+
+	var input;
+	if (typeof value === "boolean") {
+		input = document.createElement("input");
+		input.type = "checkbox";
+		input.checked = value;
+	} else if (isComplex(value)) {
+		input = document.createElement("textarea");
+		input.value = JSON.stringify(value, null, 2);
+	} else {
+		input = document.createElement("input");
+		input.type = (typeof value === "number") ? "number" : "text";
+		input.value = (value === null || value === undefined) ? "" : value;
+	}
+
+	input.name = key;
+	input.disabled = !editable;
+	label.appendChild(input);
+	parent.appendChild(label);
+}
+
+function addSection(title, keys, settings, editable) {
+	if (keys.length === 0) {
+		return;
+	}
+
+	var fieldset = document.createElement("fieldset");
+	var legend = document.createElement("legend");
+	legend.textContent = title;
+	fieldset.appendChild(legend);
+
+	keys.forEach(function (key) {
+		addField(fieldset, key, settings[key], editable.indexOf(key) !== -1);
+	});
+
+	form.appendChild(fieldset);
+}
+
+function readValue(input) {
+	var previous = original[input.name];
+
+	if (input.type === "checkbox") {
+		return input.checked;
+	}
+
+	if (isComplex(previous)) {
+		return JSON.parse(input.value);
+	}
+
+	if (typeof previous === "number") {
+		return Number(input.value);
+	}
+
+	return input.value;
+}
+
+function load() {
+	request("/api/config").then(function (response) {
+		return response.json();
+	}).then(function (config) {
+		original = config.settings;
+		form.innerHTML = "";
+
+		var seen = {};
+		config.sections.forEach(function (section) {
+			var keys = section.keys.filter(function (key) { return key in config.settings; });
+			keys.forEach(function (key) { seen[key] = true; });
+			addSection(section.title, keys, config.settings, config.editable);
+		});
+
+		var rest = Object.keys(config.settings).filter(function (key) { return !seen[key]; }).sort();
+		addSection("Other", rest, config.settings, config.editable);
+	});
+}
+
+document.getElementById("save").onclick = function () {
+	var changed = {};
+
+	try {
+		Array.prototype.forEach.call(form.elements, function (input) {
+			if (input.disabled) {
+				return;
+			}
+
+			var value = readValue(input);
+			if (JSON.stringify(value) !== JSON.stringify(original[input.name])) {
+				changed[input.name] = value;
+			}
+		});
+	} catch (e) {
+		statusEl.className = "error";
+		statusEl.textContent = "Invalid JSON: " + e.message;
+		return;
+	}
+
+	request("/api/config", {
+		method: "PUT",
+		headers: { "Content-Type": "application/json" },
+		body: JSON.stringify(changed)
+	}).then(function (response) {
+		if (response.ok) {
+			statusEl.className = "";
+			statusEl.textContent = "Saved, deej will apply your changes in a moment.";
+			setTimeout(load, 1000);
+			return;
+		}
+
+		return response.text().then(function (text) {
+			statusEl.className = "error";
+			statusEl.textContent = text;
+		});
+	});
+};
+
+function integrationRequest(method, path, body) {
+	request("/api/integrations/" + path, {
+		method: method,
+		headers: { "Content-Type": "application/json" },
+		body: body ? JSON.stringify(body) : undefined
+	}).then(function () {
+		setTimeout(loadIntegrations, 1000);
+	});
+}
+
+function addIntegrationButton(row, title, onclick) {
+	var button = document.createElement("button");
+	button.textContent = title;
+	button.onclick = onclick;
+	row.insertCell().appendChild(button);
+}
+
+function loadIntegrations() {
+	request("/api/integrations").then(function (response) {
+		return response.json();
+	}).then(function (integrations) {
+		var panel = document.getElementById("integrations");
+		var table = panel.querySelector("table");
+
+		panel.hidden = integrations.length === 0;
+		table.innerHTML = "";
+
+		integrations.forEach(function (integration) {
+			var row = table.insertRow();
+			var disabled = integration.state === "disabled";
+
+			row.insertCell().textContent = integration.name;
+
+			var state = row.insertCell();
+			state.textContent = integration.state + (integration.error ? " (" + integration.error + ")" : "");
+			state.className = integration.state === "retrying" ? "error" : "";
+
+			addIntegrationButton(row, "Reconnect", function () {
+				integrationRequest("POST", integration.name + "/reconnect");
+			});
+
+			addIntegrationButton(row, disabled ? "Turn on" : "Turn off", function () {
+				integrationRequest("PUT", integration.name, { enabled: disabled });
+			});
+		});
+	});
+}
+
+load();
+loadIntegrations();
+setInterval(loadIntegrations, 5000);
+</script>
+</body>
+</html>
+`
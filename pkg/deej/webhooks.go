@@ -0,0 +1,186 @@
+package deej
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/thoas/go-funk"
+	"go.uber.org/zap"
+)
+
+// buttons can call webhooks (i.e. Home Assistant's, or IFTTT's) with http:<method>:<url>. ones that need headers
+// (like an Authorization token), a body or a timeout of their own can be set up under webhooks instead, and then
+// called with http:<name>. the url, headers and body can have the pressed button ({button}, as the user counts
+// them) and its state ({state}, and {previous} for what it was before) filled in, and header values can refer to
+// secrets like other settings do. requests are sent in the background, so a slow server doesn't hold up the buttons
+const (
+	actionHTTPPrefix = "http:"
+
+	webhookFormatButton   = "{button}"
+	webhookFormatState    = "{state}"
+	webhookFormatPrevious = "{previous}"
+
+	defaultWebhookMethod  = "GET"
+	defaultWebhookTimeout = 10 * time.Second
+)
+
+var webhookMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+
+var (
+	errMissingWebhook       = errors.New("missing method and url, or webhook name")
+	errInvalidWebhookMethod = fmt.Errorf("method must be one of %s", strings.Join(webhookMethods, ", "))
+	errInvalidWebhookURL    = errors.New("url must start with http:// or https://")
+)
+
+// Webhook is a request set up under webhooks
+type Webhook struct {
+	Method  string            `mapstructure:"method"`
+	URL     string            `mapstructure:"url"`
+	Headers map[string]string `mapstructure:"headers"`
+	Body    string            `mapstructure:"body"`
+
+	// in milliseconds, 10 seconds if left out
+	Timeout int `mapstructure:"timeout"`
+}
+
+// validate checks the webhook's method and url, and uppercases its method
+func (w *Webhook) validate() error {
+	w.Method = strings.ToUpper(strings.TrimSpace(w.Method))
+	if w.Method == "" {
+		w.Method = defaultWebhookMethod
+	}
+
+	if !funk.ContainsString(webhookMethods, w.Method) {
+		return errInvalidWebhookMethod
+	}
+
+	parsed, err := url.Parse(w.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return errInvalidWebhookURL
+	}
+
+	if w.Timeout < 0 {
+		return errors.New("timeout can't be negative")
+	}
+
+	return nil
+}
+
+// parseWebhookAction checks an http: action, returning what comes after the prefix: either <method>:<url>
+// or the name of a webhook, which is only looked up when the button is pressed (like exec:<name> is)
+func parseWebhookAction(entry string) (string, error) {
+	value := strings.TrimSpace(strings.TrimPrefix(entry, actionHTTPPrefix))
+	if value == "" {
+		return "", errMissingWebhook
+	}
+
+	if webhook, ok := parseInlineWebhook(value); ok {
+		if err := webhook.validate(); err != nil {
+			return "", err
+		}
+	}
+
+	return value, nil
+}
+
+// parseInlineWebhook splits <method>:<url> into a webhook, returning false if the value is a webhook's name instead
+func parseInlineWebhook(value string) (Webhook, bool) {
+	separatorIdx := strings.Index(value, ":")
+	if separatorIdx == -1 {
+		return Webhook{}, false
+	}
+
+	return Webhook{Method: value[:separatorIdx], URL: value[separatorIdx+1:]}, true
+}
+
+// webhook resolves an http: action's value, which is either the name of a webhook set up under webhooks or a
+// <method>:<url> of its own
+func (cc *CanonicalConfig) webhook(value string) (Webhook, bool) {
+
+	// viper lowercases every key it reads, so names are matched regardless of case
+	if named, ok := cc.Webhooks[strings.ToLower(value)]; ok {
+		return named, true
+	}
+
+	webhook, ok := parseInlineWebhook(value)
+	if !ok || webhook.validate() != nil {
+		return Webhook{}, false
+	}
+
+	return webhook, true
+}
+
+// redactedWebhooks returns the named webhooks in a shape that's safe to write to logs
+func (cc *CanonicalConfig) redactedWebhooks() map[string]Webhook {
+	redacted := make(map[string]Webhook, len(cc.Webhooks))
+	for name, webhook := range cc.Webhooks {
+		redacted[name] = webhook.redacted()
+	}
+
+	return redacted
+}
+
+// fillIn returns a copy of the webhook with the pressed button's details filled in
+func (w Webhook) fillIn(buttonEvent ButtonPressEvent, mappingIndexBase int) Webhook {
+	replacer := strings.NewReplacer(
+		webhookFormatButton, strconv.Itoa(buttonEvent.ButtonID+mappingIndexBase),
+		webhookFormatState, strconv.Itoa(buttonEvent.ButtonValue),
+		webhookFormatPrevious, strconv.Itoa(buttonEvent.PreviousValue),
+	)
+
+	filled := w
+	filled.URL = replacer.Replace(w.URL)
+	filled.Body = replacer.Replace(w.Body)
+
+	filled.Headers = map[string]string{}
+	for name, value := range w.Headers {
+		filled.Headers[name] = replacer.Replace(value)
+	}
+
+	return filled
+}
+
+// callWebhook sends an http: action's request in the background
+func (sio *SerialIO) callWebhook(logger *zap.SugaredLogger, buttonEvent ButtonPressEvent, value string) {
+	webhook, ok := sio.deej.config.webhook(value)
+	if !ok {
+		logger.Warnw("Button calls a webhook that isn't set up, ignoring", "webhook", value)
+		return
+	}
+
+	webhook = webhook.fillIn(buttonEvent, sio.deej.config.MappingIndexBase)
+
+	timeout := defaultWebhookTimeout
+	if webhook.Timeout > 0 {
+		timeout = time.Duration(webhook.Timeout) * time.Millisecond
+	}
+
+	go func() {
+		logger.Infow("Calling webhook", "method", webhook.Method, "url", redactURL(webhook.URL))
+
+		status, err := sendWebhook(webhook, timeout)
+		if err != nil {
+			logger.Warnw("Webhook failed", "method", webhook.Method, "url", redactURL(webhook.URL), "error", err)
+			return
+		}
+
+		logger.Debugw("Webhook called", "method", webhook.Method, "url", redactURL(webhook.URL), "status", status)
+	}()
+}
+
+// redactURL leaves out a url's query and credentials, which may have tokens in them
+func redactURL(rawURL string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "<invalid url>"
+	}
+
+	parsed.User = nil
+	parsed.RawQuery = ""
+
+	return parsed.String()
+}
@@ -0,0 +1,51 @@
+//go:build !slim
+// +build !slim
+
+package deej
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// sendWebhook sends a webhook's request, and returns the status the server answered with. anything other than
+// a 2xx status counts as a failure
+func sendWebhook(webhook Webhook, timeout time.Duration) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var body io.Reader
+	if webhook.Body != "" {
+		body = strings.NewReader(webhook.Body)
+	}
+
+	request, err := http.NewRequest(webhook.Method, webhook.URL, body)
+	if err != nil {
+		return 0, fmt.Errorf("create request: %w", err)
+	}
+
+	for name, value := range webhook.Headers {
+		request.Header.Set(name, value)
+	}
+
+	response, err := http.DefaultClient.Do(request.WithContext(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("send request: %w", err)
+	}
+
+	defer response.Body.Close()
+
+	// read what's left so the connection can be reused
+	io.Copy(ioutil.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("server answered with %s", response.Status)
+	}
+
+	return response.StatusCode, nil
+}